@@ -7,4 +7,16 @@ until $(curl -k --output /dev/null --silent --fail ${HEALTH_ENDPOINT}); do
 done
 echo 'Got a pong, executing fluentbit bin'
 
+# the outputs use the credential of the agent once enrolled, the API key
+# otherwise, the bootstrapper restarts fluentbit when it gets one
+DF_AGENT_TOKEN="$DEEPFENCE_KEY"
+CREDENTIAL_FILE=$DF_INSTALL_DIR/etc/deepfence/agent-credential.json
+if [ -s "$CREDENTIAL_FILE" ]; then
+    credential=$(sed -n 's/.*"credential":"\([^"]*\)".*/\1/p' "$CREDENTIAL_FILE")
+    if [ -n "$credential" ]; then
+        DF_AGENT_TOKEN="$credential"
+    fi
+fi
+export DF_AGENT_TOKEN
+
 exec $DF_INSTALL_DIR/opt/td-agent-bit/bin/fluent-bit -c $DF_INSTALL_DIR/etc/td-agent-bit/fluentbit-agent.conf
@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"sync"
 	"sync/atomic"
 
 	"github.com/bytedance/sonic"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	"github.com/deepfence/golang_deepfence_sdk/client"
 	openapi "github.com/deepfence/golang_deepfence_sdk/utils/http"
+	"github.com/weaveworks/scope/common/hostname"
 	"github.com/weaveworks/scope/probe/common"
 	"github.com/weaveworks/scope/report"
 )
@@ -23,12 +27,29 @@ type OpenapiClient struct {
 	client               *openapi.OpenapiHttpClient
 	stopControlListening chan struct{}
 	publishInterval      atomic.Int32
-	publishReportUrl     string
+	consoleUrl           string
 	rawClient            *http.Client
+	buffer               *reportBuffer
+	reportSource         string
+	publishAccess        sync.Mutex
 }
 
+const (
+	reportPath = "/deepfence/ingest/report"
+)
+
 var PushBackError = errors.New("Server push back")
 
+// unreachableError is returned when the console did not get the report,
+// which is then buffered
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string {
+	return e.err.Error()
+}
+
 func getenv(key, fallback string) string {
 	value := os.Getenv(key)
 	if len(value) == 0 {
@@ -42,8 +63,8 @@ func NewOpenapiClient() (*OpenapiClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	publishUrl := fmt.Sprintf(
-		"https://%s:%s/deepfence/ingest/report",
+	consoleUrl := fmt.Sprintf(
+		"https://%s:%s",
 		getenv("MGMT_CONSOLE_URL", "localhost"),
 		getenv("MGMT_CONSOLE_PORT", "443"),
 	)
@@ -51,10 +72,18 @@ func NewOpenapiClient() (*OpenapiClient, error) {
 		client:               openapiClient,
 		stopControlListening: make(chan struct{}),
 		publishInterval:      atomic.Int32{},
-		publishReportUrl:     publishUrl,
+		consoleUrl:           consoleUrl,
 		rawClient:            openapiClient.Client().GetConfig().HTTPClient,
+		buffer:               newReportBuffer(),
+		reportSource:         hostname.Get(),
 	}
 	res.publishInterval.Store(10)
+	// the console numbers the reports per node: the host node, named after
+	// the hostname, or the node of the agents enrolled, which it checks the
+	// reports come from
+	if cred, err := controls.ReadAgentCredential(); err == nil {
+		res.reportSource = cred.NodeID
+	}
 
 	return res, err
 }
@@ -79,20 +108,81 @@ func (ct *OpenapiClient) Publish(r report.Report) error {
 		return err
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPost, ct.publishReportUrl, bytes.NewReader(b.Bytes()))
+	return ct.publish(reportPath, encodingGzip, b.Bytes())
+}
+
+// publish sends the payload after the buffered ones, or buffers it when the
+// console is unreachable
+func (ct *OpenapiClient) publish(path, encoding string, payload []byte) error {
+	ct.publishAccess.Lock()
+	defer ct.publishAccess.Unlock()
+
+	rpt := bufferedReport{
+		Seq:      ct.buffer.nextSeq(),
+		Path:     path,
+		Encoding: encoding,
+		Payload:  payload,
+	}
+
+	// Older reports go first
+	err := ct.replayBuffered()
+	if err == nil {
+		err = ct.send(rpt)
+	}
+	var unreachable *unreachableError
+	if errors.As(err, &unreachable) {
+		if bufErr := ct.buffer.push(rpt); bufErr != nil {
+			log.Error().Msgf("Cannot buffer report: %v", bufErr)
+		}
+	}
+	return err
+}
+
+// replayBuffered sends the reports published while the console was
+// unreachable, stopping at the first failure to keep them in order
+func (ct *OpenapiClient) replayBuffered() error {
+	if !ct.buffer.enabled() || ct.buffer.empty() {
+		return nil
+	}
+	pending := ct.buffer.pending(maxReplayPerPublish)
+	for _, rpt := range pending {
+		if err := ct.send(rpt); err != nil {
+			return err
+		}
+		ct.buffer.remove(rpt)
+	}
+	if len(pending) != 0 {
+		log.Info().Msgf("Replayed %d buffered reports", len(pending))
+	}
+	if !ct.buffer.empty() {
+		// the new report waits for the rest of the backlog
+		return &unreachableError{errors.New("replaying buffered reports")}
+	}
+	return nil
+}
+
+func (ct *OpenapiClient) send(rpt bufferedReport) error {
+	httpReq, err := http.NewRequest(http.MethodPost, ct.consoleUrl+rpt.Path, bytes.NewReader(rpt.Payload))
 	if err != nil {
 		return err
 	}
-	httpReq.Header.Add("Content-Encoding", "gzip")
+	if rpt.Encoding == encodingGzip {
+		httpReq.Header.Add("Content-Encoding", "gzip")
+	}
+	httpReq.Header.Add(controls.ReportSourceHeader, ct.reportSource)
+	httpReq.Header.Add(controls.ReportSequenceHeader, strconv.FormatInt(rpt.Seq, 10))
 
 	resp, err := ct.rawClient.Do(httpReq)
 	if err != nil {
-		return err
+		return &unreachableError{err}
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusServiceUnavailable {
 		return PushBackError
 	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return &unreachableError{fmt.Errorf("console responded %s", resp.Status)}
+	}
 
 	if resp.StatusCode == http.StatusOK {
 		decoder := json.NewDecoder(resp.Body)
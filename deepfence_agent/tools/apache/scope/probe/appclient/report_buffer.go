@@ -0,0 +1,294 @@
+package appclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+)
+
+const (
+	defaultReportBufferSizeMB = 100
+	// the console forgets the report sequences after 48h, and older
+	// topology snapshots are of no use
+	reportBufferMaxAge = 24 * time.Hour
+	// spread the replay over several publish intervals
+	maxReplayPerPublish = 10
+	// the last sequence number given, kept across restarts
+	reportSequenceFile = "sequence"
+)
+
+const (
+	encodingGzip = ".gz"
+)
+
+var errCorruptReport = errors.New("corrupt buffered report")
+
+// bufferedReport is a payload the console could not receive, along with the
+// ingest path it was sent to, its encoding and its sequence number
+type bufferedReport struct {
+	Seq      int64
+	Path     string
+	Encoding string
+	Payload  []byte
+	file     string
+}
+
+// reportBuffer keeps on disk the reports published while the console is
+// unreachable, up to maxBytes and maxAge, dropping the oldest first. They are
+// replayed in order once the console is back, ahead of the new reports.
+type reportBuffer struct {
+	sync.Mutex
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	lastSeq  int64
+}
+
+func newReportBuffer() *reportBuffer {
+	sizeMB, err := strconv.ParseInt(getenv("DF_REPORT_BUFFER_SIZE_MB", ""), 10, 64)
+	if err != nil || sizeMB < 0 {
+		sizeMB = defaultReportBufferSizeMB
+	}
+	return openReportBuffer(os.ExpandEnv("${DF_INSTALL_DIR}/var/lib/deepfence/report-buffer"),
+		sizeMB*1024*1024, reportBufferMaxAge)
+}
+
+func openReportBuffer(dir string, maxBytes int64, maxAge time.Duration) *reportBuffer {
+	b := &reportBuffer{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		log.Error().Msgf("Report buffer disabled: %v", err)
+		b.maxBytes = 0
+		return b
+	}
+	b.removeLeftovers()
+
+	// keep numbering after the reports left by the previous run
+	if data, err := os.ReadFile(filepath.Join(b.dir, reportSequenceFile)); err == nil {
+		b.lastSeq, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	if entries, err := b.list(); err == nil && len(entries) != 0 && entries[len(entries)-1].Seq > b.lastSeq {
+		b.lastSeq = entries[len(entries)-1].Seq
+	}
+	// without any, start after all the numbers a previous install may have
+	// given, they count one per report from the time it started
+	if b.lastSeq == 0 {
+		b.lastSeq = time.Now().UnixMilli()
+	}
+	return b
+}
+
+// nextSeq numbers the reports in publish order. The counter is persisted, so
+// the numbers keep increasing across restarts whatever the clock does.
+func (b *reportBuffer) nextSeq() int64 {
+	b.Lock()
+	defer b.Unlock()
+	b.lastSeq++
+	if b.enabled() {
+		err := writeFileAtomic(filepath.Join(b.dir, reportSequenceFile), []byte(strconv.FormatInt(b.lastSeq, 10)))
+		if err != nil {
+			log.Error().Msgf("Report buffer: %v", err)
+		}
+	}
+	return b.lastSeq
+}
+
+func (b *reportBuffer) enabled() bool {
+	return b.maxBytes > 0
+}
+
+func (b *reportBuffer) push(report bufferedReport) error {
+	if !b.enabled() {
+		return nil
+	}
+	name := fmt.Sprintf("%020d%s%s", report.Seq, strings.ReplaceAll(report.Path, "/", "_"), report.Encoding)
+	if err := writeFileAtomic(filepath.Join(b.dir, name), report.Payload); err != nil {
+		return err
+	}
+	b.trim()
+	return nil
+}
+
+// trim drops the oldest reports above the size limit or too old to replay
+func (b *reportBuffer) trim() {
+	entries, err := b.list()
+	if err != nil {
+		log.Error().Msgf("Report buffer: %v", err)
+		return
+	}
+	size := int64(0)
+	for _, e := range entries {
+		size += e.size
+	}
+	oldest := time.Now().Add(-b.maxAge)
+	dropped := 0
+	for _, e := range entries {
+		if size <= b.maxBytes && !e.modTime.Before(oldest) {
+			break
+		}
+		if err := os.Remove(e.file); err != nil {
+			log.Error().Msgf("Report buffer: %v", err)
+		}
+		size -= e.size
+		dropped++
+	}
+	if dropped != 0 {
+		log.Warn().Msgf("Report buffer full or expired, dropped %d oldest reports", dropped)
+	}
+}
+
+// pending returns the oldest buffered reports, up to n. The expired reports
+// are dropped and so are the ones which cannot be read back, they would
+// block the replay.
+func (b *reportBuffer) pending(n int) []bufferedReport {
+	b.trim()
+	entries, err := b.list()
+	if err != nil {
+		log.Error().Msgf("Report buffer: %v", err)
+		return nil
+	}
+	res := []bufferedReport{}
+	for _, e := range entries {
+		if len(res) == n {
+			break
+		}
+		payload, err := os.ReadFile(e.file)
+		if err == nil {
+			err = checkPayload(e.Encoding, payload)
+		}
+		if err != nil {
+			log.Warn().Msgf("Report buffer: drop %s: %v", filepath.Base(e.file), err)
+			b.remove(e.bufferedReport)
+			continue
+		}
+		e.Payload = payload
+		res = append(res, e.bufferedReport)
+	}
+	return res
+}
+
+// checkPayload tells if the payload is complete, a crash or a full disk can
+// leave truncated files
+func checkPayload(encoding string, payload []byte) error {
+	if encoding != encodingGzip {
+		return nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errCorruptReport, err)
+	}
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		return fmt.Errorf("%w: %v", errCorruptReport, err)
+	}
+	return nil
+}
+
+func (b *reportBuffer) remove(report bufferedReport) {
+	if err := os.Remove(report.file); err != nil && !os.IsNotExist(err) {
+		log.Error().Msgf("Report buffer: %v", err)
+	}
+}
+
+func (b *reportBuffer) empty() bool {
+	entries, err := b.list()
+	return err != nil || len(entries) == 0
+}
+
+// removeLeftovers deletes the files a crash left half written and the ones
+// not named as reports
+func (b *reportBuffer) removeLeftovers() {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		name := f.Name()
+		if name == reportSequenceFile {
+			continue
+		}
+		if _, ok := parseReportFileName(name); ok && !f.IsDir() {
+			continue
+		}
+		log.Warn().Msgf("Report buffer: remove unexpected file %s", name)
+		if err := os.RemoveAll(filepath.Join(b.dir, name)); err != nil {
+			log.Error().Msgf("Report buffer: %v", err)
+		}
+	}
+}
+
+type bufferEntry struct {
+	bufferedReport
+	size    int64
+	modTime time.Time
+}
+
+func (b *reportBuffer) list() ([]bufferEntry, error) {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+	res := []bufferEntry{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		report, ok := parseReportFileName(f.Name())
+		if !ok {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		report.file = filepath.Join(b.dir, f.Name())
+		res = append(res, bufferEntry{
+			bufferedReport: report,
+			size:           info.Size(),
+			modTime:        info.ModTime(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Seq < res[j].Seq
+	})
+	return res, nil
+}
+
+// parseReportFileName reads the sequence number, the ingest path and the
+// encoding of a buffered report from its file name
+func parseReportFileName(name string) (bufferedReport, bool) {
+	encoding := filepath.Ext(name)
+	if encoding != encodingGzip || len(name) < 20 {
+		return bufferedReport{}, false
+	}
+	seq, err := strconv.ParseInt(name[:20], 10, 64)
+	if err != nil {
+		return bufferedReport{}, false
+	}
+	path := strings.ReplaceAll(strings.TrimSuffix(name[20:], encoding), "_", "/")
+	if !strings.HasPrefix(path, "/") {
+		return bufferedReport{}, false
+	}
+	return bufferedReport{Seq: seq, Path: path, Encoding: encoding}, true
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path))
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
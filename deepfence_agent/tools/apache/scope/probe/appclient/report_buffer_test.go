@@ -0,0 +1,221 @@
+package appclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+func gzipped(t *testing.T, data string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func pendingSeqs(b *reportBuffer) []int64 {
+	res := []int64{}
+	for _, rpt := range b.pending(100) {
+		res = append(res, rpt.Seq)
+	}
+	return res
+}
+
+func TestReportBufferOrder(t *testing.T) {
+	b := openReportBuffer(t.TempDir(), 1<<20, time.Hour)
+
+	first := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: gzipped(t, "{}")}
+	second := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: gzipped(t, "{}")}
+	third := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: gzipped(t, "{}")}
+	for _, rpt := range []bufferedReport{third, first, second} {
+		if err := b.push(rpt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending := b.pending(2)
+	if len(pending) != 2 {
+		t.Fatalf("pending %d reports, want 2", len(pending))
+	}
+	if pending[0].Seq != first.Seq || pending[0].Path != reportPath || pending[0].Encoding != encodingGzip {
+		t.Errorf("first pending %+v", pending[0])
+	}
+	if pending[1].Seq != second.Seq || pending[1].Path != reportPath || pending[1].Encoding != encodingGzip {
+		t.Errorf("second pending %+v", pending[1])
+	}
+	if !bytes.Equal(pending[1].Payload, second.Payload) {
+		t.Errorf("payload %q, want %q", pending[1].Payload, second.Payload)
+	}
+
+	b.remove(pending[0])
+	b.remove(pending[1])
+	if have, want := pendingSeqs(b), []int64{third.Seq}; !reflect.DeepEqual(have, want) {
+		t.Errorf("pending %v, want %v", have, want)
+	}
+}
+
+func TestReportBufferEviction(t *testing.T) {
+	payload := gzipped(t, "{}")
+	b := openReportBuffer(t.TempDir(), int64(2*len(payload)), time.Hour)
+
+	seqs := []int64{}
+	for i := 0; i < 3; i++ {
+		rpt := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: payload}
+		if err := b.push(rpt); err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, rpt.Seq)
+	}
+	if have, want := pendingSeqs(b), seqs[1:]; !reflect.DeepEqual(have, want) {
+		t.Errorf("pending %v, want %v", have, want)
+	}
+
+	// expired reports are dropped too
+	old := time.Now().Add(-2 * time.Hour)
+	entries, err := b.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(entries[0].file, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := pendingSeqs(b), seqs[2:]; !reflect.DeepEqual(have, want) {
+		t.Errorf("pending %v, want %v", have, want)
+	}
+}
+
+func TestReportBufferCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	b := openReportBuffer(dir, 1<<20, time.Hour)
+
+	valid := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: gzipped(t, "{}")}
+	truncated := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: gzipped(t, "{}")[:8]}
+	invalid := bufferedReport{Seq: b.nextSeq(), Path: reportPath, Encoding: encodingGzip, Payload: []byte("{")}
+	for _, rpt := range []bufferedReport{truncated, valid, invalid} {
+		if err := b.push(rpt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if have, want := pendingSeqs(b), []int64{valid.Seq}; !reflect.DeepEqual(have, want) {
+		t.Errorf("pending %v, want %v", have, want)
+	}
+	entries, err := b.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("corrupt reports kept: %+v", entries)
+	}
+
+	// the files a crash left behind are removed on start
+	for _, name := range []string{".00000000000000000042_deepfence_ingest_report.gz", "garbage"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	openReportBuffer(dir, 1<<20, time.Hour)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	want := []string{filepath.Base(entries[0].file), reportSequenceFile}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("files %v, want %v", names, want)
+	}
+}
+
+func TestReportBufferSequence(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().UnixMilli()
+	b := openReportBuffer(dir, 1<<20, time.Hour)
+	first := b.nextSeq()
+	if first <= start {
+		t.Errorf("first sequence %d, want after %d", first, start)
+	}
+	second := b.nextSeq()
+	if second != first+1 {
+		t.Errorf("second sequence %d, want %d", second, first+1)
+	}
+
+	// the counter survives restarts, whatever the clock
+	if have := openReportBuffer(dir, 1<<20, time.Hour).nextSeq(); have != second+1 {
+		t.Errorf("sequence after restart %d, want %d", have, second+1)
+	}
+}
+
+func TestReplayBuffered(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		received []int64
+		down     = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if down {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		seq, _ := strconv.ParseInt(r.Header.Get(controls.ReportSequenceHeader), 10, 64)
+		received = append(received, seq)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ct := &OpenapiClient{
+		consoleUrl:   srv.URL,
+		rawClient:    srv.Client(),
+		buffer:       openReportBuffer(t.TempDir(), 1<<20, time.Hour),
+		reportSource: "host",
+	}
+	for i := 0; i < maxReplayPerPublish+2; i++ {
+		if err := ct.Publish(report.MakeReport()); err == nil {
+			t.Fatal("publish succeeded with the console down")
+		}
+	}
+	buffered := pendingSeqs(ct.buffer)
+
+	mtx.Lock()
+	down = false
+	mtx.Unlock()
+
+	// the backlog goes first, the new reports wait for it
+	if err := ct.Publish(report.MakeReport()); err == nil {
+		t.Fatal("publish went ahead of the backlog")
+	}
+	if err := ct.Publish(report.MakeReport()); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	last := ct.buffer.lastSeq
+	want := append(buffered, last-1, last)
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("received %v, want %v", received, want)
+	}
+	if !ct.buffer.empty() {
+		t.Error("buffer not empty after replay")
+	}
+}
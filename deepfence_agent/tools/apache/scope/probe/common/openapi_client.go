@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	oahttp "github.com/deepfence/golang_deepfence_sdk/utils/http"
 )
@@ -23,8 +24,11 @@ func NewClient() (*oahttp.OpenapiHttpClient, error) {
 		return nil, errors.New("MGMT_CONSOLE_PORT not set")
 	}
 
+	// enrolled agents use their credential in place of the API key
 	api_token := os.Getenv("DEEPFENCE_KEY")
-	if strings.Trim(api_token, "\"") == "" && oahttp.IsConsoleAgent(url) {
+	if cred, err := ctl.ReadAgentCredential(); err == nil {
+		api_token = cred.Credential
+	} else if strings.Trim(api_token, "\"") == "" && oahttp.IsConsoleAgent(url) {
 		internalURL := os.Getenv("MGMT_CONSOLE_URL_INTERNAL")
 		internalPort := os.Getenv("MGMT_CONSOLE_PORT_INTERNAL")
 		log.Info().Msg("fetch console agent token")
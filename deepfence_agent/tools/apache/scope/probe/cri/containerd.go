@@ -0,0 +1,205 @@
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	grpcmetadata "google.golang.org/grpc/metadata"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	// containerdNamespaceHeader selects the namespace of the containerd API calls
+	containerdNamespaceHeader = "containerd-namespace"
+	// criNamespace holds the containers of the CRI plugin, reported through the CRI
+	criNamespace = "k8s.io"
+	// dockerNamespace holds the containers of dockerd, reported by the Docker probe
+	dockerNamespace = "moby"
+)
+
+// ContainerdClient lists the containers of the containerd namespaces the CRI
+// does not serve, such as the ones of nerdctl or of the containerd clients
+type ContainerdClient struct {
+	namespaces namespacesapi.NamespacesClient
+	containers containersapi.ContainersClient
+	images     imagesapi.ImagesClient
+	tasks      tasksapi.TasksClient
+}
+
+// NewContainerdClient creates a client to the containerd API served on the
+// socket of its CRI plugin
+func NewContainerdClient(endpoint string) (*ContainerdClient, error) {
+	conn, err := dialEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerdClient{
+		namespaces: namespacesapi.NewNamespacesClient(conn),
+		containers: containersapi.NewContainersClient(conn),
+		images:     imagesapi.NewImagesClient(conn),
+		tasks:      tasksapi.NewTasksClient(conn),
+	}, nil
+}
+
+// containerdTopology reports the containers of the namespaces not covered by
+// the CRI or the Docker probe
+func (r *Reporter) containerdTopology() (report.Topology, error) {
+	result := report.MakeTopology()
+	ctx := context.Background()
+	resp, err := r.containerd.namespaces.List(ctx, &namespacesapi.ListNamespacesRequest{})
+	if err != nil {
+		return result, err
+	}
+
+	live := map[string]bool{}
+	for _, ns := range resp.Namespaces {
+		if ns.Name == criNamespace || ns.Name == dockerNamespace {
+			continue
+		}
+		nodes, err := r.containerdNamespaceNodes(ns.Name, live)
+		if err != nil {
+			log.Warn().Msgf("CRI: cannot list the containers of containerd namespace %s: %v", ns.Name, err)
+			continue
+		}
+		for _, node := range nodes {
+			result.AddNode(node)
+		}
+	}
+	for id := range r.containerdCache {
+		if !live[id] {
+			delete(r.containerdCache, id)
+		}
+	}
+	return result, nil
+}
+
+// containerdDetails are the attributes of a containerd container read from
+// its image and its spec, which do not change over its life
+type containerdDetails struct {
+	imageDigest string
+	details     containerDetails
+}
+
+// containerdDetails returns the details of the container, cached as they do
+// not change
+func (r *Reporter) containerdDetails(ctx context.Context, namespace string, c containersapi.Container) containerdDetails {
+	key := containerdCacheKey(namespace, c.ID)
+	if d, ok := r.containerdCache[key]; ok {
+		return d
+	}
+	var d containerdDetails
+	if image, err := r.containerd.images.Get(ctx, &imagesapi.GetImageRequest{Name: c.Image}); err == nil && image.Image != nil {
+		d.imageDigest = image.Image.Target.Digest.String()
+	}
+	if c.Spec != nil {
+		var spec ociSpec
+		if err := json.Unmarshal(c.Spec.Value, &spec); err == nil {
+			d.details = spec.details()
+		}
+	}
+	if c.Runtime != nil {
+		d.details.runtimeClass = c.Runtime.Name
+	}
+	r.containerdCache[key] = d
+	return d
+}
+
+func (r *Reporter) containerdNamespaceNodes(namespace string, live map[string]bool) ([]report.TopologyNode, error) {
+	ctx := grpcmetadata.AppendToOutgoingContext(context.Background(), containerdNamespaceHeader, namespace)
+	containers, err := r.containerd.containers.List(ctx, &containersapi.ListContainersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := r.containerd.tasks.List(ctx, &tasksapi.ListTasksRequest{})
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]string, len(tasks.Tasks))
+	for _, t := range tasks.Tasks {
+		states[t.ID] = getTaskState(t.Status)
+	}
+
+	nodes := []report.TopologyNode{}
+	for _, c := range containers.Containers {
+		live[containerdCacheKey(namespace, c.ID)] = true
+		state, ok := states[c.ID]
+		if !ok {
+			// no task yet or anymore
+			state = report.StateCreated
+		}
+		if report.SkipReportContainerState[state] {
+			continue
+		}
+
+		imageName := docker.ImageNameWithoutTag(c.Image)
+		imageTag := docker.ImageNameTag(c.Image)
+		var labels string
+		labelsJson, err := json.Marshal(c.Labels)
+		if err == nil {
+			labels = string(labelsJson)
+		}
+
+		metadata := report.Metadata{
+			Timestamp:                 time.Now().UTC().Format(time.RFC3339Nano),
+			NodeType:                  report.Container,
+			NodeID:                    c.ID,
+			NodeName:                  c.ID + " / " + r.hostID,
+			HostName:                  r.hostID,
+			DockerContainerName:       c.ID,
+			DockerContainerState:      state,
+			DockerContainerStateHuman: state,
+			DockerContainerCreated:    c.CreatedAt.UTC().Format("2006-01-02T15:04:05") + "Z",
+			ImageName:                 imageName,
+			ImageTag:                  imageTag,
+			ImageNameWithTag:          imageName + ":" + imageTag,
+			IsConsoleVm:               r.isConsoleVm,
+			KubernetesClusterName:     r.kubernetesClusterName,
+			KubernetesClusterId:       r.kubernetesClusterId,
+			DockerLabels:              labels,
+			ContainerRuntime:          RuntimeContainerd,
+			ContainerNamespace:        namespace,
+		}
+		details := r.containerdDetails(ctx, namespace, c)
+		metadata.ImageDigest = details.imageDigest
+		details.details.apply(&metadata)
+
+		nodes = append(nodes, report.TopologyNode{
+			Metadata: metadata,
+			Parents: &report.Parent{
+				KubernetesCluster: r.kubernetesClusterId,
+				Host:              r.hostID,
+			},
+		})
+	}
+	return nodes, nil
+}
+
+// containerdCacheKey keys the cache on the namespace as well, the ids are
+// only unique within a namespace
+func containerdCacheKey(namespace, id string) string {
+	return namespace + "/" + id
+}
+
+func getTaskState(status task.Status) string {
+	switch status {
+	case task.StatusRunning:
+		return report.StateRunning
+	case task.StatusPaused, task.StatusPausing:
+		return report.StatePaused
+	case task.StatusCreated:
+		return report.StateCreated
+	case task.StatusStopped:
+		return report.StateExited
+	default:
+		return report.StateUnknown
+	}
+}
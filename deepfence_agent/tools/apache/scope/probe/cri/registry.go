@@ -1,9 +1,13 @@
 package cri
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
@@ -14,8 +18,29 @@ import (
 const (
 	unixProtocol = "unix"
 	tcpProtocol  = "tcp"
+
+	detectTimeout = 2 * time.Second
+)
+
+// Container runtimes serving the CRI
+const (
+	RuntimeContainerd = "containerd"
+	RuntimeCRIO       = "cri-o"
+	RuntimeDocker     = "docker"
 )
 
+// defaultEndpoints are the usual CRI sockets, in the order they are probed
+var defaultEndpoints = []string{
+	"unix:///run/containerd/containerd.sock",
+	"unix:///run/k3s/containerd/containerd.sock",
+	"unix:///var/snap/microk8s/common/run/containerd.sock",
+	"unix:///var/run/crio/crio.sock",
+	"unix:///var/run/cri-dockerd.sock",
+	"unix:///var/run/dockershim.sock",
+}
+
+var ErrNoEndpoint = errors.New("no CRI endpoint found")
+
 func dial(addr string, timeout time.Duration) (net.Conn, error) {
 	return net.DialTimeout(unixProtocol, addr, timeout)
 }
@@ -68,16 +93,70 @@ func parseEndpoint(endpoint string) (string, string, error) {
 	}
 }
 
-// NewCRIClient creates client to CRI.
-func NewCRIClient(endpoint string) (client.RuntimeServiceClient, client.ImageServiceClient, error) {
+func dialEndpoint(endpoint string) (*grpc.ClientConn, error) {
 	addr, dailer, err := getAddressAndDialer(endpoint)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithDialer(dailer))
+	return grpc.Dial(addr, grpc.WithInsecure(), grpc.WithDialer(dailer))
+}
+
+// NewCRIClient creates client to CRI.
+func NewCRIClient(endpoint string) (client.RuntimeServiceClient, client.ImageServiceClient, error) {
+	conn, err := dialEndpoint(endpoint)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	return client.NewRuntimeServiceClient(conn), client.NewImageServiceClient(conn), nil
 }
+
+// DetectEndpoint returns the first of the usual CRI sockets answering to
+// the version request
+func DetectEndpoint() (string, error) {
+	for _, endpoint := range defaultEndpoints {
+		addr, err := parseEndpointWithFallbackProtocol(endpoint, unixProtocol)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(addr); err != nil {
+			continue
+		}
+		conn, err := dialEndpoint(endpoint)
+		if err != nil {
+			continue
+		}
+		name := runtimeName(client.NewRuntimeServiceClient(conn))
+		conn.Close()
+		if name != "" {
+			return endpoint, nil
+		}
+	}
+	return "", ErrNoEndpoint
+}
+
+// runtimeName returns the runtime behind the CRI client, empty when it does
+// not answer
+func runtimeName(cri client.RuntimeServiceClient) string {
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+	resp, err := cri.Version(ctx, &client.VersionRequest{})
+	if err != nil {
+		return ""
+	}
+	return normalizeRuntimeName(resp.RuntimeName)
+}
+
+func normalizeRuntimeName(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "containerd"):
+		return RuntimeContainerd
+	case strings.Contains(name, "cri-o"), strings.Contains(name, "crio"):
+		return RuntimeCRIO
+	case strings.Contains(name, "docker"):
+		return RuntimeDocker
+	default:
+		return name
+	}
+}
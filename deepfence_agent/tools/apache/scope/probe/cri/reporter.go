@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	dfUtils "github.com/deepfence/df-utils"
 	"github.com/dustin/go-humanize"
 	client "github.com/weaveworks/scope/cri/runtime"
@@ -18,24 +19,52 @@ import (
 type Reporter struct {
 	isConsoleVm           bool
 	hostID                string
+	runtime               string
 	cri                   client.RuntimeServiceClient
 	criImageClient        client.ImageServiceClient
+	containerd            *ContainerdClient
+	detailsCache          map[string]containerDetails
+	containerdCache       map[string]containerdDetails
 	kubernetesClusterId   string
 	kubernetesClusterName string
 }
 
-// NewReporter makes a new Reporter
-func NewReporter(cri client.RuntimeServiceClient, hostID string, criImageClient client.ImageServiceClient) *Reporter {
+// NewReporter makes a new Reporter for the CRI endpoint, detected among the
+// usual ones when empty
+func NewReporter(endpoint string, hostID string) (*Reporter, error) {
+	if endpoint == "" {
+		var err error
+		endpoint, err = DetectEndpoint()
+		if err != nil {
+			return nil, err
+		}
+	}
+	cri, criImageClient, err := NewCRIClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
 	reporter := &Reporter{
 		hostID:                hostID,
+		runtime:               runtimeName(cri),
 		cri:                   cri,
 		criImageClient:        criImageClient,
+		detailsCache:          map[string]containerDetails{},
+		containerdCache:       map[string]containerdDetails{},
 		isConsoleVm:           dfUtils.IsThisConsoleAgent(),
 		kubernetesClusterName: os.Getenv(report.KubernetesClusterName),
 		kubernetesClusterId:   os.Getenv(report.KubernetesClusterId),
 	}
+	log.Info().Msgf("CRI: %s runtime on %s", reporter.runtime, endpoint)
 
-	return reporter
+	if reporter.runtime == RuntimeContainerd {
+		reporter.containerd, err = NewContainerdClient(endpoint)
+		if err != nil {
+			log.Warn().Msgf("CRI: containerd namespaces will not be reported: %v", err)
+		}
+	}
+
+	return reporter, nil
 }
 
 // Name of this reporter, for metrics gathering
@@ -56,9 +85,30 @@ func (r *Reporter) Report() (report.Report, error) {
 
 	result.Container.Merge(containerTopol)
 	result.ContainerImage.Merge(imageTopol)
+
+	if r.containerd != nil {
+		containerdTopol, err := r.containerdTopology()
+		if err != nil {
+			log.Warn().Msgf("CRI: cannot list the containerd namespaces: %v", err)
+		} else {
+			result.Container.Merge(containerdTopol)
+		}
+	}
 	return result, nil
 }
 
+// namespace is the containerd namespace the containers of the CRI are in
+func (r *Reporter) namespace() string {
+	switch r.runtime {
+	case RuntimeContainerd:
+		return criNamespace
+	case RuntimeDocker:
+		return dockerNamespace
+	default:
+		return ""
+	}
+}
+
 func (r *Reporter) containerTopology(imageMetadataMap map[string]ImageMetadata) (report.Topology, error) {
 	result := report.MakeTopology()
 	ctx := context.Background()
@@ -67,13 +117,24 @@ func (r *Reporter) containerTopology(imageMetadataMap map[string]ImageMetadata)
 		return result, err
 	}
 
+	live := make(map[string]bool, len(resp.Containers))
+	handlers := map[string]string{}
 	for _, c := range resp.Containers {
+		live[c.Id] = true
 		node := r.getNode(c, imageMetadataMap)
 		if node == nil {
 			continue
 		}
+		if details, ok := r.containerDetails(ctx, c, handlers); ok {
+			details.apply(&node.Metadata)
+		}
 		result.AddNode(*node)
 	}
+	for id := range r.detailsCache {
+		if !live[id] {
+			delete(r.detailsCache, id)
+		}
+	}
 
 	return result, nil
 }
@@ -84,14 +145,19 @@ func (r *Reporter) getNode(c *client.Container, imageMetadataMap map[string]Imag
 		return nil
 	}
 	imageMetadata, ok := imageMetadataMap[c.ImageRef]
-	var imageID, imageName, imageTag string
+	if !ok {
+		imageMetadata, ok = imageMetadataMap[trimImageID(c.ImageRef)]
+	}
+	var imageID, imageName, imageTag, imageDigest string
 	if ok {
 		imageID = imageMetadata.ImageID
 		imageName = imageMetadata.ImageName
 		imageTag = imageMetadata.ImageTag
+		imageDigest = imageMetadata.ImageDigest
 	} else {
 		imageID = trimImageID(c.Image.GetImage())
 		imageName, imageTag = docker.ParseImageDigest(c.ImageRef)
+		imageDigest = repoDigest(c.ImageRef)
 	}
 	var dockerLabels string
 	podName := c.Labels["io.kubernetes.pod.name"]
@@ -125,12 +191,15 @@ func (r *Reporter) getNode(c *client.Container, imageMetadataMap map[string]Imag
 		ImageTag:                  imageTag,
 		DockerImageID:             imageID,
 		ImageNameWithTag:          imageName + ":" + imageTag,
+		ImageDigest:               imageDigest,
 		IsConsoleVm:               r.isConsoleVm,
 		KubernetesClusterName:     r.kubernetesClusterName,
 		KubernetesClusterId:       r.kubernetesClusterId,
 		DockerLabels:              dockerLabels,
 		PodName:                   podName,
 		PodID:                     podUid,
+		ContainerRuntime:          r.runtime,
+		ContainerNamespace:        r.namespace(),
 	}
 	return &report.TopologyNode{
 		Metadata: metadata,
@@ -159,10 +228,11 @@ func getState(c *client.Container) string {
 }
 
 type ImageMetadata struct {
-	ImageName string
-	ImageTag  string
-	ImageID   string
-	ImageRef  string
+	ImageName   string
+	ImageTag    string
+	ImageID     string
+	ImageRef    string
+	ImageDigest string
 }
 
 func (r *Reporter) containerImageTopology() (report.Topology, map[string]ImageMetadata, error) {
@@ -183,6 +253,8 @@ func (r *Reporter) containerImageTopology() (report.Topology, map[string]ImageMe
 		if imageMetadata.ImageRef != "" {
 			imageMetadataMap[imageMetadata.ImageRef] = *imageMetadata
 		}
+		// containerd refers to the images of the containers by ID
+		imageMetadataMap[imageMetadata.ImageID] = *imageMetadata
 		result.AddNode(*imageNode)
 	}
 
@@ -207,6 +279,7 @@ func (r *Reporter) getImage(image *client.Image) (*report.TopologyNode, *ImageMe
 	var imageRef string
 	if len(image.RepoDigests) > 0 {
 		imageRef = image.RepoDigests[0]
+		metadata.ImageDigest = repoDigest(imageRef)
 	}
 	if len(image.RepoTags) > 0 {
 		imageFullName := image.RepoTags[0]
@@ -226,10 +299,11 @@ func (r *Reporter) getImage(image *client.Image) (*report.TopologyNode, *ImageMe
 			Parents:  &report.Parent{Host: r.hostID},
 		},
 		&ImageMetadata{
-			ImageName: metadata.ImageName,
-			ImageTag:  metadata.ImageTag,
-			ImageID:   imageID,
-			ImageRef:  imageRef,
+			ImageName:   metadata.ImageName,
+			ImageTag:    metadata.ImageTag,
+			ImageID:     imageID,
+			ImageRef:    imageRef,
+			ImageDigest: metadata.ImageDigest,
 		}
 }
 
@@ -239,6 +313,14 @@ func trimImageID(id string) string {
 	return strings.TrimPrefix(id, "sha256:")
 }
 
+// repoDigest returns the digest of a name@digest image reference
+func repoDigest(ref string) string {
+	if i := strings.LastIndex(ref, "@"); i != -1 {
+		return ref[i+1:]
+	}
+	return ""
+}
+
 func getShortImageID(id string) string {
 	return id[:12]
 }
@@ -0,0 +1,220 @@
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	client "github.com/weaveworks/scope/cri/runtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// criOHandlerAnnotation is set by CRI-O on the sandboxes with the runtime
+// handler of their runtime class
+const criOHandlerAnnotation = "io.kubernetes.cri-o.RuntimeHandler"
+
+// defaultCapabilities are granted by containerd, CRI-O and Docker to all the
+// containers, only the added ones are reported like for Docker
+var defaultCapabilities = map[string]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true,
+	"FSETID": true, "KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true,
+	"NET_RAW": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true,
+	"SETUID": true, "SYS_CHROOT": true,
+}
+
+// containerDetails are the attributes of a container only found in its
+// verbose status, which do not change over its life
+type containerDetails struct {
+	command        string
+	networkMode    string
+	runtimeClass   string
+	mounts         []string
+	capabilities   []string
+	privileged     bool
+	runAsRoot      bool
+	readOnlyRootfs bool
+}
+
+func (d containerDetails) apply(m *report.Metadata) {
+	m.DockerContainerCommand = d.command
+	m.DockerContainerNetworkMode = d.networkMode
+	m.ContainerRuntimeClass = d.runtimeClass
+	m.ContainerMounts = d.mounts
+	m.ContainerCapabilities = d.capabilities
+	m.ContainerPrivileged = d.privileged
+	m.ContainerRunAsRoot = d.runAsRoot
+	m.ContainerReadOnlyRootfs = d.readOnlyRootfs
+}
+
+// containerInfo is the verbose info of containerd and CRI-O, both carry the
+// OCI spec of the container
+type containerInfo struct {
+	// CRI-O
+	Privileged bool `json:"privileged"`
+	// containerd
+	Config struct {
+		Linux struct {
+			SecurityContext struct {
+				Privileged bool `json:"privileged"`
+			} `json:"security_context"`
+		} `json:"linux"`
+	} `json:"config"`
+	RuntimeSpec ociSpec `json:"runtimeSpec"`
+}
+
+// sandboxInfo is the verbose info of a pod sandbox, containerd gives the
+// runtime handler, CRI-O an annotation of the spec
+type sandboxInfo struct {
+	RuntimeHandler string  `json:"runtimeHandler"`
+	RuntimeSpec    ociSpec `json:"runtimeSpec"`
+}
+
+func (s sandboxInfo) runtimeHandler() string {
+	if s.RuntimeHandler != "" {
+		return s.RuntimeHandler
+	}
+	return s.RuntimeSpec.Annotations[criOHandlerAnnotation]
+}
+
+// ociSpec is the part of the OCI runtime spec describing the security of the
+// container
+type ociSpec struct {
+	Process *struct {
+		Args []string `json:"args"`
+		User struct {
+			UID uint32 `json:"uid"`
+		} `json:"user"`
+		Capabilities struct {
+			Effective []string `json:"effective"`
+		} `json:"capabilities"`
+	} `json:"process"`
+	Root struct {
+		Readonly bool `json:"readonly"`
+	} `json:"root"`
+	Mounts []struct {
+		Destination string   `json:"destination"`
+		Type        string   `json:"type"`
+		Source      string   `json:"source"`
+		Options     []string `json:"options"`
+	} `json:"mounts"`
+	Annotations map[string]string `json:"annotations"`
+	Linux       *struct {
+		Namespaces []struct {
+			Type string `json:"type"`
+		} `json:"namespaces"`
+		MaskedPaths   []string         `json:"maskedPaths"`
+		ReadonlyPaths []string         `json:"readonlyPaths"`
+		Seccomp       *json.RawMessage `json:"seccomp"`
+	} `json:"linux"`
+}
+
+// details reads the spec, the runtimes may leave parts of it out
+func (s ociSpec) details() containerDetails {
+	d := containerDetails{readOnlyRootfs: s.Root.Readonly}
+	if s.Process != nil {
+		d.command = strings.Join(s.Process.Args, " ")
+		d.runAsRoot = s.Process.User.UID == 0
+		for _, c := range s.Process.Capabilities.Effective {
+			c = strings.TrimPrefix(c, "CAP_")
+			if !defaultCapabilities[c] {
+				d.capabilities = append(d.capabilities, c)
+			}
+		}
+	}
+	if s.Linux != nil {
+		// containers sharing the network of the host have no network namespace
+		d.networkMode = "host"
+		for _, ns := range s.Linux.Namespaces {
+			if ns.Type == "network" {
+				d.networkMode = ""
+			}
+		}
+		// the spec has no privileged flag, privileged containers get every
+		// capability and neither masked paths nor a seccomp profile
+		d.privileged = s.Process != nil && slices.Contains(s.Process.Capabilities.Effective, "CAP_SYS_ADMIN") &&
+			len(s.Linux.MaskedPaths) == 0 && len(s.Linux.ReadonlyPaths) == 0 && s.Linux.Seccomp == nil
+	}
+	for _, m := range s.Mounts {
+		if !isBindMount(m.Type, m.Options) {
+			continue
+		}
+		d.mounts = append(d.mounts, formatMount(m.Source, m.Destination, hasOption(m.Options, "ro")))
+	}
+	return d
+}
+
+// parseContainerDetails reads the details of a container from its verbose
+// status, the mounts of the CRI status take precedence over the ones of the
+// spec which include the ones the runtime adds
+func parseContainerDetails(status *client.ContainerStatusResponse) containerDetails {
+	var info containerInfo
+	if raw, ok := status.GetInfo()["info"]; ok {
+		_ = json.Unmarshal([]byte(raw), &info)
+	}
+	d := info.RuntimeSpec.details()
+	d.privileged = info.Privileged || info.Config.Linux.SecurityContext.Privileged
+
+	if mounts := status.GetStatus().GetMounts(); len(mounts) > 0 {
+		d.mounts = nil
+		for _, m := range mounts {
+			d.mounts = append(d.mounts, formatMount(m.HostPath, m.ContainerPath, m.Readonly))
+		}
+	}
+	return d
+}
+
+func parseSandboxRuntimeHandler(status *client.PodSandboxStatusResponse) string {
+	var info sandboxInfo
+	if raw, ok := status.GetInfo()["info"]; ok {
+		_ = json.Unmarshal([]byte(raw), &info)
+	}
+	return info.runtimeHandler()
+}
+
+// containerDetails returns the details of the container, cached as they do
+// not change
+func (r *Reporter) containerDetails(ctx context.Context, c *client.Container, handlers map[string]string) (containerDetails, bool) {
+	if d, ok := r.detailsCache[c.Id]; ok {
+		return d, true
+	}
+	status, err := r.cri.ContainerStatus(ctx, &client.ContainerStatusRequest{ContainerId: c.Id, Verbose: true})
+	if err != nil {
+		return containerDetails{}, false
+	}
+	d := parseContainerDetails(status)
+
+	handler, ok := handlers[c.PodSandboxId]
+	if !ok && c.PodSandboxId != "" {
+		sandbox, err := r.cri.PodSandboxStatus(ctx, &client.PodSandboxStatusRequest{PodSandboxId: c.PodSandboxId, Verbose: true})
+		if err == nil {
+			handler = parseSandboxRuntimeHandler(sandbox)
+			handlers[c.PodSandboxId] = handler
+		}
+	}
+	d.runtimeClass = handler
+
+	r.detailsCache[c.Id] = d
+	return d, true
+}
+
+func isBindMount(fsType string, options []string) bool {
+	return fsType == "bind" || hasOption(options, "bind") || hasOption(options, "rbind")
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func formatMount(source, destination string, readonly bool) string {
+	mount := source + ":" + destination
+	if readonly {
+		mount += ":ro"
+	}
+	return mount
+}
@@ -0,0 +1,105 @@
+package cri
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	client "github.com/weaveworks/scope/cri/runtime"
+)
+
+const containerdInfo = `{
+	"sandboxID": "4dbb3b8c9f3b",
+	"pid": 4242,
+	"runtimeType": "io.containerd.runc.v2",
+	"config": {"linux": {"security_context": {"privileged": true}}},
+	"runtimeSpec": {
+		"process": {
+			"args": ["nginx", "-g", "daemon off;"],
+			"user": {"uid": 0, "gid": 0},
+			"capabilities": {"effective": ["CAP_CHOWN", "CAP_NET_ADMIN"]}
+		},
+		"root": {"path": "rootfs", "readonly": true},
+		"mounts": [
+			{"destination": "/proc", "type": "proc", "source": "proc"},
+			{"destination": "/data", "type": "bind", "source": "/var/data", "options": ["rbind", "ro"]}
+		],
+		"linux": {"namespaces": [{"type": "pid"}, {"type": "network", "path": "/proc/4242/ns/net"}]}
+	}
+}`
+
+const crioInfo = `{
+	"sandboxID": "62c50b4ede7a",
+	"pid": 4343,
+	"privileged": false,
+	"runtimeSpec": {
+		"process": {"args": ["/pause"], "user": {"uid": 65535}},
+		"mounts": [{"destination": "/etc/hosts", "type": "bind", "source": "/run/hosts", "options": ["bind"]}],
+		"linux": {"namespaces": [{"type": "pid"}]}
+	}
+}`
+
+func TestParseContainerdDetails(t *testing.T) {
+	d := parseContainerDetails(&client.ContainerStatusResponse{
+		Status: &client.ContainerStatus{},
+		Info:   map[string]string{"info": containerdInfo},
+	})
+
+	assert.Equal(t, "nginx -g daemon off;", d.command)
+	assert.Equal(t, "", d.networkMode)
+	assert.Equal(t, []string{"/var/data:/data:ro"}, d.mounts)
+	assert.Equal(t, []string{"NET_ADMIN"}, d.capabilities)
+	assert.Equal(t, true, d.privileged)
+	assert.Equal(t, true, d.runAsRoot)
+	assert.Equal(t, true, d.readOnlyRootfs)
+}
+
+func TestParseCRIODetails(t *testing.T) {
+	d := parseContainerDetails(&client.ContainerStatusResponse{
+		Status: &client.ContainerStatus{
+			Mounts: []*client.Mount{{ContainerPath: "/etc/hosts", HostPath: "/var/lib/hosts", Readonly: true}},
+		},
+		Info: map[string]string{"info": crioInfo},
+	})
+
+	assert.Equal(t, "host", d.networkMode)
+	assert.Equal(t, []string{"/var/lib/hosts:/etc/hosts:ro"}, d.mounts)
+	assert.Equal(t, false, d.privileged)
+	assert.Equal(t, false, d.runAsRoot)
+}
+
+func TestParseDetailsWithoutInfo(t *testing.T) {
+	d := parseContainerDetails(&client.ContainerStatusResponse{Status: &client.ContainerStatus{}})
+
+	assert.Equal(t, "", d.networkMode)
+	assert.Equal(t, false, d.runAsRoot)
+}
+
+func TestSpecPrivileged(t *testing.T) {
+	for spec, privileged := range map[string]bool{
+		`{"process": {"capabilities": {"effective": ["CAP_SYS_ADMIN", "CAP_NET_ADMIN"]}}, "linux": {}}`:                              true,
+		`{"process": {"capabilities": {"effective": ["CAP_SYS_ADMIN"]}}, "linux": {"maskedPaths": ["/proc/kcore"]}}`:                 false,
+		`{"process": {"capabilities": {"effective": ["CAP_SYS_ADMIN"]}}, "linux": {"seccomp": {"defaultAction": "SCMP_ACT_ERRNO"}}}`: false,
+		`{"process": {"capabilities": {"effective": ["CAP_CHOWN"]}}, "linux": {}}`:                                                   false,
+	} {
+		var s ociSpec
+		assert.Equal(t, nil, json.Unmarshal([]byte(spec), &s))
+		assert.Equal(t, privileged, s.details().privileged)
+	}
+}
+
+func TestParseSandboxRuntimeHandler(t *testing.T) {
+	for info, handler := range map[string]string{
+		`{"runtimeHandler": "kata"}`: "kata",
+		`{"runtimeSpec": {"annotations": {"io.kubernetes.cri-o.RuntimeHandler": "gvisor"}}}`: "gvisor",
+		`{}`: "",
+	} {
+		status := &client.PodSandboxStatusResponse{Info: map[string]string{"info": info}}
+		assert.Equal(t, handler, parseSandboxRuntimeHandler(status))
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	assert.Equal(t, "sha256:ab21", repoDigest("quay.io/app@sha256:ab21"))
+	assert.Equal(t, "", repoDigest("sha256:ab21"))
+}
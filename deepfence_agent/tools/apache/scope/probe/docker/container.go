@@ -389,9 +389,37 @@ func (c *container) getBaseNode() (report.Metadata, report.Parent) {
 			result.DockerEnv = string(dockerEnvJson)
 		}
 	}
+	c.setSecurityContext(&result)
 	return result, parents
 }
 
+// setSecurityContext sets the runtime attributes the CRI reporter also
+// reports for containerd and CRI-O containers
+func (c *container) setSecurityContext(m *report.Metadata) {
+	// dockerd runs its containers in the moby namespace of containerd
+	m.ContainerRuntime = "docker"
+	m.ContainerNamespace = "moby"
+	for _, mount := range c.container.Mounts {
+		spec := mount.Source + ":" + mount.Destination
+		if !mount.RW {
+			spec += ":ro"
+		}
+		m.ContainerMounts = append(m.ContainerMounts, spec)
+	}
+	if c.container.HostConfig != nil {
+		m.ContainerRuntimeClass = c.container.HostConfig.Runtime
+		m.ContainerPrivileged = c.container.HostConfig.Privileged
+		for _, capability := range c.container.HostConfig.CapAdd {
+			m.ContainerCapabilities = append(m.ContainerCapabilities, strings.TrimPrefix(capability, "CAP_"))
+		}
+		m.ContainerReadOnlyRootfs = c.container.HostConfig.ReadonlyRootfs
+	}
+	if c.container.Config != nil {
+		user := strings.SplitN(c.container.Config.User, ":", 2)[0]
+		m.ContainerRunAsRoot = user == "" || user == "0" || user == "root"
+	}
+}
+
 func (c *container) GetParent() *report.Parent {
 	return &c.baseParent
 }
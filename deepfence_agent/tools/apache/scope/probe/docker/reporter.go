@@ -159,6 +159,11 @@ func (r *Reporter) containerImageTopology() report.Topology {
 			KubernetesClusterName:  r.kubernetesClusterName,
 		}
 
+		if len(image.RepoDigests) > 0 {
+			if i := strings.LastIndex(image.RepoDigests[0], "@"); i != -1 {
+				metadata.ImageDigest = image.RepoDigests[0][i+1:]
+			}
+		}
 		if len(image.RepoTags) > 0 {
 			imageFullName := image.RepoTags[0]
 			metadata.NodeName = imageFullName + " (" + shortImageID + ")"
@@ -39,6 +39,7 @@ type Client interface {
 	WalkPods(f func(Pod) error) error
 	WalkServices(f func(Service) error) error
 	WalkNamespaces(f func(NamespaceResource) error) error
+	WalkRoleBindings(f func(RoleBinding) error) error
 	WatchPods(f func(Event, Pod))
 	DeletePod(namespaceID, podID string) error
 	GetCNIPlugin() string
@@ -52,6 +53,7 @@ type client struct {
 	serviceStore   cache.Store
 	nodeStore      cache.Store
 	namespaceStore cache.Store
+	rbac           *rbacCollector
 	//calicoAPIClient            *calico_helper.CalicoAPIClient
 	cniPlugin       string
 	podWatchesMutex sync.Mutex
@@ -132,6 +134,7 @@ func NewClient(config ClientConfig) (Client, error) {
 	result.serviceStore = result.setupStore("services")
 	result.nodeStore = result.setupStore("nodes")
 	result.namespaceStore = result.setupStore("namespaces")
+	result.rbac = newRBACCollector(c, rbacRefreshInterval)
 
 	return result, nil
 }
@@ -312,6 +315,10 @@ func (c *client) WalkNamespaces(f func(NamespaceResource) error) error {
 	return nil
 }
 
+func (c *client) WalkRoleBindings(f func(RoleBinding) error) error {
+	return c.rbac.walk(f)
+}
+
 func (c *client) DeletePod(namespaceID, podID string) error {
 	return c.client.CoreV1().Pods(namespaceID).Delete(context.Background(), podID, metav1.DeleteOptions{})
 }
@@ -87,6 +87,7 @@ func (p *pod) GetNode() report.TopologyNode {
 		labelsStr = string(labels)
 	}
 	hostname := kubernetesClusterName + "-" + p.Pod.Spec.NodeName
+	security := getPodSecurity(p.Pod)
 	metadata := report.Metadata{
 		Timestamp:                 time.Now().UTC().Format(time.RFC3339Nano),
 		NodeID:                    p.UID(),
@@ -98,6 +99,13 @@ func (p *pod) GetNode() report.TopologyNode {
 		KubernetesState:           p.State(),
 		KubernetesIP:              p.Status.PodIP,
 		KubernetesIsInHostNetwork: p.Pod.Spec.HostNetwork,
+		KubernetesServiceAccount:  p.Pod.Spec.ServiceAccountName,
+		KubernetesPrivileged:      security.privileged,
+		KubernetesHostPID:         security.hostPID,
+		KubernetesHostPaths:       security.hostPaths,
+		KubernetesCapabilities:    security.capabilities,
+		KubernetesRunAsRoot:       security.runAsRoot,
+		KubernetesAutomountToken:  security.automountToken,
 		KubernetesNamespace:       p.Namespace(),
 		HostName:                  hostname,
 		KubernetesCreated:         p.Created(),
@@ -0,0 +1,227 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	RoleKindClusterRole = "ClusterRole"
+
+	// RBAC objects rarely change, they are listed again at most
+	// once per interval instead of being watched
+	rbacRefreshInterval = 5 * time.Minute
+)
+
+// RoleBinding represents a Kubernetes RoleBinding or ClusterRoleBinding
+// along with the permissions granted by the role it binds
+type RoleBinding interface {
+	Meta
+	GetNode() report.TopologyNode
+	RoleKind() string
+	RoleName() string
+	Subjects() []string
+	Permissions() []string
+}
+
+type roleBinding struct {
+	Meta
+	roleRef     rbacv1.RoleRef
+	subjects    []rbacv1.Subject
+	permissions []string
+}
+
+// NewRoleBinding creates a new RoleBinding from a namespaced binding.
+// rules are the rules of the bound Role or ClusterRole, nil if not found.
+func NewRoleBinding(b *rbacv1.RoleBinding, rules []rbacv1.PolicyRule) RoleBinding {
+	return &roleBinding{
+		Meta:        meta{b.ObjectMeta},
+		roleRef:     b.RoleRef,
+		subjects:    b.Subjects,
+		permissions: rulesPermissions(rules),
+	}
+}
+
+// NewClusterRoleBinding creates a new RoleBinding from a cluster wide binding.
+func NewClusterRoleBinding(b *rbacv1.ClusterRoleBinding, rules []rbacv1.PolicyRule) RoleBinding {
+	return &roleBinding{
+		Meta:        meta{b.ObjectMeta},
+		roleRef:     b.RoleRef,
+		subjects:    b.Subjects,
+		permissions: rulesPermissions(rules),
+	}
+}
+
+func (b *roleBinding) RoleKind() string {
+	return b.roleRef.Kind
+}
+
+func (b *roleBinding) RoleName() string {
+	return b.roleRef.Name
+}
+
+// Subjects are formatted as kind:name for users and groups and as
+// ServiceAccount:namespace/name for service accounts. Service accounts
+// without namespace belong to the namespace of the binding.
+func (b *roleBinding) Subjects() []string {
+	subjects := make([]string, 0, len(b.subjects))
+	for _, s := range b.subjects {
+		if s.Kind == rbacv1.ServiceAccountKind {
+			namespace := s.Namespace
+			if namespace == "" {
+				namespace = b.Namespace()
+			}
+			subjects = append(subjects, s.Kind+":"+namespace+"/"+s.Name)
+			continue
+		}
+		subjects = append(subjects, s.Kind+":"+s.Name)
+	}
+	return subjects
+}
+
+// Permissions are formatted as verb:resource, e.g. get:secrets or *:*
+func (b *roleBinding) Permissions() []string {
+	return b.permissions
+}
+
+func (b *roleBinding) GetNode() report.TopologyNode {
+	metadata := b.MetaNode(b.UID(), report.RoleBinding)
+	metadata.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	metadata.KubernetesClusterId = kubernetesClusterId
+	metadata.KubernetesClusterName = kubernetesClusterName
+	metadata.KubernetesRoleKind = b.RoleKind()
+	metadata.KubernetesRoleName = b.RoleName()
+	metadata.KubernetesSubjects = b.Subjects()
+	metadata.KubernetesPermissions = b.Permissions()
+	if b.Namespace() == "" {
+		metadata.NodeName = b.Name() + " / " + kubernetesClusterName
+	} else {
+		metadata.NodeName = b.Name() + " / " + b.Namespace() + " / " + kubernetesClusterName
+	}
+	parents := &report.Parent{
+		CloudProvider:     cloudProviderNodeId,
+		KubernetesCluster: kubernetesClusterId,
+	}
+	if b.Namespace() != "" {
+		parents.Namespace = kubernetesClusterId + "-" + b.Namespace()
+	}
+	return report.TopologyNode{
+		Metadata: metadata,
+		Parents:  parents,
+	}
+}
+
+// rulesPermissions flattens policy rules into sorted verb:resource pairs.
+// Non resource URLs are not reported.
+func rulesPermissions(rules []rbacv1.PolicyRule) []string {
+	set := map[string]struct{}{}
+	for _, rule := range rules {
+		for _, verb := range rule.Verbs {
+			for _, resource := range rule.Resources {
+				set[verb+":"+resource] = struct{}{}
+			}
+		}
+	}
+	permissions := make([]string, 0, len(set))
+	for p := range set {
+		permissions = append(permissions, p)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// rbacCollector lists the RBAC objects of the cluster and resolves each
+// binding against the role it references
+type rbacCollector struct {
+	client      kubernetes.Interface
+	interval    time.Duration
+	mtx         sync.Mutex
+	refreshedAt time.Time
+	bindings    []RoleBinding
+}
+
+func newRBACCollector(client kubernetes.Interface, interval time.Duration) *rbacCollector {
+	return &rbacCollector{
+		client:   client,
+		interval: interval,
+	}
+}
+
+func (r *rbacCollector) walk(f func(RoleBinding) error) error {
+	r.mtx.Lock()
+	if time.Since(r.refreshedAt) >= r.interval {
+		bindings, err := r.collect(context.Background())
+		// a failed list, such as a forbidden one, is not retried before the
+		// interval elapses either
+		r.refreshedAt = time.Now()
+		if err != nil {
+			r.mtx.Unlock()
+			return err
+		}
+		r.bindings = bindings
+	}
+	bindings := r.bindings
+	r.mtx.Unlock()
+
+	for _, b := range bindings {
+		if err := f(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rbacCollector) collect(ctx context.Context) ([]RoleBinding, error) {
+	rbac := r.client.RbacV1()
+
+	clusterRoles, err := rbac.ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	roles, err := rbac.Roles(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleBindings, err := rbac.ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	roleBindings, err := rbac.RoleBindings(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	clusterRoleRules := map[string][]rbacv1.PolicyRule{}
+	for _, cr := range clusterRoles.Items {
+		clusterRoleRules[cr.Name] = cr.Rules
+	}
+	roleRules := map[string][]rbacv1.PolicyRule{}
+	for _, role := range roles.Items {
+		roleRules[role.Namespace+"/"+role.Name] = role.Rules
+	}
+
+	res := make([]RoleBinding, 0, len(clusterRoleBindings.Items)+len(roleBindings.Items))
+	for i := range clusterRoleBindings.Items {
+		b := &clusterRoleBindings.Items[i]
+		res = append(res, NewClusterRoleBinding(b, clusterRoleRules[b.RoleRef.Name]))
+	}
+	for i := range roleBindings.Items {
+		b := &roleBindings.Items[i]
+		var rules []rbacv1.PolicyRule
+		if b.RoleRef.Kind == RoleKindClusterRole {
+			rules = clusterRoleRules[b.RoleRef.Name]
+		} else {
+			rules = roleRules[b.Namespace+"/"+b.RoleRef.Name]
+		}
+		res = append(res, NewRoleBinding(b, rules))
+	}
+	return res, nil
+}
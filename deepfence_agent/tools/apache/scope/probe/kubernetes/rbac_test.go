@@ -0,0 +1,166 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRBACCollector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader", Namespace: "payments"},
+			Rules:      []rbacv1.PolicyRule{{Resources: []string{"secrets", "configmaps"}, Verbs: []string{"get", "list"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-admin", UID: "crb-1"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "api", Namespace: "payments"},
+				{Kind: rbacv1.GroupKind, Name: "system:masters"},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "payments", UID: "rb-1"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "secret-reader"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "worker"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "dangling", Namespace: "payments", UID: "rb-2"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "missing"},
+		},
+	)
+
+	collector := newRBACCollector(clientset, time.Hour)
+	bindings := map[string]RoleBinding{}
+	err := collector.walk(func(b RoleBinding) error {
+		bindings[b.UID()] = b
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings) != 3 {
+		t.Fatalf("expected 3 bindings, got %d", len(bindings))
+	}
+
+	admin := bindings["crb-1"].GetNode().Metadata
+	if admin.KubernetesRoleKind != "ClusterRole" || admin.KubernetesRoleName != "cluster-admin" {
+		t.Errorf("unexpected role ref %s/%s", admin.KubernetesRoleKind, admin.KubernetesRoleName)
+	}
+	if !reflect.DeepEqual(admin.KubernetesPermissions, []string{"*:*"}) {
+		t.Errorf("unexpected permissions %v", admin.KubernetesPermissions)
+	}
+	if !reflect.DeepEqual(admin.KubernetesSubjects, []string{"ServiceAccount:payments/api", "Group:system:masters"}) {
+		t.Errorf("unexpected subjects %v", admin.KubernetesSubjects)
+	}
+
+	reader := bindings["rb-1"].GetNode()
+	if !reflect.DeepEqual(reader.Metadata.KubernetesPermissions,
+		[]string{"get:configmaps", "get:secrets", "list:configmaps", "list:secrets"}) {
+		t.Errorf("unexpected permissions %v", reader.Metadata.KubernetesPermissions)
+	}
+	if !reflect.DeepEqual(reader.Metadata.KubernetesSubjects, []string{"ServiceAccount:payments/worker"}) {
+		t.Errorf("unexpected subjects %v", reader.Metadata.KubernetesSubjects)
+	}
+	if reader.Parents.Namespace == "" {
+		t.Errorf("namespaced binding without namespace parent")
+	}
+
+	if len(bindings["rb-2"].Permissions()) != 0 {
+		t.Errorf("binding to a missing role should not grant permissions")
+	}
+
+	// cached until the refresh interval elapses
+	if err := clientset.RbacV1().RoleBindings("payments").Delete(context.Background(), "reader", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	_ = collector.walk(func(RoleBinding) error { count++; return nil })
+	if count != 3 {
+		t.Errorf("expected cached bindings, got %d", count)
+	}
+}
+
+func TestRBACCollectorBackoff(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	lists := 0
+	clientset.PrependReactor("list", "clusterroles", func(k8stesting.Action) (bool, runtime.Object, error) {
+		lists++
+		return true, nil, errors.New("forbidden")
+	})
+
+	collector := newRBACCollector(clientset, time.Hour)
+	if err := collector.walk(func(RoleBinding) error { return nil }); err == nil {
+		t.Fatal("expected the list error")
+	}
+	if err := collector.walk(func(RoleBinding) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if lists != 1 {
+		t.Errorf("expected the failed list not to be retried before the interval, got %d lists", lists)
+	}
+}
+
+func TestPodSecurity(t *testing.T) {
+	privileged, nonRoot, root := true, true, int64(0)
+	clientset := fake.NewSimpleClientset(&apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments", UID: "pod-1"},
+		Spec: apiv1.PodSpec{
+			ServiceAccountName: "api",
+			HostPID:            true,
+			SecurityContext:    &apiv1.PodSecurityContext{RunAsNonRoot: &nonRoot},
+			Volumes: []apiv1.Volume{
+				{Name: "docker", VolumeSource: apiv1.VolumeSource{HostPath: &apiv1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}},
+				{Name: "cache", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}},
+			},
+			Containers: []apiv1.Container{
+				{Name: "app"},
+				{Name: "sidecar", SecurityContext: &apiv1.SecurityContext{
+					Privileged:   &privileged,
+					RunAsUser:    &root,
+					Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"SYS_ADMIN", "NET_ADMIN"}},
+				}},
+			},
+		},
+	})
+
+	pod, err := clientset.CoreV1().Pods("payments").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := NewPod(pod).GetNode().Metadata
+	if !metadata.KubernetesPrivileged || !metadata.KubernetesHostPID || !metadata.KubernetesRunAsRoot {
+		t.Errorf("unexpected security context %+v", metadata)
+	}
+	if !metadata.KubernetesAutomountToken {
+		t.Errorf("service account token should be mounted by default")
+	}
+	if !reflect.DeepEqual(metadata.KubernetesHostPaths, []string{"/var/run/docker.sock"}) {
+		t.Errorf("unexpected host paths %v", metadata.KubernetesHostPaths)
+	}
+	if !reflect.DeepEqual(metadata.KubernetesCapabilities, []string{"NET_ADMIN", "SYS_ADMIN"}) {
+		t.Errorf("unexpected capabilities %v", metadata.KubernetesCapabilities)
+	}
+
+	automount := false
+	pod.Spec.AutomountServiceAccountToken = &automount
+	pod.Spec.Containers = pod.Spec.Containers[:1]
+	metadata = NewPod(pod).GetNode().Metadata
+	if metadata.KubernetesRunAsRoot || metadata.KubernetesPrivileged || metadata.KubernetesAutomountToken {
+		t.Errorf("unexpected security context %+v", metadata)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+
 	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/weaveworks/scope/probe"
@@ -143,10 +145,17 @@ func (r *Reporter) Report() (report.Report, error) {
 	if err != nil {
 		return result, err
 	}
+	// RBAC objects require extra permissions, the rest of the
+	// report is still useful without them
+	roleBindingTopology, err := r.roleBindingTopology()
+	if err != nil {
+		log.Warn().Msgf("kubernetes: failed to list rbac objects: %v", err)
+	}
 	result.KubernetesCluster.Merge(r.k8sClusterTopology)
 	result.Pod.Merge(podTopology)
 	result.Service.Merge(serviceTopology)
 	result.Namespace.Merge(namespaceTopology)
+	result.RoleBinding.Merge(roleBindingTopology)
 	return result, nil
 }
 
@@ -221,3 +230,12 @@ func (r *Reporter) namespaceTopology() (report.Topology, error) {
 	})
 	return result, err
 }
+
+func (r *Reporter) roleBindingTopology() (report.Topology, error) {
+	result := report.MakeTopology()
+	err := r.client.WalkRoleBindings(func(b RoleBinding) error {
+		result.AddNode(b.GetNode())
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// podSecurity summarizes the security relevant settings of a pod spec
+// and of all its containers, init containers included.
+type podSecurity struct {
+	privileged     bool
+	hostPID        bool
+	hostPaths      []string
+	capabilities   []string
+	runAsRoot      bool
+	automountToken bool
+}
+
+func getPodSecurity(p *apiv1.Pod) podSecurity {
+	res := podSecurity{
+		hostPID: p.Spec.HostPID,
+		// service account tokens are mounted unless explicitly disabled
+		automountToken: p.Spec.AutomountServiceAccountToken == nil || *p.Spec.AutomountServiceAccountToken,
+	}
+
+	for _, volume := range p.Spec.Volumes {
+		if volume.HostPath != nil {
+			res.hostPaths = append(res.hostPaths, volume.HostPath.Path)
+		}
+	}
+
+	var podRunAsNonRoot *bool
+	var podRunAsUser *int64
+	if p.Spec.SecurityContext != nil {
+		podRunAsNonRoot = p.Spec.SecurityContext.RunAsNonRoot
+		podRunAsUser = p.Spec.SecurityContext.RunAsUser
+	}
+
+	capabilities := map[string]struct{}{}
+	containers := make([]apiv1.Container, 0, len(p.Spec.InitContainers)+len(p.Spec.Containers))
+	containers = append(containers, p.Spec.InitContainers...)
+	containers = append(containers, p.Spec.Containers...)
+	for _, c := range containers {
+		runAsNonRoot, runAsUser := podRunAsNonRoot, podRunAsUser
+		if sc := c.SecurityContext; sc != nil {
+			if sc.Privileged != nil && *sc.Privileged {
+				res.privileged = true
+			}
+			if sc.Capabilities != nil {
+				for _, capability := range sc.Capabilities.Add {
+					capabilities[string(capability)] = struct{}{}
+				}
+			}
+			if sc.RunAsNonRoot != nil {
+				runAsNonRoot = sc.RunAsNonRoot
+			}
+			if sc.RunAsUser != nil {
+				runAsUser = sc.RunAsUser
+			}
+		}
+		// Without an explicit user the image decides, which is root for
+		// most images unless runAsNonRoot is enforced.
+		if runAsUser != nil {
+			if *runAsUser == 0 {
+				res.runAsRoot = true
+			}
+		} else if runAsNonRoot == nil || !*runAsNonRoot {
+			res.runAsRoot = true
+		}
+	}
+
+	for capability := range capabilities {
+		res.capabilities = append(res.capabilities, capability)
+	}
+	sort.Strings(res.capabilities)
+	return res
+}
@@ -191,6 +191,9 @@ func (r *Reporter) getImageNode(c ContainerImage) (*report.TopologyNode, *ImageM
 	var imageRef string
 	if len(c.RepoDigests) > 0 {
 		imageRef = c.RepoDigests[0]
+		if i := strings.LastIndex(imageRef, "@"); i != -1 {
+			metadata.ImageDigest = imageRef[i+1:]
+		}
 	}
 	if len(c.RepoTags) > 0 {
 		imageFullName := c.RepoTags[0]
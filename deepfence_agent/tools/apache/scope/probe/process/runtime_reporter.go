@@ -0,0 +1,77 @@
+package process
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/weaveworks/scope/common/hostname"
+	"github.com/weaveworks/scope/report"
+)
+
+// RuntimeReporter generates Reports containing the RuntimeEvent topology.
+type RuntimeReporter struct {
+	hostID                 string
+	hostName               string
+	noCommandLineArguments bool
+	tracer                 *RuntimeTracer
+}
+
+// NewRuntimeReporter makes a new RuntimeReporter and starts the exec/exit
+// and file write tracer.
+func NewRuntimeReporter(hostID, procRoot string, sensitivePaths []string, noCommandLineArguments bool) (*RuntimeReporter, error) {
+	tracer, err := NewRuntimeTracer(procRoot, sensitivePaths)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeReporter{
+		hostID:                 hostID,
+		hostName:               hostname.Get(),
+		noCommandLineArguments: noCommandLineArguments,
+		tracer:                 tracer,
+	}, nil
+}
+
+// Name of this reporter, for metrics gathering
+func (*RuntimeReporter) Name() string { return "RuntimeEvent" }
+
+// Stop stops the tracer
+func (r *RuntimeReporter) Stop() {
+	r.tracer.Stop()
+}
+
+// Report implements Reporter.
+func (r *RuntimeReporter) Report() (report.Report, error) {
+	result := report.MakeReport()
+	for _, ev := range r.tracer.Drain() {
+		pidstr := strconv.Itoa(ev.Pid)
+		metadata := report.Metadata{
+			Timestamp:     time.Unix(0, ev.Timestamp).UTC().Format(time.RFC3339Nano),
+			NodeID:        report.MakeRuntimeEventNodeID(r.hostID, pidstr, ev.Type, ev.Timestamp),
+			NodeName:      ev.Comm,
+			ShortNodeName: shortProcessName(ev.Comm),
+			NodeType:      report.RuntimeEvent,
+			HostName:      r.hostName,
+			Pid:           ev.Pid,
+			Ppid:          ev.Ppid,
+			EventType:     ev.Type,
+			FilePath:      ev.Filename,
+			ExitCode:      ev.ExitCode,
+			Lineage:       ev.Lineage,
+		}
+		if ev.Args != "" {
+			if r.noCommandLineArguments {
+				metadata.Cmdline = report.StripCommandArgs(ev.Args)
+			} else {
+				metadata.Cmdline = ev.Args
+			}
+		}
+		result.RuntimeEvent.AddNode(report.TopologyNode{
+			Metadata: metadata,
+			Parents: &report.Parent{
+				Host:      r.hostName,
+				Container: ev.ContainerID,
+			},
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+)
+
+// Runtime event types reported by the tracer
+const (
+	RuntimeEventExec      = "exec"
+	RuntimeEventExit      = "exit"
+	RuntimeEventFileWrite = "file_write"
+)
+
+const (
+	maxRuntimeEvents = 4096
+	maxTrackedProcs  = 65536
+	maxLineageDepth  = 8
+	maxWatchedMounts = 4096
+)
+
+// DefaultSensitivePaths are the path prefixes whose modifications are reported
+var DefaultSensitivePaths = []string{
+	"/etc",
+	"/bin",
+	"/sbin",
+	"/usr/bin",
+	"/usr/sbin",
+	"/usr/local/bin",
+	"/var/spool/cron",
+}
+
+var containerIDRegex = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// RuntimeEvent is a single process exec/exit or file write event
+type RuntimeEvent struct {
+	Type      string
+	Pid       int
+	Ppid      int
+	Comm      string
+	Args      string
+	Filename  string
+	ExitCode  int
+	Timestamp int64
+
+	Lineage     []string
+	ContainerID string
+}
+
+type procInfo struct {
+	ppid int
+	comm string
+}
+
+// RuntimeTracer collects process exec/exit events from the kernel proc
+// connector and sensitive file write events from fanotify until they are
+// drained by the reporter. The writes are watched on the host mounts and on
+// the root mounts of the containers, added when their first process execs.
+type RuntimeTracer struct {
+	fds            []int
+	fanotifyFd     int
+	procRoot       string
+	sensitivePaths []string
+
+	sync.Mutex
+	events        []RuntimeEvent
+	dropped       int
+	procs         map[int]procInfo
+	watchedMounts map[string]struct{}
+}
+
+// NewRuntimeTracer subscribes to the kernel events and consumes them.
+func NewRuntimeTracer(procRoot string, sensitivePaths []string) (*RuntimeTracer, error) {
+	if len(sensitivePaths) == 0 {
+		sensitivePaths = DefaultSensitivePaths
+	}
+	cleaned := make([]string, 0, len(sensitivePaths))
+	for _, p := range sensitivePaths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		cleaned = append(cleaned, filepath.Clean(p))
+	}
+
+	rt := &RuntimeTracer{
+		procRoot:       procRoot,
+		sensitivePaths: cleaned,
+		events:         make([]RuntimeEvent, 0, maxRuntimeEvents),
+		procs:          map[int]procInfo{},
+		fanotifyFd:     -1,
+		watchedMounts:  map[string]struct{}{},
+	}
+	if err := rt.start(); err != nil {
+		rt.Stop()
+		return nil, err
+	}
+	return rt, nil
+}
+
+// Drain returns the events collected since the previous call
+func (rt *RuntimeTracer) Drain() []RuntimeEvent {
+	rt.Lock()
+	defer rt.Unlock()
+	res := rt.events
+	rt.events = make([]RuntimeEvent, 0, maxRuntimeEvents)
+	if rt.dropped > 0 {
+		log.Warn().Msgf("Dropped %d runtime events", rt.dropped)
+		rt.dropped = 0
+	}
+	return res
+}
+
+func (rt *RuntimeTracer) isSensitivePath(path string) bool {
+	path = filepath.Clean(path)
+	for _, prefix := range rt.sensitivePaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (rt *RuntimeTracer) handle(ev RuntimeEvent) {
+	if ev.Type == RuntimeEventFileWrite && !rt.isSensitivePath(ev.Filename) {
+		return
+	}
+
+	rt.Lock()
+	defer rt.Unlock()
+
+	switch ev.Type {
+	case RuntimeEventExec:
+		if len(rt.procs) >= maxTrackedProcs {
+			rt.procs = map[int]procInfo{}
+		}
+		rt.procs[ev.Pid] = procInfo{ppid: ev.Ppid, comm: ev.Comm}
+		ev.ContainerID = rt.containerID(ev.Pid)
+		ev.Lineage = rt.lineage(ev.Ppid)
+	case RuntimeEventExit:
+		ev.Lineage = rt.lineage(ev.Ppid)
+		delete(rt.procs, ev.Pid)
+	case RuntimeEventFileWrite:
+		ev.ContainerID = rt.containerID(ev.Pid)
+		ev.Lineage = rt.lineage(ev.Ppid)
+	default:
+		return
+	}
+
+	if len(rt.events) >= maxRuntimeEvents {
+		rt.events = rt.events[1:]
+		rt.dropped += 1
+	}
+	rt.events = append(rt.events, ev)
+}
+
+// lineage lists the ancestor process names starting from pid,
+// closest first. Processes not seen exec'ing are looked up in procfs.
+func (rt *RuntimeTracer) lineage(pid int) []string {
+	res := []string{}
+	for i := 0; i < maxLineageDepth && pid > 0; i++ {
+		info, ok := rt.procs[pid]
+		if !ok {
+			info, ok = rt.readProcStat(pid)
+			if !ok {
+				break
+			}
+		}
+		res = append(res, info.comm)
+		pid = info.ppid
+	}
+	return res
+}
+
+// readProcStat reads comm and ppid from /proc/<pid>/stat
+func (rt *RuntimeTracer) readProcStat(pid int) (procInfo, bool) {
+	buf, err := os.ReadFile(filepath.Join(rt.procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procInfo{}, false
+	}
+	stat := string(buf)
+	// comm is enclosed in parenthesis and may contain spaces
+	start := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return procInfo{}, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return procInfo{}, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procInfo{}, false
+	}
+	return procInfo{ppid: ppid, comm: stat[start+1 : end]}, true
+}
+
+// containerID extracts the container ID from /proc/<pid>/cgroup, if any
+func (rt *RuntimeTracer) containerID(pid int) string {
+	buf, err := os.ReadFile(filepath.Join(rt.procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	return containerIDRegex.FindString(string(buf))
+}
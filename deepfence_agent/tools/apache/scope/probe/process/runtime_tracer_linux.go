@@ -0,0 +1,286 @@
+package process
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"golang.org/x/sys/unix"
+)
+
+// Kernel proc connector, see linux/connector.h and linux/cn_proc.h
+const (
+	cnIdxProc          = 0x1
+	cnValProc          = 0x1
+	procCnMcastListen  = 0x1
+	procEventExec      = 0x00000002
+	procEventExit      = 0x80000000
+	cnMsgSize          = 20
+	procEventHdrSize   = 16
+	procEventExitSize  = 16
+	procEventExecSize  = 8
+	fanotifyBufferSize = 4096
+)
+
+func (rt *RuntimeTracer) start() error {
+	procFd, err := openProcConnector()
+	if err != nil {
+		return err
+	}
+	rt.fds = append(rt.fds, procFd)
+
+	fanFd, err := rt.openFanotify()
+	if err != nil {
+		return err
+	}
+	rt.fds = append(rt.fds, fanFd)
+	rt.fanotifyFd = fanFd
+
+	go rt.readProcEvents(procFd)
+	go rt.readFanotifyEvents(fanFd)
+	return nil
+}
+
+// Stop closes the kernel subscriptions
+func (rt *RuntimeTracer) Stop() {
+	for _, fd := range rt.fds {
+		unix.Close(fd)
+	}
+	rt.fds = nil
+}
+
+func openProcConnector() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return -1, err
+	}
+	err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc, Pid: uint32(os.Getpid())})
+	if err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+
+	// nlmsghdr, cn_msg and the PROC_CN_MCAST_LISTEN operation
+	msg := make([]byte, unix.NLMSG_HDRLEN+cnMsgSize+4)
+	binary.LittleEndian.PutUint32(msg[0:], uint32(len(msg)))
+	binary.LittleEndian.PutUint16(msg[4:], unix.NLMSG_DONE)
+	binary.LittleEndian.PutUint32(msg[12:], uint32(os.Getpid()))
+	cn := msg[unix.NLMSG_HDRLEN:]
+	binary.LittleEndian.PutUint32(cn[0:], cnIdxProc)
+	binary.LittleEndian.PutUint32(cn[4:], cnValProc)
+	binary.LittleEndian.PutUint16(cn[16:], 4)
+	binary.LittleEndian.PutUint32(cn[cnMsgSize:], procCnMcastListen)
+
+	err = unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	if err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// openFanotify watches the writes to the mounts of the sensitive paths of
+// the host, reached through the root of its init process
+func (rt *RuntimeTracer) openFanotify() (int, error) {
+	fd, err := unix.FanotifyInit(unix.FAN_CLASS_NOTIF|unix.FAN_CLOEXEC, unix.O_RDONLY|unix.O_LARGEFILE)
+	if err != nil {
+		return -1, err
+	}
+	if marked := rt.markMounts(fd, 1); marked == 0 {
+		unix.Close(fd)
+		return -1, errors.New("no sensitive path can be watched")
+	}
+	if ns, err := os.Readlink(filepath.Join(rt.procRoot, "1", "ns", "mnt")); err == nil {
+		rt.watchedMounts[ns] = struct{}{}
+	}
+	return fd, nil
+}
+
+// markMounts watches the writes to the mounts holding the sensitive paths in
+// the mount namespace of pid. A mark only covers its own mount, the overlay
+// root of each container has to be marked.
+func (rt *RuntimeTracer) markMounts(fd, pid int) int {
+	marked := 0
+	for _, path := range rt.sensitivePaths {
+		nsPath := filepath.Join(rt.procRoot, strconv.Itoa(pid), "root", path)
+		err := unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_MOUNT, unix.FAN_CLOSE_WRITE, unix.AT_FDCWD, nsPath)
+		if err != nil {
+			if pid == 1 {
+				log.Warn().Msgf("Cannot watch writes to %s: %v", path, err)
+			}
+			continue
+		}
+		marked += 1
+	}
+	return marked
+}
+
+// watchContainerMounts marks the mounts of the mount namespace of pid the
+// first time one of its processes execs
+func (rt *RuntimeTracer) watchContainerMounts(pid int) {
+	ns, err := os.Readlink(filepath.Join(rt.procRoot, strconv.Itoa(pid), "ns", "mnt"))
+	if err != nil {
+		return
+	}
+	rt.Lock()
+	_, has := rt.watchedMounts[ns]
+	if !has {
+		if len(rt.watchedMounts) >= maxWatchedMounts {
+			rt.watchedMounts = map[string]struct{}{}
+		}
+		rt.watchedMounts[ns] = struct{}{}
+	}
+	rt.Unlock()
+	if has || rt.fanotifyFd < 0 {
+		return
+	}
+	rt.markMounts(rt.fanotifyFd, pid)
+}
+
+func (rt *RuntimeTracer) readProcEvents(fd int) {
+	buf := make([]byte, os.Getpagesize())
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err == unix.EINTR || err == unix.ENOBUFS {
+			continue
+		} else if err != nil {
+			log.Info().Msgf("Proc connector stream ended: %v", err)
+			return
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			ev, ok := parseProcEvent(msg.Data)
+			if !ok {
+				continue
+			}
+			rt.fillProcEvent(&ev)
+			if ev.Type == RuntimeEventExec {
+				rt.watchContainerMounts(ev.Pid)
+			}
+			rt.handle(ev)
+		}
+	}
+}
+
+// parseProcEvent decodes the exec and exit events of processes, the ones of
+// threads are skipped
+func parseProcEvent(data []byte) (RuntimeEvent, bool) {
+	if len(data) < cnMsgSize+procEventHdrSize {
+		return RuntimeEvent{}, false
+	}
+	ev := data[cnMsgSize:]
+	what := binary.LittleEndian.Uint32(ev[0:])
+	body := ev[procEventHdrSize:]
+	switch what {
+	case procEventExec:
+		if len(body) < procEventExecSize {
+			return RuntimeEvent{}, false
+		}
+		pid := int(binary.LittleEndian.Uint32(body[0:]))
+		tgid := int(binary.LittleEndian.Uint32(body[4:]))
+		if pid != tgid {
+			return RuntimeEvent{}, false
+		}
+		return RuntimeEvent{Type: RuntimeEventExec, Pid: pid}, true
+	case procEventExit:
+		if len(body) < procEventExitSize {
+			return RuntimeEvent{}, false
+		}
+		pid := int(binary.LittleEndian.Uint32(body[0:]))
+		tgid := int(binary.LittleEndian.Uint32(body[4:]))
+		if pid != tgid {
+			return RuntimeEvent{}, false
+		}
+		status := binary.LittleEndian.Uint32(body[8:])
+		return RuntimeEvent{Type: RuntimeEventExit, Pid: pid, ExitCode: int(status>>8) & 0xff}, true
+	}
+	return RuntimeEvent{}, false
+}
+
+// fillProcEvent completes the event with what procfs still has of the
+// process
+func (rt *RuntimeTracer) fillProcEvent(ev *RuntimeEvent) {
+	ev.Timestamp = time.Now().UnixNano()
+	switch ev.Type {
+	case RuntimeEventExec:
+		if info, ok := rt.readProcStat(ev.Pid); ok {
+			ev.Ppid = info.ppid
+			ev.Comm = info.comm
+		}
+		cmdline, err := os.ReadFile(filepath.Join(rt.procRoot, strconv.Itoa(ev.Pid), "cmdline"))
+		if err == nil {
+			ev.Args = strings.TrimSpace(string(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '})))
+		}
+		ev.Filename = rt.readExe(ev.Pid)
+	case RuntimeEventExit:
+		rt.Lock()
+		info, ok := rt.procs[ev.Pid]
+		rt.Unlock()
+		if ok {
+			ev.Ppid = info.ppid
+			ev.Comm = info.comm
+		}
+	}
+}
+
+// readExe returns the path of the executable of the process, as seen in its
+// own mount namespace, the one of its container
+func (rt *RuntimeTracer) readExe(pid int) string {
+	exe, err := os.Readlink(filepath.Join(rt.procRoot, strconv.Itoa(pid), "exe"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(exe, " (deleted)")
+}
+
+func (rt *RuntimeTracer) readFanotifyEvents(fd int) {
+	buf := make([]byte, fanotifyBufferSize)
+	metaSize := int(unsafe.Sizeof(unix.FanotifyEventMetadata{}))
+	for {
+		n, err := unix.Read(fd, buf)
+		if err == unix.EINTR {
+			continue
+		} else if err != nil {
+			log.Info().Msgf("Fanotify stream ended: %v", err)
+			return
+		}
+		for i := 0; i+metaSize <= n; {
+			meta := (*unix.FanotifyEventMetadata)(unsafe.Pointer(&buf[i]))
+			if meta.Event_len < uint32(metaSize) || meta.Vers != unix.FANOTIFY_METADATA_VERSION {
+				break
+			}
+			i += int(meta.Event_len)
+			if meta.Fd == unix.FAN_NOFD {
+				continue
+			}
+			path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(meta.Fd)))
+			unix.Close(int(meta.Fd))
+			if err != nil {
+				continue
+			}
+
+			ev := RuntimeEvent{
+				Type:      RuntimeEventFileWrite,
+				Pid:       int(meta.Pid),
+				Filename:  path,
+				Timestamp: time.Now().UnixNano(),
+			}
+			if info, ok := rt.readProcStat(ev.Pid); ok {
+				ev.Ppid = info.ppid
+				ev.Comm = info.comm
+			}
+			rt.handle(ev)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package process
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeProcEvent(what uint32, body ...uint32) []byte {
+	data := make([]byte, cnMsgSize+procEventHdrSize+4*len(body))
+	binary.LittleEndian.PutUint32(data[cnMsgSize:], what)
+	for i, v := range body {
+		binary.LittleEndian.PutUint32(data[cnMsgSize+procEventHdrSize+4*i:], v)
+	}
+	return data
+}
+
+func TestParseProcEvent(t *testing.T) {
+	ev, ok := parseProcEvent(makeProcEvent(procEventExec, 42, 42))
+	if !ok || ev.Type != RuntimeEventExec || ev.Pid != 42 {
+		t.Fatalf("unexpected exec event: %v %v", ev, ok)
+	}
+
+	ev, ok = parseProcEvent(makeProcEvent(procEventExit, 42, 42, 3<<8, 17))
+	if !ok || ev.Type != RuntimeEventExit || ev.Pid != 42 || ev.ExitCode != 3 {
+		t.Fatalf("unexpected exit event: %v %v", ev, ok)
+	}
+
+	// threads are skipped
+	if _, ok = parseProcEvent(makeProcEvent(procEventExec, 43, 42)); ok {
+		t.Fatal("thread exec reported")
+	}
+	// fork events are skipped
+	if _, ok = parseProcEvent(makeProcEvent(0x1, 42, 42, 43, 43)); ok {
+		t.Fatal("fork reported")
+	}
+	if _, ok = parseProcEvent(make([]byte, 8)); ok {
+		t.Fatal("truncated event reported")
+	}
+}
+
+func TestReadExe(t *testing.T) {
+	rt := &RuntimeTracer{procRoot: t.TempDir()}
+	for pid, target := range map[string]string{
+		"42": "/usr/local/bin/app",
+		"43": "/tmp/dropped (deleted)",
+	} {
+		if err := os.MkdirAll(filepath.Join(rt.procRoot, pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(rt.procRoot, pid, "exe")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exe := rt.readExe(42); exe != "/usr/local/bin/app" {
+		t.Errorf("exe of 42: %q", exe)
+	}
+	if exe := rt.readExe(43); exe != "/tmp/dropped" {
+		t.Errorf("exe of 43: %q", exe)
+	}
+	if exe := rt.readExe(44); exe != "" {
+		t.Errorf("exe of 44: %q", exe)
+	}
+}
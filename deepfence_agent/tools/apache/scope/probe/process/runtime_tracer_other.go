@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package process
+
+import "errors"
+
+func (rt *RuntimeTracer) start() error {
+	return errors.New("runtime events are only traced on linux")
+}
+
+// Stop dummy
+func (rt *RuntimeTracer) Stop() {}
@@ -0,0 +1,63 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestRuntimeTracer(t *testing.T) *RuntimeTracer {
+	procRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(procRoot, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := "1 (systemd init) S 0 1 1 0 -1 4194560"
+	if err := os.WriteFile(filepath.Join(procRoot, "1", "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &RuntimeTracer{
+		procRoot:       procRoot,
+		sensitivePaths: []string{"/etc", "/usr/bin"},
+		events:         []RuntimeEvent{},
+		procs:          map[int]procInfo{},
+	}
+}
+
+func TestRuntimeTracerLineage(t *testing.T) {
+	rt := newTestRuntimeTracer(t)
+
+	rt.handle(RuntimeEvent{Type: RuntimeEventExec, Pid: 10, Ppid: 1, Comm: "bash"})
+	rt.handle(RuntimeEvent{Type: RuntimeEventExec, Pid: 11, Ppid: 10, Comm: "curl"})
+	rt.handle(RuntimeEvent{Type: RuntimeEventExit, Pid: 11, Ppid: 10, Comm: "curl"})
+
+	events := rt.Drain()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if want := []string{"bash", "systemd init"}; !reflect.DeepEqual(events[1].Lineage, want) {
+		t.Errorf("expected lineage %v, got %v", want, events[1].Lineage)
+	}
+	if _, ok := rt.procs[11]; ok {
+		t.Errorf("exited process still tracked")
+	}
+	if len(rt.Drain()) != 0 {
+		t.Errorf("events not drained")
+	}
+}
+
+func TestRuntimeTracerSensitivePaths(t *testing.T) {
+	rt := newTestRuntimeTracer(t)
+
+	for _, path := range []string{"/etc/passwd", "/usr/bin/ls", "/etc", "/etcetera/file", "/tmp/x", "/usr/bin/../lib/x"} {
+		rt.handle(RuntimeEvent{Type: RuntimeEventFileWrite, Pid: 1, Filename: path})
+	}
+
+	paths := []string{}
+	for _, ev := range rt.Drain() {
+		paths = append(paths, ev.Filename)
+	}
+	if want := []string{"/etc/passwd", "/usr/bin/ls", "/etc"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+}
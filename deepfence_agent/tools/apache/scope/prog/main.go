@@ -15,6 +15,7 @@ import (
 	"github.com/weaveworks/scope/probe/appclient"
 	"github.com/weaveworks/scope/probe/host"
 	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/process"
 )
 
 var (
@@ -77,7 +78,9 @@ type probeFlags struct {
 	procEnabled       bool // Produce process topology & process nodes in endpoint
 	useEbpfConn       bool // Enable connection tracking with eBPF
 	procRoot          string
-	trackProcDeploads bool // Track process dependency loading at runtime
+	trackProcDeploads bool   // Track process dependency loading at runtime
+	runtimeEvents     bool   // Trace process exec/exit and sensitive file writes
+	sensitivePaths    string // Comma separated path prefixes whose writes are reported
 
 	dockerEnabled  bool
 	dockerInterval time.Duration
@@ -176,6 +179,8 @@ func setupFlags(flags *flags) {
 	flag.BoolVar(&flags.probe.procEnabled, "probe.processes", true, "produce process topology & include procspied connections")
 	flag.BoolVar(&flags.probe.trackProcDeploads, "probe.track.deploads", false, "Enable dependency open runtime tracing")
 	flag.BoolVar(&flags.probe.useEbpfConn, "probe.ebpf.connections", true, "enable connection tracking with eBPF")
+	flag.BoolVar(&flags.probe.runtimeEvents, "probe.runtime.events", false, "trace process exec/exit with the proc connector and sensitive file writes with fanotify")
+	flag.StringVar(&flags.probe.sensitivePaths, "probe.runtime.sensitive-paths", strings.Join(process.DefaultSensitivePaths, ","), "comma separated path prefixes whose modifications are reported")
 
 	// Docker
 	flag.BoolVar(&flags.probe.dockerEnabled, "probe.docker", false, "collect Docker-related attributes for processes")
@@ -184,7 +189,7 @@ func setupFlags(flags *flags) {
 
 	// CRI
 	flag.BoolVar(&flags.probe.criEnabled, "probe.cri", false, "collect CRI-related attributes for processes")
-	flag.StringVar(&flags.probe.criEndpoint, "probe.cri.endpoint", "unix///var/run/dockershim.sock", "The endpoint to connect to the CRI")
+	flag.StringVar(&flags.probe.criEndpoint, "probe.cri.endpoint", "", "The endpoint to connect to the CRI, detected among the containerd, CRI-O and dockershim sockets when empty")
 
 	// Podman
 	flag.BoolVar(&flags.probe.podmanEnabled, "probe.podman", false, "collect Podman-related attributes for processes")
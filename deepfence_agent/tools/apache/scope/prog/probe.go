@@ -66,6 +66,10 @@ func checkFlagsRequiringRoot(flags probeFlags) {
 		if flags.trackProcDeploads {
 			log.Warn().Msg("--probe.proc.track-deploads=true, but that requires root to find everything")
 		}
+
+		if flags.runtimeEvents {
+			log.Warn().Msg("--probe.runtime.events=true, but that requires root to subscribe to process and file events")
+		}
 	}
 }
 
@@ -241,6 +245,18 @@ endNestedIf:
 				log.Debug().Msg("Attached proc report")
 			}
 
+			if flags.runtimeEvents {
+				runtimeReporter, err := process.NewRuntimeReporter(hostName, flags.procRoot,
+					strings.Split(flags.sensitivePaths, ","), flags.noCommandLineArguments)
+				if err != nil {
+					log.Error().Msgf("Failed to start runtime events tracer: %v", err)
+				} else {
+					defer runtimeReporter.Stop()
+					p.AddReporter(runtimeReporter)
+					log.Debug().Msg("Attached runtime events report")
+				}
+			}
+
 			if flags.endpointEnabled {
 				dnsSnooper, err := endpoint.NewDNSSnooper()
 				if err != nil {
@@ -296,11 +312,11 @@ endNestedIf:
 		}
 
 		if flags.criEnabled {
-			runtimeClient, imageClient, err := cri.NewCRIClient(flags.criEndpoint)
+			reporter, err := cri.NewReporter(flags.criEndpoint, hostName)
 			if err != nil {
 				log.Error().Msgf("CRI: failed to start registry: %v", err)
 			} else {
-				p.AddReporter(cri.NewReporter(runtimeClient, hostName, imageClient))
+				p.AddReporter(reporter)
 				log.Debug().Msg("Attached cri report")
 			}
 		}
@@ -77,6 +77,15 @@ type Metadata struct {
 	DockerLabels               string   `json:"docker_label,omitempty"`
 	DockerEnv                  string   `json:"docker_env,omitempty"`
 
+	ContainerRuntime        string   `json:"container_runtime,omitempty"`
+	ContainerRuntimeClass   string   `json:"container_runtime_class,omitempty"`
+	ContainerNamespace      string   `json:"container_namespace,omitempty"`
+	ContainerMounts         []string `json:"container_mounts,omitempty"`
+	ContainerPrivileged     bool     `json:"container_privileged,omitempty"`
+	ContainerCapabilities   []string `json:"container_capabilities,omitempty"`
+	ContainerRunAsRoot      bool     `json:"container_run_as_root,omitempty"`
+	ContainerReadOnlyRootfs bool     `json:"container_read_only_rootfs,omitempty"`
+
 	ImageName              string `json:"docker_image_name,omitempty"`
 	ImageNameWithTag       string `json:"docker_image_name_with_tag,omitempty"`
 	ImageTag               string `json:"docker_image_tag,omitempty"`
@@ -84,6 +93,7 @@ type Metadata struct {
 	DockerImageCreatedAt   string `json:"docker_image_created_at,omitempty"`
 	DockerImageVirtualSize string `json:"docker_image_virtual_size,omitempty"`
 	DockerImageID          string `json:"docker_image_id,omitempty"`
+	ImageDigest            string `json:"image_digest,omitempty"`
 
 	// process
 	Pid       int      `json:"pid,omitempty"`
@@ -92,6 +102,12 @@ type Metadata struct {
 	Threads   int      `json:"threads,omitempty"`
 	OpenFiles []string `json:"open_files,omitempty"`
 
+	// runtime event
+	EventType string   `json:"event_type,omitempty"`
+	FilePath  string   `json:"file_path,omitempty"`
+	ExitCode  int      `json:"exit_code,omitempty"`
+	Lineage   []string `json:"lineage,omitempty"`
+
 	// endpoint
 	ConnectionCount int    `json:"connection_count,omitempty"`
 	CopyOf          string `json:"copy_of,omitempty"`
@@ -107,6 +123,17 @@ type Metadata struct {
 	KubernetesNamespace       string   `json:"kubernetes_namespace,omitempty"`
 	KubernetesCreated         string   `json:"kubernetes_created,omitempty"`
 	KubernetesIsInHostNetwork bool     `json:"kubernetes_is_in_host_network,omitempty"`
+	KubernetesServiceAccount  string   `json:"kubernetes_service_account,omitempty"`
+	KubernetesPrivileged      bool     `json:"kubernetes_privileged,omitempty"`
+	KubernetesHostPID         bool     `json:"kubernetes_host_pid,omitempty"`
+	KubernetesHostPaths       []string `json:"kubernetes_host_paths,omitempty"`
+	KubernetesCapabilities    []string `json:"kubernetes_capabilities,omitempty"`
+	KubernetesRunAsRoot       bool     `json:"kubernetes_run_as_root,omitempty"`
+	KubernetesAutomountToken  bool     `json:"kubernetes_automount_token,omitempty"`
+	KubernetesRoleKind        string   `json:"kubernetes_role_kind,omitempty"`
+	KubernetesRoleName        string   `json:"kubernetes_role_name,omitempty"`
+	KubernetesSubjects        []string `json:"kubernetes_subjects,omitempty"`
+	KubernetesPermissions     []string `json:"kubernetes_permissions,omitempty"`
 	KubernetesType            string   `json:"kubernetes_type,omitempty"`
 	KubernetesPorts           []string `json:"kubernetes_ports,omitempty"`
 	KubernetesClusterId       string   `json:"kubernetes_cluster_id,omitempty"`
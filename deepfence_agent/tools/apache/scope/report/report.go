@@ -20,6 +20,8 @@ const (
 	Host              = "host"
 	Overlay           = "overlay"
 	KubernetesCluster = "kubernetes_cluster"
+	RuntimeEvent      = "runtime_event"
+	RoleBinding       = "kubernetes_role_binding"
 
 	// Shapes used for different nodes
 	Circle         = "circle"
@@ -244,6 +246,8 @@ var topologyNames = []string{
 	Namespace,
 	Host,
 	Overlay,
+	RuntimeEvent,
+	RoleBinding,
 }
 
 type TopologyNode struct {
@@ -488,6 +492,18 @@ type Report struct {
 	// their status endpoints. Edges are present.
 	Overlay Topology
 
+	// RuntimeEvent nodes are process exec/exit and sensitive file write
+	// events traced on hosts running probes. Metadata includes things like
+	// event type, process lineage and modified file path. Edges are not
+	// present.
+	RuntimeEvent Topology
+
+	// RoleBinding nodes are the RoleBindings and ClusterRoleBindings
+	// of the cluster, resolved against the Role or ClusterRole they bind.
+	// Metadata includes things like subjects and granted permissions. Edges
+	// are not present.
+	RoleBinding Topology
+
 	DNS DNSRecords `json:"DNS,omitempty" deepequal:"nil==empty"`
 	// Backwards-compatibility for an accident in commit 951629a / release 1.11.6.
 	BugDNS DNSRecords `json:"nodes,omitempty"`
@@ -526,6 +542,8 @@ func MakeReport() Report {
 		ContainerImage:    MakeTopology(),
 		Host:              MakeTopology(),
 		Overlay:           MakeTopology(),
+		RuntimeEvent:      MakeTopology(),
+		RoleBinding:       MakeTopology(),
 		DNS:               DNSRecords{},
 		Window:            0,
 		ID:                fmt.Sprintf("%d", rand.Int63()),
@@ -569,6 +587,12 @@ func (r *Report) Clear() {
 	for k := range r.Overlay {
 		delete(r.Overlay, k)
 	}
+	for k := range r.RuntimeEvent {
+		delete(r.RuntimeEvent, k)
+	}
+	for k := range r.RoleBinding {
+		delete(r.RoleBinding, k)
+	}
 }
 
 // Copy returns a value copy of the report.
@@ -661,6 +685,10 @@ func (r *Report) topology(name string) *Topology {
 		return &r.Host
 	case Overlay:
 		return &r.Overlay
+	case RuntimeEvent:
+		return &r.RuntimeEvent
+	case RoleBinding:
+		return &r.RoleBinding
 	}
 	return nil
 }
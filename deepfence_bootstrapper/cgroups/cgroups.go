@@ -3,8 +3,12 @@ package cgroups
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"runtime"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/containerd/cgroups/v3"
 	"github.com/containerd/cgroups/v3/cgroup1"
@@ -13,7 +17,16 @@ import (
 	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
+const (
+	// kernel defaults, restored when the io weight is removed
+	defaultBlkioWeight = 500
+	defaultBFQWeight   = 100
+)
+
 var (
+	// guards cgroups1 and cgroups2, limits are set while the health
+	// endpoint reads the usage
+	cgroupsLock       sync.RWMutex
 	cgroups1          = map[string]cgroup1.Cgroup{}
 	cgroups2          = map[string]*cgroup2.Manager{}
 	ErrFailUpdate     = errors.New("failed to update")
@@ -29,6 +42,8 @@ func init() {
 }
 
 func LoadCgroup(name string, cpulimit int64, memlimit int64) error {
+	cgroupsLock.Lock()
+	defer cgroupsLock.Unlock()
 	if !cgroupV2 {
 		shares := uint64(cpulimit)
 		path := cgroup1.StaticPath(fmt.Sprintf("/%s", name))
@@ -92,6 +107,8 @@ func LoadCgroup(name string, cpulimit int64, memlimit int64) error {
 }
 
 func AttachProcessToCgroup(name string, pid int) error {
+	cgroupsLock.RLock()
+	defer cgroupsLock.RUnlock()
 	if !cgroupV2 {
 		control, has := cgroups1[name]
 		if !has {
@@ -107,7 +124,164 @@ func AttachProcessToCgroup(name string, pid int) error {
 	}
 }
 
+// SetLimits applies live CPU, memory and IO limits to the cgroup, creating
+// it if needed. cpuPercent is a percentage of all the CPUs, ioWeight is
+// between 1 and 10000, 0 means unlimited for all of them.
+func SetLimits(name string, cpuPercent int64, memLimit int64, ioWeight uint16) error {
+	cgroupsLock.Lock()
+	defer cgroupsLock.Unlock()
+
+	period := uint64(100000) // 100 ms
+	var quota *int64
+	if cpuPercent > 0 {
+		q := int64(period) * int64(runtime.NumCPU()) * cpuPercent / 100
+		quota = &q
+	}
+
+	// the kernel takes weights between 10 and 1000 for v1, 1 and 1000 for bfq
+	weight := ioWeight / 10
+	if weight < 10 {
+		weight = 10
+	}
+	if weight > 1000 {
+		weight = 1000
+	}
+
+	if !cgroupV2 {
+		unlimited := int64(-1)
+		if quota == nil {
+			quota = &unlimited
+		}
+		if memLimit <= 0 {
+			memLimit = unlimited
+		}
+		res := &specs.LinuxResources{
+			CPU: &specs.LinuxCPU{
+				Period: &period,
+				Quota:  quota,
+			},
+			Memory: &specs.LinuxMemory{
+				Limit: &memLimit,
+			},
+		}
+		if ioWeight == 0 {
+			weight = defaultBlkioWeight
+		}
+		res.BlockIO = &specs.LinuxBlockIO{Weight: &weight}
+		control, has := cgroups1[name]
+		if !has {
+			path := cgroup1.StaticPath(fmt.Sprintf("/%s", name))
+			var err error
+			control, err = cgroup1.Load(path)
+			if err != nil {
+				control, err = cgroup1.New(path, res)
+				if err != nil {
+					return ErrFailCreate
+				}
+			}
+			cgroups1[name] = control
+		}
+		if err := control.Update(res); err != nil {
+			return ErrFailUpdate
+		}
+		return nil
+	}
+
+	if memLimit <= 0 {
+		memLimit = math.MaxInt64
+	}
+	res := cgroup2.Resources{
+		CPU: &cgroup2.CPU{
+			Max: cgroup2.NewCPUMax(quota, &period),
+		},
+		Memory: &cgroup2.Memory{
+			Max: &memLimit,
+		},
+	}
+	m, has := cgroups2[name]
+	if !has {
+		var err error
+		m, err = cgroup2.LoadSystemd("/", name+".slice")
+		if err != nil || m.Update(&res) != nil {
+			m, err = cgroup2.NewSystemd("/", name+".slice", -1, &res)
+			if err != nil {
+				return ErrFailCreate
+			}
+		}
+		cgroups2[name] = m
+	}
+	if err := m.Update(&res); err != nil {
+		return ErrFailUpdate
+	}
+	if ioWeight == 0 {
+		weight = defaultBFQWeight
+	}
+	// bfq might not be the io scheduler of the node
+	err := m.Update(&cgroup2.Resources{IO: &cgroup2.IO{BFQ: cgroup2.BFQ{Weight: weight}}})
+	if err != nil && ioWeight > 0 {
+		log.Warn().Msgf("io weight of %s: %v", name, err)
+	}
+	return nil
+}
+
+// Usage is the cumulated CPU time and the current memory usage of a cgroup
+type Usage struct {
+	CPUTime     time.Duration
+	MemoryUsage uint64
+	MemoryLimit uint64
+}
+
+func Names() []string {
+	cgroupsLock.RLock()
+	defer cgroupsLock.RUnlock()
+	res := []string{}
+	for name := range cgroups1 {
+		res = append(res, name)
+	}
+	for name := range cgroups2 {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func GetUsage(name string) (Usage, error) {
+	cgroupsLock.RLock()
+	defer cgroupsLock.RUnlock()
+	if !cgroupV2 {
+		control, has := cgroups1[name]
+		if !has {
+			return Usage{}, ErrCgroupNotExist
+		}
+		metrics, err := control.Stat(cgroup1.IgnoreNotExist)
+		if err != nil {
+			return Usage{}, err
+		}
+		return Usage{
+			CPUTime:     time.Duration(metrics.GetCPU().GetUsage().GetTotal()),
+			MemoryUsage: metrics.GetMemory().GetUsage().GetUsage(),
+			MemoryLimit: metrics.GetMemory().GetUsage().GetLimit(),
+		}, nil
+	} else {
+		m, has := cgroups2[name]
+		if !has {
+			return Usage{}, ErrCgroupNotExist
+		}
+		metrics, err := m.Stat()
+		if err != nil {
+			return Usage{}, err
+		}
+		return Usage{
+			CPUTime:     time.Duration(metrics.GetCPU().GetUsageUsec()) * time.Microsecond,
+			MemoryUsage: metrics.GetMemory().GetUsage(),
+			MemoryLimit: metrics.GetMemory().GetUsageLimit(),
+		}, nil
+	}
+}
+
 func UnloadAll() {
+	cgroupsLock.Lock()
+	defer cgroupsLock.Unlock()
 	for _, v := range cgroups1 {
 		_ = v.Delete()
 	}
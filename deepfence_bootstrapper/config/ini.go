@@ -8,13 +8,18 @@ import (
 )
 
 type ProcessEntry struct {
-	Autorestart bool
-	Autostart   bool
-	Path        string
-	Name        string
-	Cgroup      string
-	Env         string
-	Command     string
+	Autorestart    bool
+	Autostart      bool
+	Path           string
+	Name           string
+	Cgroup         string
+	Env            string
+	Command        string
+	HealthCheck    string
+	HealthInterval int
+	HealthFailures int
+	BackoffInitial int
+	BackoffMax     int
 }
 
 type CgroupEntry struct {
@@ -51,6 +56,12 @@ func NewIniConfig(input []byte) (Config, error) {
 				Command:     section.Key("command").String(),
 				Env:         section.Key("environment").String(),
 				Name:        typeName[1],
+				HealthCheck: section.Key("healthcheck").String(),
+				// seconds, 0 for the defaults
+				HealthInterval: section.Key("healthcheck_interval").MustInt(),
+				HealthFailures: section.Key("healthcheck_failures").MustInt(),
+				BackoffInitial: section.Key("backoff_initial").MustInt(),
+				BackoffMax:     section.Key("backoff_max").MustInt(),
 			})
 		} else if typeName[0] == "cgroup" {
 			cgroupEntries = append(cgroupEntries, CgroupEntry{
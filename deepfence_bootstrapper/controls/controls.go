@@ -110,6 +110,32 @@ func SetAgentControls() {
 	if err != nil {
 		log.Error().Err(err).Msg("set controls")
 	}
+	err = router.RegisterControl(ctl.RollbackAgentUpgrade,
+		func(req ctl.RollbackAgentUpgradeRequest) error {
+			log.Info().Msg("Rollback Agent Upgrade")
+			router.SetUpgrade()
+			defer router.UnsetUpgrade()
+			return router.RollbackAgentUpgrade(req)
+		})
+	if err != nil {
+		log.Error().Err(err).Msg("set controls")
+	}
+	err = router.RegisterControl(ctl.UpdateAgentResourceLimits,
+		func(req ctl.UpdateAgentResourceLimitsRequest) error {
+			log.Info().Msg("Update Agent Resource Limits")
+			return router.UpdateAgentResourceLimits(req)
+		})
+	if err != nil {
+		log.Error().Err(err).Msg("set controls")
+	}
+	err = router.RegisterControl(ctl.UpdateCustomScanRules,
+		func(req ctl.UpdateCustomScanRulesRequest) error {
+			log.Info().Msg("Update Custom Scan Rules")
+			return router.UpdateCustomScanRules(req)
+		})
+	if err != nil {
+		log.Error().Err(err).Msg("set controls")
+	}
 	err = router.RegisterControl(ctl.StartAgentPlugin,
 		func(req ctl.EnableAgentPluginRequest) error {
 			log.Info().Msg("Start & download Agent Plugin")
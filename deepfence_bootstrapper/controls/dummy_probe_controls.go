@@ -90,6 +90,30 @@ func setAgentControls() {
 	if err != nil {
 		log.Error().Msgf("set controls: %v", err)
 	}
+	err = controls.RegisterControl(ctl.RollbackAgentUpgrade,
+		func(req ctl.RollbackAgentUpgradeRequest) error {
+			log.Info("Rollback Agent Upgrade")
+			return errors.New("Not implemented")
+		})
+	if err != nil {
+		log.Error().Msgf("set controls: %v", err)
+	}
+	err = controls.RegisterControl(ctl.UpdateAgentResourceLimits,
+		func(req ctl.UpdateAgentResourceLimitsRequest) error {
+			log.Info("Update Agent Resource Limits")
+			return errors.New("Not implemented")
+		})
+	if err != nil {
+		log.Error().Msgf("set controls: %v", err)
+	}
+	err = controls.RegisterControl(ctl.UpdateCustomScanRules,
+		func(req ctl.UpdateCustomScanRulesRequest) error {
+			log.Info("Update Custom Scan Rules")
+			return errors.New("Not implemented")
+		})
+	if err != nil {
+		log.Error().Msgf("set controls: %v", err)
+	}
 	err = controls.RegisterControl(ctl.SendAgentDiagnosticLogs,
 		func(req ctl.SendAgentDiagnosticLogsRequest) error {
 			log.Info("Generate Agent Diagnostic Logs")
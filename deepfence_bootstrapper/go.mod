@@ -29,6 +29,7 @@ require (
 	github.com/weaveworks/scope v1.13.2
 	google.golang.org/grpc v1.56.1
 	gopkg.in/ini.v1 v1.67.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
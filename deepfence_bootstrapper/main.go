@@ -22,6 +22,8 @@ import (
 
 var authCheckPeriod = time.Second * 10
 
+const enrollRetryPeriod = time.Minute
+
 //go:embed assets/config.ini
 var configFile []byte
 
@@ -92,9 +94,32 @@ func main() {
 		}
 	}
 
+	nodeID := hostname
+	if enableClusterDiscovery {
+		nodeID, _, _, _, _ = dfUtils.GetKubernetesDetails()
+	}
+	// before starting the processes so they use the credential too
+	enrollPending := false
+	if err := router.EnrollAgent(nodeID); errors.Is(err, router.ErrEnrollmentPending) {
+		log.Warn().Msgf("Agent enrollment of %s waits for approval on the console, using the API key", nodeID)
+		enrollPending = true
+	} else if err != nil {
+		log.Warn().Msgf("Agent enrollment failed, using the API key: %v", err)
+	}
+
 	autostart := []string{}
 	for _, entry := range cfg.Processes {
-		supervisor.LoadProcess(entry.Name, entry.Path, entry.Command, entry.Env, entry.Autorestart, entry.Cgroup)
+		health, err := supervisor.ParseHealthCheck(entry.HealthCheck,
+			time.Duration(entry.HealthInterval)*time.Second, entry.HealthFailures)
+		if err != nil {
+			log.Error().Msgf("Health check of %v ignored: %v", entry.Name, err)
+		}
+		backoff := supervisor.Backoff{
+			Initial: time.Duration(entry.BackoffInitial) * time.Second,
+			Max:     time.Duration(entry.BackoffMax) * time.Second,
+		}
+		supervisor.LoadProcess(entry.Name, entry.Path, entry.Command, entry.Env, entry.Autorestart, entry.Cgroup,
+			health, backoff)
 		if entry.Autorestart {
 			autostart = append(autostart, entry.Name)
 		}
@@ -107,6 +132,10 @@ func main() {
 		}
 	}
 
+	if enrollPending {
+		go waitEnrollment(nodeID)
+	}
+
 	if enableClusterDiscovery {
 		_, k8sClusterName, _, _, _ := dfUtils.GetKubernetesDetails()
 		controls.SetClusterAgentControls(k8sClusterName)
@@ -148,3 +177,31 @@ func main() {
 	log.Info().Msgf("Signal received, wrapping up: %v", ctx.Err())
 	cgroups.UnloadAll()
 }
+
+// waitEnrollment retries the enrollment until an admin approves it, the
+// running processes are restarted to use the credential
+func waitEnrollment(nodeID string) {
+	for {
+		time.Sleep(enrollRetryPeriod)
+		err := router.EnrollAgent(nodeID)
+		if errors.Is(err, router.ErrEnrollmentPending) {
+			continue
+		} else if err != nil {
+			log.Warn().Msgf("Agent enrollment failed: %v", err)
+			continue
+		}
+		log.Info().Msgf("Agent enrollment of %s approved", nodeID)
+		for _, status := range supervisor.ProcessesStatus() {
+			if !status.Running {
+				continue
+			}
+			if err := supervisor.StopProcess(status.Name); err != nil {
+				log.Error().Msgf("Cannot stop %s: %v", status.Name, err)
+			}
+			if err := supervisor.StartProcess(status.Name); err != nil {
+				log.Error().Msgf("Cannot start %s: %v", status.Name, err)
+			}
+		}
+		return
+	}
+}
@@ -26,6 +26,9 @@ func RegisterControl[T ctl.StartVulnerabilityScanRequest |
 	ctl.StartComplianceScanRequest |
 	ctl.StartMalwareScanRequest |
 	ctl.StartAgentUpgradeRequest |
+	ctl.RollbackAgentUpgradeRequest |
+	ctl.UpdateAgentResourceLimitsRequest |
+	ctl.UpdateCustomScanRulesRequest |
 	ctl.SendAgentDiagnosticLogsRequest |
 	ctl.DisableAgentPluginRequest |
 	ctl.EnableAgentPluginRequest |
@@ -0,0 +1,284 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/deepfence/agent-plugins-grpc/srcgo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
+
+	"github.com/deepfence/ThreatMapper/deepfence_bootstrapper/supervisor"
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+)
+
+const (
+	restartIdlePeriod  = 10 * time.Second
+	restartIdleTimeout = time.Hour
+)
+
+var (
+	secretScannerConfig    = os.ExpandEnv("${DF_INSTALL_DIR}/home/deepfence/bin/secret-scanner/config.yaml")
+	malwareScannerConfig   = os.ExpandEnv("${DF_INSTALL_DIR}/home/deepfence/bin/yara-hunter/config.yaml")
+	malwareCustomRules     = os.ExpandEnv("${DF_INSTALL_DIR}/home/deepfence/bin/yara-hunter/yara-rules/custom.yar")
+	customRulesVersionFile = os.ExpandEnv("${DF_INSTALL_DIR}/var/lib/deepfence/custom-rules-version")
+)
+
+// UpdateCustomScanRules installs the custom rules of the console next to the
+// bundled ones and restarts the scanners to load them, once their running
+// scans are over. A scanner not starting with the new rules gets the
+// previous ones back.
+func UpdateCustomScanRules(req ctl.UpdateCustomScanRulesRequest) error {
+	current, err := installedCustomRulesVersion()
+	if err != nil {
+		return err
+	}
+	if current == req.Version {
+		log.Info().Msgf("Custom rules version %d already installed", req.Version)
+		return nil
+	}
+
+	secret, err := fetchRules(req.SecretRulesURL)
+	if err != nil {
+		return err
+	}
+	malware, err := fetchRules(req.MalwareRulesURL)
+	if err != nil {
+		return err
+	}
+
+	restoreSecret, err := keepPrevious(secretScannerConfig)
+	if err != nil {
+		return err
+	}
+	restoreMalware, err := keepPrevious(malwareCustomRules)
+	if err != nil {
+		return err
+	}
+	restoreVersion, err := keepPrevious(customRulesVersionFile)
+	if err != nil {
+		return err
+	}
+
+	err = writeSecretRules(secret)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(malwareCustomRules, malware)
+	if err != nil {
+		if err := restoreSecret(); err != nil {
+			log.Error().Msgf("Cannot restore the secret scanner config: %v", err)
+		}
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(customRulesVersionFile), 0755); err != nil {
+		return err
+	}
+	err = os.WriteFile(customRulesVersionFile, []byte(strconv.FormatInt(req.Version, 10)), 0644)
+	if err != nil {
+		return err
+	}
+
+	go secretScannerProfile.restartWhenIdle(func() error {
+		if err := restoreSecret(); err != nil {
+			return err
+		}
+		return restoreVersion()
+	})
+	go malwareScannerProfile.restartWhenIdle(func() error {
+		if err := restoreMalware(); err != nil {
+			return err
+		}
+		return restoreVersion()
+	})
+	return nil
+}
+
+// installedCustomRulesVersion returns the version of the custom rules the
+// scanners run with, reported to the console with the heartbeats, 0 when none
+func installedCustomRulesVersion() (int64, error) {
+	data, err := os.ReadFile(customRulesVersionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	version, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		// a corrupt version gets the rules installed again
+		return 0, nil
+	}
+	return version, nil
+}
+
+// keepPrevious reads the file about to be replaced and returns the function
+// writing it back, or removing the file when there was none
+func keepPrevious(path string) (func() error, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return func() error {
+			err := os.Remove(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return func() error {
+		return writeFileAtomic(path, data)
+	}, nil
+}
+
+func fetchRules(url string) ([]byte, error) {
+	if url == "" {
+		return nil, nil
+	}
+	f, err := os.CreateTemp("", "custom-rules-*")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	err = downloadFile(f.Name(), url)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(f.Name())
+}
+
+func bundledConfigPath(path string) string {
+	return path + ".bundled"
+}
+
+// keepBundledConfig keeps aside the config shipped with the scanner, if any,
+// before its first update
+func keepBundledConfig(path string) error {
+	bundled := bundledConfigPath(path)
+	if _, err := os.Stat(bundled); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return supervisor.WriteTo(bundled, path)
+}
+
+// writeSecretRules appends the custom signatures to the ones of the bundled
+// config, the exclude paths of the current scan profile are kept
+func writeSecretRules(custom []byte) error {
+	if err := keepBundledConfig(secretScannerConfig); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(bundledConfigPath(secretScannerConfig))
+	if err != nil {
+		return err
+	}
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	current := map[string]interface{}{}
+	if data, err := os.ReadFile(secretScannerConfig); err == nil {
+		if err := yaml.Unmarshal(data, &current); err == nil {
+			if excludes, has := current[scanProfileExcludeKey]; has {
+				config[scanProfileExcludeKey] = excludes
+			}
+		}
+	}
+
+	var customConfig struct {
+		Signatures []interface{} `yaml:"signatures"`
+	}
+	if err := yaml.Unmarshal(custom, &customConfig); err != nil {
+		return err
+	}
+	signatures, _ := config["signatures"].([]interface{})
+	config["signatures"] = append(signatures, customConfig.Signatures...)
+
+	data, err = yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(secretScannerConfig, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restartWhenIdle restarts the scanner once it has no scan running, or after
+// the timeout. No scan starts meanwhile. When the scanner does not serve
+// with the new rules, they are rolled back and the scanner restarted again.
+func (sp *scannerProfile) restartWhenIdle(rollback func() error) {
+	deadline := time.Now().Add(restartIdleTimeout)
+	for {
+		sp.access.Lock()
+		if sp.jobs() == 0 || time.Now().After(deadline) {
+			break
+		}
+		sp.access.Unlock()
+		time.Sleep(restartIdlePeriod)
+	}
+	defer sp.access.Unlock()
+
+	err := sp.restart()
+	if errors.Is(err, supervisor.ErrNotRunning) {
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot restart %s to load the custom rules: %v", sp.process, err)
+		return
+	}
+
+	err = sp.waitServing()
+	if err == nil {
+		return
+	}
+	log.Error().Msgf("%s does not start with the custom rules, restoring the previous ones: %v", sp.process, err)
+	if err := rollback(); err != nil {
+		log.Error().Msgf("Cannot restore the custom rules of %s: %v", sp.process, err)
+		return
+	}
+	if err := sp.restart(); err != nil {
+		log.Error().Msgf("Cannot restart %s with the previous custom rules: %v", sp.process, err)
+	}
+}
+
+func (sp *scannerProfile) restart() error {
+	err := supervisor.StopProcess(sp.process)
+	if err != nil {
+		return err
+	}
+	return supervisor.StartProcess(sp.process)
+}
+
+// waitServing waits for the scanner to answer on its socket, the scanners
+// load their rules before serving
+func (sp *scannerProfile) waitServing() error {
+	ctx, cancel := context.WithTimeout(context.Background(), scannerStartTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "unix://"+sp.socket, grpc.WithAuthority("dummy"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pb.NewScannersClient(conn).ReportJobsStatus(ctx, &pb.Empty{}, grpc.WaitForReady(true))
+	return err
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_bootstrapper/cgroups"
+	"github.com/deepfence/ThreatMapper/deepfence_bootstrapper/supervisor"
+	openapi "github.com/deepfence/golang_deepfence_sdk/client"
+	"github.com/rs/zerolog/log"
+)
+
+type cpuSample struct {
+	cpuTime time.Duration
+	at      time.Time
+}
+
+var (
+	cpuSamples      = map[string]cpuSample{}
+	cpuSamplesGuard sync.Mutex
+)
+
+// getAgentHealth reports the state of the supervised plugins and the
+// resources used by the cgroups along with the controls polling
+func getAgentHealth() openapi.ModelAgentHealthReport {
+	plugins := []openapi.ModelAgentPluginStatus{}
+	for _, process := range supervisor.ProcessesStatus() {
+		status := "stopped"
+		if process.Crashed {
+			status = "crashed"
+		} else if process.Running {
+			status = "running"
+		}
+		plugin := openapi.NewModelAgentPluginStatusWithDefaults()
+		plugin.SetName(process.Name)
+		plugin.SetStatus(status)
+		plugin.SetRestarts(int32(process.Restarts))
+		plugin.SetRecentRestarts(int32(process.RecentRestarts))
+		plugin.SetLastExitCode(int32(process.LastExitCode))
+		plugin.SetHealthCheck(process.HealthCheck)
+		plugin.SetCrashReason(process.CrashReason)
+		plugin.SetLastLogLines(process.LastLogLines)
+		plugin.SetSince(process.Since.UnixMilli())
+		plugins = append(plugins, *plugin)
+	}
+
+	cpuSamplesGuard.Lock()
+	defer cpuSamplesGuard.Unlock()
+
+	usages := []openapi.ModelAgentCgroupUsage{}
+	now := time.Now()
+	for _, name := range cgroups.Names() {
+		usage, err := cgroups.GetUsage(name)
+		if err != nil {
+			log.Warn().Msgf("cgroup %s usage: %v", name, err)
+			continue
+		}
+		millicore := int64(0)
+		if prev, has := cpuSamples[name]; has && now.After(prev.at) && usage.CPUTime >= prev.cpuTime {
+			millicore = int64(usage.CPUTime-prev.cpuTime) * 1000 / int64(now.Sub(prev.at))
+		}
+		cpuSamples[name] = cpuSample{cpuTime: usage.CPUTime, at: now}
+
+		cgroup := openapi.NewModelAgentCgroupUsageWithDefaults()
+		cgroup.SetName(name)
+		cgroup.SetCpuUsageMillicore(millicore)
+		cgroup.SetMemoryUsage(int64(usage.MemoryUsage))
+		// no limit is reported as the max value
+		if usage.MemoryLimit <= math.MaxInt64 {
+			cgroup.SetMemoryLimit(int64(usage.MemoryLimit))
+		}
+		usages = append(usages, *cgroup)
+	}
+
+	health := openapi.NewModelAgentHealthReportWithDefaults()
+	health.SetPlugins(plugins)
+	health.SetCgroups(usages)
+	return *health
+}
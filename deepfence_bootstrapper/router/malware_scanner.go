@@ -86,7 +86,15 @@ func StartMalwareScan(req ctl.StartMalwareScanRequest) error {
 	if err != nil {
 		return err
 	}
-	_, err = client.FindMalwareInfo(context.Background(), &greq)
+	malwareScannerProfile.access.Lock()
+	defer malwareScannerProfile.access.Unlock()
+	ctx, cancel, err := malwareScannerProfile.apply(req.Profile, req.NodeType, MalwareScanDir)
+	if err != nil {
+		log.Error().Msgf("Cannot apply scan profile: %v", err)
+		return err
+	}
+	defer cancel()
+	_, err = client.FindMalwareInfo(ctx, &greq, grpc.WaitForReady(true))
 	if err != nil {
 		return err
 	}
@@ -1,12 +1,14 @@
 package router
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"os"
 	"strings"
 	"sync/atomic"
 
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
 	"github.com/deepfence/golang_deepfence_sdk/client"
 	openapi "github.com/deepfence/golang_deepfence_sdk/utils/http"
 	"github.com/rs/zerolog/log"
@@ -19,7 +21,10 @@ type OpenapiClient struct {
 	rawClient            *http.Client
 }
 
-var ErrPushBack = errors.New("server push back")
+var (
+	ErrPushBack          = errors.New("server push back")
+	ErrEnrollmentPending = errors.New("enrollment waiting for approval")
+)
 
 func NewOpenapiClient() (*OpenapiClient, error) {
 	openapiClient, err := NewClient()
@@ -49,15 +54,21 @@ var (
 	ErrConn = errors.New("connection error")
 )
 
+// NewClient authenticates with the credential of the agent once enrolled,
+// with the API key otherwise
 func NewClient() (*openapi.OpenapiHttpClient, error) {
+	cred, err := ctl.ReadAgentCredential()
+	if err != nil {
+		return newAPIKeyClient()
+	}
+	return newConsoleClient(cred.Credential)
+}
+
+func newAPIKeyClient() (*openapi.OpenapiHttpClient, error) {
 	url := os.Getenv("MGMT_CONSOLE_URL")
 	if url == "" {
 		return nil, errors.New("MGMT_CONSOLE_URL not set")
 	}
-	port := os.Getenv("MGMT_CONSOLE_PORT")
-	if port == "" {
-		return nil, errors.New("MGMT_CONSOLE_PORT not set")
-	}
 
 	apiToken := os.Getenv("DEEPFENCE_KEY")
 	if strings.Trim(apiToken, "\"") == "" && openapi.IsConsoleAgent(url) {
@@ -72,6 +83,19 @@ func NewClient() (*openapi.OpenapiHttpClient, error) {
 		return nil, errors.New("DEEPFENCE_KEY not set")
 	}
 
+	return newConsoleClient(apiToken)
+}
+
+func newConsoleClient(apiToken string) (*openapi.OpenapiHttpClient, error) {
+	url := os.Getenv("MGMT_CONSOLE_URL")
+	if url == "" {
+		return nil, errors.New("MGMT_CONSOLE_URL not set")
+	}
+	port := os.Getenv("MGMT_CONSOLE_PORT")
+	if port == "" {
+		return nil, errors.New("MGMT_CONSOLE_PORT not set")
+	}
+
 	httpsClient := openapi.NewHttpsConsoleClient(url, port)
 	err := httpsClient.APITokenAuthenticate(apiToken)
 	if err != nil {
@@ -79,3 +103,38 @@ func NewClient() (*openapi.OpenapiHttpClient, error) {
 	}
 	return httpsClient, nil
 }
+
+// EnrollAgent exchanges the API key for a credential bound to nodeID, shared
+// with the other agent processes. Agents already enrolled as nodeID keep
+// their credential. The enrollments of nodes already enrolled wait for the
+// approval of an admin, ErrEnrollmentPending is returned meanwhile.
+func EnrollAgent(nodeID string) error {
+	if cred, err := ctl.ReadAgentCredential(); err == nil && cred.NodeID == nodeID {
+		return nil
+	}
+
+	key, err := ctl.AgentEnrollmentKey()
+	if err != nil {
+		return err
+	}
+
+	httpsClient, err := newAPIKeyClient()
+	if err != nil {
+		return err
+	}
+
+	req := httpsClient.Client().ControlsAPI.EnrollAgent(context.Background())
+	req = req.ModelAgentEnrollReq(*client.NewModelAgentEnrollReq(key, nodeID))
+	res, _, err := httpsClient.Client().ControlsAPI.EnrollAgentExecute(req)
+	if err != nil {
+		return err
+	}
+	if res.GetPending() {
+		return ErrEnrollmentPending
+	}
+
+	return ctl.WriteAgentCredential(ctl.AgentCredential{
+		NodeID:     nodeID,
+		Credential: res.GetCredential(),
+	})
+}
@@ -18,13 +18,15 @@ func (ct *OpenapiClient) StartControlsWatching(nodeID string, isClusterAgent boo
 
 	} else {
 		req := ct.API().ControlsAPI.GetAgentInitControls(context.Background())
-		req = req.ModelInitAgentReq(
-			*openapi.NewModelInitAgentReq(
-				getMaxAllocatable(),
-				nodeID,
-				version,
-			),
+		initReq := openapi.NewModelInitAgentReq(
+			getMaxAllocatable(),
+			nodeID,
+			version,
 		)
+		if rulesVersion, err := installedCustomRulesVersion(); err == nil {
+			initReq.SetCustomRulesVersion(rulesVersion)
+		}
+		req = req.ModelInitAgentReq(*initReq)
 		ctl, _, err := ct.API().ControlsAPI.GetAgentInitControlsExecute(req)
 
 		if err != nil {
@@ -88,6 +90,12 @@ func (ct *OpenapiClient) StartControlsWatching(nodeID string, isClusterAgent boo
 					break
 				}
 				agentID.SetAvailableWorkload(getMaxAllocatable())
+				agentID.SetHealth(getAgentHealth())
+				if version, err := installedCustomRulesVersion(); err == nil {
+					agentID.SetCustomRulesVersion(version)
+				} else {
+					log.Warn().Msgf("Cannot read custom rules version: %v", err)
+				}
 				req = req.ModelAgentID(*agentID)
 				ctl, _, err := ct.API().ControlsAPI.GetAgentControlsExecute(req)
 				if err != nil {
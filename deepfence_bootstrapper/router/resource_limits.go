@@ -0,0 +1,138 @@
+package router
+
+import (
+	"sync"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_bootstrapper/cgroups"
+	"github.com/deepfence/ThreatMapper/deepfence_bootstrapper/supervisor"
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	resourceLimitsPeriod = 10 * time.Second
+	cgroupPrefix         = "deepfence_"
+)
+
+// scanning tells if the plugin is running a scan, to switch to its scan
+// limits
+var scanning = map[string]func() bool{
+	"package_scanner": func() bool { return GetPackageScannerJobCount() > 0 },
+	"secret_scanner":  func() bool { return GetSecretScannerJobCount() > 0 },
+	"malware_scanner": func() bool { return GetMalwareScannerJobCount() > 0 },
+}
+
+type appliedLimits struct {
+	limits ctl.PluginResourceLimits
+	scan   bool
+}
+
+// the cgroups and the processes the limits apply to
+var (
+	setCgroupLimits    = cgroups.SetLimits
+	setProcessCgroup   = supervisor.SetProcessCgroup
+	resetProcessCgroup = supervisor.ResetProcessCgroup
+)
+
+var (
+	resourceLimits      = map[string]ctl.PluginResourceLimits{}
+	appliedResource     = map[string]appliedLimits{}
+	resourceLimitsGuard sync.Mutex
+	resourceLimitsOnce  sync.Once
+)
+
+// UpdateAgentResourceLimits replaces the resource limits of the plugins. The
+// limits are checked periodically to follow the scans starting and ending.
+func UpdateAgentResourceLimits(req ctl.UpdateAgentResourceLimitsRequest) error {
+	resourceLimitsGuard.Lock()
+	resourceLimits = map[string]ctl.PluginResourceLimits{}
+	for _, limits := range req.Limits {
+		resourceLimits[limits.PluginName] = limits
+	}
+	resourceLimitsGuard.Unlock()
+
+	resourceLimitsOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(resourceLimitsPeriod)
+			defer ticker.Stop()
+			for range ticker.C {
+				applyResourceLimits()
+			}
+		}()
+	})
+
+	return applyResourceLimits()
+}
+
+func applyResourceLimits() error {
+	resourceLimitsGuard.Lock()
+	defer resourceLimitsGuard.Unlock()
+
+	var lastErr error
+
+	// plugins not limited anymore go back to the cgroup of their config
+	for name := range appliedResource {
+		if _, has := resourceLimits[name]; has {
+			continue
+		}
+		err := setCgroupLimits(cgroupPrefix+name, 0, 0, 0)
+		if err != nil {
+			log.Error().Msgf("Remove limits of %s: %v", name, err)
+			lastErr = err
+			continue
+		}
+		err = resetProcessCgroup(name)
+		if err != nil {
+			log.Error().Msgf("Move %s back to its cgroup: %v", name, err)
+			lastErr = err
+			continue
+		}
+		log.Info().Msgf("Limits of %s removed", name)
+		delete(appliedResource, name)
+	}
+
+	for name, limits := range resourceLimits {
+		scan := false
+		if isScanning, has := scanning[name]; has {
+			scan = isScanning()
+		}
+		if applied, has := appliedResource[name]; has && applied.limits == limits && applied.scan == scan {
+			continue
+		}
+
+		cpu, mem := pluginLimits(limits, scan)
+		cgroup := cgroupPrefix + name
+		err := setCgroupLimits(cgroup, int64(cpu), mem*1024*1024, uint16(limits.IOWeight))
+		if err != nil {
+			log.Error().Msgf("Set limits of %s: %v", name, err)
+			lastErr = err
+			continue
+		}
+		err = setProcessCgroup(name, cgroup)
+		if err != nil {
+			log.Error().Msgf("Move %s to %s: %v", name, cgroup, err)
+			lastErr = err
+			continue
+		}
+		log.Info().Msgf("Limits of %s: cpu %d%%, memory %dMB, scanning: %v", name, cpu, mem, scan)
+		appliedResource[name] = appliedLimits{limits: limits, scan: scan}
+	}
+
+	return lastErr
+}
+
+// pluginLimits returns the CPU percentage and the memory in MB of the plugin,
+// the scan limits replacing the others while it scans
+func pluginLimits(limits ctl.PluginResourceLimits, scan bool) (int, int64) {
+	cpu, mem := limits.CPUPercentage, limits.MemoryMB
+	if scan {
+		if limits.ScanCPUPercentage > 0 {
+			cpu = limits.ScanCPUPercentage
+		}
+		if limits.ScanMemoryMB > 0 {
+			mem = limits.ScanMemoryMB
+		}
+	}
+	return cpu, mem
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+)
+
+func TestPluginLimits(t *testing.T) {
+	limits := ctl.PluginResourceLimits{
+		CPUPercentage:     20,
+		MemoryMB:          512,
+		ScanCPUPercentage: 50,
+	}
+	for _, tc := range []struct {
+		limits ctl.PluginResourceLimits
+		scan   bool
+		cpu    int
+		mem    int64
+	}{
+		{limits, false, 20, 512},
+		{limits, true, 50, 512},
+		{ctl.PluginResourceLimits{CPUPercentage: 20, ScanMemoryMB: 2048}, true, 20, 2048},
+		{ctl.PluginResourceLimits{}, true, 0, 0},
+	} {
+		cpu, mem := pluginLimits(tc.limits, tc.scan)
+		if cpu != tc.cpu || mem != tc.mem {
+			t.Errorf("limits of %+v scanning %v: cpu %d, memory %d, want %d, %d",
+				tc.limits, tc.scan, cpu, mem, tc.cpu, tc.mem)
+		}
+	}
+}
+
+func TestUpdateAgentResourceLimits(t *testing.T) {
+	calls := []string{}
+	scan := false
+	prevSet, prevMove, prevReset, prevScanning := setCgroupLimits, setProcessCgroup, resetProcessCgroup, scanning
+	t.Cleanup(func() {
+		setCgroupLimits, setProcessCgroup, resetProcessCgroup, scanning = prevSet, prevMove, prevReset, prevScanning
+		resourceLimits = map[string]ctl.PluginResourceLimits{}
+		appliedResource = map[string]appliedLimits{}
+	})
+	setCgroupLimits = func(name string, cpu int64, mem int64, io uint16) error {
+		calls = append(calls, fmt.Sprintf("limit %s %d %d %d", name, cpu, mem, io))
+		return nil
+	}
+	setProcessCgroup = func(name, cgroup string) error {
+		calls = append(calls, fmt.Sprintf("move %s %s", name, cgroup))
+		return nil
+	}
+	resetProcessCgroup = func(name string) error {
+		calls = append(calls, fmt.Sprintf("reset %s", name))
+		return nil
+	}
+	scanning = map[string]func() bool{"secret_scanner": func() bool { return scan }}
+	// no periodic check in the test
+	resourceLimitsOnce.Do(func() {})
+
+	check := func(step string, want []string) {
+		t.Helper()
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("%s: calls %v, want %v", step, calls, want)
+		}
+		calls = []string{}
+	}
+
+	err := UpdateAgentResourceLimits(ctl.UpdateAgentResourceLimitsRequest{
+		Limits: []ctl.PluginResourceLimits{{
+			PluginName:        "secret_scanner",
+			CPUPercentage:     20,
+			MemoryMB:          1,
+			IOWeight:          100,
+			ScanCPUPercentage: 50,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("profile pushed", []string{
+		"limit deepfence_secret_scanner 20 1048576 100",
+		"move secret_scanner deepfence_secret_scanner",
+	})
+
+	if err := applyResourceLimits(); err != nil {
+		t.Fatal(err)
+	}
+	check("unchanged", []string{})
+
+	scan = true
+	if err := applyResourceLimits(); err != nil {
+		t.Fatal(err)
+	}
+	check("scan started", []string{
+		"limit deepfence_secret_scanner 50 1048576 100",
+		"move secret_scanner deepfence_secret_scanner",
+	})
+
+	err = UpdateAgentResourceLimits(ctl.UpdateAgentResourceLimitsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("profile removed", []string{
+		"limit deepfence_secret_scanner 0 0 0",
+		"reset secret_scanner",
+	})
+	if len(appliedResource) != 0 {
+		t.Errorf("limits still applied: %v", appliedResource)
+	}
+}
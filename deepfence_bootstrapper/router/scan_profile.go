@@ -0,0 +1,312 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/deepfence/ThreatMapper/deepfence_bootstrapper/supervisor"
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+)
+
+// scanProfileExcludeKey is the key of the scanner configs listing the path
+// prefixes not to scan
+const scanProfileExcludeKey = "exclude_paths"
+
+const scannerStartTimeout = 2 * time.Minute
+
+var (
+	ErrScannerBusy            = errors.New("scanner is running scans of another scan profile")
+	ErrScanProfileUnsupported = errors.New("scan profile option not supported by the scanner")
+)
+
+// scannerProfile is the scan profile state of a scanner. The scanners read
+// the paths to exclude from their config at start, so a profile applies to
+// all the scans of the scanner and the scanner restarts when it changes.
+type scannerProfile struct {
+	process string
+	config  string
+	socket  string
+	jobs    func() int32
+	access  sync.Mutex
+}
+
+var (
+	secretScannerProfile = &scannerProfile{
+		process: "secret_scanner",
+		config:  secretScannerConfig,
+		socket:  ebpfSocketPath,
+		jobs:    GetSecretScannerJobCount,
+	}
+	malwareScannerProfile = &scannerProfile{
+		process: "malware_scanner",
+		config:  malwareScannerConfig,
+		socket:  ebpfMalwareSocketPath,
+		jobs:    GetMalwareScannerJobCount,
+	}
+)
+
+var (
+	pseudoFilesystems = map[string]bool{
+		"proc": true, "sysfs": true, "cgroup": true, "cgroup2": true, "devpts": true,
+		"devtmpfs": true, "debugfs": true, "tracefs": true, "securityfs": true,
+		"pstore": true, "bpf": true, "mqueue": true, "hugetlbfs": true, "fusectl": true,
+		"configfs": true, "autofs": true, "binfmt_misc": true, "nsfs": true,
+		"efivarfs": true, "rpc_pipefs": true, "selinuxfs": true,
+	}
+	networkFilesystems = map[string]bool{
+		"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true,
+		"ceph": true, "glusterfs": true, "fuse.glusterfs": true, "fuse.sshfs": true,
+		"fuse.s3fs": true, "9p": true, "afs": true, "lustre": true, "gpfs": true,
+	}
+)
+
+// apply writes the excludes of the profile to the scanner config and
+// restarts the scanner if they changed. It returns the context to send the
+// scan request with, which waits for the restarted scanner. The caller holds
+// the lock until the request is sent.
+func (sp *scannerProfile) apply(profile *ctl.ScanProfile, nodeType ctl.ScanResource, root string) (context.Context, context.CancelFunc, error) {
+	excludes, err := scanProfileExcludes(profile, nodeType, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(sp.config)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	bundled, err := os.ReadFile(bundledConfigPath(sp.config))
+	if errors.Is(err, os.ErrNotExist) {
+		bundled = data
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	updated, err := withExcludes(data, bundled, excludes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(updated, data) {
+		ctx, cancel := context.WithTimeout(context.Background(), scannerStartTimeout)
+		return ctx, cancel, nil
+	}
+
+	if sp.jobs() > 0 {
+		return nil, nil, ErrScannerBusy
+	}
+	if err := keepBundledConfig(sp.config); err != nil {
+		return nil, nil, err
+	}
+	if err := writeFileAtomic(sp.config, updated); err != nil {
+		return nil, nil, err
+	}
+	log.Info().Msgf("Restart %s to apply the scan profile", sp.process)
+	err = supervisor.StopProcess(sp.process)
+	if err != nil && !errors.Is(err, supervisor.ErrNotRunning) {
+		return nil, nil, err
+	}
+	if err := supervisor.StartProcess(sp.process); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scannerStartTimeout)
+	return ctx, cancel, nil
+}
+
+// scanProfileExcludes returns the path prefixes the scans of the profile skip.
+// For host scans, scanning the host filesystem mounted at root, the mounts
+// to skip and the paths out of the include paths are excluded too.
+func scanProfileExcludes(profile *ctl.ScanProfile, nodeType ctl.ScanResource, root string) ([]string, error) {
+	if profile == nil {
+		return nil, nil
+	}
+	if profile.MaxFileSizeMB > 0 {
+		return nil, fmt.Errorf("%w: max file size", ErrScanProfileUnsupported)
+	}
+
+	excludes := []string{}
+	for _, pattern := range profile.ExcludePaths {
+		prefix, err := globPrefix(pattern)
+		if err != nil {
+			return nil, err
+		}
+		excludes = append(excludes, prefix)
+	}
+	if nodeType != ctl.Host {
+		if len(profile.IncludePaths) > 0 {
+			return nil, fmt.Errorf("%w: include paths of %s scans", ErrScanProfileUnsupported, ctl.ResourceTypeToString(nodeType))
+		}
+		return excludes, nil
+	}
+
+	if len(profile.IncludePaths) > 0 {
+		includes := []string{}
+		for _, pattern := range profile.IncludePaths {
+			prefix, err := globPrefix(pattern)
+			if err != nil {
+				return nil, err
+			}
+			includes = append(includes, prefix)
+		}
+		excludes = append(excludes, includeExcludes(root, includes)...)
+	}
+
+	mountinfo := "/proc/1/mountinfo"
+	if root == "/" {
+		mountinfo = "/proc/self/mountinfo"
+	}
+	f, err := os.Open(mountinfo)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	for _, pattern := range mountExcludes(f, profile.SkipMountedVolumes, profile.SkipNetworkFilesystems) {
+		excludes = append(excludes, strings.TrimSuffix(pattern, "/**"))
+	}
+
+	if root != "/" {
+		for i := range excludes {
+			excludes[i] = filepath.Join(root, excludes[i])
+		}
+	}
+	return excludes, nil
+}
+
+// globPrefix returns the path prefix matched by the pattern, the scanners
+// only exclude prefixes so the only glob allowed is a trailing /**
+func globPrefix(pattern string) (string, error) {
+	prefix := strings.TrimSuffix(pattern, "/**")
+	if prefix == "" {
+		prefix = "/"
+	}
+	if strings.ContainsAny(prefix, "*?[") {
+		return "", fmt.Errorf("%w: path pattern %q", ErrScanProfileUnsupported, pattern)
+	}
+	return filepath.Clean(prefix), nil
+}
+
+// includeExcludes returns the paths next to the include paths, whose
+// exclusion leaves only the include paths to scan
+func includeExcludes(root string, includes []string) []string {
+	res := []string{}
+	var walk func(dir string)
+	walk = func(dir string) {
+		for _, include := range includes {
+			if include == dir {
+				return
+			}
+		}
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			under := false
+			for _, include := range includes {
+				if include == path || strings.HasPrefix(include, path+"/") {
+					under = true
+					break
+				}
+			}
+			if under {
+				walk(path)
+			} else {
+				res = append(res, path)
+			}
+		}
+	}
+	walk("/")
+	return res
+}
+
+// withExcludes returns the config with the exclude paths of the bundled
+// config followed by the ones of the profile
+func withExcludes(data, bundled []byte, excludes []string) ([]byte, error) {
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	bundledConfig := map[string]interface{}{}
+	if err := yaml.Unmarshal(bundled, &bundledConfig); err != nil {
+		return nil, err
+	}
+
+	paths, _ := bundledConfig[scanProfileExcludeKey].([]interface{})
+	paths = append([]interface{}{}, paths...)
+	for _, exclude := range excludes {
+		paths = append(paths, exclude)
+	}
+	current, _ := config[scanProfileExcludeKey].([]interface{})
+	if reflect.DeepEqual(current, paths) || (len(current) == 0 && len(paths) == 0) {
+		return data, nil
+	}
+	config[scanProfileExcludeKey] = paths
+	return yaml.Marshal(config)
+}
+
+// mountExcludes returns the exclude globs of the mount points to skip, read
+// from a mountinfo file. The pseudo filesystems, like /proc, are always
+// skipped.
+func mountExcludes(mountinfo io.Reader, skipMounted, skipNetwork bool) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(mountinfo)
+	for scanner.Scan() {
+		// id parent major:minor root mount_point options [optional...] - fstype source super_options
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+1 >= len(fields) {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		fsType := fields[sep+1]
+		if mountPoint == "/" || seen[mountPoint] {
+			continue
+		}
+
+		if skipMounted || pseudoFilesystems[fsType] || (skipNetwork && networkFilesystems[fsType]) {
+			seen[mountPoint] = true
+			res = append(res, strings.TrimSuffix(mountPoint, "/")+"/**")
+		}
+	}
+	return res
+}
+
+// unescapeMountPath decodes the octal escapes of mountinfo paths, like \040
+// for spaces
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
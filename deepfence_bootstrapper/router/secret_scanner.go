@@ -60,7 +60,15 @@ func StartSecretsScan(req ctl.StartSecretScanRequest) error {
 	defer conn.Close()
 
 	ssClient := pb.NewSecretScannerClient(conn)
-	_, err = ssClient.FindSecretInfo(context.Background(), &greq)
+	secretScannerProfile.access.Lock()
+	defer secretScannerProfile.access.Unlock()
+	ctx, cancel, err := secretScannerProfile.apply(req.Profile, req.NodeType, scanDir)
+	if err != nil {
+		log.Error().Msgf("Cannot apply scan profile: %v", err)
+		return err
+	}
+	defer cancel()
+	_, err = ssClient.FindSecretInfo(ctx, &greq, grpc.WaitForReady(true))
 
 	if err != nil {
 		fmt.Println("FindSecretInfo error" + err.Error())
@@ -2,6 +2,7 @@ package router
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -20,47 +21,139 @@ const (
 	binariesFile = "/tmp/binaries.tar.gz"
 )
 
+var (
+	backupDir = os.ExpandEnv("${DF_INSTALL_DIR}/var/lib/deepfence/upgrade-backup")
+)
+
 func StartAgentUpgrade(req ctl.StartAgentUpgradeRequest) error {
-	log.Info().Msgf("Fetching %v", req.HomeDirectoryURL)
-	err := downloadFile(binariesFile, req.HomeDirectoryURL)
+	dir, err := fetchBinaries(req.HomeDirectoryURL)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(binariesFile)
-	log.Info().Msgf("Download done")
+	cleanup := func() { os.RemoveAll(dir) }
+	defer cleanup()
 
-	dir, err := os.MkdirTemp("/tmp", "bins")
+	plugins, err := listBinaries(dir)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(dir)
 
-	err = extractTarGz(binariesFile, dir)
+	err = backupBinaries(plugins)
+	if err != nil {
+		log.Error().Msgf("Backup failed, rollback will need a download: %v", err)
+	}
+
+	return upgradeBinaries(plugins, cleanup)
+}
+
+// RollbackAgentUpgrade restores the binaries saved by the last upgrade. The
+// binaries of the requested version are downloaded when there is no backup.
+func RollbackAgentUpgrade(req ctl.RollbackAgentUpgradeRequest) error {
+	plugins, err := listBinaries(backupDir)
+	if err == nil && len(plugins) != 0 {
+		log.Info().Msgf("Restoring binaries from %v", backupDir)
+		cleanup := func() { os.RemoveAll(backupDir) }
+		err = upgradeBinaries(plugins, cleanup)
+		if err == nil {
+			cleanup()
+		}
+		return err
+	}
+
+	if req.HomeDirectoryURL == "" {
+		return errors.New("no backup and no binaries to rollback to")
+	}
+
+	dir, err := fetchBinaries(req.HomeDirectoryURL)
 	if err != nil {
 		return err
 	}
+	cleanup := func() { os.RemoveAll(dir) }
+	defer cleanup()
+
+	plugins, err = listBinaries(dir)
+	if err != nil {
+		return err
+	}
+	return upgradeBinaries(plugins, cleanup)
+}
+
+type namePath struct {
+	name string
+	path string
+}
+
+func fetchBinaries(url string) (string, error) {
+	log.Info().Msgf("Fetching %v", url)
+	err := downloadFile(binariesFile, url)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(binariesFile)
+	log.Info().Msgf("Download done")
 
-	type NamePath struct {
-		name string
-		path string
+	dir, err := os.MkdirTemp("/tmp", "bins")
+	if err != nil {
+		return "", err
 	}
 
-	plugins := []NamePath{}
-	err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+	err = extractTarGz(binariesFile, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func listBinaries(dir string) ([]namePath, error) {
+	plugins := []namePath{}
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
 			return nil
 		}
-		plugins = append(plugins, NamePath{name: filepath.Base(path), path: path})
+		plugins = append(plugins, namePath{name: filepath.Base(path), path: path})
 		return nil
 	})
+	return plugins, err
+}
 
+// backupBinaries saves the current binaries of the plugins about to be
+// upgraded so that a failed rollout can be rolled back without a download
+func backupBinaries(plugins []namePath) error {
+	err := os.MkdirAll(backupDir, 0755)
+	if err != nil {
+		return err
+	}
+	// Backup moves the previous backup aside and leaves an empty directory
+	err = os.RemoveAll(backupDir + ".old")
+	if err != nil {
+		return err
+	}
+	err = Backup(backupDir)
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(backupDir + ".old")
+	for _, plugin := range plugins {
+		path, err := supervisor.ProcessPath(plugin.name)
+		if err != nil {
+			continue
+		}
+		err = supervisor.WriteTo(filepath.Join(backupDir, plugin.name), path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// upgradeBinaries installs the binaries, cleanup runs before the bootstrapper
+// re-executes itself as the deferred calls of the callers would never run
+func upgradeBinaries(plugins []namePath, cleanup func()) error {
+	var err error
 	restart := false
 	for _, plugin := range plugins {
 		err = supervisor.UpgradeProcessFromFile(plugin.name, plugin.path)
@@ -73,6 +166,7 @@ func StartAgentUpgrade(req ctl.StartAgentUpgradeRequest) error {
 
 	if restart {
 		log.Info().Msgf("Restart self")
+		cleanup()
 		err = restartSelf()
 	}
 
@@ -0,0 +1,147 @@
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	HealthCheckSocket  = "socket"
+	HealthCheckHTTP    = "http"
+	HealthCheckCommand = "command"
+
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthFailures = 3
+	healthCheckTimeout    = 10 * time.Second
+
+	DefaultBackoffInitial = 5 * time.Second
+	DefaultBackoffMax     = 5 * time.Minute
+
+	// runs shorter than this count towards the crash loop detection
+	shortRunDuration = 10 * time.Second
+	crashLoopRuns    = 5
+
+	crashLogLines = 20
+)
+
+var (
+	ErrHealthCheckKind = errors.New("unknown health check kind")
+)
+
+// HealthCheck probes a running process: a unix socket to dial, an url to get
+// or a command to run. The process is restarted after Failures probes fail
+// in a row.
+type HealthCheck struct {
+	Kind     string
+	Target   string
+	Interval time.Duration
+	Failures int
+}
+
+// ParseHealthCheck reads a "kind:target" spec from config.ini, e.g.
+// socket:$DF_INSTALL_DIR/tmp/secret-scanner.sock. An empty spec disables
+// the health check.
+func ParseHealthCheck(spec string, interval time.Duration, failures int) (*HealthCheck, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	kind, target, found := strings.Cut(spec, ":")
+	if !found || target == "" {
+		return nil, fmt.Errorf("ill-formed health check %q", spec)
+	}
+	switch kind {
+	case HealthCheckSocket, HealthCheckCommand:
+	case HealthCheckHTTP:
+		// the url keeps its scheme
+		target = spec
+	default:
+		return nil, ErrHealthCheckKind
+	}
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	if failures <= 0 {
+		failures = defaultHealthFailures
+	}
+	return &HealthCheck{
+		Kind:     kind,
+		Target:   os.ExpandEnv(target),
+		Interval: interval,
+		Failures: failures,
+	}, nil
+}
+
+func (hc *HealthCheck) probe() error {
+	switch hc.Kind {
+	case HealthCheckSocket:
+		conn, err := net.DialTimeout("unix", hc.Target, healthCheckTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case HealthCheckHTTP:
+		client := http.Client{Timeout: healthCheckTimeout}
+		resp, err := client.Get(hc.Target)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("bad status: %s", resp.Status)
+		}
+		return nil
+	case HealthCheckCommand:
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+		return exec.CommandContext(ctx, "/bin/bash", "-c", hc.Target).Run()
+	}
+	return ErrHealthCheckKind
+}
+
+// Backoff is the delay between two restarts of a process, doubled after
+// every short run up to Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b Backoff) next(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > b.Max {
+		return b.Max
+	}
+	return delay
+}
+
+// tailLog returns the last lines of the log file of a process
+func tailLog(name string, n int) []string {
+	f, err := os.Open(logRoot + name + ".log")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	// only the end of the file is of interest
+	const maxRead = 64 * 1024
+	if info, err := f.Stat(); err == nil && info.Size() > maxRead {
+		_, _ = f.Seek(-maxRead, 2)
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
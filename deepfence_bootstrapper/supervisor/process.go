@@ -2,10 +2,12 @@ package supervisor
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -20,6 +22,7 @@ const (
 	SelfID               = "self"
 	logRootEnv           = "${DF_INSTALL_DIR}/var/log/deepfenced/"
 	ExitCodeBashNotFound = 127
+	recentRestartsWindow = 10 * time.Minute
 )
 
 var (
@@ -48,19 +51,63 @@ type procHandler struct {
 	command     string
 	env         []string
 	started     bool
+	done        chan struct{}
 	wait        func() error
 	kill        func() error
 	autorestart bool
 	access      sync.Mutex
 	cgroup      string
+	baseCgroup  string
+	health      *HealthCheck
+	backoff     Backoff
+	pid         int
+	stats       procStats
+	statsAccess sync.Mutex
 }
 
-func NewProcHandler(name, path, command, env string, autorestart bool, cgroup string) *procHandler {
+type procStats struct {
+	running        bool
+	crashed        bool
+	restarts       int
+	recentRestarts []time.Time
+	lastExitCode   int
+	healthCheck    string
+	crashReason    string
+	lastLogLines   []string
+	since          time.Time
+}
+
+// ProcessStatus is the state of a supervised process reported to the console
+type ProcessStatus struct {
+	Name           string
+	Running        bool
+	Crashed        bool
+	Restarts       int
+	RecentRestarts int
+	LastExitCode   int
+	HealthCheck    string
+	CrashReason    string
+	LastLogLines   []string
+	Since          time.Time
+}
+
+func NewProcHandler(name, path, command, env string, autorestart bool, cgroup string,
+	health *HealthCheck, backoff Backoff) *procHandler {
 	envs := strings.Split(env, ",")
 	expandedEnvs := os.Environ()
 	for i := range envs {
 		expandedEnvs = append(expandedEnvs, os.ExpandEnv(envs[i]))
 	}
+	if backoff.Initial <= 0 {
+		backoff.Initial = DefaultBackoffInitial
+	}
+	if backoff.Max < backoff.Initial {
+		backoff.Max = DefaultBackoffMax
+	}
+	healthCheck := "none"
+	if health != nil {
+		healthCheck = "passing"
+	}
 	return &procHandler{
 		name:        name,
 		path:        os.ExpandEnv(path),
@@ -69,8 +116,12 @@ func NewProcHandler(name, path, command, env string, autorestart bool, cgroup st
 		kill:        func() error { return ErrNotRunning },
 		autorestart: autorestart,
 		cgroup:      cgroup,
+		baseCgroup:  cgroup,
 		command:     os.ExpandEnv(command),
 		env:         expandedEnvs,
+		health:      health,
+		backoff:     backoff,
+		stats:       procStats{healthCheck: healthCheck},
 	}
 }
 
@@ -84,110 +135,193 @@ func startLogging(name string, cmd *exec.Cmd) {
 	cmd.Stderr = f
 }
 
-func (ph *procHandler) start() error {
-	if ph.started {
-		return ErrAlreadyRunning
-	}
+func (ph *procHandler) newCmd() *exec.Cmd {
 	cmd := exec.Command("/bin/bash", "-c", ph.command)
 	cmd.Env = ph.env
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Pdeathsig: syscall.SIGKILL,
 	}
 	startLogging(ph.name, cmd)
-	if !ph.autorestart {
-		err := cmd.Start()
+	return cmd
+}
+
+func (ph *procHandler) startCmd() (*exec.Cmd, error) {
+	cmd := ph.newCmd()
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	ph.statsAccess.Lock()
+	ph.pid = cmd.Process.Pid
+	cgroup := ph.cgroup
+	ph.statsAccess.Unlock()
+	if cgroup != "" {
+		err = cgroups.AttachProcessToCgroup(cgroup, cmd.Process.Pid)
 		if err != nil {
-			return err
+			log.Error().Msgf("cgroup failed: %v", err)
 		}
-		if ph.cgroup != "" {
-			err = cgroups.AttachProcessToCgroup(ph.cgroup, cmd.Process.Pid)
-			if err != nil {
-				log.Error().Msgf("cgroup failed: %v", err)
+	}
+	return cmd, nil
+}
+
+// watchHealth probes the process until stop is closed. The returned channel
+// gets the reason of the failure once the probes failed too many times in a
+// row.
+func (ph *procHandler) watchHealth(stop <-chan struct{}) <-chan string {
+	unhealthy := make(chan string, 1)
+	if ph.health == nil {
+		return unhealthy
+	}
+	go func() {
+		failures := 0
+		ticker := time.NewTicker(ph.health.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			err := ph.health.probe()
+			if err == nil {
+				failures = 0
+				ph.recordHealth(true)
+				continue
+			}
+			failures += 1
+			log.Warn().Msgf("%s health check failed (%d/%d): %v", ph.name, failures, ph.health.Failures, err)
+			if failures >= ph.health.Failures {
+				ph.recordHealth(false)
+				unhealthy <- fmt.Sprintf("health check failed: %v", err)
+				return
 			}
 		}
+	}()
+	return unhealthy
+}
+
+// reap resets started once the restart loop gave up on the crashing process,
+// so that it can be started again, and returns whether it did
+func (ph *procHandler) reap() bool {
+	if !ph.started || ph.done == nil {
+		return false
+	}
+	select {
+	case <-ph.done:
+		ph.started = false
+		ph.done = nil
+		return true
+	default:
+		return false
+	}
+}
+
+func (ph *procHandler) start() error {
+	ph.reap()
+	if ph.started {
+		return ErrAlreadyRunning
+	}
+	if !ph.autorestart {
+		cmd, err := ph.startCmd()
+		if err != nil {
+			return err
+		}
+		ph.recordRunning(true)
+		stopHealth := make(chan struct{})
+		// Without autorestart, a failing health check is only reported
+		ph.watchHealth(stopHealth)
 		ph.wait = func() error {
-			return cmd.Wait()
+			err := cmd.Wait()
+			close(stopHealth)
+			ph.recordExit(err, "")
+			return err
 		}
 		ph.kill = func() error {
 			return cmd.Process.Signal(syscall.SIGTERM)
 		}
 	} else {
 		stop := make(chan struct{})
-		ackstop := make(chan struct{})
-		done := make(chan bool)
+		stopped := make(chan struct{})
+		ph.recordRunning(true)
 
 		go func() {
-			shortRetries := 5
-		loop:
+			defer close(stopped)
+			delay := ph.backoff.Initial
+			shortRuns := 0
 			for {
-				err := cmd.Start()
-				startTime := time.Now()
+				cmd, err := ph.startCmd()
 				if err != nil {
 					log.Error().Msgf("Failed to start: %v", err)
-					break loop
-				}
-				if ph.cgroup != "" {
-					err = cgroups.AttachProcessToCgroup(ph.cgroup, cmd.Process.Pid)
-					if err != nil {
-						log.Error().Msgf("cgroup failed: %v", err)
-					}
+					ph.recordCrash(fmt.Sprintf("failed to start: %v", err))
+					return
 				}
+				startTime := time.Now()
+
+				exited := make(chan error, 1)
 				go func() {
-					err = cmd.Wait()
-					if err != nil {
-						if e, is := err.(*exec.ExitError); is {
-							if e.ExitCode() == ExitCodeBashNotFound {
-								done <- false
-							}
-						}
-						log.Error().Msgf("Done with error: %v", err)
-					}
-					done <- true
+					exited <- cmd.Wait()
 				}()
 
+				stopHealth := make(chan struct{})
+				unhealthy := ph.watchHealth(stopHealth)
+
+				reason := ""
 				select {
 				case <-stop:
+					close(stopHealth)
 					_ = cmd.Process.Signal(syscall.SIGTERM)
-					break loop
-				case restart := <-done:
-					if !restart {
+					<-exited
+					return
+				case reason = <-unhealthy:
+					log.Error().Msgf("%s is unhealthy, restarting: %s", ph.command, reason)
+					_ = cmd.Process.Signal(syscall.SIGTERM)
+					err = <-exited
+				case err = <-exited:
+				}
+				close(stopHealth)
+				ph.recordExit(err, reason)
+
+				if err != nil {
+					log.Error().Msgf("Done with error: %v", err)
+					if e, is := err.(*exec.ExitError); is && e.ExitCode() == ExitCodeBashNotFound {
 						log.Info().Msgf("%s defenitively stopped", ph.command)
-						break loop
+						ph.recordCrash("")
+						return
 					}
+				}
 
-					stopTime := time.Now()
-					if stopTime.Sub(startTime) <= 5*time.Second {
-						shortRetries -= 1
-						if shortRetries == 0 {
-							log.Info().Msgf("%s keeps crashing, stopped", ph.command)
-							break loop
-						}
-					} else {
-						shortRetries = 5
+				if time.Since(startTime) <= shortRunDuration {
+					shortRuns += 1
+					if shortRuns >= crashLoopRuns {
+						log.Info().Msgf("%s keeps crashing, stopped", ph.command)
+						ph.recordCrash("")
+						return
 					}
+				} else {
+					shortRuns = 0
+					delay = ph.backoff.Initial
+				}
 
-					log.Info().Msgf("%s restarting...", ph.command)
-					time.Sleep(time.Second * 5)
-					cmd = exec.Command("/bin/bash", "-c", ph.command)
-					cmd.Env = ph.env
-					cmd.SysProcAttr = &syscall.SysProcAttr{
-						Pdeathsig: syscall.SIGKILL,
-					}
-					startLogging(ph.name, cmd)
+				log.Info().Msgf("%s restarting in %v...", ph.command, delay)
+				ph.recordRestart()
+				select {
+				case <-stop:
+					return
+				case <-time.After(delay):
 				}
+				delay = ph.backoff.next(delay)
 			}
-			<-done
-			ackstop <- struct{}{}
 		}()
 
 		ph.wait = func() error {
-			<-ackstop
+			<-stopped
 			return nil
 		}
 		ph.kill = func() error {
-			stop <- struct{}{}
+			close(stop)
 			return nil
 		}
+		ph.done = stopped
 	}
 	ph.started = true
 
@@ -198,12 +332,106 @@ func (ph *procHandler) stop() error {
 	_ = ph.kill()
 
 	ph.started = false
+	ph.done = nil
 
 	_ = ph.wait()
 
+	ph.recordRunning(false)
+
 	return nil
 }
 
+func (ph *procHandler) recordRunning(running bool) {
+	ph.statsAccess.Lock()
+	defer ph.statsAccess.Unlock()
+	ph.stats.running = running
+	ph.stats.crashed = false
+	ph.stats.since = time.Now()
+}
+
+func (ph *procHandler) recordHealth(passing bool) {
+	ph.statsAccess.Lock()
+	defer ph.statsAccess.Unlock()
+	if passing {
+		ph.stats.healthCheck = "passing"
+	} else {
+		ph.stats.healthCheck = "failing"
+	}
+}
+
+// recordExit keeps the exit code and, for failures, the reason and the last
+// lines logged by the process
+func (ph *procHandler) recordExit(err error, reason string) {
+	code := 0
+	if e, is := err.(*exec.ExitError); is {
+		code = e.ExitCode()
+	}
+	if reason == "" && err != nil {
+		reason = err.Error()
+	}
+	var lines []string
+	if reason != "" {
+		lines = tailLog(ph.name, crashLogLines)
+	}
+
+	ph.statsAccess.Lock()
+	defer ph.statsAccess.Unlock()
+	ph.stats.lastExitCode = code
+	if reason != "" {
+		ph.stats.crashReason = reason
+		ph.stats.lastLogLines = lines
+	}
+}
+
+func (ph *procHandler) recordRestart() {
+	ph.statsAccess.Lock()
+	defer ph.statsAccess.Unlock()
+	now := time.Now()
+	ph.stats.restarts += 1
+	ph.stats.recentRestarts = append(ph.stats.recentRestarts, now)
+	if ph.health != nil {
+		ph.stats.healthCheck = "passing"
+	}
+	ph.stats.since = now
+}
+
+func (ph *procHandler) recordCrash(reason string) {
+	ph.statsAccess.Lock()
+	defer ph.statsAccess.Unlock()
+	ph.stats.running = false
+	ph.stats.crashed = true
+	if reason != "" {
+		ph.stats.crashReason = reason
+	}
+	ph.stats.since = time.Now()
+}
+
+func (ph *procHandler) status() ProcessStatus {
+	ph.statsAccess.Lock()
+	defer ph.statsAccess.Unlock()
+
+	recent := ph.stats.recentRestarts[:0]
+	for _, t := range ph.stats.recentRestarts {
+		if time.Since(t) <= recentRestartsWindow {
+			recent = append(recent, t)
+		}
+	}
+	ph.stats.recentRestarts = recent
+
+	return ProcessStatus{
+		Name:           ph.name,
+		Running:        ph.stats.running,
+		Crashed:        ph.stats.crashed,
+		Restarts:       ph.stats.restarts,
+		RecentRestarts: len(recent),
+		LastExitCode:   ph.stats.lastExitCode,
+		HealthCheck:    ph.stats.healthCheck,
+		CrashReason:    ph.stats.crashReason,
+		LastLogLines:   ph.stats.lastLogLines,
+		Since:          ph.stats.since,
+	}
+}
+
 var selfAccess sync.Mutex
 
 func selfUpgradeFromFile(path string) error {
@@ -316,7 +544,8 @@ func UpgradeProcessFromFile(name, path string) error {
 	process.access.Lock()
 	defer process.access.Unlock()
 
-	restart := false
+	// a process the supervisor gave up on is restarted with the new binary
+	restart := process.reap()
 	if process.started {
 		log.Debug().Msg("Stop process")
 		err := process.stop()
@@ -353,7 +582,8 @@ func UpgradeProcessFromURL(name, url string) error {
 	process.access.Lock()
 	defer process.access.Unlock()
 
-	restart := false
+	// a process the supervisor gave up on is restarted with the new binary
+	restart := process.reap()
 	if process.started {
 		log.Debug().Msg("Stop process")
 		err := process.stop()
@@ -375,6 +605,21 @@ func UpgradeProcessFromURL(name, url string) error {
 	return err
 }
 
+// ProcessPath returns the path of the binary run for name
+func ProcessPath(name string) (string, error) {
+	if name == SelfID {
+		return os.Executable()
+	}
+
+	access.RLock()
+	defer access.RUnlock()
+	process, has := processes[name]
+	if !has {
+		return "", ErrPath
+	}
+	return process.path, nil
+}
+
 func StartProcess(name string) error {
 	access.RLock()
 	process, has := processes[name]
@@ -385,6 +630,7 @@ func StartProcess(name string) error {
 	access.RUnlock()
 	process.access.Lock()
 	defer process.access.Unlock()
+	process.reap()
 	if process.started {
 		return ErrAlreadyRunning
 	}
@@ -401,6 +647,7 @@ func StopProcess(name string) error {
 	access.RUnlock()
 	process.access.Lock()
 	defer process.access.Unlock()
+	process.reap()
 	if !process.started {
 		return ErrNotRunning
 	}
@@ -422,8 +669,69 @@ func StopAllProcesses() []error {
 	return errs
 }
 
-func LoadProcess(name, path, command, env string, autorestart bool, cgroup string) {
+// SetProcessCgroup moves the process, if running, and its next restarts to
+// the cgroup
+func SetProcessCgroup(name, cgroup string) error {
+	access.RLock()
+	process, has := processes[name]
+	access.RUnlock()
+	if !has {
+		return ErrPath
+	}
+
+	process.statsAccess.Lock()
+	process.cgroup = cgroup
+	pid := process.pid
+	running := process.stats.running
+	process.statsAccess.Unlock()
+
+	if !running || pid == 0 {
+		return nil
+	}
+	return cgroups.AttachProcessToCgroup(cgroup, pid)
+}
+
+// ResetProcessCgroup moves the process, if running, and its next restarts
+// back to the cgroup of its config. Without any, the running process stays
+// where it is until its next restart.
+func ResetProcessCgroup(name string) error {
+	access.RLock()
+	process, has := processes[name]
+	access.RUnlock()
+	if !has {
+		return ErrPath
+	}
+
+	process.statsAccess.Lock()
+	process.cgroup = process.baseCgroup
+	pid := process.pid
+	running := process.stats.running
+	process.statsAccess.Unlock()
+
+	if !running || pid == 0 || process.baseCgroup == "" {
+		return nil
+	}
+	return cgroups.AttachProcessToCgroup(process.baseCgroup, pid)
+}
+
+// ProcessesStatus returns the state of all the loaded processes, by name
+func ProcessesStatus() []ProcessStatus {
+	access.RLock()
+	defer access.RUnlock()
+
+	res := make([]ProcessStatus, 0, len(processes))
+	for _, process := range processes {
+		res = append(res, process.status())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func LoadProcess(name, path, command, env string, autorestart bool, cgroup string,
+	health *HealthCheck, backoff Backoff) {
 	access.Lock()
 	defer access.Unlock()
-	processes[name] = NewProcHandler(name, path, command, env, autorestart, cgroup)
+	processes[name] = NewProcHandler(name, path, command, env, autorestart, cgroup, health, backoff)
 }
@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"context"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/deepfence/ThreatMapper/deepfence_ctl/http"
+	"github.com/deepfence/ThreatMapper/deepfence_ctl/output"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	deepfence_server_client "github.com/deepfence/golang_deepfence_sdk/client"
 )
@@ -109,11 +111,116 @@ var agentDisableSubCmd = &cobra.Command{
 	},
 }
 
+var agentStatusSubCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Agents health",
+	Long:  `This subcommand lists the health of the agents, flagging stuck or crash-looping plugins`,
+	Run: func(cmd *cobra.Command, args []string) {
+		node_ids := []string{}
+		node_ids_filter, _ := cmd.Flags().GetString("node-ids")
+		if node_ids_filter != "" {
+			node_ids = strings.Split(node_ids_filter, ",")
+		}
+
+		versions := []string{}
+		versions_filter, _ := cmd.Flags().GetString("versions")
+		if versions_filter != "" {
+			versions = strings.Split(versions_filter, ",")
+		}
+
+		statuses := []string{}
+		statuses_filter, _ := cmd.Flags().GetString("statuses")
+		if statuses_filter != "" {
+			statuses = strings.Split(statuses_filter, ",")
+		}
+
+		req := http.Client().ControlsAPI.GetAgentsHealth(context.Background())
+		req = req.ModelAgentHealthReq(deepfence_server_client.ModelAgentHealthReq{
+			NodeIds:  node_ids,
+			Versions: versions,
+			Statuses: statuses,
+			Window:   deepfence_server_client.ModelFetchWindow{Offset: 0, Size: 0},
+		})
+		res, rh, err := http.Client().ControlsAPI.GetAgentsHealthExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+		output.Out(res)
+	},
+}
+
+var agentApproveSubCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approve agent enrollments",
+	Long:  `This subcommand approves the enrollments of agents already enrolled or with another enrollment key`,
+	Run: func(cmd *cobra.Command, args []string) {
+		node_ids, _ := cmd.Flags().GetString("node-ids")
+		if node_ids == "" {
+			log.Fatal().Msg("Please provide some ids")
+		}
+
+		req := http.Client().ControlsAPI.ApproveAgentEnrollments(context.Background())
+		req = req.ModelAgentCredentialsReq(deepfence_server_client.ModelAgentCredentialsReq{
+			NodeIds: strings.Split(node_ids, ","),
+		})
+		rh, err := http.Client().ControlsAPI.ApproveAgentEnrollmentsExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+	},
+}
+
+var agentRevokeSubCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke agents",
+	Long:  `This subcommand revokes the credentials of compromised agents, which cannot enroll again until reinstated`,
+	Run: func(cmd *cobra.Command, args []string) {
+		node_ids, _ := cmd.Flags().GetString("node-ids")
+		if node_ids == "" {
+			log.Fatal().Msg("Please provide some ids")
+		}
+
+		req := http.Client().ControlsAPI.RevokeAgentCredentials(context.Background())
+		req = req.ModelAgentCredentialsReq(deepfence_server_client.ModelAgentCredentialsReq{
+			NodeIds: strings.Split(node_ids, ","),
+		})
+		rh, err := http.Client().ControlsAPI.RevokeAgentCredentialsExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+	},
+}
+
+var agentReinstateSubCmd = &cobra.Command{
+	Use:   "reinstate",
+	Short: "Reinstate agents",
+	Long:  `This subcommand lets revoked agents enroll again`,
+	Run: func(cmd *cobra.Command, args []string) {
+		node_ids, _ := cmd.Flags().GetString("node-ids")
+		if node_ids == "" {
+			log.Fatal().Msg("Please provide some ids")
+		}
+
+		req := http.Client().ControlsAPI.ReinstateAgents(context.Background())
+		req = req.ModelAgentCredentialsReq(deepfence_server_client.ModelAgentCredentialsReq{
+			NodeIds: strings.Split(node_ids, ","),
+		})
+		rh, err := http.Client().ControlsAPI.ReinstateAgentsExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(agentCmd)
 	agentCmd.AddCommand(agentUpgradeSubCmd)
 	agentCmd.AddCommand(agentEnableSubCmd)
 	agentCmd.AddCommand(agentDisableSubCmd)
+	agentCmd.AddCommand(agentStatusSubCmd)
+	agentCmd.AddCommand(agentApproveSubCmd)
+	agentCmd.AddCommand(agentRevokeSubCmd)
+	agentCmd.AddCommand(agentReinstateSubCmd)
 
 	agentUpgradeSubCmd.PersistentFlags().String("node-ids", "", "Agent IDs")
 	agentUpgradeSubCmd.PersistentFlags().String("version", "", "Agent version to upgrade to")
@@ -125,4 +232,14 @@ func init() {
 	agentDisableSubCmd.PersistentFlags().String("node-ids", "", "Agent IDs")
 	agentDisableSubCmd.PersistentFlags().String("plugin", "", "Agent plugin to disable")
 
+	agentStatusSubCmd.PersistentFlags().String("node-ids", "", "Agent IDs, all if empty")
+	agentStatusSubCmd.PersistentFlags().String("versions", "", "Agent versions, all if empty")
+	agentStatusSubCmd.PersistentFlags().String("statuses", "", "healthy/degraded/offline, all if empty")
+
+	agentApproveSubCmd.PersistentFlags().String("node-ids", "", "Agent IDs")
+
+	agentRevokeSubCmd.PersistentFlags().String("node-ids", "", "Agent IDs")
+
+	agentReinstateSubCmd.PersistentFlags().String("node-ids", "", "Agent IDs")
+
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	stdhttp "net/http"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -89,6 +90,22 @@ var graphTopologySubCmd = &cobra.Command{
 
 		root, _ := cmd.Flags().GetString("root")
 
+		format, _ := cmd.Flags().GetString("format")
+		if format != "" {
+			req := http.Client().TopologyAPI.ExportTopologyGraph(context.Background())
+			req = req.GraphTopologyExportRequest(deepfence_server_client.GraphTopologyExportRequest{
+				Filters: filters,
+				Format:  format,
+				Root:    &root,
+			})
+			res, rh, err := http.Client().TopologyAPI.ExportTopologyGraphExecute(req)
+			if err != nil {
+				log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+			}
+			fmt.Print(res.GetContent())
+			return
+		}
+
 		var res *deepfence_server_client.ModelGraphResult
 		var rh *stdhttp.Response
 		switch root {
@@ -171,6 +188,79 @@ var attackPathsSubCmd = &cobra.Command{
 	},
 }
 
+var graphNetpolSubCmd = &cobra.Command{
+	Use:   "netpol",
+	Short: "Get Network Policies",
+	Long:  `This subcommand generates Kubernetes NetworkPolicies from the observed connections`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		cluster_id, _ := cmd.Flags().GetString("cluster-id")
+		if cluster_id == "" {
+			log.Fatal().Msg("Please provide a cluster-id")
+		}
+		namespace, _ := cmd.Flags().GetString("namespace")
+		window, _ := cmd.Flags().GetInt32("window")
+		output_file, _ := cmd.Flags().GetString("output")
+
+		policy_req := deepfence_server_client.NewGraphNetworkPolicyRequest(cluster_id)
+		if namespace != "" {
+			policy_req.SetNamespace(namespace)
+		}
+		if window > 0 {
+			policy_req.SetWindowMinutes(window)
+		}
+
+		req := http.Client().TopologyAPI.GetNetworkPolicies(context.Background())
+		req = req.GraphNetworkPolicyRequest(*policy_req)
+		res, rh, err := http.Client().TopologyAPI.GetNetworkPoliciesExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+
+		if output_file == "" {
+			fmt.Print(res.GetYaml())
+			return
+		}
+		err = os.WriteFile(output_file, []byte(res.GetYaml()), 0644)
+		if err != nil {
+			log.Fatal().Msgf("Fail to write %s: %v", output_file, err)
+		}
+		log.Info().Msgf("%d policies written to %s", res.GetPolicyCount(), output_file)
+	},
+}
+
+var graphBlastRadiusSubCmd = &cobra.Command{
+	Use:   "blast-radius",
+	Short: "Get blast radius of a node",
+	Long:  `This subcommand lists the assets reachable from a compromised node, ranked by their exploitable findings`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		node_id, _ := cmd.Flags().GetString("node-id")
+		if node_id == "" {
+			log.Fatal().Msg("Please provide a node-id")
+		}
+		node_type, _ := cmd.Flags().GetString("node-type")
+		max_hops, _ := cmd.Flags().GetInt32("max-hops")
+		limit, _ := cmd.Flags().GetInt32("limit")
+
+		blast_req := deepfence_server_client.NewGraphBlastRadiusRequest(node_id, node_type)
+		if max_hops > 0 {
+			blast_req.SetMaxHops(max_hops)
+		}
+		if limit > 0 {
+			blast_req.SetLimit(limit)
+		}
+
+		req := http.Client().TopologyAPI.GetBlastRadius(context.Background())
+		req = req.GraphBlastRadiusRequest(*blast_req)
+		res, rh, err := http.Client().TopologyAPI.GetBlastRadiusExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+		output.Out(res)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(graphCmd)
 	graphCmd.AddCommand(graphTopologySubCmd)
@@ -184,10 +274,23 @@ func init() {
 	graphTopologySubCmd.PersistentFlags().String("fields-contain", "", "CSV fields filter containing values, e.g. (blah=boo,foo=bar)")
 
 	graphTopologySubCmd.PersistentFlags().String("root", "", "Root can be: ''/hosts/containers/pods/kubernetes")
+	graphTopologySubCmd.PersistentFlags().String("format", "", "Export format: graphml/dot/jgf, json graph if empty")
 
 	graphCmd.AddCommand(graphThreatSubCmd)
 	graphThreatSubCmd.PersistentFlags().String("issue-filter", "", "vulnerability/malware/secrets/compliance/cloud_complaince/all")
 	graphThreatSubCmd.PersistentFlags().Bool("cloud-only", false, "vulnerability/malware/secrets/compliance/cloud_complaince/all")
 
 	graphCmd.AddCommand(attackPathsSubCmd)
+
+	graphCmd.AddCommand(graphNetpolSubCmd)
+	graphNetpolSubCmd.PersistentFlags().String("cluster-id", "", "Kubernetes cluster node id")
+	graphNetpolSubCmd.PersistentFlags().String("namespace", "", "Kubernetes namespace, all namespaces if empty")
+	graphNetpolSubCmd.PersistentFlags().Int32("window", 0, "Observation window in minutes, 24h if not set")
+	graphNetpolSubCmd.PersistentFlags().String("output", "", "Output YAML file, stdout if empty")
+
+	graphCmd.AddCommand(graphBlastRadiusSubCmd)
+	graphBlastRadiusSubCmd.PersistentFlags().String("node-id", "", "Compromised node id")
+	graphBlastRadiusSubCmd.PersistentFlags().String("node-type", "host", "host/container/pod/cloud_resource")
+	graphBlastRadiusSubCmd.PersistentFlags().Int32("max-hops", 0, "Maximum number of hops, 3 if not set")
+	graphBlastRadiusSubCmd.PersistentFlags().Int32("limit", 0, "Maximum number of assets, 100 if not set")
 }
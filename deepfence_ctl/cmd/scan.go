@@ -2,6 +2,10 @@ package cmd
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -386,6 +390,16 @@ var scanResultsSubCmd = &cobra.Command{
 				},
 			})
 			res, _, err = http.Client().CloudScannerAPI.ResultsCloudComplianceScanExecute(req)
+		case "iac":
+			req := http.Client().IaCScanAPI.ResultsIaCScan(context.Background())
+			req = req.ModelScanResultsReq(deepfence_server_client.ModelScanResultsReq{
+				ScanId: scan_id,
+				Window: deepfence_server_client.ModelFetchWindow{
+					Offset: 0,
+					Size:   20,
+				},
+			})
+			res, _, err = http.Client().IaCScanAPI.ResultsIaCScanExecute(req)
 		default:
 			log.Fatal().Msg("Unsupported")
 		}
@@ -458,6 +472,89 @@ var scanStopSubCmd = &cobra.Command{
 	},
 }
 
+var iacManifestExtensions = map[string]struct{}{".yaml": {}, ".yml": {}, ".json": {}}
+
+// readIaCFiles reads the manifests under path, or stdin when path is -
+func readIaCFiles(path string) ([]deepfence_server_client.ModelIaCFile, error) {
+	files := []deepfence_server_client.ModelIaCFile{}
+	if path == "-" {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return append(files, deepfence_server_client.ModelIaCFile{Path: "stdin", Content: string(content)}), nil
+	}
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, has := iacManifestExtensions[strings.ToLower(filepath.Ext(p))]; !has && p != path {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil || rel == "." {
+			rel = filepath.Base(p)
+		}
+		files = append(files, deepfence_server_client.ModelIaCFile{Path: filepath.ToSlash(rel), Content: string(content)})
+		return nil
+	})
+	return files, err
+}
+
+var scanIaCSubCmd = &cobra.Command{
+	Use:   "iac",
+	Short: "Scan Kubernetes manifests",
+	Long:  `This subcommand uploads Kubernetes manifests, or helm template output, for misconfiguration checks`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("path")
+		if path == "" {
+			log.Fatal().Msg("Please provide a path")
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			if path == "-" {
+				log.Fatal().Msg("Please provide a name")
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				log.Fatal().Msg(err.Error())
+			}
+			name = filepath.Base(abs)
+		}
+
+		files, err := readIaCFiles(path)
+		if err != nil {
+			log.Fatal().Msgf("Fail to read manifests: %v", err)
+		}
+		if len(files) == 0 {
+			log.Fatal().Msgf("No manifests found in %s", path)
+		}
+
+		req := http.Client().IaCScanAPI.StartIaCScan(context.Background())
+		req = req.ModelIaCScanRequest(deepfence_server_client.ModelIaCScanRequest{
+			NodeName: name,
+			Files:    files,
+		})
+		res, rh, err := http.Client().IaCScanAPI.StartIaCScanExecute(req)
+		if err != nil {
+			log.Fatal().Msgf("Fail to execute: %v: %v", err, rh)
+		}
+		output.Out(res)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(scanCmd)
 	scanCmd.AddCommand(scanStartSubCmd)
@@ -466,6 +563,7 @@ func init() {
 	scanCmd.AddCommand(scanResultsSubCmd)
 	scanCmd.AddCommand(scanSearchSubCmd)
 	scanCmd.AddCommand(scanStopSubCmd)
+	scanCmd.AddCommand(scanIaCSubCmd)
 
 	scanCmd.PersistentFlags().String("type", "", "Scan type")
 
@@ -485,4 +583,7 @@ func init() {
 
 	scanStopSubCmd.PersistentFlags().String("scan-id", "", "Scan id")
 
+	scanIaCSubCmd.PersistentFlags().String("path", "", "Manifest file or directory, - to read helm template output from stdin")
+	scanIaCSubCmd.PersistentFlags().String("name", "", "Source name the results are stored under, directory name if empty")
+
 }
@@ -0,0 +1,166 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+)
+
+const (
+	authTokenPath       = "/deepfence/auth/token"
+	imageScanStatusPath = "/deepfence/scan/images/status"
+)
+
+var ErrUnauthorized = errors.New("console rejected the api token")
+
+// ScanStatusClient fetches the latest scan results of images
+type ScanStatusClient interface {
+	GetImageScanStatus(ctx context.Context, images []string) ([]model.ImageScanStatus, error)
+}
+
+// ConsoleClient queries the console API, authenticating with an API token
+// and caching image results for cacheTTL
+type ConsoleClient struct {
+	url      string
+	apiToken string
+	http     *http.Client
+	cacheTTL time.Duration
+
+	mtx         sync.Mutex
+	accessToken string
+	cache       map[string]cachedStatus
+}
+
+type cachedStatus struct {
+	status    model.ImageScanStatus
+	expiresAt time.Time
+}
+
+func NewConsoleClient(url, apiToken string, insecure bool, timeout, cacheTTL time.Duration) *ConsoleClient {
+	return &ConsoleClient{
+		url:      strings.TrimSuffix(url, "/"),
+		apiToken: apiToken,
+		http: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}, //nolint:gosec
+			},
+		},
+		cacheTTL: cacheTTL,
+		cache:    map[string]cachedStatus{},
+	}
+}
+
+func (c *ConsoleClient) GetImageScanStatus(ctx context.Context, images []string) ([]model.ImageScanStatus, error) {
+	res := make([]model.ImageScanStatus, 0, len(images))
+	missing := []string{}
+	now := time.Now()
+
+	c.mtx.Lock()
+	for _, image := range images {
+		if cached, has := c.cache[image]; has && now.Before(cached.expiresAt) {
+			res = append(res, cached.status)
+		} else {
+			missing = append(missing, image)
+		}
+	}
+	c.mtx.Unlock()
+
+	if len(missing) == 0 {
+		return res, nil
+	}
+
+	var statuses []model.ImageScanStatus
+	err := c.post(ctx, imageScanStatusPath, model.ImageScanStatusRequest{Images: missing}, &statuses)
+	if errors.Is(err, ErrUnauthorized) {
+		// access tokens are short lived, get a new one and retry once
+		c.setAccessToken("")
+		err = c.post(ctx, imageScanStatusPath, model.ImageScanStatusRequest{Images: missing}, &statuses)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	for _, status := range statuses {
+		c.cache[status.Image] = cachedStatus{status: status, expiresAt: now.Add(c.cacheTTL)}
+	}
+	for image, cached := range c.cache {
+		if now.After(cached.expiresAt) {
+			delete(c.cache, image)
+		}
+	}
+	c.mtx.Unlock()
+
+	return append(res, statuses...), nil
+}
+
+func (c *ConsoleClient) setAccessToken(token string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.accessToken = token
+}
+
+func (c *ConsoleClient) getAccessToken(ctx context.Context) (string, error) {
+	c.mtx.Lock()
+	token := c.accessToken
+	c.mtx.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	var resp model.ResponseAccessToken
+	if err := c.do(ctx, authTokenPath, "", model.APIAuthRequest{APIToken: c.apiToken}, &resp); err != nil {
+		return "", err
+	}
+	c.setAccessToken(resp.AccessToken)
+	return resp.AccessToken, nil
+}
+
+func (c *ConsoleClient) post(ctx context.Context, path string, req, resp interface{}) error {
+	token, err := c.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, path, token, req, resp)
+}
+
+func (c *ConsoleClient) do(ctx context.Context, path, token string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpResp, err := c.http.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return fmt.Errorf("console returned %d on %s: %s", httpResp.StatusCode, path, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
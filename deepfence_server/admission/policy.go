@@ -0,0 +1,172 @@
+package admission
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ActionAllow = "allow"
+	ActionWarn  = "warn"
+	ActionDeny  = "deny"
+
+	// FailOpen admits pods when the console cannot be reached,
+	// FailClosed rejects them
+	FailOpen   = "open"
+	FailClosed = "closed"
+
+	scanTypeVulnerability = "vulnerability"
+	scanTypeSecret        = "secret"
+	scanTypeMalware       = "malware"
+)
+
+var (
+	ErrInvalidAction      = errors.New("invalid policy action, expected allow, warn or deny")
+	ErrInvalidFailureMode = errors.New("invalid failure mode, expected open or closed")
+
+	actionRank = map[string]int{ActionAllow: 0, ActionWarn: 1, ActionDeny: 2}
+)
+
+// Policy decides which images can be deployed from their latest scan
+// results. Max* map a severity to the maximum number of findings of
+// that severity allowed, severities not listed are not limited.
+type Policy struct {
+	// Action taken when an image exceeds a threshold, warn or deny
+	Action string `json:"action"`
+	// FailureMode applies when the scan results cannot be fetched
+	FailureMode string `json:"failure_mode"`
+	// UnscannedAction applies to images never scanned, or whose
+	// latest scan is older than MaxScanAgeHours
+	UnscannedAction    string           `json:"unscanned_action"`
+	MaxScanAgeHours    int              `json:"max_scan_age_hours"`
+	MaxVulnerabilities map[string]int32 `json:"max_vulnerabilities"`
+	MaxSecrets         map[string]int32 `json:"max_secrets"`
+	MaxMalwares        map[string]int32 `json:"max_malwares"`
+	ExemptNamespaces   []string         `json:"exempt_namespaces"`
+	// ExemptImages are image name prefixes never checked
+	ExemptImages []string `json:"exempt_images"`
+}
+
+type Decision struct {
+	Action  string   `json:"action"`
+	Reasons []string `json:"reasons"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Action:             ActionDeny,
+		FailureMode:        FailOpen,
+		UnscannedAction:    ActionWarn,
+		MaxVulnerabilities: map[string]int32{"critical": 0},
+		MaxSecrets:         map[string]int32{"critical": 0},
+		MaxMalwares:        map[string]int32{"critical": 0},
+		ExemptNamespaces:   []string{"kube-system"},
+	}
+}
+
+// LoadPolicy reads a YAML or JSON policy file, unset fields keep the
+// default policy values
+func LoadPolicy(path string) (Policy, error) {
+	policy := DefaultPolicy()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := yaml.Unmarshal(b, &policy); err != nil {
+		return policy, err
+	}
+	return policy, policy.Validate()
+}
+
+func (p Policy) Validate() error {
+	for _, action := range []string{p.Action, p.UnscannedAction} {
+		if _, has := actionRank[action]; !has {
+			return ErrInvalidAction
+		}
+	}
+	if p.FailureMode != FailOpen && p.FailureMode != FailClosed {
+		return ErrInvalidFailureMode
+	}
+	return nil
+}
+
+func (p Policy) IsExemptNamespace(namespace string) bool {
+	for _, ns := range p.ExemptNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) IsExemptImage(image string) bool {
+	for _, prefix := range p.ExemptImages {
+		if strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the most restrictive action over all the images
+func (p Policy) Evaluate(statuses []model.ImageScanStatus, now time.Time) Decision {
+	decision := Decision{Action: ActionAllow, Reasons: []string{}}
+	apply := func(action, reason string) {
+		if actionRank[action] > actionRank[decision.Action] {
+			decision.Action = action
+		}
+		if action != ActionAllow {
+			decision.Reasons = append(decision.Reasons, reason)
+		}
+	}
+
+	limits := map[string]map[string]int32{
+		scanTypeVulnerability: p.MaxVulnerabilities,
+		scanTypeSecret:        p.MaxSecrets,
+		scanTypeMalware:       p.MaxMalwares,
+	}
+	scanTypes := []string{scanTypeVulnerability, scanTypeSecret, scanTypeMalware}
+
+	for _, status := range statuses {
+		if !status.Found {
+			apply(p.UnscannedAction, fmt.Sprintf("image %s is not known to the console", status.Image))
+			continue
+		}
+		for _, scanType := range scanTypes {
+			if len(limits[scanType]) == 0 {
+				continue
+			}
+			scan, has := status.LatestScans[scanType]
+			if !has {
+				apply(p.UnscannedAction, fmt.Sprintf("image %s has no completed %s scan", status.Image, scanType))
+				continue
+			}
+			if p.MaxScanAgeHours > 0 &&
+				now.Sub(time.UnixMilli(scan.UpdatedAt)) > time.Duration(p.MaxScanAgeHours)*time.Hour {
+				apply(p.UnscannedAction, fmt.Sprintf("image %s %s scan is older than %d hours",
+					status.Image, scanType, p.MaxScanAgeHours))
+				continue
+			}
+			severities := make([]string, 0, len(limits[scanType]))
+			for severity := range limits[scanType] {
+				severities = append(severities, severity)
+			}
+			sort.Strings(severities)
+			for _, severity := range severities {
+				limit := limits[scanType][severity]
+				if count := scan.SeverityCounts[severity]; count > limit {
+					apply(p.Action, fmt.Sprintf("image %s has %d %s %s findings (max %d)",
+						status.Image, count, severity, scanType, limit))
+				}
+			}
+		}
+	}
+	return decision
+}
@@ -0,0 +1,197 @@
+package admission
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const maxReviewSize = 4 * 1024 * 1024
+
+// AuditRecord is written for every pod admission request reviewed
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	UID       string    `json:"uid"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Operation string    `json:"operation"`
+	User      string    `json:"user"`
+	DryRun    bool      `json:"dry_run"`
+	Images    []string  `json:"images"`
+	Decision  string    `json:"decision"`
+	Reasons   []string  `json:"reasons"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Auditor writes audit records as JSON lines
+type Auditor struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+}
+
+func NewAuditor(w io.Writer) *Auditor {
+	return &Auditor{enc: json.NewEncoder(w)}
+}
+
+func (a *Auditor) Record(rec AuditRecord) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if err := a.enc.Encode(rec); err != nil {
+		log.Error().Msgf("failed to write audit record: %v", err)
+	}
+}
+
+// Webhook is a validating admission webhook checking the images of pods
+// against the policy
+type Webhook struct {
+	policy  Policy
+	client  ScanStatusClient
+	auditor *Auditor
+}
+
+func NewWebhook(policy Policy, client ScanStatusClient, auditor *Auditor) *Webhook {
+	return &Webhook{
+		policy:  policy,
+		client:  client,
+		auditor: auditor,
+	}
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxReviewSize)).Decode(&review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "missing admission request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = wh.review(r, review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (wh *Webhook) review(r *http.Request, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != "Pod" {
+		return allowed
+	}
+
+	rec := AuditRecord{
+		Timestamp: time.Now().UTC(),
+		UID:       string(req.UID),
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		Operation: string(req.Operation),
+		User:      req.UserInfo.Username,
+		DryRun:    req.DryRun != nil && *req.DryRun,
+		Images:    []string{},
+		Decision:  ActionAllow,
+		Reasons:   []string{},
+	}
+	defer func() { wh.auditor.Record(rec) }()
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		rec.Error = err.Error()
+		return wh.failure(&rec, "cannot decode pod: "+err.Error())
+	}
+	if rec.Name == "" {
+		rec.Name = pod.GenerateName
+	}
+	if wh.policy.IsExemptNamespace(req.Namespace) {
+		rec.Reasons = append(rec.Reasons, "namespace exempt")
+		return allowed
+	}
+
+	rec.Images = wh.podImages(&pod)
+	if len(rec.Images) == 0 {
+		return allowed
+	}
+
+	statuses, err := wh.client.GetImageScanStatus(r.Context(), rec.Images)
+	if err != nil {
+		rec.Error = err.Error()
+		return wh.failure(&rec, "deepfence: image scan results unavailable: "+err.Error())
+	}
+
+	decision := wh.policy.Evaluate(statuses, time.Now())
+	rec.Decision = decision.Action
+	rec.Reasons = decision.Reasons
+	switch decision.Action {
+	case ActionDeny:
+		return denied("deepfence: " + strings.Join(decision.Reasons, "; "))
+	case ActionWarn:
+		return &admissionv1.AdmissionResponse{Allowed: true, Warnings: prefixed(decision.Reasons)}
+	}
+	return allowed
+}
+
+// failure applies the failure mode when the images cannot be checked
+func (wh *Webhook) failure(rec *AuditRecord, msg string) *admissionv1.AdmissionResponse {
+	rec.Reasons = append(rec.Reasons, "failure mode "+wh.policy.FailureMode)
+	if wh.policy.FailureMode == FailClosed {
+		rec.Decision = ActionDeny
+		return denied(msg)
+	}
+	rec.Decision = ActionWarn
+	return &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{msg}}
+}
+
+func (wh *Webhook) podImages(pod *corev1.Pod) []string {
+	images := []string{}
+	seen := map[string]struct{}{}
+	add := func(image string) {
+		if _, has := seen[image]; has || image == "" || wh.policy.IsExemptImage(image) {
+			return
+		}
+		seen[image] = struct{}{}
+		images = append(images, image)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		add(c.Image)
+	}
+	for _, c := range pod.Spec.Containers {
+		add(c.Image)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		add(c.Image)
+	}
+	return images
+}
+
+func denied(msg string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: msg,
+		},
+	}
+}
+
+func prefixed(reasons []string) []string {
+	res := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		res = append(res, "deepfence: "+reason)
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+package admission
+
+import (
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FailurePolicy is the failure policy of the webhook registration matching
+// the failure mode: the API server applies it when the webhook itself is
+// unavailable
+func (p Policy) FailurePolicy() admissionregistrationv1.FailurePolicyType {
+	if p.FailureMode == FailClosed {
+		return admissionregistrationv1.Fail
+	}
+	return admissionregistrationv1.Ignore
+}
+
+// setFailurePolicy sets the failure policy of all the webhooks of the
+// configuration, it returns false when they already had it
+func setFailurePolicy(config *admissionregistrationv1.ValidatingWebhookConfiguration, policy admissionregistrationv1.FailurePolicyType) bool {
+	changed := false
+	for i := range config.Webhooks {
+		current := config.Webhooks[i].FailurePolicy
+		if current == nil || *current != policy {
+			p := policy
+			config.Webhooks[i].FailurePolicy = &p
+			changed = true
+		}
+	}
+	return changed
+}
+
+// SyncFailurePolicy updates the validating webhook configuration name with
+// the failure policy of the failure mode
+func SyncFailurePolicy(ctx context.Context, client kubernetes.Interface, name string, policy Policy) error {
+	configs := client.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	config, err := configs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !setFailurePolicy(config, policy.FailurePolicy()) {
+		return nil
+	}
+	_, err = configs.Update(ctx, config, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,213 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"gotest.tools/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type fakeClient struct {
+	statuses map[string]model.ImageScanStatus
+	err      error
+}
+
+func (c fakeClient) GetImageScanStatus(_ context.Context, images []string) ([]model.ImageScanStatus, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	res := []model.ImageScanStatus{}
+	for _, image := range images {
+		status, has := c.statuses[image]
+		if !has {
+			status = model.ImageScanStatus{Image: image}
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
+
+func scanned(image string, vulnerabilities, secrets map[string]int32, updatedAt time.Time) model.ImageScanStatus {
+	return model.ImageScanStatus{
+		Image: image,
+		Found: true,
+		LatestScans: map[string]model.ImageScanSummary{
+			scanTypeVulnerability: {ScanID: "v", UpdatedAt: updatedAt.UnixMilli(), SeverityCounts: vulnerabilities},
+			scanTypeSecret:        {ScanID: "s", UpdatedAt: updatedAt.UnixMilli(), SeverityCounts: secrets},
+			scanTypeMalware:       {ScanID: "m", UpdatedAt: updatedAt.UnixMilli(), SeverityCounts: map[string]int32{}},
+		},
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	now := time.Now()
+	policy := DefaultPolicy()
+	policy.MaxScanAgeHours = 24
+
+	decision := policy.Evaluate([]model.ImageScanStatus{
+		scanned("nginx:1.25", map[string]int32{"high": 4}, map[string]int32{}, now),
+	}, now)
+	assert.Equal(t, decision.Action, ActionAllow)
+	assert.Equal(t, len(decision.Reasons), 0)
+
+	decision = policy.Evaluate([]model.ImageScanStatus{
+		{Image: "unknown:1"},
+		scanned("old:1", map[string]int32{}, map[string]int32{}, now.Add(-48*time.Hour)),
+	}, now)
+	assert.Equal(t, decision.Action, ActionWarn)
+	assert.Equal(t, len(decision.Reasons), 4)
+
+	decision = policy.Evaluate([]model.ImageScanStatus{
+		{Image: "unknown:1"},
+		scanned("app:2", map[string]int32{"critical": 2}, map[string]int32{}, now),
+	}, now)
+	assert.Equal(t, decision.Action, ActionDeny)
+	assert.Equal(t, decision.Reasons[1], "image app:2 has 2 critical vulnerability findings (max 0)")
+
+	policy.Action = "block"
+	assert.Equal(t, policy.Validate(), ErrInvalidAction)
+}
+
+func review(t *testing.T, wh *Webhook, namespace string, pod corev1.Pod) *admissionv1.AdmissionResponse {
+	raw, err := json.Marshal(pod)
+	assert.NilError(t, err)
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "uid-1",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: namespace,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+	assert.NilError(t, err)
+
+	w := httptest.NewRecorder()
+	wh.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	var res admissionv1.AdmissionReview
+	assert.NilError(t, json.NewDecoder(w.Body).Decode(&res))
+	assert.Equal(t, string(res.Response.UID), "uid-1")
+	return res.Response
+}
+
+func TestWebhook(t *testing.T) {
+	now := time.Now()
+	client := fakeClient{statuses: map[string]model.ImageScanStatus{
+		"app:1":   scanned("app:1", map[string]int32{"critical": 1}, map[string]int32{}, now),
+		"proxy:1": scanned("proxy:1", map[string]int32{}, map[string]int32{}, now),
+	}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "app-"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "proxy:1"}},
+			Containers:     []corev1.Container{{Name: "app", Image: "app:1"}, {Name: "proxy", Image: "proxy:1"}},
+		},
+	}
+
+	var audit bytes.Buffer
+	wh := NewWebhook(DefaultPolicy(), client, NewAuditor(&audit))
+
+	resp := review(t, wh, "payments", pod)
+	assert.Assert(t, !resp.Allowed)
+	assert.Assert(t, strings.Contains(resp.Result.Message, "app:1 has 1 critical vulnerability"))
+
+	var rec AuditRecord
+	assert.NilError(t, json.NewDecoder(&audit).Decode(&rec))
+	assert.Equal(t, rec.Decision, ActionDeny)
+	assert.Equal(t, rec.Name, "app-")
+	assert.DeepEqual(t, rec.Images, []string{"proxy:1", "app:1"})
+
+	// exempt namespace
+	resp = review(t, wh, "kube-system", pod)
+	assert.Assert(t, resp.Allowed)
+
+	// warn only
+	policy := DefaultPolicy()
+	policy.Action = ActionWarn
+	resp = review(t, NewWebhook(policy, client, NewAuditor(&audit)), "payments", pod)
+	assert.Assert(t, resp.Allowed)
+	assert.Equal(t, len(resp.Warnings), 1)
+
+	// console unreachable
+	down := fakeClient{err: errors.New("connection refused")}
+	resp = review(t, NewWebhook(DefaultPolicy(), down, NewAuditor(&audit)), "payments", pod)
+	assert.Assert(t, resp.Allowed)
+	assert.Equal(t, len(resp.Warnings), 1)
+
+	policy = DefaultPolicy()
+	policy.FailureMode = FailClosed
+	resp = review(t, NewWebhook(policy, down, NewAuditor(&audit)), "payments", pod)
+	assert.Assert(t, !resp.Allowed)
+}
+
+func TestConsoleClient(t *testing.T) {
+	calls, tokens := 0, 0
+	console := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case authTokenPath:
+			tokens++
+			_ = json.NewEncoder(w).Encode(model.ResponseAccessToken{AccessToken: "token"})
+		case imageScanStatusPath:
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, r.Header.Get("Authorization"), "Bearer token")
+			var req model.ImageScanStatusRequest
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(&req))
+			res := []model.ImageScanStatus{}
+			for _, image := range req.Images {
+				res = append(res, model.ImageScanStatus{Image: image, Found: true})
+			}
+			_ = json.NewEncoder(w).Encode(res)
+		}
+	}))
+	defer console.Close()
+
+	client := NewConsoleClient(console.URL, "api-token", false, time.Second, time.Minute)
+	statuses, err := client.GetImageScanStatus(context.Background(), []string{"app:1"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(statuses), 1)
+	assert.Equal(t, tokens, 2)
+
+	// served from cache
+	statuses, err = client.GetImageScanStatus(context.Background(), []string{"app:1"})
+	assert.NilError(t, err)
+	assert.Assert(t, statuses[0].Found)
+	assert.Equal(t, calls, 2)
+}
+
+func TestSetFailurePolicy(t *testing.T) {
+	ignore := admissionregistrationv1.Ignore
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "images.deepfence.io", FailurePolicy: &ignore},
+		},
+	}
+
+	policy := DefaultPolicy()
+	assert.Equal(t, policy.FailurePolicy(), admissionregistrationv1.Ignore)
+	assert.Assert(t, !setFailurePolicy(config, policy.FailurePolicy()))
+
+	policy.FailureMode = FailClosed
+	assert.Assert(t, setFailurePolicy(config, policy.FailurePolicy()))
+	assert.Equal(t, *config.Webhooks[0].FailurePolicy, admissionregistrationv1.Fail)
+	assert.Assert(t, !setFailurePolicy(config, policy.FailurePolicy()))
+}
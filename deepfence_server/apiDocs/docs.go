@@ -25,6 +25,7 @@ const (
 	tagSecretScan        = "Secret Scan"
 	tagVulnerability     = "Vulnerability"
 	tagMalwareScan       = "Malware Scan"
+	tagIaCScan           = "IaC Scan"
 	tagControls          = "Controls"
 	tagDiagnosis         = "Diagnosis"
 	tagRegistry          = "Registry"
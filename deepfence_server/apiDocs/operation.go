@@ -9,6 +9,7 @@ import (
 	. "github.com/deepfence/ThreatMapper/deepfence_server/reporters/completion" //nolint:stylecheck
 	. "github.com/deepfence/ThreatMapper/deepfence_server/reporters/graph"      //nolint:stylecheck
 	. "github.com/deepfence/ThreatMapper/deepfence_server/reporters/lookup"     //nolint:stylecheck
+	. "github.com/deepfence/ThreatMapper/deepfence_server/reporters/posture"    //nolint:stylecheck
 	. "github.com/deepfence/ThreatMapper/deepfence_server/reporters/search"     //nolint:stylecheck
 	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
 	postgresqldb "github.com/deepfence/ThreatMapper/deepfence_utils/postgresql/postgresql-db"
@@ -114,6 +115,22 @@ func (d *OpenAPIDocs) AddGraphOperations() {
 		"Get Topology Delta", "Retrieve addition or deletion toplogy deltas",
 		http.StatusOK, []string{tagTopology}, bearerToken, new(TopologyDeltaReq), new(TopologyDeltaResponse))
 
+	d.AddOperation("exportTopologyGraph", http.MethodPost, "/deepfence/graph/topology/export",
+		"Export Topology Graph", "Export the filtered topology graph as GraphML, DOT or JSON Graph Format",
+		http.StatusOK, []string{tagTopology}, bearerToken, new(TopologyExportRequest), new(TopologyExportResponse))
+
+	d.AddOperation("streamTopology", http.MethodGet, "/deepfence/graph/topology/stream",
+		"Stream Topology Changes", "Server-Sent Events stream of node and connection changes, filtered by cluster, host or node type",
+		http.StatusOK, []string{tagTopology}, bearerToken, new(TopologyStreamRequest), new(utils.TopologyEvent))
+
+	d.AddOperation("getNetworkPolicies", http.MethodPost, "/deepfence/graph/network-policies",
+		"Get Network Policies", "Generate least privilege Kubernetes NetworkPolicies from the connections observed in a cluster or namespace",
+		http.StatusOK, []string{tagTopology}, bearerToken, new(NetworkPolicyRequest), new(NetworkPolicyResponse))
+
+	d.AddOperation("getBlastRadius", http.MethodPost, "/deepfence/graph/blast-radius",
+		"Get Blast Radius", "List the assets reachable from a compromised node, ranked by their exploitable findings",
+		http.StatusOK, []string{tagTopology}, bearerToken, new(BlastRadiusRequest), new(BlastRadius))
+
 	d.AddOperation("getThreatGraph", http.MethodPost, "/deepfence/graph/threat",
 		"Get Threat Graph", "Retrieve the full threat graph associated with the account",
 		http.StatusOK, []string{tagThreat}, bearerToken, new(ThreatFilters), new(ThreatGraph))
@@ -121,6 +138,10 @@ func (d *OpenAPIDocs) AddGraphOperations() {
 	d.AddOperation("getIndividualThreatGraph", http.MethodPost, "/deepfence/graph/threat/individual",
 		"Get Vulnerability Threat Graph", "Retrieve threat graph associated with vulnerabilities",
 		http.StatusOK, []string{tagThreat}, bearerToken, new(IndividualThreatGraphRequest), new([]IndividualThreatGraph))
+
+	d.AddOperation("getAttackPaths", http.MethodPost, "/deepfence/graph/threat/attack-paths",
+		"Get Attack Paths", "Retrieve the attack paths from the internet to the riskiest nodes, with the exposures and exploitable findings of each hop",
+		http.StatusOK, []string{tagThreat}, bearerToken, new(AttackPathsRequest), new([]AttackPath))
 }
 
 func (d *OpenAPIDocs) AddLookupOperations() {
@@ -175,6 +196,14 @@ func (d *OpenAPIDocs) AddLookupOperations() {
 	d.AddOperation("getCloudCompliances", http.MethodPost, "/deepfence/lookup/cloud-compliances",
 		"Retrieve Cloud Compliances data", "Retrieve all the data associated with cloud-compliances",
 		http.StatusOK, []string{tagLookup}, bearerToken, new(LookupFilter), new([]CloudCompliance))
+
+	d.AddOperation("updateAssetTags", http.MethodPut, "/deepfence/asset-tags",
+		"Update asset tags", "Set the user tags, owner and criticality of hosts, containers, images, pods, clusters and cloud resources",
+		http.StatusOK, []string{tagLookup}, bearerToken, new(AssetTagsUpdateRequest), new(AssetTagsUpdateResponse))
+
+	d.AddOperation("importAssetTags", http.MethodPost, "/deepfence/asset-tags/import",
+		"Import asset tags", "Import asset tags from a CSV file with node_id,node_type,owner,asset_criticality,user_tags columns, user tags separated by semicolons",
+		http.StatusOK, []string{tagLookup}, bearerToken, new(AssetTagsImportRequest), new(AssetTagsUpdateResponse))
 }
 
 func (d *OpenAPIDocs) AddSearchOperations() {
@@ -219,6 +248,10 @@ func (d *OpenAPIDocs) AddSearchOperations() {
 		"Search Compliances", "Search across all the data associated with compliances",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]Compliance))
 
+	d.AddOperation("searchIaCFindings", http.MethodPost, "/deepfence/search/iac-findings",
+		"Search IaC Findings", "Search across all the data associated with IaC findings",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]IaCFinding))
+
 	d.AddOperation("searchVulerabilityRules", http.MethodPost, "/deepfence/search/vulnerability-rules",
 		"Search Vulnerability Rules", "Search across all the data associated with vulnerability rules",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]VulnerabilityRule))
@@ -239,6 +272,14 @@ func (d *OpenAPIDocs) AddSearchOperations() {
 		"Search Pods", "Search across all the data associated with pods",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]Pod))
 
+	d.AddOperation("searchDrifts", http.MethodPost, "/deepfence/search/drifts",
+		"Search Drifts", "Search across all the executables run in containers which are not part of their image or not owned by its packages",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]Drift))
+
+	d.AddOperation("searchImageConfigFindings", http.MethodPost, "/deepfence/search/image-config-findings",
+		"Search Image Config Findings", "Search across all the insecure settings found in the config of images",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]ImageConfigFinding))
+
 	d.AddOperation("searchVulnerabilityScans", http.MethodPost, "/deepfence/search/vulnerability/scans",
 		"Search Vulnerability Scan results", "Search across all the data associated with vulnerability scan",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchScanReq), new([]ScanInfo))
@@ -259,6 +300,10 @@ func (d *OpenAPIDocs) AddSearchOperations() {
 		"Search Cloud Compliance Scan results", "Search across all the data associated with cloud-compliance scan",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchScanReq), new([]ScanInfo))
 
+	d.AddOperation("searchIaCScans", http.MethodPost, "/deepfence/search/iac/scans",
+		"Search IaC Scan results", "Search across all the data associated with IaC scans",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchScanReq), new([]ScanInfo))
+
 	d.AddOperation("searchCloudAccounts", http.MethodPost, "/deepfence/search/cloud-accounts",
 		"Search Cloud Nodes", "Search across all the data associated with cloud nodes",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new([]CloudNodeAccountInfo))
@@ -308,6 +353,14 @@ func (d *OpenAPIDocs) AddSearchOperations() {
 		"Count Pods", "Count across all the data associated with pods",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
 
+	d.AddOperation("countDrifts", http.MethodPost, "/deepfence/search/count/drifts",
+		"Count Drifts", "Count across all the executables run in containers which are not part of their image or not owned by its packages",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
+
+	d.AddOperation("countImageConfigFindings", http.MethodPost, "/deepfence/search/count/image-config-findings",
+		"Count Image Config Findings", "Count across all the insecure settings found in the config of images",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
+
 	d.AddOperation("countCloudCompliances", http.MethodPost, "/deepfence/search/count/cloud-compliances",
 		"Count Cloud compliances", "Count across all the data ssociated with cloud compliances",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
@@ -324,6 +377,10 @@ func (d *OpenAPIDocs) AddSearchOperations() {
 		"Count Compliances", "Count across all the data associated with compliances",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
 
+	d.AddOperation("countIaCFindings", http.MethodPost, "/deepfence/search/count/iac-findings",
+		"Count IaC Findings", "Count across all the data associated with IaC findings",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
+
 	d.AddOperation("countVulnerabilityRules", http.MethodPost, "/deepfence/search/count/vulnerability-rules",
 		"Count Vulnerability Rules", "Count across all the data associated with vulnerability rules",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
@@ -360,6 +417,10 @@ func (d *OpenAPIDocs) AddSearchOperations() {
 		"Count Cloud Compliance Scan results", "Count across all the data associated with cloud-compliance scans",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchScanReq), new(SearchCountResp))
 
+	d.AddOperation("countIaCScans", http.MethodPost, "/deepfence/search/count/iac/scans",
+		"Count IaC Scan results", "Count across all the data associated with IaC scans",
+		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchScanReq), new(SearchCountResp))
+
 	d.AddOperation("countCloudAccounts", http.MethodPost, "/deepfence/search/count/cloud-accounts",
 		"Count Cloud Nodes", "Search across all the data associated with cloud nodes",
 		http.StatusOK, []string{tagSearch}, bearerToken, new(SearchNodeReq), new(SearchCountResp))
@@ -386,6 +447,98 @@ func (d *OpenAPIDocs) AddControlsOperations() {
 		"Schedule new agent version upgrade", "Schedule new agent version upgrade",
 		http.StatusOK, []string{tagControls}, bearerToken, new(AgentUpgrade), nil)
 
+	d.AddOperation("getAgentsHealth", http.MethodPost, "/deepfence/agents/health",
+		"Get agents health", "Version, heartbeat, report ingestion lag, plugins and resources usage of the agents, flagging crash-looping or stuck plugins",
+		http.StatusOK, []string{tagControls}, bearerToken, new(AgentHealthReq), new(AgentHealthResp))
+
+	d.AddOperation("enrollAgent", http.MethodPost, "/deepfence/agents/enroll",
+		"Enroll agent", "Exchange the API key of an agent for a credential bound to its node, re-enrollments wait for the approval of an admin",
+		http.StatusOK, []string{tagControls}, bearerToken, new(AgentEnrollReq), new(AgentEnrollResp))
+
+	d.AddOperation("listAgentCredentials", http.MethodGet, "/deepfence/agents/credentials",
+		"List agent credentials", "List the credentials issued to the enrolled agents",
+		http.StatusOK, []string{tagControls}, bearerToken, nil, new(ListAgentCredentialsResp))
+
+	d.AddOperation("approveAgentEnrollments", http.MethodPost, "/deepfence/agents/credentials/approve",
+		"Approve agent enrollments", "Approve the enrollments of agents waiting for approval",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(AgentCredentialsReq), nil)
+
+	d.AddOperation("revokeAgentCredentials", http.MethodPost, "/deepfence/agents/credentials/revoke",
+		"Revoke agent credentials", "Revoke the credentials of agents and prevent them from enrolling again",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(AgentCredentialsReq), nil)
+
+	d.AddOperation("reinstateAgents", http.MethodPost, "/deepfence/agents/credentials/reinstate",
+		"Reinstate agents", "Let revoked agents enroll again",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(AgentCredentialsReq), nil)
+
+	d.AddOperation("listAgentRollouts", http.MethodGet, "/deepfence/controls/agent-rollouts",
+		"List agent rollouts", "List the staged agent upgrades with the status of their nodes",
+		http.StatusOK, []string{tagControls}, bearerToken, nil, new(ListAgentRolloutsResp))
+
+	d.AddOperation("startAgentRollout", http.MethodPost, "/deepfence/controls/agent-rollouts/start",
+		"Start agent rollout", "Upgrade agents in waves starting with a canary group, rolling back when a wave fails",
+		http.StatusOK, []string{tagControls}, bearerToken, new(AgentRolloutRequest), new(AgentRolloutIDReq))
+
+	d.AddOperation("cancelAgentRollout", http.MethodPost, "/deepfence/controls/agent-rollouts/cancel",
+		"Cancel agent rollout", "Stop scheduling the next waves of an agent rollout",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(AgentRolloutIDReq), nil)
+
+	d.AddOperation("scheduleAgentResourceLimits", http.MethodPost, "/deepfence/controls/agent-resource-limits",
+		"Set agent resource limits", "Push the CPU, memory and IO limits of the plugins, idle and while scanning, to the agents of nodes or clusters",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(AgentResourceLimitsReq), nil)
+
+	d.AddOperation("listScanWindows", http.MethodGet, "/deepfence/scan-windows",
+		"List scan windows", "List the maintenance windows restricting when agents start scans",
+		http.StatusOK, []string{tagControls}, bearerToken, nil, new(ListScanWindowsResp))
+
+	d.AddOperation("addScanWindow", http.MethodPost, "/deepfence/scan-windows",
+		"Add scan window", "Only start scans on matching hosts, clusters or tags during the window, in its timezone",
+		http.StatusOK, []string{tagControls}, bearerToken, new(AddScanWindowReq), new(ScanWindow))
+
+	d.AddOperation("deleteScanWindow", http.MethodDelete, "/deepfence/scan-windows/{id}",
+		"Delete scan window", "Delete scan window by ID",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(ScanWindowIDReq), nil)
+
+	d.AddOperation("setClusterScanLimit", http.MethodPut, "/deepfence/scan-windows/cluster-limits",
+		"Set cluster scan limit", "Limit the scans running at once on the nodes of clusters, 0 removes the limit",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(ClusterScanLimitReq), nil)
+
+	d.AddOperation("listScanProfiles", http.MethodGet, "/deepfence/scan-profiles",
+		"List scan profiles", "List the profiles scoping the files of secret and malware scans",
+		http.StatusOK, []string{tagControls}, bearerToken, nil, new(ListScanProfilesResp))
+
+	d.AddOperation("addScanProfile", http.MethodPost, "/deepfence/scan-profiles",
+		"Add scan profile", "Add a profile of included and excluded path prefixes and skipped filesystems for secret and malware scans, the included paths only apply to hosts",
+		http.StatusOK, []string{tagControls}, bearerToken, new(AddScanProfileReq), new(ScanProfile))
+
+	d.AddOperation("deleteScanProfile", http.MethodDelete, "/deepfence/scan-profiles/{id}",
+		"Delete scan profile", "Delete scan profile by ID",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(ScanProfileIDReq), nil)
+
+	d.AddOperation("assignScanProfile", http.MethodPut, "/deepfence/scan-profiles/assign",
+		"Assign scan profile", "Set the scan profile of nodes, an empty profile_id puts them back on the default profile. Profiles with included paths can only be set on hosts",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(AssignScanProfileReq), nil)
+
+	d.AddOperation("listCustomRules", http.MethodGet, "/deepfence/custom-rules",
+		"List custom rules", "List the custom secret and malware rules, the version of their bundles in use and why the latest was rejected",
+		http.StatusOK, []string{tagControls}, bearerToken, nil, new(ListCustomRulesResp))
+
+	d.AddOperation("addCustomRule", http.MethodPost, "/deepfence/custom-rules",
+		"Add custom rule", "Add a secret regex rule or YARA rules, distributed to the agents and the registry scanners once they compile with the bundled rules",
+		http.StatusOK, []string{tagControls}, bearerToken, new(CustomRuleReq), new(CustomRule))
+
+	d.AddOperation("listCustomRulesBundles", http.MethodGet, "/deepfence/custom-rules/bundles",
+		"List custom rules bundles", "List the versions of the custom rules bundles kept in the file server",
+		http.StatusOK, []string{tagControls}, bearerToken, nil, new(ListCustomRulesBundlesResp))
+
+	d.AddOperation("updateCustomRule", http.MethodPut, "/deepfence/custom-rules/{id}",
+		"Update custom rule", "Update custom rule by ID",
+		http.StatusOK, []string{tagControls}, bearerToken, new(UpdateCustomRuleReq), new(CustomRule))
+
+	d.AddOperation("deleteCustomRule", http.MethodDelete, "/deepfence/custom-rules/{id}",
+		"Delete custom rule", "Delete custom rule by ID",
+		http.StatusNoContent, []string{tagControls}, bearerToken, new(CustomRuleIDReq), nil)
+
 	d.AddOperation("enableAgentPlugin", http.MethodPost, "/deepfence/controls/agent-plugins/enable",
 		"Schedule new agent plugin version enabling", "Schedule agent plugin enable",
 		http.StatusOK, []string{tagControls}, bearerToken, new(AgentPluginEnable), nil)
@@ -501,6 +654,9 @@ func (d *OpenAPIDocs) AddScansOperations() {
 	d.AddOperation("startMalwareScan", http.MethodPost, "/deepfence/scan/start/malware",
 		"Start Malware Scan", "Start Malware Scan on agent or registry",
 		http.StatusAccepted, []string{tagMalwareScan}, bearerToken, new(MalwareScanTriggerReq), new(ScanTriggerResp))
+	d.AddOperation("startIaCScan", http.MethodPost, "/deepfence/scan/start/iac",
+		"Start IaC Scan", "Scan uploaded Kubernetes manifests or helm template output for misconfigurations",
+		http.StatusOK, []string{tagIaCScan}, bearerToken, new(IaCScanRequest), new(IaCScanResult))
 
 	// Stop scan
 	d.AddOperation("stopVulnerabilityScan", http.MethodPost, "/deepfence/scan/stop/vulnerability",
@@ -549,6 +705,9 @@ func (d *OpenAPIDocs) AddScansOperations() {
 	d.AddOperation("listCloudComplianceScan", http.MethodPost, "/deepfence/scan/list/cloud-compliance",
 		"Get Cloud Compliance Scans List", "Get Cloud Compliance Scans list for cloud node",
 		http.StatusOK, []string{tagCloudScanner}, bearerToken, new(ScanListReq), new(ScanListResp))
+	d.AddOperation("listIaCScan", http.MethodPost, "/deepfence/scan/list/iac",
+		"Get IaC Scans List", "Get IaC Scans list of manifest sources",
+		http.StatusOK, []string{tagIaCScan}, bearerToken, new(ScanListReq), new(ScanListResp))
 
 	// Scans' Results
 	d.AddOperation("resultsVulnerabilityScans", http.MethodPost, "/deepfence/scan/results/vulnerability",
@@ -575,6 +734,9 @@ func (d *OpenAPIDocs) AddScansOperations() {
 	d.AddOperation("resultsCloudComplianceScan", http.MethodPost, "/deepfence/scan/results/cloud-compliance",
 		"Get Cloud Compliance Scan Results", "Get Cloud Compliance Scan results for cloud node",
 		http.StatusOK, []string{tagCloudScanner}, bearerToken, new(ScanResultsReq), new(CloudComplianceScanResult))
+	d.AddOperation("resultsIaCScan", http.MethodPost, "/deepfence/scan/results/iac",
+		"Get IaC Scan Results", "Get IaC Scan results of a manifest source",
+		http.StatusOK, []string{tagIaCScan}, bearerToken, new(ScanResultsReq), new(IaCScanResult))
 
 	// Scans results counts
 	d.AddOperation("countResultsVulnerabilityScans", http.MethodPost, "/deepfence/scan/results/count/vulnerability",
@@ -608,6 +770,18 @@ func (d *OpenAPIDocs) AddScansOperations() {
 		"Get all nodes in given scan result ids", "Get all nodes in given scan result ids",
 		http.StatusOK, []string{tagScanResults}, bearerToken, new(NodesInScanResultRequest), new([]ScanResultBasicNode))
 
+	d.AddOperation("getScanCoverage", http.MethodPost, "/deepfence/scan/coverage",
+		"Get Scan Coverage", "List active nodes never scanned or not scanned in the last stale_days days, per scan type and node type",
+		http.StatusOK, []string{tagScanResults}, bearerToken, new(CoverageFilter), new(ScanCoverage))
+
+	d.AddOperation("getKubernetesPosture", http.MethodPost, "/deepfence/scan/kubernetes-posture",
+		"Get Kubernetes Posture", "Evaluate RBAC and pod security context rules against the reported kubernetes clusters",
+		http.StatusOK, []string{tagScanResults}, bearerToken, new(KubernetesPostureFilter), new(KubernetesPosture))
+
+	d.AddOperation("getImageScanStatus", http.MethodPost, "/deepfence/scan/images/status",
+		"Get Image Scan Status", "Get the latest completed scans and their severity counts for image references, used by the admission webhook",
+		http.StatusOK, []string{tagScanResults}, bearerToken, new(ImageScanStatusRequest), new([]ImageScanStatus))
+
 	// Scan Result Actions
 	d.AddOperation("maskScanResult", http.MethodPost, "/deepfence/scan/results/action/mask",
 		"Mask Scans Results", "Mask scan results",
@@ -800,6 +974,12 @@ func (d *OpenAPIDocs) AddSettingsOperations() {
 	d.AddOperation("updateSetting", http.MethodPatch, "/deepfence/settings/global-settings/{id}",
 		"Update setting", "Update setting",
 		http.StatusNoContent, []string{tagSettings}, bearerToken, new(SettingUpdateRequest), nil)
+	d.AddOperation("getThreatScoringModel", http.MethodGet, "/deepfence/settings/threat-scoring",
+		"Get threat scoring model", "Get the model used to compute the exploitable counts and risk scores of the threat graph",
+		http.StatusOK, []string{tagSettings}, bearerToken, nil, new(ThreatScoringModel))
+	d.AddOperation("updateThreatScoringModel", http.MethodPut, "/deepfence/settings/threat-scoring",
+		"Update threat scoring model", "Update the model used to compute the exploitable counts and risk scores of the threat graph",
+		http.StatusNoContent, []string{tagSettings}, bearerToken, new(ThreatScoringModel), nil)
 	d.AddOperation("getUserAuditLogs", http.MethodPost, "/deepfence/settings/user-audit-log",
 		"Get user audit logs", "Get audit logs for all users",
 		http.StatusOK, []string{tagSettings}, bearerToken, new(GetAuditLogsRequest), new([]postgresqldb.GetAuditLogsRow))
@@ -822,7 +1002,7 @@ func (d *OpenAPIDocs) AddSettingsOperations() {
 		http.StatusNoContent, []string{tagSettings}, bearerToken, new(ScheduleJobID), nil)
 
 	d.AddOperation("uploadAgentVersion", http.MethodPut, "/deepfence/settings/agent/version",
-		"Upload New agent version", "Upload Agent version",
+		"Upload New agent version", "Upload Agent version, the agents running an older patch of the version are upgraded in a staged rollout with the default waves and a 30 minutes soak period",
 		http.StatusOK, []string{tagSettings}, bearerToken, new(BinUploadRequest), nil)
 
 	d.AddOperation("getAgentVersions", http.MethodGet, "/deepfence/settings/agent/versions",
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/admission"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	listen     = flag.String("listen", ":8443", "webhook listen address")
+	tlsCert    = flag.String("tls-cert", "/etc/webhook/tls/tls.crt", "TLS certificate file")
+	tlsKey     = flag.String("tls-key", "/etc/webhook/tls/tls.key", "TLS key file")
+	policyPath = flag.String("policy", "", "policy file (yaml or json), default policy if empty")
+	auditPath  = flag.String("audit-log", "-", "audit log file, - for stdout")
+	insecure   = flag.Bool("console-insecure", false, "skip console TLS certificate verification")
+	timeout    = flag.Duration("console-timeout", 5*time.Second, "console API timeout")
+	cacheTTL   = flag.Duration("cache-ttl", 30*time.Second, "image scan results cache duration")
+	configName = flag.String("webhook-config", "", "ValidatingWebhookConfiguration whose failure policy follows the failure mode, not updated if empty")
+)
+
+func main() {
+	flag.Parse()
+
+	consoleURL := os.Getenv("DEEPFENCE_URL")
+	apiToken := os.Getenv("DEEPFENCE_KEY")
+	if consoleURL == "" || apiToken == "" {
+		log.Fatal().Msg("DEEPFENCE_URL and DEEPFENCE_KEY are required")
+	}
+
+	policy := admission.DefaultPolicy()
+	if *policyPath != "" {
+		var err error
+		policy, err = admission.LoadPolicy(*policyPath)
+		if err != nil {
+			log.Fatal().Msgf("invalid policy %s: %v", *policyPath, err)
+		}
+	}
+
+	// the API server fails closed too when the webhook is unavailable
+	if *configName != "" {
+		kubeConfig, err := rest.InClusterConfig()
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err = admission.SyncFailurePolicy(ctx, kubeClient, *configName, policy)
+		cancel()
+		if err != nil {
+			log.Fatal().Msgf("cannot set the failure policy of %s: %v", *configName, err)
+		}
+	}
+
+	var auditWriter io.Writer = os.Stdout
+	if *auditPath != "-" {
+		f, err := os.OpenFile(*auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		defer f.Close()
+		auditWriter = f
+	}
+
+	client := admission.NewConsoleClient(consoleURL, apiToken, *insecure, *timeout, *cacheTTL)
+	webhook := admission.NewWebhook(policy, client, admission.NewAuditor(auditWriter))
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", webhook)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Info().Msgf("admission webhook listening on %s, action=%s failure_mode=%s",
+			*listen, policy.Action, policy.FailureMode)
+		if err := server.ListenAndServeTLS(*tlsCert, *tlsKey); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Msg(err.Error())
+		}
+	}()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
@@ -21,7 +21,7 @@ var (
 	ErrMissingNodeID = errors.New("missing node_id")
 )
 
-func GetAgentActions(ctx context.Context, nodeID string, workNumToExtract int) ([]controls.Action, []error) {
+func GetAgentActions(ctx context.Context, nodeID string, workNumToExtract int, customRulesVersion *int64) ([]controls.Action, []error) {
 
 	// Append more actions here
 	actions := []controls.Action{}
@@ -45,8 +45,19 @@ func GetAgentActions(ctx context.Context, nodeID string, workNumToExtract int) (
 		actions = append(actions, diagnosticLogActions...)
 	}
 
+	// Resource limits not part of workNumToExtract either
+	limitsActions, limitsErr := ExtractPendingAgentResourceLimits(ctx, nodeID)
+	if limitsErr == nil {
+		actions = append(actions, limitsActions...)
+	}
+
+	rulesActions, rulesErr := ExtractPendingCustomRules(ctx, nodeID, customRulesVersion)
+	if rulesErr == nil {
+		actions = append(actions, rulesActions...)
+	}
+
 	if workNumToExtract == 0 {
-		return actions, []error{stopActionsErr, diagnosticLogErr}
+		return actions, []error{stopActionsErr, diagnosticLogErr, limitsErr, rulesErr}
 	}
 
 	upgradeActions, upgradeErr := ExtractPendingAgentUpgrade(ctx, nodeID, workNumToExtract)
@@ -61,7 +72,7 @@ func GetAgentActions(ctx context.Context, nodeID string, workNumToExtract int) (
 		actions = append(actions, scanActions...)
 	}
 
-	return actions, []error{scanErr, upgradeErr, diagnosticLogErr, stopActionsErr}
+	return actions, []error{scanErr, upgradeErr, diagnosticLogErr, stopActionsErr, limitsErr, rulesErr}
 }
 
 func GetPendingAgentScans(ctx context.Context, nodeID string, availableWorkload int) ([]controls.Action, error) {
@@ -271,14 +282,26 @@ func ExtractStartingAgentScans(ctx context.Context, nodeID string, maxWork int)
 	}
 	defer tx.Close()
 
+	blocked, capacity, err := agentScanRestrictions(tx, nodeID, time.Now())
+	if err != nil {
+		return res, err
+	}
+	if capacity >= 0 && capacity < maxWork {
+		maxWork = capacity
+	}
+	if maxWork <= 0 {
+		return res, nil
+	}
+
 	r, err := tx.Run(`MATCH (s) -[:SCHEDULED]-> (n:Node{node_id:$id})
 		WHERE s.status = '`+utils.ScanStatusStarting+`'
 		AND s.retries < 3
+		AND NOT any(l IN labels(s) WHERE l IN $blocked)
 		WITH s ORDER BY s.is_priority DESC, s.updated_at ASC LIMIT $max_work
 		SET s.status = '`+utils.ScanStatusInProgress+`', s.updated_at = TIMESTAMP()
 		WITH s
 		RETURN s.trigger_action`,
-		map[string]interface{}{"id": nodeID, "max_work": maxWork})
+		map[string]interface{}{"id": nodeID, "max_work": maxWork, "blocked": blocked})
 
 	if err != nil {
 		return res, err
@@ -0,0 +1,463 @@
+package controls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+var (
+	ErrAgentCredentialNotFound = errors.New("agent credential not found")
+	ErrAgentCredentialRevoked  = errors.New("agent credential is revoked")
+	ErrAgentRevoked            = errors.New("agent is revoked, reinstate it to enroll again")
+	ErrAgentEnrollmentPending  = errors.New("agent enrollment is waiting for approval")
+	ErrAgentEnrollmentNotFound = errors.New("no enrollment waiting for approval")
+)
+
+// agentEnrollment is the state of the enrollments of a node: the key it is
+// bound to and the one an admin approved to replace it
+type agentEnrollment struct {
+	keyHash         string
+	approvedKeyHash string
+	hasCredential   bool
+}
+
+// canEnroll tells if the node can get a new credential right away. The first
+// enrollment binds the node to its key, the agent then has to prove it holds
+// that key. Re-enrolling a node which has a credential, or with another key,
+// needs an admin to approve it.
+func (e agentEnrollment) canEnroll(keyHash string) bool {
+	if e.approvedKeyHash != "" {
+		return keyHash == e.approvedKeyHash
+	}
+	if e.hasCredential {
+		return false
+	}
+	return e.keyHash == "" || e.keyHash == keyHash
+}
+
+// EnrollAgent issues a new credential for the node, replacing the previous
+// ones, when the enrollment does not need approval. Otherwise it records the
+// enrollment for an admin to approve and returns ErrAgentEnrollmentPending.
+// Revoked agents cannot enroll.
+func EnrollAgent(ctx context.Context, nodeID, enrollmentKey string, userID int64) (model.AgentCredential, error) {
+	cred := model.AgentCredential{
+		ID:        utils.NewUUIDString(),
+		NodeID:    nodeID,
+		UserID:    userID,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	keyHash := model.HashEnrollmentKey(enrollmentKey)
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return cred, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return cred, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		OPTIONAL MATCH (a:RevokedAgent{node_id: $node_id})
+		OPTIONAL MATCH (e:AgentEnrollment{node_id: $node_id})
+		OPTIONAL MATCH (c:AgentCredential{agent_node_id: $node_id})
+		WHERE c.revoked = false
+		RETURN a IS NOT NULL, COALESCE(e.key_hash, ''), COALESCE(e.approved_key_hash, ''), COUNT(c) > 0`,
+		map[string]interface{}{"node_id": nodeID})
+	if err != nil {
+		return cred, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return cred, err
+	}
+	if rec.Values[0].(bool) {
+		return cred, ErrAgentRevoked
+	}
+	enrollment := agentEnrollment{
+		keyHash:         rec.Values[1].(string),
+		approvedKeyHash: rec.Values[2].(string),
+		hasCredential:   rec.Values[3].(bool),
+	}
+
+	if !enrollment.canEnroll(keyHash) {
+		_, err = tx.Run(`
+			MERGE (e:AgentEnrollment{node_id: $node_id})
+			SET e.pending_key_hash = $key_hash,
+				e.user_id = $user_id,
+				e.requested_at = TIMESTAMP()`,
+			map[string]interface{}{
+				"node_id":  nodeID,
+				"key_hash": keyHash,
+				"user_id":  userID,
+			})
+		if err != nil {
+			return cred, err
+		}
+		if err = tx.Commit(); err != nil {
+			return cred, err
+		}
+		return cred, ErrAgentEnrollmentPending
+	}
+
+	_, err = tx.Run(`
+		MATCH (c:AgentCredential{agent_node_id: $node_id})
+		WHERE c.revoked = false
+		SET c.revoked = true, c.revoked_at = TIMESTAMP()`,
+		map[string]interface{}{"node_id": nodeID})
+	if err != nil {
+		return cred, err
+	}
+
+	_, err = tx.Run(`
+		CREATE (c:AgentCredential{node_id: $id})
+		SET c.agent_node_id = $node_id,
+			c.user_id = $user_id,
+			c.created_at = $created_at,
+			c.last_used = 0,
+			c.revoked = false,
+			c.revoked_at = 0
+		MERGE (e:AgentEnrollment{node_id: $node_id})
+		SET e.key_hash = $key_hash
+		REMOVE e.pending_key_hash, e.approved_key_hash, e.requested_at, e.user_id`,
+		map[string]interface{}{
+			"id":         cred.ID,
+			"node_id":    cred.NodeID,
+			"user_id":    cred.UserID,
+			"created_at": cred.CreatedAt,
+			"key_hash":   keyHash,
+		})
+	if err != nil {
+		return cred, err
+	}
+
+	return cred, tx.Commit()
+}
+
+// ListAgentEnrollments returns the enrollments waiting for approval
+func ListAgentEnrollments(ctx context.Context) ([]model.AgentEnrollment, error) {
+	res := []model.AgentEnrollment{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (e:AgentEnrollment)
+		WHERE e.pending_key_hash IS NOT NULL
+		RETURN e.node_id, e.user_id, e.requested_at, e.approved_key_hash = e.pending_key_hash
+		ORDER BY e.requested_at DESC`,
+		map[string]interface{}{})
+	if err != nil {
+		return res, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, record := range records {
+		enrollment := model.AgentEnrollment{}
+		enrollment.NodeID, _ = record.Values[0].(string)
+		enrollment.UserID, _ = record.Values[1].(int64)
+		enrollment.RequestedAt, _ = record.Values[2].(int64)
+		enrollment.Approved, _ = record.Values[3].(bool)
+		res = append(res, enrollment)
+	}
+	return res, nil
+}
+
+// ApproveAgentEnrollments lets the last enrollments requested for the nodes
+// go through, the agents get their credential on their next attempt
+func ApproveAgentEnrollments(ctx context.Context, nodeIDs []string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (e:AgentEnrollment)
+		WHERE e.node_id IN $node_ids
+		AND e.pending_key_hash IS NOT NULL
+		SET e.approved_key_hash = e.pending_key_hash
+		RETURN COUNT(e)`,
+		map[string]interface{}{"node_ids": nodeIDs})
+	if err != nil {
+		return err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return err
+	}
+	if rec.Values[0].(int64) == 0 {
+		return ErrAgentEnrollmentNotFound
+	}
+	return tx.Commit()
+}
+
+// UseAgentCredential returns the valid credential of id and records its use
+func UseAgentCredential(ctx context.Context, id string) (model.AgentCredential, error) {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return model.AgentCredential{}, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return model.AgentCredential{}, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (c:AgentCredential{node_id: $id})
+		SET c.last_used = CASE WHEN c.revoked THEN c.last_used ELSE TIMESTAMP() END
+		RETURN c`,
+		map[string]interface{}{"id": id})
+	if err != nil {
+		return model.AgentCredential{}, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return model.AgentCredential{}, err
+	}
+	if len(records) == 0 {
+		return model.AgentCredential{}, ErrAgentCredentialNotFound
+	}
+
+	cred := toAgentCredential(records[0].Values[0].(neo4j.Node).Props)
+	if cred.Revoked {
+		return cred, ErrAgentCredentialRevoked
+	}
+	return cred, tx.Commit()
+}
+
+func ListAgentCredentials(ctx context.Context) ([]model.AgentCredential, error) {
+	res := []model.AgentCredential{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (c:AgentCredential)
+		RETURN c
+		ORDER BY c.agent_node_id, c.created_at DESC`,
+		map[string]interface{}{})
+	if err != nil {
+		return res, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, record := range records {
+		res = append(res, toAgentCredential(record.Values[0].(neo4j.Node).Props))
+	}
+	return res, nil
+}
+
+// RevokeAgentCredentials revokes the credentials of the nodes and prevents
+// them from enrolling again. It returns the ids of the revoked credentials.
+func RevokeAgentCredentials(ctx context.Context, nodeIDs []string) ([]string, error) {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	_, err = tx.Run(`
+		UNWIND $node_ids AS node_id
+		MERGE (a:RevokedAgent{node_id: node_id})
+		ON CREATE SET a.revoked_at = TIMESTAMP()`,
+		map[string]interface{}{"node_ids": nodeIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := tx.Run(`
+		MATCH (c:AgentCredential)
+		WHERE c.agent_node_id IN $node_ids
+		AND c.revoked = false
+		SET c.revoked = true, c.revoked_at = TIMESTAMP()
+		RETURN c.node_id`,
+		map[string]interface{}{"node_ids": nodeIDs})
+	if err != nil {
+		return nil, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, record := range records {
+		ids = append(ids, record.Values[0].(string))
+	}
+	return ids, tx.Commit()
+}
+
+// ReinstateAgents lets revoked agents enroll again
+func ReinstateAgents(ctx context.Context, nodeIDs []string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	_, err = tx.Run(`
+		MATCH (a:RevokedAgent)
+		WHERE a.node_id IN $node_ids
+		DELETE a`,
+		map[string]interface{}{"node_ids": nodeIDs})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountAgentScans counts the scans of scanIDs which scanned the node or a
+// resource it hosts
+func CountAgentScans(ctx context.Context, nodeID string, scanType utils.Neo4jScanType, scanIDs []string) (int, error) {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(fmt.Sprintf(`
+		MATCH (s:%s)-[:SCANNED]->(t)
+		WHERE s.node_id IN $scan_ids
+		AND (t.node_id = $node_id OR EXISTS((:Node{node_id: $node_id})-[:HOSTS]->(t)))
+		RETURN COUNT(DISTINCT s)`, scanType),
+		map[string]interface{}{"node_id": nodeID, "scan_ids": scanIDs})
+	if err != nil {
+		return 0, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return 0, err
+	}
+	return int(rec.Values[0].(int64)), nil
+}
+
+// ScannedNodeIDs returns the nodes scanned by the scans, along with the
+// nodes hosting them
+func ScannedNodeIDs(ctx context.Context, scanType utils.Neo4jScanType, scanIDs []string) ([]string, error) {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(fmt.Sprintf(`
+		MATCH (s:%s)-[:SCANNED]->(t)
+		WHERE s.node_id IN $scan_ids
+		OPTIONAL MATCH (h:Node)-[:HOSTS]->(t)
+		WITH collect(t.node_id) + collect(h.node_id) AS ids
+		UNWIND ids AS id
+		RETURN DISTINCT id`, scanType),
+		map[string]interface{}{"scan_ids": scanIDs})
+	if err != nil {
+		return nil, err
+	}
+	recs, err := r.Collect()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		if id, ok := rec.Values[0].(string); ok {
+			res = append(res, id)
+		}
+	}
+	return res, nil
+}
+
+func toAgentCredential(props map[string]interface{}) model.AgentCredential {
+	cred := model.AgentCredential{}
+	cred.ID, _ = props["node_id"].(string)
+	cred.NodeID, _ = props["agent_node_id"].(string)
+	cred.UserID, _ = props["user_id"].(int64)
+	cred.CreatedAt, _ = props["created_at"].(int64)
+	cred.LastUsed, _ = props["last_used"].(int64)
+	cred.Revoked, _ = props["revoked"].(bool)
+	cred.RevokedAt, _ = props["revoked_at"].(int64)
+	return cred
+}
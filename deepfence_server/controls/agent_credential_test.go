@@ -0,0 +1,26 @@
+package controls
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAgentEnrollmentCanEnroll(t *testing.T) {
+	// first enrollment binds the node to the key
+	assert.Assert(t, agentEnrollment{}.canEnroll("key"))
+
+	bound := agentEnrollment{keyHash: "key"}
+	assert.Assert(t, bound.canEnroll("key"))
+	assert.Assert(t, !bound.canEnroll("other"))
+
+	// re-enrolling a node with a credential needs approval, even with its key
+	enrolled := agentEnrollment{keyHash: "key", hasCredential: true}
+	assert.Assert(t, !enrolled.canEnroll("key"))
+	assert.Assert(t, !enrolled.canEnroll("other"))
+
+	approved := agentEnrollment{keyHash: "key", approvedKeyHash: "other", hasCredential: true}
+	assert.Assert(t, approved.canEnroll("other"))
+	assert.Assert(t, !approved.canEnroll("key"))
+	assert.Assert(t, !approved.canEnroll("third"))
+}
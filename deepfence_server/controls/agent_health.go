@@ -0,0 +1,369 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	agentHeartbeatTimeout  = 3 * time.Minute
+	agentReportLagLimit    = 5 * time.Minute
+	agentStuckScanTimeout  = time.Hour
+	pluginCrashLoopRestart = 3
+
+	agentHealthKey           = "agent_health"
+	agentHealthFlushKey      = "agent_health_flush"
+	agentHealthFlushInterval = 30 * time.Second
+)
+
+// scanPlugins maps the scans to the config.ini process running them
+var scanPlugins = map[string]string{
+	string(utils.NEO4JSecretScan):        "secret_scanner",
+	string(utils.NEO4JMalwareScan):       "malware_scanner",
+	string(utils.NEO4JVulnerabilityScan): "package_scanner",
+}
+
+// UpdateAgentHealth buffers the heartbeat of the agent polling its controls
+// along with the health report of its bootstrapper, if any. The buffered
+// health of all the agents is written to the graph at most every
+// agentHealthFlushInterval by one of the heartbeats.
+func UpdateAgentHealth(ctx context.Context, nodeID string, health *model.AgentHealthReport) error {
+	if len(nodeID) == 0 {
+		return ErrMissingNodeID
+	}
+
+	buffered := bufferedAgentHealth{HeartbeatAt: time.Now().UnixMilli()}
+	if health != nil {
+		plugins, err := json.Marshal(health.Plugins)
+		if err != nil {
+			return err
+		}
+		cgroups, err := json.Marshal(health.Cgroups)
+		if err != nil {
+			return err
+		}
+		buffered.Plugins = string(plugins)
+		buffered.Cgroups = string(cgroups)
+	}
+	data, err := json.Marshal(buffered)
+	if err != nil {
+		return err
+	}
+
+	rdb, err := directory.RedisClient(ctx)
+	if err != nil {
+		return err
+	}
+	if err := rdb.HSet(ctx, agentHealthKey, nodeID, data).Err(); err != nil {
+		return err
+	}
+
+	flush, err := rdb.SetNX(ctx, agentHealthFlushKey, 1, agentHealthFlushInterval).Result()
+	if err != nil || !flush {
+		return err
+	}
+	ns, err := directory.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := flushAgentsHealth(directory.NewContextWithNameSpace(ns)); err != nil {
+			log.Error().Msgf("Cannot flush agents health: %v", err)
+		}
+	}()
+	return nil
+}
+
+// bufferedAgentHealth is the health of an agent waiting to be written to its
+// node, without plugins and cgroups when it did not report them
+type bufferedAgentHealth struct {
+	HeartbeatAt int64  `json:"heartbeat_at"`
+	Plugins     string `json:"plugins,omitempty"`
+	Cgroups     string `json:"cgroups,omitempty"`
+}
+
+var popAgentsHealth = redis.NewScript(`
+local health = redis.call('HGETALL', KEYS[1])
+redis.call('DEL', KEYS[1])
+return health
+`)
+
+func flushAgentsHealth(ctx context.Context) error {
+	rdb, err := directory.RedisClient(ctx)
+	if err != nil {
+		return err
+	}
+	values, err := popAgentsHealth.Run(ctx, rdb, []string{agentHealthKey}).StringSlice()
+	if err != nil {
+		return err
+	}
+	batch := []map[string]interface{}{}
+	for i := 0; i+1 < len(values); i += 2 {
+		var buffered bufferedAgentHealth
+		if err := json.Unmarshal([]byte(values[i+1]), &buffered); err != nil {
+			log.Warn().Msgf("Invalid buffered health of %s: %v", values[i], err)
+			continue
+		}
+		row := map[string]interface{}{
+			"node_id":      values[i],
+			"heartbeat_at": buffered.HeartbeatAt,
+		}
+		if buffered.Plugins != "" {
+			row["plugins"] = buffered.Plugins
+			row["cgroups"] = buffered.Cgroups
+		}
+		batch = append(batch, row)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	_, err = tx.Run(`
+		UNWIND $batch AS row
+		MATCH (n:Node{node_id: row.node_id})
+		SET n.agent_heartbeat_at = row.heartbeat_at,
+			n.agent_plugins = COALESCE(row.plugins, n.agent_plugins),
+			n.agent_cgroups = COALESCE(row.cgroups, n.agent_cgroups)`,
+		map[string]interface{}{"batch": batch})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pendingAgentsHealth returns the health buffered since the last flush, newer
+// than the one of the nodes
+func pendingAgentsHealth(ctx context.Context) (map[string]bufferedAgentHealth, error) {
+	res := map[string]bufferedAgentHealth{}
+	rdb, err := directory.RedisClient(ctx)
+	if err != nil {
+		return res, err
+	}
+	values, err := rdb.HGetAll(ctx, agentHealthKey).Result()
+	if err != nil {
+		return res, err
+	}
+	for nodeID, value := range values {
+		var buffered bufferedAgentHealth
+		if err := json.Unmarshal([]byte(value), &buffered); err == nil {
+			res[nodeID] = buffered
+		}
+	}
+	return res, nil
+}
+
+// GetAgentsHealth lists the health of the agents matching the filters,
+// ordered by node id.
+func GetAgentsHealth(ctx context.Context, req model.AgentHealthReq) ([]model.AgentHealth, error) {
+	res := []model.AgentHealth{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	now := time.Now()
+	nodeIDs := req.NodeIDs
+	if nodeIDs == nil {
+		nodeIDs = []string{}
+	}
+	versions := req.Versions
+	if versions == nil {
+		versions = []string{}
+	}
+
+	r, err := tx.Run(`
+		MATCH (n:Node)
+		WHERE n.pseudo = false
+		AND n.agent_running = true
+		AND (size($node_ids) = 0 OR n.node_id IN $node_ids)
+		OPTIONAL MATCH (n) -[:VERSIONED]-> (v:AgentVersion)
+		WITH n, COALESCE(v.node_id, n.version, '') AS version
+		WHERE size($versions) = 0 OR version IN $versions
+		OPTIONAL MATCH (s) -[r:SCHEDULED]-> (n)
+		WITH n, version,
+			sum(CASE WHEN s.status = '`+utils.ScanStatusStarting+`' OR r.status = '`+utils.ScanStatusStarting+`' THEN 1 ELSE 0 END) AS pending,
+			collect(CASE WHEN s.status = '`+utils.ScanStatusInProgress+`' AND s.updated_at < $stuck_before THEN labels(s)[0] END) AS stuck
+		RETURN n.node_id, COALESCE(n.node_name, ''), version,
+			COALESCE(n.active, false), COALESCE(n.updated_at, 0), COALESCE(n.agent_heartbeat_at, 0),
+			COALESCE(n.agent_plugins, '[]'), COALESCE(n.agent_cgroups, '[]'),
+			pending, stuck
+		ORDER BY n.node_id`,
+		map[string]interface{}{
+			"node_ids":     nodeIDs,
+			"versions":     versions,
+			"stuck_before": now.Add(-agentStuckScanTimeout).UnixMilli(),
+		})
+	if err != nil {
+		return res, err
+	}
+
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	pending, err := pendingAgentsHealth(ctx)
+	if err != nil {
+		log.Warn().Msgf("Cannot get buffered agents health: %v", err)
+	}
+
+	statuses := map[string]struct{}{}
+	for _, status := range req.Statuses {
+		statuses[status] = struct{}{}
+	}
+
+	for _, record := range records {
+		agent := model.AgentHealth{
+			NodeID:         record.Values[0].(string),
+			NodeName:       record.Values[1].(string),
+			Version:        record.Values[2].(string),
+			LastHeartbeat:  record.Values[5].(int64),
+			PendingActions: record.Values[8].(int64),
+			Plugins:        []model.AgentPluginHealth{},
+			Cgroups:        []model.AgentCgroupUsage{},
+		}
+
+		pluginsData, cgroupsData := record.Values[6].(string), record.Values[7].(string)
+		if buffered, has := pending[agent.NodeID]; has {
+			agent.LastHeartbeat = buffered.HeartbeatAt
+			if buffered.Plugins != "" {
+				pluginsData, cgroupsData = buffered.Plugins, buffered.Cgroups
+			}
+		}
+
+		var plugins []model.AgentPluginStatus
+		if err := json.Unmarshal([]byte(pluginsData), &plugins); err != nil {
+			log.Warn().Msgf("Invalid plugins for %s: %v", agent.NodeID, err)
+		}
+		if err := json.Unmarshal([]byte(cgroupsData), &agent.Cgroups); err != nil {
+			log.Warn().Msgf("Invalid cgroups for %s: %v", agent.NodeID, err)
+		}
+
+		stuck := []string{}
+		for _, label := range record.Values[9].([]interface{}) {
+			if plugin, has := scanPlugins[label.(string)]; has {
+				stuck = append(stuck, plugin)
+			}
+		}
+
+		evaluateAgentHealth(&agent, plugins, stuck,
+			record.Values[3].(bool), record.Values[4].(int64), now)
+
+		if len(statuses) != 0 {
+			if _, has := statuses[agent.Status]; !has {
+				continue
+			}
+		}
+		res = append(res, agent)
+	}
+
+	if req.Window.Size == 0 {
+		return res, nil
+	}
+	if req.Window.Offset >= len(res) {
+		return []model.AgentHealth{}, nil
+	}
+	end := req.Window.Offset + req.Window.Size
+	if end > len(res) {
+		end = len(res)
+	}
+	return res[req.Window.Offset:end], nil
+}
+
+// evaluateAgentHealth flags the crash-looping plugins, the plugins with scans
+// stuck in progress or failing their health check, the agents that stopped
+// polling and the agents whose reports are not ingested anymore.
+func evaluateAgentHealth(agent *model.AgentHealth, plugins []model.AgentPluginStatus,
+	stuckPlugins []string, active bool, updatedAt int64, now time.Time) {
+
+	stuck := map[string]struct{}{}
+	for _, plugin := range stuckPlugins {
+		stuck[plugin] = struct{}{}
+	}
+
+	issues := []string{}
+	offline := !active || agent.LastHeartbeat == 0 ||
+		now.Sub(time.UnixMilli(agent.LastHeartbeat)) > agentHeartbeatTimeout
+	if offline {
+		issues = append(issues, "no_heartbeat")
+	}
+
+	if updatedAt > 0 && now.UnixMilli() > updatedAt {
+		agent.IngestionLagSeconds = (now.UnixMilli() - updatedAt) / 1000
+	}
+	if time.Duration(agent.IngestionLagSeconds)*time.Second > agentReportLagLimit {
+		issues = append(issues, "report_ingestion_lag")
+	}
+
+	for _, plugin := range plugins {
+		health := model.AgentPluginHealthOK
+		if plugin.Status == model.AgentPluginCrashed || plugin.RecentRestarts >= pluginCrashLoopRestart {
+			health = model.AgentPluginHealthCrashLooping
+		} else if _, has := stuck[plugin.Name]; has {
+			health = model.AgentPluginHealthStuck
+		} else if plugin.HealthCheck == model.AgentPluginCheckFailing {
+			health = model.AgentPluginHealthUnhealthy
+		}
+		delete(stuck, plugin.Name)
+		if health != model.AgentPluginHealthOK {
+			issues = append(issues, "plugin_"+health+":"+plugin.Name)
+		}
+		agent.Plugins = append(agent.Plugins, model.AgentPluginHealth{
+			AgentPluginStatus: plugin,
+			Health:            health,
+		})
+	}
+
+	// Agents not reporting their plugins yet
+	remaining := []string{}
+	for plugin := range stuck {
+		remaining = append(remaining, plugin)
+	}
+	sort.Strings(remaining)
+	for _, plugin := range remaining {
+		issues = append(issues, "plugin_"+model.AgentPluginHealthStuck+":"+plugin)
+	}
+
+	agent.Issues = issues
+	switch {
+	case offline:
+		agent.Status = model.AgentOffline
+	case len(issues) != 0:
+		agent.Status = model.AgentDegraded
+	default:
+		agent.Status = model.AgentHealthy
+	}
+}
@@ -0,0 +1,41 @@
+package controls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"gotest.tools/assert"
+)
+
+func TestEvaluateAgentHealth(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+
+	agent := model.AgentHealth{LastHeartbeat: now.Add(-time.Minute).UnixMilli()}
+	evaluateAgentHealth(&agent, []model.AgentPluginStatus{
+		{Name: "package_scanner", Status: model.AgentPluginRunning},
+	}, nil, true, now.Add(-time.Minute).UnixMilli(), now)
+	assert.Equal(t, agent.Status, model.AgentHealthy)
+	assert.Equal(t, agent.IngestionLagSeconds, int64(60))
+	assert.Equal(t, agent.Plugins[0].Health, model.AgentPluginHealthOK)
+
+	agent = model.AgentHealth{LastHeartbeat: now.Add(-time.Minute).UnixMilli()}
+	evaluateAgentHealth(&agent, []model.AgentPluginStatus{
+		{Name: "secret_scanner", Status: model.AgentPluginRunning, RecentRestarts: 4},
+		{Name: "package_scanner", Status: model.AgentPluginRunning},
+		{Name: "fluentbit", Status: model.AgentPluginRunning, HealthCheck: model.AgentPluginCheckFailing},
+	}, []string{"package_scanner", "malware_scanner"}, true, now.Add(-10*time.Minute).UnixMilli(), now)
+	assert.Equal(t, agent.Status, model.AgentDegraded)
+	assert.DeepEqual(t, agent.Issues, []string{
+		"report_ingestion_lag",
+		"plugin_crash_looping:secret_scanner",
+		"plugin_stuck:package_scanner",
+		"plugin_unhealthy:fluentbit",
+		"plugin_stuck:malware_scanner",
+	})
+
+	agent = model.AgentHealth{LastHeartbeat: now.Add(-time.Hour).UnixMilli()}
+	evaluateAgentHealth(&agent, nil, nil, true, 0, now)
+	assert.Equal(t, agent.Status, model.AgentOffline)
+	assert.DeepEqual(t, agent.Issues, []string{"no_heartbeat"})
+}
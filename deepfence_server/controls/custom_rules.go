@@ -0,0 +1,545 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"sigs.k8s.io/yaml"
+)
+
+const customRulesURLExpiry = time.Hour
+
+var ErrCustomRuleNotFound = errors.New("custom rule not found")
+
+// InvalidCustomRuleError is a rule the scanners would reject
+type InvalidCustomRuleError struct {
+	Field string
+	Err   error
+}
+
+func (e *InvalidCustomRuleError) Error() string {
+	return e.Field + ":" + e.Err.Error()
+}
+
+// ValidateCustomRule reports the rules the scanners would reject when they are
+// saved, the worker still compiles the bundles with the engines of the
+// scanners, hyperscan and libyara, before their release. The RE2 syntax
+// rejects the backreferences and lookarounds hyperscan does not support, and
+// hyperscan rejects the patterns matching an empty buffer.
+func ValidateCustomRule(req model.CustomRuleReq) error {
+	switch req.Type {
+	case model.CustomRuleSecret:
+		re, err := regexp.Compile(req.Regex)
+		if err != nil {
+			return &InvalidCustomRuleError{Field: "regex", Err: err}
+		}
+		if re.MatchString("") {
+			return &InvalidCustomRuleError{Field: "regex", Err: errors.New("matches an empty string")}
+		}
+	case model.CustomRuleMalware:
+		if _, err := yaraRuleNames(req.Content); err != nil {
+			return &InvalidCustomRuleError{Field: "content", Err: err}
+		}
+	}
+	return nil
+}
+
+func AddCustomRule(ctx context.Context, req model.CustomRuleReq) (model.CustomRule, error) {
+	now := time.Now().UnixMilli()
+	rule := toCustomRuleFromReq(utils.NewUUIDString(), req, now)
+	rule.CreatedAt = now
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return rule, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return rule, err
+	}
+	defer tx.Close()
+
+	_, err = tx.Run(`
+		CREATE (r:CustomRule{node_id: $id})
+		SET r += $props, r.created_at = $created_at`,
+		map[string]interface{}{
+			"id":         rule.ID,
+			"props":      customRuleProps(rule),
+			"created_at": rule.CreatedAt,
+		})
+	if err != nil {
+		return rule, err
+	}
+
+	if err = publishCustomRules(ctx, tx); err != nil {
+		return rule, err
+	}
+	return rule, tx.Commit()
+}
+
+func UpdateCustomRule(ctx context.Context, req model.UpdateCustomRuleReq) (model.CustomRule, error) {
+	rule := toCustomRuleFromReq(req.ID, req.CustomRuleReq, time.Now().UnixMilli())
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return rule, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return rule, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (r:CustomRule{node_id: $id})
+		SET r += $props
+		RETURN r.created_at`,
+		map[string]interface{}{
+			"id":    rule.ID,
+			"props": customRuleProps(rule),
+		})
+	if err != nil {
+		return rule, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return rule, err
+	}
+	if len(records) == 0 {
+		return rule, ErrCustomRuleNotFound
+	}
+	rule.CreatedAt, _ = records[0].Values[0].(int64)
+
+	if err = publishCustomRules(ctx, tx); err != nil {
+		return rule, err
+	}
+	return rule, tx.Commit()
+}
+
+func DeleteCustomRule(ctx context.Context, id string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (r:CustomRule{node_id: $id})
+		DELETE r
+		RETURN count(r)`,
+		map[string]interface{}{"id": id})
+	if err != nil {
+		return err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return err
+	}
+	if rec.Values[0].(int64) == 0 {
+		return ErrCustomRuleNotFound
+	}
+
+	if err = publishCustomRules(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func ListCustomRules(ctx context.Context) (model.ListCustomRulesResp, error) {
+	res := model.ListCustomRulesResp{Rules: []model.CustomRule{}}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	res.Rules, err = customRules(tx, false)
+	if err != nil {
+		return res, err
+	}
+
+	r, err := tx.Run(`
+		OPTIONAL MATCH (v:CustomRulesVersion{node_id: $id})
+		RETURN COALESCE(v.version, 0), COALESCE(v.published_version, v.version, 0),
+			CASE WHEN v.validated_version = v.published_version THEN COALESCE(v.validation_error, '') ELSE '' END`,
+		map[string]interface{}{"id": utils.CustomRulesVersionID})
+	if err != nil {
+		return res, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return res, err
+	}
+	res.Version = rec.Values[0].(int64)
+	res.PublishedVersion = rec.Values[1].(int64)
+	res.ValidationError = rec.Values[2].(string)
+	return res, nil
+}
+
+// ListCustomRulesBundles lists the versions of the rules kept in the file
+// server, latest first
+func ListCustomRulesBundles(ctx context.Context) ([]model.CustomRulesBundle, error) {
+	res := []model.CustomRulesBundle{}
+
+	mc, err := directory.MinioClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	for ruleType, dir := range map[string]string{
+		model.CustomRuleSecret:  utils.CustomSecretRulesDir,
+		model.CustomRuleMalware: utils.CustomMalwareRulesDir,
+	} {
+		for _, obj := range mc.ListFiles(ctx, dir, false, 0, true) {
+			version, err := customRulesFileVersion(obj.Key)
+			if err != nil {
+				continue
+			}
+			res = append(res, model.CustomRulesBundle{
+				Type:      ruleType,
+				Version:   version,
+				Size:      obj.Size,
+				CreatedAt: obj.LastModified.UnixMilli(),
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Version != res[j].Version {
+			return res[i].Version > res[j].Version
+		}
+		return res[i].Type < res[j].Type
+	})
+	return res, nil
+}
+
+// ExtractPendingCustomRules returns the rules update of the node when the
+// version of the rules it reports is not the released one. The agents not
+// reporting a version do not support custom rules.
+func ExtractPendingCustomRules(ctx context.Context, nodeID string, agentVersion *int64) ([]controls.Action, error) {
+	if agentVersion == nil {
+		return []controls.Action{}, nil
+	}
+	return extractCustomRules(ctx, nodeID, agentVersion)
+}
+
+// GetCustomRules returns the rules update for the agents starting up, which
+// may have missed the updates while down
+func GetCustomRules(ctx context.Context, nodeID string, agentVersion *int64) ([]controls.Action, error) {
+	return extractCustomRules(ctx, nodeID, agentVersion)
+}
+
+// extractCustomRules hands out the released rules until the agent reports
+// running them, so that a failed download is retried. The version the agent
+// reports is recorded on its node when it changes.
+func extractCustomRules(ctx context.Context, nodeID string, agentVersion *int64) ([]controls.Action, error) {
+	res := []controls.Action{}
+	if len(nodeID) == 0 {
+		return res, ErrMissingNodeID
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (n:Node{node_id: $id})
+		OPTIONAL MATCH (v:CustomRulesVersion{node_id: $version_id})
+		RETURN v.version, n.custom_rules_version`,
+		map[string]interface{}{
+			"id":         nodeID,
+			"version_id": utils.CustomRulesVersionID,
+		})
+	if err != nil {
+		return res, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+	if len(records) == 0 {
+		return res, nil
+	}
+	if agentVersion != nil {
+		if recorded, ok := records[0].Values[1].(int64); !ok || recorded != *agentVersion {
+			if err := recordCustomRulesVersion(client, nodeID, *agentVersion); err != nil {
+				log.Warn().Msgf("Cannot record custom rules version of %s: %v", nodeID, err)
+			}
+		}
+	}
+	version, ok := records[0].Values[0].(int64)
+	if !ok || (agentVersion != nil && *agentVersion == version) {
+		return res, nil
+	}
+
+	mc, err := directory.MinioClient(ctx)
+	if err != nil {
+		return res, err
+	}
+	req := controls.UpdateCustomScanRulesRequest{Version: version}
+	req.SecretRulesURL, err = mc.ExposeFile(ctx, customRulesFile(utils.CustomSecretRulesDir, version, ".yaml"),
+		true, customRulesURLExpiry, url.Values{})
+	if err != nil {
+		return res, err
+	}
+	req.MalwareRulesURL, err = mc.ExposeFile(ctx, customRulesFile(utils.CustomMalwareRulesDir, version, ".yar"),
+		true, customRulesURLExpiry, url.Values{})
+	if err != nil {
+		return res, err
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return res, err
+	}
+	res = append(res, controls.Action{ID: controls.UpdateCustomScanRules, RequestPayload: string(b)})
+	return res, nil
+}
+
+func recordCustomRulesVersion(client neo4j.Driver, nodeID string, version int64) error {
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	_, err = tx.Run(`
+		MATCH (n:Node{node_id: $id})
+		SET n.custom_rules_version = $version`,
+		map[string]interface{}{
+			"id":      nodeID,
+			"version": version,
+		})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// publishCustomRules uploads the bundles of the enabled rules as a new
+// version, released to the scanners once validated by the worker. It runs in
+// the transaction changing the rules, which is rolled back when the bundles
+// are rejected.
+func publishCustomRules(ctx context.Context, tx neo4j.Transaction) error {
+	rules, err := customRules(tx, true)
+	if err != nil {
+		return err
+	}
+	secretBundle, malwareBundle, err := customRulesBundles(rules)
+	if err != nil {
+		return err
+	}
+
+	r, err := tx.Run(`
+		MERGE (v:CustomRulesVersion{node_id: $id})
+		SET v.published_version = COALESCE(v.published_version, v.version, 0) + 1,
+			v.updated_at = TIMESTAMP()
+		RETURN v.published_version`,
+		map[string]interface{}{"id": utils.CustomRulesVersionID})
+	if err != nil {
+		return err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return err
+	}
+	version := rec.Values[0].(int64)
+
+	mc, err := directory.MinioClient(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = mc.UploadFile(ctx, customRulesFile(utils.CustomSecretRulesDir, version, ".yaml"),
+		secretBundle, true, minio.PutObjectOptions{ContentType: "application/yaml"})
+	if err != nil {
+		return err
+	}
+	_, err = mc.UploadFile(ctx, customRulesFile(utils.CustomMalwareRulesDir, version, ".yar"),
+		malwareBundle, true, minio.PutObjectOptions{ContentType: "text/plain"})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Published custom rules version %d", version)
+	return nil
+}
+
+func customRules(tx neo4j.Transaction, enabledOnly bool) ([]model.CustomRule, error) {
+	res := []model.CustomRule{}
+	r, err := tx.Run(`
+		MATCH (r:CustomRule)
+		WHERE NOT $enabled_only OR r.enabled = true
+		RETURN r
+		ORDER BY r.created_at`,
+		map[string]interface{}{"enabled_only": enabledOnly})
+	if err != nil {
+		return res, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+	for _, record := range records {
+		res = append(res, toCustomRule(record.Values[0].(neo4j.Node).Props))
+	}
+	return res, nil
+}
+
+type secretSignature struct {
+	Name     string `json:"name"`
+	Part     string `json:"part"`
+	Regex    string `json:"regex"`
+	Severity string `json:"severity"`
+}
+
+// customRulesBundles builds the files the scanners load: the signatures of
+// the secret scanner config and the concatenated YARA sources, whose rule
+// names must be unique
+func customRulesBundles(rules []model.CustomRule) ([]byte, []byte, error) {
+	signatures := []secretSignature{}
+	var yara strings.Builder
+	names := map[string]string{}
+	for _, rule := range rules {
+		switch rule.Type {
+		case model.CustomRuleSecret:
+			signatures = append(signatures, secretSignature{
+				Name:     rule.Name,
+				Part:     rule.Part,
+				Regex:    rule.Regex,
+				Severity: rule.Severity,
+			})
+		case model.CustomRuleMalware:
+			ruleNames, err := yaraRuleNames(rule.Content)
+			if err != nil {
+				return nil, nil, &InvalidCustomRuleError{Field: "content", Err: err}
+			}
+			for _, name := range ruleNames {
+				if other, has := names[name]; has {
+					return nil, nil, &InvalidCustomRuleError{Field: "content",
+						Err: fmt.Errorf("rule %s is already defined by %s", name, other)}
+				}
+				names[name] = rule.Name
+			}
+			fmt.Fprintf(&yara, "// %s\n%s\n\n", rule.Name, strings.TrimSpace(rule.Content))
+		}
+	}
+
+	secret, err := yaml.Marshal(map[string]interface{}{"signatures": signatures})
+	if err != nil {
+		return nil, nil, err
+	}
+	return secret, []byte(yara.String()), nil
+}
+
+func customRulesFile(dir string, version int64, ext string) string {
+	return path.Join(dir, fmt.Sprintf(utils.CustomRulesFileFormat, version)+ext)
+}
+
+func customRulesFileVersion(key string) (int64, error) {
+	name := path.Base(key)
+	return strconv.ParseInt(strings.TrimSuffix(name, path.Ext(name)), 10, 64)
+}
+
+func toCustomRuleFromReq(id string, req model.CustomRuleReq, updatedAt int64) model.CustomRule {
+	rule := model.CustomRule{
+		ID:          id,
+		Name:        req.Name,
+		Type:        req.Type,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		UpdatedAt:   updatedAt,
+	}
+	switch req.Type {
+	case model.CustomRuleSecret:
+		rule.Part = req.Part
+		rule.Regex = req.Regex
+		rule.Severity = req.Severity
+	case model.CustomRuleMalware:
+		rule.Content = req.Content
+	}
+	return rule
+}
+
+func customRuleProps(rule model.CustomRule) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        rule.Name,
+		"type":        rule.Type,
+		"description": rule.Description,
+		"part":        rule.Part,
+		"regex":       rule.Regex,
+		"severity":    rule.Severity,
+		"content":     rule.Content,
+		"enabled":     rule.Enabled,
+		"updated_at":  rule.UpdatedAt,
+	}
+}
+
+func toCustomRule(props map[string]interface{}) model.CustomRule {
+	rule := model.CustomRule{}
+	rule.ID, _ = props["node_id"].(string)
+	rule.Name, _ = props["name"].(string)
+	rule.Type, _ = props["type"].(string)
+	rule.Description, _ = props["description"].(string)
+	rule.Part, _ = props["part"].(string)
+	rule.Regex, _ = props["regex"].(string)
+	rule.Severity, _ = props["severity"].(string)
+	rule.Content, _ = props["content"].(string)
+	rule.Enabled, _ = props["enabled"].(bool)
+	rule.CreatedAt, _ = props["created_at"].(int64)
+	rule.UpdatedAt, _ = props["updated_at"].(int64)
+	return rule
+}
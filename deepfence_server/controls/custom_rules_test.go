@@ -0,0 +1,96 @@
+package controls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"gotest.tools/assert"
+)
+
+func TestYaraRuleNames(t *testing.T) {
+	src := `
+import "pe"
+
+/* packed binaries { */
+private rule packed : exe {
+	meta:
+		description = "brace } in a string"
+	strings:
+		$mz = { 4D 5A [2-4] ?? }
+		$re = /upx\/[0-9]+/ nocase
+	condition:
+		$mz at 0 and $re // and { comment
+}
+
+rule internal_token {
+	strings:
+		$a = "acme_internal_"
+	condition:
+		$a and filesize < 10MB
+}`
+	names, err := yaraRuleNames(src)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"packed", "internal_token"})
+
+	for _, invalid := range []string{
+		"",
+		`include "other.yar"`,
+		`rule a { condition: true } rule a { condition: true }`,
+		`rule a { strings: $a = "x" }`,
+		`rule a { condition: true`,
+		`rule condition { condition: true }`,
+		`rule a { strings: $a = "x condition: $a }`,
+		`rule a { strings: $a = "x" condition: $b }`,
+		`rule a { strings: $a = "x" $b = "y" condition: $a }`,
+		`rule a { strings: $a = "x" $a = "y" condition: $a }`,
+		`rule a { strings: $a = condition: $a }`,
+		`rule a { true }`,
+	} {
+		_, err := yaraRuleNames(invalid)
+		assert.Assert(t, err != nil, invalid)
+	}
+}
+
+func TestValidateCustomRule(t *testing.T) {
+	rule := model.CustomRuleReq{Type: model.CustomRuleSecret, Regex: `acme_[a-z0-9]{32}`}
+	assert.NilError(t, ValidateCustomRule(rule))
+
+	for _, invalid := range []string{`acme_(?=key)`, `(a)\1`, `[a-`, `a*`} {
+		rule.Regex = invalid
+		err := ValidateCustomRule(rule)
+		assert.Assert(t, err != nil, invalid)
+		assert.Assert(t, strings.HasPrefix(err.Error(), "regex:"), err.Error())
+	}
+
+	rule = model.CustomRuleReq{Type: model.CustomRuleMalware, Content: `
+rule wildcard {
+	strings:
+		$k1 = "key_" xor(1-2)
+		$k2 = { 6B [1-2] ( 65 | 45 ) }
+		$ = "anonymous"
+	condition:
+		any of ($k*) and #k1 > 1 and for all of them : ( @ > 0 )
+}`}
+	assert.NilError(t, ValidateCustomRule(rule))
+}
+
+func TestCustomRulesBundles(t *testing.T) {
+	rules := []model.CustomRule{
+		{Name: "acme token", Type: model.CustomRuleSecret, Part: "contents", Regex: "acme_[a-z0-9]{32}", Severity: "high"},
+		{Name: "acme malware", Type: model.CustomRuleMalware, Content: "rule acme { condition: true }"},
+	}
+	secret, malware, err := customRulesBundles(rules)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(secret), "regex: acme_[a-z0-9]{32}"))
+	assert.Equal(t, string(malware), "// acme malware\nrule acme { condition: true }\n\n")
+
+	rules = append(rules, model.CustomRule{Name: "copy", Type: model.CustomRuleMalware, Content: "rule acme { condition: false }"})
+	_, _, err = customRulesBundles(rules)
+	assert.ErrorContains(t, err, "rule acme is already defined by acme malware")
+
+	secret, malware, err = customRulesBundles(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, string(secret), "signatures: []\n")
+	assert.Equal(t, string(malware), "")
+}
@@ -0,0 +1,214 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// ScheduleAgentResourceLimits stores the resource profile on the nodes and
+// the clusters, the agents pick it up on their next controls polling.
+func ScheduleAgentResourceLimits(ctx context.Context, req model.AgentResourceLimitsReq) error {
+
+	action, err := resourceLimitsAction(req)
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := req.NodeIDs
+	if nodeIDs == nil {
+		nodeIDs = []string{}
+	}
+	clusterIDs := req.KubernetesClusterIDs
+	if clusterIDs == nil {
+		clusterIDs = []string{}
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	params := map[string]interface{}{
+		"node_ids":    nodeIDs,
+		"cluster_ids": clusterIDs,
+		"action":      action,
+	}
+
+	_, err = tx.Run(`
+		MATCH (k:KubernetesCluster)
+		WHERE k.node_id IN $cluster_ids
+		SET k.resource_limits = $action
+		WITH k
+		MATCH (k) -[:INSTANCIATE]-> (n:Node)
+		SET n.resource_limits = $action,
+			n.resource_limits_pending = true`, params)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Run(`
+		MATCH (n:Node)
+		WHERE n.node_id IN $node_ids
+		SET n.resource_limits = $action,
+			n.resource_limits_pending = true`, params)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ExtractPendingAgentResourceLimits returns the resource profile pushed to
+// the node since its last polling. The node is only written when there is
+// one, the agents poll often.
+func ExtractPendingAgentResourceLimits(ctx context.Context, nodeID string) ([]controls.Action, error) {
+	pending, err := hasPendingAgentResourceLimits(ctx, nodeID)
+	if err != nil || !pending {
+		return []controls.Action{}, err
+	}
+	return extractAgentResourceLimits(ctx, nodeID, `
+		MATCH (n:Node{node_id:$id})
+		WHERE n.resource_limits_pending = true
+		SET n.resource_limits_pending = false
+		RETURN n.resource_limits`)
+}
+
+func hasPendingAgentResourceLimits(ctx context.Context, nodeID string) (bool, error) {
+	if len(nodeID) == 0 {
+		return false, ErrMissingNodeID
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	r, err := session.Run(`
+		MATCH (n:Node{node_id:$id})
+		WHERE n.resource_limits_pending = true
+		RETURN count(n)`,
+		map[string]interface{}{"id": nodeID}, neo4j.WithTxTimeout(30*time.Second))
+	if err != nil {
+		return false, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return false, err
+	}
+	return rec.Values[0].(int64) != 0, nil
+}
+
+// GetAgentResourceLimits returns the resource profile of the node, or the
+// one of its cluster, for the agents starting up.
+func GetAgentResourceLimits(ctx context.Context, nodeID string) ([]controls.Action, error) {
+	return extractAgentResourceLimits(ctx, nodeID, `
+		MATCH (n:Node{node_id:$id})
+		OPTIONAL MATCH (k:KubernetesCluster) -[:INSTANCIATE]-> (n)
+		WITH n, head(collect(k.resource_limits)) AS cluster_limits
+		SET n.resource_limits_pending = false
+		RETURN COALESCE(n.resource_limits, cluster_limits)`)
+}
+
+func extractAgentResourceLimits(ctx context.Context, nodeID, query string) ([]controls.Action, error) {
+	res := []controls.Action{}
+	if len(nodeID) == 0 {
+		return res, ErrMissingNodeID
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(query, map[string]interface{}{"id": nodeID})
+	if err != nil {
+		return res, err
+	}
+
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, record := range records {
+		action, ok := toResourceLimitsAction(record.Values[0])
+		if !ok {
+			continue
+		}
+		res = append(res, action)
+	}
+
+	if len(records) != 0 {
+		err = tx.Commit()
+	}
+
+	return res, err
+}
+
+// resourceLimitsAction is the action stored on the nodes for their agents
+func resourceLimitsAction(req model.AgentResourceLimitsReq) (string, error) {
+	internalReq := controls.UpdateAgentResourceLimitsRequest{
+		Limits: []controls.PluginResourceLimits{},
+	}
+	for _, limits := range req.Limits {
+		internalReq.Limits = append(internalReq.Limits, controls.PluginResourceLimits{
+			PluginName:        limits.PluginName,
+			CPUPercentage:     limits.CPUPercentage,
+			MemoryMB:          limits.MemoryMB,
+			IOWeight:          limits.IOWeight,
+			ScanCPUPercentage: limits.ScanCPUPercentage,
+			ScanMemoryMB:      limits.ScanMemoryMB,
+		})
+	}
+	b, err := json.Marshal(internalReq)
+	if err != nil {
+		return "", err
+	}
+	action, err := json.Marshal(controls.Action{
+		ID:             controls.UpdateAgentResourceLimits,
+		RequestPayload: string(b),
+	})
+	return string(action), err
+}
+
+func toResourceLimitsAction(value interface{}) (controls.Action, bool) {
+	var action controls.Action
+	data, ok := value.(string)
+	if !ok {
+		return action, false
+	}
+	err := json.Unmarshal([]byte(data), &action)
+	if err != nil {
+		log.Error().Msgf("Unmarshal of action failed: %v", err)
+		return action, false
+	}
+	return action, true
+}
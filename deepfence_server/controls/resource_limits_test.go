@@ -0,0 +1,53 @@
+package controls
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"gotest.tools/assert"
+)
+
+func TestResourceLimitsAction(t *testing.T) {
+	action, err := resourceLimitsAction(model.AgentResourceLimitsReq{
+		Limits: []model.AgentPluginResourceLimits{{
+			PluginName:        "secret_scanner",
+			CPUPercentage:     20,
+			MemoryMB:          512,
+			IOWeight:          100,
+			ScanCPUPercentage: 50,
+			ScanMemoryMB:      1024,
+		}},
+	})
+	assert.NilError(t, err)
+
+	res, ok := toResourceLimitsAction(action)
+	assert.Assert(t, ok)
+	assert.Equal(t, res.ID, controls.UpdateAgentResourceLimits)
+
+	var req controls.UpdateAgentResourceLimitsRequest
+	assert.NilError(t, json.Unmarshal([]byte(res.RequestPayload), &req))
+	assert.DeepEqual(t, req.Limits, []controls.PluginResourceLimits{{
+		PluginName:        "secret_scanner",
+		CPUPercentage:     20,
+		MemoryMB:          512,
+		IOWeight:          100,
+		ScanCPUPercentage: 50,
+		ScanMemoryMB:      1024,
+	}})
+
+	// an empty profile removes the limits on the agents
+	action, err = resourceLimitsAction(model.AgentResourceLimitsReq{})
+	assert.NilError(t, err)
+	res, ok = toResourceLimitsAction(action)
+	assert.Assert(t, ok)
+	assert.NilError(t, json.Unmarshal([]byte(res.RequestPayload), &req))
+	assert.Equal(t, len(req.Limits), 0)
+
+	// nodes without a profile
+	_, ok = toResourceLimitsAction(nil)
+	assert.Assert(t, !ok)
+	_, ok = toResourceLimitsAction("{")
+	assert.Assert(t, !ok)
+}
@@ -0,0 +1,659 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const (
+	DefaultRolloutCanaryPercentage     = 10
+	DefaultRolloutWavePercentage       = 25
+	DefaultRolloutSoakPeriodMinutes    = 30
+	DefaultRolloutMaxFailurePercentage = 20
+)
+
+var (
+	ErrRolloutNotFound   = errors.New("agent rollout not found")
+	ErrRolloutNotRunning = errors.New("agent rollout is not running")
+)
+
+// planRolloutWaves splits the nodes in waves, the first being the canary
+// group. The named canary nodes are used when any is part of the rollout,
+// otherwise canaryPercentage of the nodes.
+func planRolloutWaves(nodeIDs, canaryNodeIDs []string, canaryPercentage, wavePercentage int) [][]string {
+	seen := map[string]struct{}{}
+	nodes := []string{}
+	for _, id := range nodeIDs {
+		if _, has := seen[id]; has {
+			continue
+		}
+		seen[id] = struct{}{}
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+	if len(nodes) == 0 {
+		return [][]string{}
+	}
+
+	canary := []string{}
+	inCanary := map[string]struct{}{}
+	for _, id := range canaryNodeIDs {
+		if _, has := seen[id]; !has {
+			continue
+		}
+		if _, has := inCanary[id]; has {
+			continue
+		}
+		inCanary[id] = struct{}{}
+		canary = append(canary, id)
+	}
+	if len(canary) == 0 {
+		for _, id := range nodes[:percentageOf(len(nodes), canaryPercentage)] {
+			inCanary[id] = struct{}{}
+			canary = append(canary, id)
+		}
+	}
+
+	waves := [][]string{canary}
+	waveSize := percentageOf(len(nodes), wavePercentage)
+	wave := []string{}
+	for _, id := range nodes {
+		if _, has := inCanary[id]; has {
+			continue
+		}
+		wave = append(wave, id)
+		if len(wave) == waveSize {
+			waves = append(waves, wave)
+			wave = []string{}
+		}
+	}
+	if len(wave) != 0 {
+		waves = append(waves, wave)
+	}
+	return waves
+}
+
+// percentageOf rounds up, so that any non zero percentage holds a node
+func percentageOf(total, percentage int) int {
+	n := (total*percentage + 99) / 100
+	if n < 1 {
+		n = 1
+	}
+	if n > total {
+		n = total
+	}
+	return n
+}
+
+// waveFailed reports whether failed nodes out of total exceed the
+// tolerated percentage
+func waveFailed(total, failed, maxFailurePercentage int) bool {
+	return total > 0 && failed*100 > maxFailurePercentage*total
+}
+
+func withRolloutDefaults(req model.AgentRolloutRequest) model.AgentRolloutRequest {
+	if req.CanaryPercentage == 0 {
+		req.CanaryPercentage = DefaultRolloutCanaryPercentage
+	}
+	if req.WavePercentage == 0 {
+		req.WavePercentage = DefaultRolloutWavePercentage
+	}
+	if req.SoakPeriodMinutes == 0 {
+		req.SoakPeriodMinutes = DefaultRolloutSoakPeriodMinutes
+	}
+	if req.MaxFailurePercentage == 0 {
+		req.MaxFailurePercentage = DefaultRolloutMaxFailurePercentage
+	}
+	return req
+}
+
+// StartAgentRollout plans the waves of the rollout and schedules the
+// upgrade of the canary wave
+func StartAgentRollout(ctx context.Context, req model.AgentRolloutRequest) (string, error) {
+	req = withRolloutDefaults(req)
+
+	action, err := PrepareAgentUpgradeAction(ctx, req.Version)
+	if err != nil {
+		return "", err
+	}
+	actionStr, err := json.Marshal(action)
+	if err != nil {
+		return "", err
+	}
+
+	waves := planRolloutWaves(req.NodeIDs, req.CanaryNodeIDs, req.CanaryPercentage, req.WavePercentage)
+	targets := []map[string]interface{}{}
+	for i, wave := range waves {
+		for _, nodeID := range wave {
+			targets = append(targets, map[string]interface{}{"node_id": nodeID, "wave": i})
+		}
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return "", err
+	}
+	defer tx.Close()
+
+	rolloutID := fmt.Sprintf("rollout-%s-%d", req.Version, time.Now().UnixMilli())
+
+	if _, err = tx.Run(`
+		CREATE (r:AgentRollout{
+			node_id: $rollout_id,
+			version: $version,
+			status: $status,
+			status_message: "",
+			current_wave: 0,
+			total_waves: $total_waves,
+			soak_period_minutes: $soak_period_minutes,
+			max_failure_percentage: $max_failure_percentage,
+			trigger_action: $action,
+			wave_started_at: TIMESTAMP(),
+			created_at: TIMESTAMP(),
+			updated_at: TIMESTAMP()})
+		WITH r
+		UNWIND $targets as row
+		MATCH (n:Node{node_id: row.node_id})
+		OPTIONAL MATCH (n) -[:VERSIONED]-> (pv:AgentVersion)
+		MERGE (r) -[:ROLLOUT_TARGET{wave: row.wave, previous_version: COALESCE(pv.node_id, ""), status: $pending}]-> (n)`,
+		map[string]interface{}{
+			"rollout_id":             rolloutID,
+			"version":                req.Version,
+			"status":                 model.AgentRolloutRunning,
+			"total_waves":            len(waves),
+			"soak_period_minutes":    req.SoakPeriodMinutes,
+			"max_failure_percentage": req.MaxFailurePercentage,
+			"action":                 string(actionStr),
+			"targets":                targets,
+			"pending":                model.AgentRolloutNodePending,
+		}); err != nil {
+		return "", err
+	}
+
+	if err = scheduleRolloutWave(tx, rolloutID, 0); err != nil {
+		return "", err
+	}
+
+	return rolloutID, tx.Commit()
+}
+
+// scheduleRolloutWave schedules the upgrade of the nodes of the wave, with
+// the action prepared when the rollout started. The inactive nodes are
+// skipped.
+func scheduleRolloutWave(tx neo4j.Transaction, rolloutID string, wave int) error {
+	if _, err := tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id})
+		SET r.current_wave = $wave,
+			r.wave_started_at = TIMESTAMP(),
+			r.updated_at = TIMESTAMP()
+		WITH r
+		MATCH (r) -[t:ROLLOUT_TARGET{wave: $wave}]-> (n:Node)
+		WHERE NOT COALESCE(n.active, false)
+		SET t.status = $skipped`,
+		map[string]interface{}{
+			"rollout_id": rolloutID,
+			"wave":       wave,
+			"skipped":    model.AgentRolloutNodeSkipped,
+		}); err != nil {
+		return err
+	}
+
+	_, err := tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id}) -[t:ROLLOUT_TARGET{wave: $wave}]-> (n:Node)
+		WHERE COALESCE(n.active, false)
+		MATCH (v:AgentVersion{node_id: r.version})
+		SET t.status = $upgrading
+		MERGE (v) -[:SCHEDULED{status: $status, retries: 0, trigger_action: r.trigger_action, updated_at: TIMESTAMP()}]-> (n)`,
+		map[string]interface{}{
+			"rollout_id": rolloutID,
+			"wave":       wave,
+			"upgrading":  model.AgentRolloutNodeUpgrading,
+			"status":     utils.ScanStatusStarting,
+		})
+	return err
+}
+
+func ListAgentRollouts(ctx context.Context) ([]model.AgentRollout, error) {
+	res := []model.AgentRollout{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (r:AgentRollout)
+		OPTIONAL MATCH (r) -[t:ROLLOUT_TARGET]-> (n:Node)
+		WITH r, t, n
+		ORDER BY t.wave, n.node_id
+		RETURN r{.*} AS rollout, collect({node_id: n.node_id, wave: t.wave, previous_version: t.previous_version, status: t.status})
+		ORDER BY rollout.created_at DESC`,
+		map[string]interface{}{})
+	if err != nil {
+		return res, err
+	}
+	recs, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, rec := range recs {
+		props := rec.Values[0].(map[string]interface{})
+		rollout := model.AgentRollout{
+			RolloutID:            props["node_id"].(string),
+			Version:              props["version"].(string),
+			Status:               props["status"].(string),
+			StatusMessage:        props["status_message"].(string),
+			CurrentWave:          int(props["current_wave"].(int64)),
+			TotalWaves:           int(props["total_waves"].(int64)),
+			SoakPeriodMinutes:    int(props["soak_period_minutes"].(int64)),
+			MaxFailurePercentage: int(props["max_failure_percentage"].(int64)),
+			WaveStartedAt:        props["wave_started_at"].(int64),
+			CreatedAt:            props["created_at"].(int64),
+			UpdatedAt:            props["updated_at"].(int64),
+			Nodes:                []model.AgentRolloutNode{},
+		}
+		for _, n := range rec.Values[1].([]interface{}) {
+			node := n.(map[string]interface{})
+			if node["node_id"] == nil {
+				continue
+			}
+			rollout.Nodes = append(rollout.Nodes, model.AgentRolloutNode{
+				NodeID:          node["node_id"].(string),
+				Wave:            int(node["wave"].(int64)),
+				PreviousVersion: node["previous_version"].(string),
+				Status:          node["status"].(string),
+			})
+		}
+		res = append(res, rollout)
+	}
+
+	return res, nil
+}
+
+// CancelAgentRollout stops scheduling the next waves, agents already
+// upgraded keep the new version
+func CancelAgentRollout(ctx context.Context, rolloutID string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id})
+		WITH r, r.status = $running AS running
+		SET r.status = CASE WHEN running THEN $cancelled ELSE r.status END,
+			r.updated_at = TIMESTAMP()
+		RETURN running`,
+		map[string]interface{}{
+			"rollout_id": rolloutID,
+			"running":    model.AgentRolloutRunning,
+			"cancelled":  model.AgentRolloutCancelled,
+		})
+	if err != nil {
+		return err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return ErrRolloutNotFound
+	}
+	if !rec.Values[0].(bool) {
+		return ErrRolloutNotRunning
+	}
+
+	return tx.Commit()
+}
+
+type rolloutProgress struct {
+	id                   string
+	currentWave          int
+	totalWaves           int
+	maxFailurePercentage int
+	soakElapsed          bool
+	healthy              []string
+	failed               []string
+	skipped              []string
+}
+
+// ProgressAgentRollouts moves the running rollouts whose current wave soaked
+// to their next wave, or rolls them back
+func ProgressAgentRollouts(ctx context.Context) error {
+	rollouts, err := runningAgentRollouts(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range rollouts {
+		if !p.soakElapsed {
+			continue
+		}
+		total := len(p.healthy) + len(p.failed)
+		if waveFailed(total, len(p.failed), p.maxFailurePercentage) {
+			reason := fmt.Sprintf("%d of %d agents of wave %d not healthy", len(p.failed), total, p.currentWave)
+			log.Warn().Msgf("Rolling back agent rollout %s: %s", p.id, reason)
+			err = rollbackAgentRollout(ctx, p, reason)
+		} else {
+			err = advanceAgentRollout(ctx, p)
+		}
+		if err != nil {
+			log.Error().Msgf("Agent rollout %s: %v", p.id, err)
+		}
+	}
+
+	return nil
+}
+
+// runningAgentRollouts returns the progress of the current wave of the
+// running rollouts. Nodes of the wave are healthy once they run the new
+// version and sent a report since the wave started. Nodes gone inactive
+// before getting the upgrade are skipped, the deleted ones are not counted.
+func runningAgentRollouts(ctx context.Context) ([]rolloutProgress, error) {
+	res := []rolloutProgress{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (r:AgentRollout{status: $running})
+		OPTIONAL MATCH (r) -[t:ROLLOUT_TARGET]-> (n:Node)
+		WHERE t.wave = r.current_wave
+		AND t.status = $upgrading
+		OPTIONAL MATCH (n) -[:VERSIONED]-> (cv:AgentVersion)
+		OPTIONAL MATCH (:AgentVersion{node_id: r.version}) -[s:SCHEDULED{status: $starting}]-> (n)
+		WITH r, n, COALESCE(cv.node_id = r.version, false)
+			AND COALESCE(n.active, false)
+			AND COALESCE(n.updated_at, 0) > r.wave_started_at AS healthy,
+			NOT COALESCE(n.active, false) AND s IS NOT NULL AS skipped
+		WITH r, [x IN collect({node_id: n.node_id, healthy: healthy, skipped: skipped}) WHERE x.node_id IS NOT NULL] AS nodes
+		RETURN r.node_id, r.current_wave, r.total_waves, r.max_failure_percentage,
+			r.wave_started_at + r.soak_period_minutes * 60000 < TIMESTAMP(),
+			[x IN nodes WHERE x.healthy | x.node_id],
+			[x IN nodes WHERE NOT x.healthy AND NOT x.skipped | x.node_id],
+			[x IN nodes WHERE x.skipped | x.node_id]`,
+		map[string]interface{}{
+			"running":   model.AgentRolloutRunning,
+			"upgrading": model.AgentRolloutNodeUpgrading,
+			"starting":  utils.ScanStatusStarting,
+		})
+	if err != nil {
+		return res, err
+	}
+	recs, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, rec := range recs {
+		res = append(res, rolloutProgress{
+			id:                   rec.Values[0].(string),
+			currentWave:          int(rec.Values[1].(int64)),
+			totalWaves:           int(rec.Values[2].(int64)),
+			maxFailurePercentage: int(rec.Values[3].(int64)),
+			soakElapsed:          rec.Values[4].(bool),
+			healthy:              toStrings(rec.Values[5].([]interface{})),
+			failed:               toStrings(rec.Values[6].([]interface{})),
+			skipped:              toStrings(rec.Values[7].([]interface{})),
+		})
+	}
+
+	return res, nil
+}
+
+func toStrings(values []interface{}) []string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		res = append(res, v.(string))
+	}
+	return res
+}
+
+func setRolloutNodesStatus(tx neo4j.Transaction, rolloutID string, nodeIDs []string, status string) error {
+	_, err := tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id}) -[t:ROLLOUT_TARGET]-> (n:Node)
+		WHERE n.node_id IN $node_ids
+		SET t.status = $status`,
+		map[string]interface{}{
+			"rollout_id": rolloutID,
+			"node_ids":   nodeIDs,
+			"status":     status,
+		})
+	return err
+}
+
+// skipRolloutNodes cancels the upgrade of the nodes gone before getting it,
+// they are not upgraded outside of the rollout when back
+func skipRolloutNodes(tx neo4j.Transaction, rolloutID string, nodeIDs []string) error {
+	if err := setRolloutNodesStatus(tx, rolloutID, nodeIDs, model.AgentRolloutNodeSkipped); err != nil {
+		return err
+	}
+	_, err := tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id}) -[:ROLLOUT_TARGET]-> (n:Node)
+		WHERE n.node_id IN $node_ids
+		MATCH (:AgentVersion{node_id: r.version}) -[s:SCHEDULED{status: $starting}]-> (n)
+		DELETE s`,
+		map[string]interface{}{
+			"rollout_id": rolloutID,
+			"node_ids":   nodeIDs,
+			"starting":   utils.ScanStatusStarting,
+		})
+	return err
+}
+
+func advanceAgentRollout(ctx context.Context, p rolloutProgress) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	if err = setRolloutNodesStatus(tx, p.id, p.healthy, model.AgentRolloutNodeHealthy); err != nil {
+		return err
+	}
+	if err = setRolloutNodesStatus(tx, p.id, p.failed, model.AgentRolloutNodeFailed); err != nil {
+		return err
+	}
+	if err = skipRolloutNodes(tx, p.id, p.skipped); err != nil {
+		return err
+	}
+
+	if p.currentWave+1 < p.totalWaves {
+		if err = scheduleRolloutWave(tx, p.id, p.currentWave+1); err != nil {
+			return err
+		}
+	} else if _, err = tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id})
+		SET r.status = $completed,
+			r.updated_at = TIMESTAMP()`,
+		map[string]interface{}{
+			"rollout_id": p.id,
+			"completed":  model.AgentRolloutCompleted,
+		}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// prepareAgentRollbackAction points to the tarball of the version when it
+// is still available, the bootstrapper restores its own backup first
+func prepareAgentRollbackAction(ctx context.Context, version string) (controls.Action, error) {
+	url, err := GetAgentVersionTarball(ctx, version)
+	if err != nil {
+		log.Warn().Msgf("No tarball to roll back to %s: %v", version, err)
+		url = ""
+	}
+
+	b, err := json.Marshal(controls.RollbackAgentUpgradeRequest{
+		HomeDirectoryURL: url,
+		Version:          version,
+	})
+	if err != nil {
+		return controls.Action{}, err
+	}
+
+	return controls.Action{
+		ID:             controls.RollbackAgentUpgrade,
+		RequestPayload: string(b),
+	}, nil
+}
+
+// rollbackAgentRollout cancels the pending upgrades of the rollout and
+// schedules the agents already upgraded back to their previous version
+func rollbackAgentRollout(ctx context.Context, p rolloutProgress, reason string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	r, err := session.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id}) -[t:ROLLOUT_TARGET]-> (n:Node) -[:VERSIONED]-> (v:AgentVersion)
+		WHERE t.wave <= r.current_wave
+		AND v.node_id = r.version
+		AND t.previous_version <> ""
+		RETURN t.previous_version, collect(n.node_id)`,
+		map[string]interface{}{"rollout_id": p.id})
+	if err != nil {
+		return err
+	}
+	recs, err := r.Collect()
+	if err != nil {
+		return err
+	}
+
+	type rollback struct {
+		version string
+		action  string
+		nodeIDs []interface{}
+	}
+	rollbacks := []rollback{}
+	for _, rec := range recs {
+		version := rec.Values[0].(string)
+		action, err := prepareAgentRollbackAction(ctx, version)
+		if err != nil {
+			return err
+		}
+		actionStr, err := json.Marshal(action)
+		if err != nil {
+			return err
+		}
+		rollbacks = append(rollbacks, rollback{
+			version: version,
+			action:  string(actionStr),
+			nodeIDs: rec.Values[1].([]interface{}),
+		})
+	}
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	if err = setRolloutNodesStatus(tx, p.id, p.failed, model.AgentRolloutNodeFailed); err != nil {
+		return err
+	}
+	if err = skipRolloutNodes(tx, p.id, p.skipped); err != nil {
+		return err
+	}
+
+	if _, err = tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id}) -[:ROLLOUT_TARGET]-> (n:Node)
+		MATCH (:AgentVersion{node_id: r.version}) -[s:SCHEDULED]-> (n)
+		DELETE s`,
+		map[string]interface{}{"rollout_id": p.id}); err != nil {
+		return err
+	}
+
+	for _, rb := range rollbacks {
+		if _, err = tx.Run(`
+			MATCH (r:AgentRollout{node_id: $rollout_id}) -[t:ROLLOUT_TARGET]-> (n:Node)
+			WHERE n.node_id IN $node_ids
+			MATCH (v:AgentVersion{node_id: $version})
+			SET t.status = $rolled_back
+			MERGE (v) -[:SCHEDULED{status: $status, retries: 0, rollback: true, trigger_action: $action, updated_at: TIMESTAMP()}]-> (n)`,
+			map[string]interface{}{
+				"rollout_id":  p.id,
+				"node_ids":    rb.nodeIDs,
+				"version":     rb.version,
+				"rolled_back": model.AgentRolloutNodeRolledBack,
+				"status":      utils.ScanStatusStarting,
+				"action":      rb.action,
+			}); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Run(`
+		MATCH (r:AgentRollout{node_id: $rollout_id})
+		SET r.status = $rolled_back,
+			r.status_message = $reason,
+			r.updated_at = TIMESTAMP()`,
+		map[string]interface{}{
+			"rollout_id":  p.id,
+			"rolled_back": model.AgentRolloutRolledBack,
+			"reason":      reason,
+		}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
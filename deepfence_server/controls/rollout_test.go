@@ -0,0 +1,36 @@
+package controls
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPlanRolloutWaves(t *testing.T) {
+	nodes := []string{"h05", "h01", "h02", "h03", "h04", "h06", "h07", "h08", "h09", "h10", "h01"}
+
+	// 10% canary, then waves of 30%
+	assert.DeepEqual(t, planRolloutWaves(nodes, nil, 10, 30), [][]string{
+		{"h01"},
+		{"h02", "h03", "h04"},
+		{"h05", "h06", "h07"},
+		{"h08", "h09", "h10"},
+	})
+
+	// named canary group, unknown nodes ignored
+	assert.DeepEqual(t, planRolloutWaves(nodes, []string{"h09", "h04", "other"}, 10, 50), [][]string{
+		{"h09", "h04"},
+		{"h01", "h02", "h03", "h05", "h06"},
+		{"h07", "h08", "h10"},
+	})
+
+	assert.DeepEqual(t, planRolloutWaves([]string{"h01"}, nil, 10, 25), [][]string{{"h01"}})
+	assert.DeepEqual(t, planRolloutWaves(nil, nil, 10, 25), [][]string{})
+}
+
+func TestWaveFailed(t *testing.T) {
+	assert.Assert(t, !waveFailed(10, 2, 20))
+	assert.Assert(t, waveFailed(10, 3, 20))
+	assert.Assert(t, waveFailed(1, 1, 20))
+	assert.Assert(t, !waveFailed(0, 0, 20))
+}
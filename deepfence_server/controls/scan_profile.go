@@ -0,0 +1,369 @@
+package controls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+var (
+	ErrScanProfileNotFound = errors.New("scan profile not found")
+	ErrScanProfileHostOnly = errors.New("the include paths of the scan profile only apply to hosts")
+)
+
+func AddScanProfile(ctx context.Context, req model.AddScanProfileReq) (model.ScanProfile, error) {
+	profile := model.ScanProfile{
+		ID:                     utils.NewUUIDString(),
+		Name:                   req.Name,
+		IncludePaths:           nonNil(req.IncludePaths),
+		ExcludePaths:           nonNil(req.ExcludePaths),
+		MaxFileSizeMB:          req.MaxFileSizeMB,
+		SkipMountedVolumes:     req.SkipMountedVolumes,
+		SkipNetworkFilesystems: req.SkipNetworkFilesystems,
+		IsDefault:              req.IsDefault,
+		NodeIDs:                []string{},
+		CreatedAt:              time.Now().UnixMilli(),
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return profile, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return profile, err
+	}
+	defer tx.Close()
+
+	if profile.IsDefault {
+		_, err = tx.Run(`
+			MATCH (p:ScanProfile{is_default: true})
+			SET p.is_default = false`,
+			map[string]interface{}{})
+		if err != nil {
+			return profile, err
+		}
+	}
+
+	_, err = tx.Run(`
+		CREATE (p:ScanProfile{node_id: $id})
+		SET p.name = $name,
+			p.include_paths = $include_paths,
+			p.exclude_paths = $exclude_paths,
+			p.max_file_size_mb = $max_file_size_mb,
+			p.skip_mounted_volumes = $skip_mounted_volumes,
+			p.skip_network_filesystems = $skip_network_filesystems,
+			p.is_default = $is_default,
+			p.node_ids = [],
+			p.created_at = $created_at`,
+		map[string]interface{}{
+			"id":                       profile.ID,
+			"name":                     profile.Name,
+			"include_paths":            profile.IncludePaths,
+			"exclude_paths":            profile.ExcludePaths,
+			"max_file_size_mb":         profile.MaxFileSizeMB,
+			"skip_mounted_volumes":     profile.SkipMountedVolumes,
+			"skip_network_filesystems": profile.SkipNetworkFilesystems,
+			"is_default":               profile.IsDefault,
+			"created_at":               profile.CreatedAt,
+		})
+	if err != nil {
+		return profile, err
+	}
+
+	return profile, tx.Commit()
+}
+
+func ListScanProfiles(ctx context.Context) ([]model.ScanProfile, error) {
+	res := []model.ScanProfile{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (p:ScanProfile)
+		RETURN p
+		ORDER BY p.created_at`,
+		map[string]interface{}{})
+	if err != nil {
+		return res, err
+	}
+
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, record := range records {
+		res = append(res, toScanProfile(record.Values[0].(neo4j.Node).Props))
+	}
+	return res, nil
+}
+
+func DeleteScanProfile(ctx context.Context, id string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (p:ScanProfile{node_id: $id})
+		DELETE p
+		RETURN count(p)`,
+		map[string]interface{}{"id": id})
+	if err != nil {
+		return err
+	}
+
+	rec, err := r.Single()
+	if err != nil {
+		return err
+	}
+	if rec.Values[0].(int64) == 0 {
+		return ErrScanProfileNotFound
+	}
+
+	return tx.Commit()
+}
+
+// AssignScanProfile moves the nodes to the profile, a node has at most one
+// profile of its own
+func AssignScanProfile(ctx context.Context, req model.AssignScanProfileReq) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	if req.ProfileID != "" {
+		// the scanners only restrict the scans of hosts to the include paths
+		r, err := tx.Run(`
+			MATCH (p:ScanProfile{node_id: $id})
+			WHERE size(p.include_paths) > 0
+			UNWIND $node_ids AS id
+			OPTIONAL MATCH (n:Node{node_id: id})
+			WITH id, n
+			WHERE n IS NULL
+			RETURN count(id)`,
+			map[string]interface{}{
+				"id":       req.ProfileID,
+				"node_ids": req.NodeIDs,
+			})
+		if err != nil {
+			return err
+		}
+		rec, err := r.Single()
+		if err != nil {
+			return err
+		}
+		if rec.Values[0].(int64) > 0 {
+			return ErrScanProfileHostOnly
+		}
+	}
+
+	_, err = tx.Run(`
+		MATCH (p:ScanProfile)
+		WHERE any(id IN p.node_ids WHERE id IN $node_ids)
+		SET p.node_ids = [id IN p.node_ids WHERE NOT id IN $node_ids]`,
+		map[string]interface{}{"node_ids": req.NodeIDs})
+	if err != nil {
+		return err
+	}
+
+	if req.ProfileID != "" {
+		r, err := tx.Run(`
+			MATCH (p:ScanProfile{node_id: $id})
+			SET p.node_ids = p.node_ids + $node_ids
+			RETURN count(p)`,
+			map[string]interface{}{
+				"id":       req.ProfileID,
+				"node_ids": req.NodeIDs,
+			})
+		if err != nil {
+			return err
+		}
+		rec, err := r.Single()
+		if err != nil {
+			return err
+		}
+		if rec.Values[0].(int64) == 0 {
+			return ErrScanProfileNotFound
+		}
+	}
+
+	return tx.Commit()
+}
+
+// NodeScanProfile returns the profile the scans of the node run with: the
+// profile of profileID when given, else the profile of the node, else the
+// profile of the host running it, else the default one. It is nil when there
+// is none.
+func NodeScanProfile(ctx context.Context, nodeID, profileID string) (*controls.ScanProfile, error) {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		OPTIONAL MATCH (n{node_id: $node_id})
+		OPTIONAL MATCH (h:Node)-[:HOSTS]->(n)
+		WHERE n:Container OR n:ContainerImage
+		RETURN any(x IN collect(DISTINCT n) WHERE x:Node), collect(h.node_id)`,
+		map[string]interface{}{"node_id": nodeID})
+	if err != nil {
+		return nil, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return nil, err
+	}
+	isHost := rec.Values[0].(bool)
+	hostIDs := []string{}
+	for _, id := range rec.Values[1].([]interface{}) {
+		hostIDs = append(hostIDs, id.(string))
+	}
+
+	r, err = tx.Run(`
+		MATCH (p:ScanProfile)
+		WHERE p.node_id = $profile_id
+		OR any(id IN p.node_ids WHERE id = $node_id OR id IN $host_ids)
+		OR p.is_default = true
+		RETURN p`,
+		map[string]interface{}{
+			"node_id":    nodeID,
+			"host_ids":   hostIDs,
+			"profile_id": profileID,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := r.Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []model.ScanProfile{}
+	for _, record := range records {
+		profiles = append(profiles, toScanProfile(record.Values[0].(neo4j.Node).Props))
+	}
+	return pickScanProfile(profiles, nodeID, isHost, hostIDs, profileID)
+}
+
+// pickScanProfile prefers the override of the node itself over the one of
+// its hosts, an image on several hosts takes the first host override found.
+// The profiles with include paths are left out for the other nodes than hosts.
+func pickScanProfile(profiles []model.ScanProfile, nodeID string, isHost bool, hostIDs []string, profileID string) (*controls.ScanProfile, error) {
+	var override, hostOverride, def *model.ScanProfile
+	for i := range profiles {
+		p := &profiles[i]
+		hostOnly := !isHost && len(p.IncludePaths) > 0
+		if profileID != "" && p.ID == profileID {
+			if hostOnly {
+				return nil, ErrScanProfileHostOnly
+			}
+			return toAgentScanProfile(*p), nil
+		}
+		if hostOnly {
+			continue
+		}
+		for _, id := range p.NodeIDs {
+			if id == nodeID {
+				override = p
+			}
+			for _, hostID := range hostIDs {
+				if id == hostID && hostOverride == nil {
+					hostOverride = p
+				}
+			}
+		}
+		if p.IsDefault {
+			def = p
+		}
+	}
+	switch {
+	case profileID != "":
+		return nil, ErrScanProfileNotFound
+	case override != nil:
+		return toAgentScanProfile(*override), nil
+	case hostOverride != nil:
+		return toAgentScanProfile(*hostOverride), nil
+	case def != nil:
+		return toAgentScanProfile(*def), nil
+	}
+	return nil, nil
+}
+
+func toAgentScanProfile(p model.ScanProfile) *controls.ScanProfile {
+	return &controls.ScanProfile{
+		IncludePaths:           p.IncludePaths,
+		ExcludePaths:           p.ExcludePaths,
+		MaxFileSizeMB:          p.MaxFileSizeMB,
+		SkipMountedVolumes:     p.SkipMountedVolumes,
+		SkipNetworkFilesystems: p.SkipNetworkFilesystems,
+	}
+}
+
+func toScanProfile(props map[string]interface{}) model.ScanProfile {
+	p := model.ScanProfile{
+		ID:           fmt.Sprintf("%v", props["node_id"]),
+		Name:         fmt.Sprintf("%v", props["name"]),
+		IncludePaths: propStrings(props["include_paths"]),
+		ExcludePaths: propStrings(props["exclude_paths"]),
+		NodeIDs:      propStrings(props["node_ids"]),
+	}
+	p.MaxFileSizeMB, _ = props["max_file_size_mb"].(int64)
+	p.SkipMountedVolumes, _ = props["skip_mounted_volumes"].(bool)
+	p.SkipNetworkFilesystems, _ = props["skip_network_filesystems"].(bool)
+	p.IsDefault, _ = props["is_default"].(bool)
+	p.CreatedAt, _ = props["created_at"].(int64)
+	return p
+}
@@ -0,0 +1,63 @@
+package controls
+
+import (
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"gotest.tools/assert"
+)
+
+func TestPickScanProfile(t *testing.T) {
+	profiles := []model.ScanProfile{
+		{ID: "default", IsDefault: true, MaxFileSizeMB: 10},
+		{ID: "data", NodeIDs: []string{"host-1"}, ExcludePaths: []string{"/data/**"}},
+		{ID: "quick", IncludePaths: []string{"/etc/**"}},
+	}
+
+	p, err := pickScanProfile(profiles, "host-2", true, nil, "")
+	assert.NilError(t, err)
+	assert.Equal(t, p.MaxFileSizeMB, int64(10))
+
+	p, err = pickScanProfile(profiles, "host-1", true, nil, "")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.ExcludePaths, []string{"/data/**"})
+
+	p, err = pickScanProfile(profiles, "host-1", true, nil, "quick")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.IncludePaths, []string{"/etc/**"})
+
+	_, err = pickScanProfile(profiles, "host-1", true, nil, "missing")
+	assert.Equal(t, err, ErrScanProfileNotFound)
+
+	p, err = pickScanProfile(profiles[1:], "host-2", true, nil, "")
+	assert.NilError(t, err)
+	assert.Assert(t, p == nil)
+
+	// containers and images follow the override of their host
+	p, err = pickScanProfile(profiles, "container-1", false, []string{"host-1"}, "")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.ExcludePaths, []string{"/data/**"})
+
+	p, err = pickScanProfile(profiles, "container-2", false, []string{"host-2"}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, p.MaxFileSizeMB, int64(10))
+
+	// an override of the container itself wins over the host one
+	profiles = append(profiles, model.ScanProfile{ID: "app", NodeIDs: []string{"container-1"}, MaxFileSizeMB: 1})
+	p, err = pickScanProfile(profiles, "container-1", false, []string{"host-1"}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, p.MaxFileSizeMB, int64(1))
+
+	// the include paths only apply to the scans of hosts
+	profiles = append(profiles, model.ScanProfile{ID: "etc", NodeIDs: []string{"host-3"}, IncludePaths: []string{"/etc/**"}})
+	p, err = pickScanProfile(profiles, "host-3", true, nil, "")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p.IncludePaths, []string{"/etc/**"})
+
+	p, err = pickScanProfile(profiles, "container-3", false, []string{"host-3"}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, p.MaxFileSizeMB, int64(10))
+
+	_, err = pickScanProfile(profiles, "container-3", false, []string{"host-3"}, "quick")
+	assert.Equal(t, err, ErrScanProfileHostOnly)
+}
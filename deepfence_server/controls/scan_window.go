@@ -0,0 +1,363 @@
+package controls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+var (
+	ErrScanWindowNotFound = errors.New("scan window not found")
+
+	// scans the windows apply to when no scan type is given
+	windowScanTypes = []string{
+		string(utils.NEO4JSecretScan),
+		string(utils.NEO4JMalwareScan),
+		string(utils.NEO4JVulnerabilityScan),
+		string(utils.NEO4JComplianceScan),
+	}
+)
+
+func AddScanWindow(ctx context.Context, req model.AddScanWindowReq) (model.ScanWindow, error) {
+	window := model.ScanWindow{
+		ID:                   utils.NewUUIDString(),
+		Name:                 req.Name,
+		HostIDs:              nonNil(req.HostIDs),
+		KubernetesClusterIDs: nonNil(req.KubernetesClusterIDs),
+		Tags:                 nonNil(req.Tags),
+		ScanTypes:            nonNil(req.ScanTypes),
+		Days:                 req.Days,
+		StartTime:            req.StartTime,
+		EndTime:              req.EndTime,
+		Timezone:             req.Timezone,
+		CreatedAt:            time.Now().UnixMilli(),
+	}
+	if window.Days == nil {
+		window.Days = []int{}
+	}
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return window, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return window, err
+	}
+	defer tx.Close()
+
+	_, err = tx.Run(`
+		CREATE (w:ScanWindow{node_id: $id})
+		SET w.name = $name,
+			w.host_ids = $host_ids,
+			w.kubernetes_cluster_ids = $cluster_ids,
+			w.tags = $tags,
+			w.scan_types = $scan_types,
+			w.days = $days,
+			w.start_time = $start_time,
+			w.end_time = $end_time,
+			w.timezone = $timezone,
+			w.created_at = $created_at`,
+		map[string]interface{}{
+			"id":          window.ID,
+			"name":        window.Name,
+			"host_ids":    window.HostIDs,
+			"cluster_ids": window.KubernetesClusterIDs,
+			"tags":        window.Tags,
+			"scan_types":  window.ScanTypes,
+			"days":        window.Days,
+			"start_time":  window.StartTime,
+			"end_time":    window.EndTime,
+			"timezone":    window.Timezone,
+			"created_at":  window.CreatedAt,
+		})
+	if err != nil {
+		return window, err
+	}
+
+	return window, tx.Commit()
+}
+
+func ListScanWindows(ctx context.Context) ([]model.ScanWindow, error) {
+	res := []model.ScanWindow{}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (w:ScanWindow)
+		RETURN w
+		ORDER BY w.created_at`,
+		map[string]interface{}{})
+	if err != nil {
+		return res, err
+	}
+
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, record := range records {
+		res = append(res, toScanWindow(record.Values[0].(neo4j.Node).Props))
+	}
+	return res, nil
+}
+
+func DeleteScanWindow(ctx context.Context, id string) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (w:ScanWindow{node_id: $id})
+		DELETE w
+		RETURN count(w)`,
+		map[string]interface{}{"id": id})
+	if err != nil {
+		return err
+	}
+
+	rec, err := r.Single()
+	if err != nil {
+		return err
+	}
+	if rec.Values[0].(int64) == 0 {
+		return ErrScanWindowNotFound
+	}
+
+	return tx.Commit()
+}
+
+func SetClusterScanLimit(ctx context.Context, req model.ClusterScanLimitReq) error {
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	var limit interface{}
+	if req.MaxConcurrentScans > 0 {
+		limit = req.MaxConcurrentScans
+	}
+
+	_, err = tx.Run(`
+		MATCH (k:KubernetesCluster)
+		WHERE k.node_id IN $ids
+		SET k.max_concurrent_scans = $limit`,
+		map[string]interface{}{
+			"ids":   req.KubernetesClusterIDs,
+			"limit": limit,
+		})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// agentScanRestrictions returns the scan types outside of their windows
+// for the node and the number of scans its cluster can still start, -1
+// when there is no limit.
+func agentScanRestrictions(tx neo4j.Transaction, nodeID string, now time.Time) ([]string, int, error) {
+	r, err := tx.Run(`
+		MATCH (n:Node{node_id:$id})
+		OPTIONAL MATCH (k:KubernetesCluster) -[:INSTANCIATE]-> (n)
+		WITH n, k LIMIT 1
+		OPTIONAL MATCH (w:ScanWindow)
+		WHERE $id IN w.host_ids
+		OR (k IS NOT NULL AND k.node_id IN w.kubernetes_cluster_ids)
+		OR any(t IN COALESCE(n.user_tags, []) WHERE t IN w.tags)
+		RETURN k.node_id, k.max_concurrent_scans, collect(w)`,
+		map[string]interface{}{"id": nodeID})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	rec, err := r.Single()
+	if err != nil {
+		return nil, -1, err
+	}
+
+	windows := []model.ScanWindow{}
+	for _, w := range rec.Values[2].([]interface{}) {
+		windows = append(windows, toScanWindow(w.(neo4j.Node).Props))
+	}
+	blocked := blockedScanTypes(windows, now)
+
+	if rec.Values[0] == nil || rec.Values[1] == nil {
+		return blocked, -1, nil
+	}
+	limit := int(rec.Values[1].(int64))
+
+	r, err = tx.Run(`
+		MATCH (k:KubernetesCluster{node_id:$cluster_id}) -[:INSTANCIATE]-> (n:Node)
+		MATCH (s) -[:SCHEDULED]-> (n)
+		WHERE s.status = '`+utils.ScanStatusInProgress+`'
+		RETURN count(s)`,
+		map[string]interface{}{"cluster_id": rec.Values[0].(string)})
+	if err != nil {
+		return blocked, -1, err
+	}
+	rec, err = r.Single()
+	if err != nil {
+		return blocked, -1, err
+	}
+
+	capacity := limit - int(rec.Values[0].(int64))
+	if capacity < 0 {
+		capacity = 0
+	}
+	return blocked, capacity, nil
+}
+
+// blockedScanTypes returns the scan types covered by some windows but by none
+// open at now
+func blockedScanTypes(windows []model.ScanWindow, now time.Time) []string {
+	covered := map[string]bool{}
+	for _, w := range windows {
+		types := w.ScanTypes
+		if len(types) == 0 {
+			types = windowScanTypes
+		}
+		open := scanWindowOpen(w, now)
+		for _, t := range types {
+			covered[t] = covered[t] || open
+		}
+	}
+	res := []string{}
+	for _, t := range windowScanTypes {
+		if open, has := covered[t]; has && !open {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+func scanWindowOpen(w model.ScanWindow, now time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		log.Warn().Msgf("Scan window %s: %v", w.ID, err)
+		loc = time.UTC
+	}
+	start, err := time.Parse("15:04", w.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", w.EndTime)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	day := local.Weekday()
+	switch {
+	case startMinute == endMinute:
+	case startMinute < endMinute:
+		if minute < startMinute || minute >= endMinute {
+			return false
+		}
+	case minute >= startMinute:
+	case minute < endMinute:
+		// the window started the day before
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+func toScanWindow(props map[string]interface{}) model.ScanWindow {
+	w := model.ScanWindow{
+		ID:                   fmt.Sprintf("%v", props["node_id"]),
+		Name:                 fmt.Sprintf("%v", props["name"]),
+		HostIDs:              propStrings(props["host_ids"]),
+		KubernetesClusterIDs: propStrings(props["kubernetes_cluster_ids"]),
+		Tags:                 propStrings(props["tags"]),
+		ScanTypes:            propStrings(props["scan_types"]),
+		Days:                 []int{},
+		StartTime:            fmt.Sprintf("%v", props["start_time"]),
+		EndTime:              fmt.Sprintf("%v", props["end_time"]),
+		Timezone:             fmt.Sprintf("%v", props["timezone"]),
+	}
+	if days, ok := props["days"].([]interface{}); ok {
+		for _, d := range days {
+			w.Days = append(w.Days, int(d.(int64)))
+		}
+	}
+	if createdAt, ok := props["created_at"].(int64); ok {
+		w.CreatedAt = createdAt
+	}
+	return w
+}
+
+func propStrings(prop interface{}) []string {
+	values, ok := prop.([]interface{})
+	if !ok {
+		return []string{}
+	}
+	return toStrings(values)
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
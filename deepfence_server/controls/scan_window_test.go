@@ -0,0 +1,50 @@
+package controls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"gotest.tools/assert"
+)
+
+func TestScanWindowOpen(t *testing.T) {
+	// Wednesday
+	now := time.Date(2023, 11, 15, 2, 30, 0, 0, time.UTC)
+
+	night := model.ScanWindow{StartTime: "01:00", EndTime: "05:00", Timezone: "UTC"}
+	assert.Assert(t, scanWindowOpen(night, now))
+	assert.Assert(t, !scanWindowOpen(night, now.Add(3*time.Hour)))
+
+	night.Timezone = "Asia/Kolkata"
+	assert.Assert(t, !scanWindowOpen(night, now))
+	assert.Assert(t, scanWindowOpen(night, now.Add(-5*time.Hour)))
+
+	overnight := model.ScanWindow{StartTime: "22:00", EndTime: "03:00", Timezone: "UTC",
+		Days: []int{int(time.Tuesday)}}
+	assert.Assert(t, scanWindowOpen(overnight, now))
+	assert.Assert(t, !scanWindowOpen(overnight, now.Add(21*time.Hour)))
+	assert.Assert(t, scanWindowOpen(overnight, now.Add(-3*time.Hour)))
+
+	allDay := model.ScanWindow{StartTime: "00:00", EndTime: "00:00", Timezone: "UTC",
+		Days: []int{int(time.Saturday), int(time.Sunday)}}
+	assert.Assert(t, !scanWindowOpen(allDay, now))
+	assert.Assert(t, scanWindowOpen(allDay, now.Add(4*24*time.Hour)))
+}
+
+func TestBlockedScanTypes(t *testing.T) {
+	now := time.Date(2023, 11, 15, 12, 0, 0, 0, time.UTC)
+
+	assert.DeepEqual(t, blockedScanTypes(nil, now), []string{})
+
+	windows := []model.ScanWindow{
+		{StartTime: "01:00", EndTime: "05:00", Timezone: "UTC"},
+	}
+	assert.DeepEqual(t, blockedScanTypes(windows, now), windowScanTypes)
+
+	windows = []model.ScanWindow{
+		{StartTime: "01:00", EndTime: "05:00", Timezone: "UTC", ScanTypes: []string{"SecretScan", "MalwareScan"}},
+		{StartTime: "10:00", EndTime: "14:00", Timezone: "UTC", ScanTypes: []string{"SecretScan"}},
+	}
+	assert.DeepEqual(t, blockedScanTypes(windows, now), []string{"MalwareScan"})
+}
@@ -116,7 +116,13 @@ func GetAgentVersionTarball(ctx context.Context, version string) (string, error)
 		return "", err
 	}
 
-	return r.Values[0].(string), nil
+	// url is removed from versions no longer available
+	url, ok := r.Values[0].(string)
+	if !ok {
+		return "", fmt.Errorf("no tarball for agent version %s", version)
+	}
+
+	return url, nil
 }
 
 func GetAgentPluginVersionTarball(ctx context.Context, version, pluginName string) (string, error) {
@@ -156,11 +162,13 @@ func GetAgentPluginVersionTarball(ctx context.Context, version, pluginName strin
 	return r.Values[0].(string), nil
 }
 
-func hasPendingUpgradeOrNew(ctx context.Context, version string, nodeID string) (bool, error) {
+// hasPendingUpgradeOrNew also reports whether the pending upgrade is the
+// rollback of a staged rollout
+func hasPendingUpgradeOrNew(ctx context.Context, version string, nodeID string) (bool, bool, error) {
 
 	client, err := directory.Neo4jClient(ctx)
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
@@ -168,7 +176,7 @@ func hasPendingUpgradeOrNew(ctx context.Context, version string, nodeID string)
 
 	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 	defer tx.Close()
 
@@ -177,21 +185,21 @@ func hasPendingUpgradeOrNew(ctx context.Context, version string, nodeID string)
 		MATCH (v:AgentVersion{node_id:$version})
 		OPTIONAL MATCH (v) -[rs:SCHEDULED]-> (n)
 		OPTIONAL MATCH (n) -[rv:VERSIONED]-> (v)
-		RETURN rs IS NOT NULL OR rv IS NULL`,
+		RETURN count(rs) > 0 OR count(rv) = 0, any(r IN collect(rs) WHERE COALESCE(r.rollback, false))`,
 		map[string]interface{}{
 			"node_id": nodeID,
 			"version": version,
 		})
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	r, err := res.Single()
 	if err != nil {
 		// No results means new
-		return true, nil
+		return true, false, nil
 	}
-	return r.Values[0].(bool), nil
+	return r.Values[0].(bool), r.Values[1].(bool), nil
 }
 
 func wasAttachedToNewer(ctx context.Context, version string, nodeID string) (bool, string, error) {
@@ -231,7 +239,7 @@ func wasAttachedToNewer(ctx context.Context, version string, nodeID string) (boo
 
 func CompleteAgentUpgrade(ctx context.Context, version string, nodeID string) error {
 
-	has, err := hasPendingUpgradeOrNew(ctx, version, nodeID)
+	has, rollback, err := hasPendingUpgradeOrNew(ctx, version, nodeID)
 
 	if err != nil {
 		return err
@@ -291,8 +299,8 @@ func CompleteAgentUpgrade(ctx context.Context, version string, nodeID string) er
 		return err
 	}
 
-	// If attached to newer, schedule an ugprade
-	if newer {
+	// If attached to newer, schedule an ugprade, unless it was rolled back
+	if newer && !rollback {
 		action, err := PrepareAgentUpgradeAction(ctx, prevVer)
 		if err != nil {
 			return err
@@ -0,0 +1,274 @@
+package controls
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	yaraIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,127}$`)
+	yaraKeywords   = map[string]bool{
+		"all": true, "and": true, "any": true, "ascii": true, "at": true, "base64": true,
+		"base64wide": true, "condition": true, "contains": true, "endswith": true,
+		"entrypoint": true, "false": true, "filesize": true, "for": true, "fullword": true,
+		"global": true, "import": true, "icontains": true, "iendswith": true, "iequals": true,
+		"in": true, "include": true, "int16": true, "int32": true, "int8": true,
+		"istartswith": true, "matches": true, "meta": true, "nocase": true, "none": true,
+		"not": true, "of": true, "or": true, "private": true, "rule": true,
+		"startswith": true, "strings": true, "them": true, "true": true, "uint16": true,
+		"uint32": true, "uint8": true, "wide": true, "xor": true,
+	}
+)
+
+// yaraRuleNames checks the structure of YARA source and returns the names of
+// its rules, to report the common mistakes and the name clashes between
+// custom rules when they are saved. Includes are rejected, they cannot be
+// resolved on the agents. The worker compiles the bundles with libyara along
+// with the bundled rules before releasing them.
+func yaraRuleNames(src string) ([]string, error) {
+	tokens, err := yaraTokens(src)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for i := 0; i < len(tokens); {
+		switch tokens[i] {
+		case "import":
+			if i+1 >= len(tokens) || !strings.HasPrefix(tokens[i+1], `"`) {
+				return nil, errors.New("import expects a module name")
+			}
+			i += 2
+			continue
+		case "include":
+			return nil, errors.New("include is not supported, paste the included rules")
+		case "private", "global":
+			i++
+			continue
+		case "rule":
+		default:
+			return nil, fmt.Errorf("unexpected %q outside of a rule", tokens[i])
+		}
+
+		if i+1 >= len(tokens) || !yaraIdentifier.MatchString(tokens[i+1]) || yaraKeywords[tokens[i+1]] {
+			return nil, errors.New("rule expects a valid name")
+		}
+		name := tokens[i+1]
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate rule %s", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+
+		// tags up to the body
+		i += 2
+		for i < len(tokens) && tokens[i] != "{" {
+			i++
+		}
+		if i == len(tokens) {
+			return nil, fmt.Errorf("rule %s has no body", name)
+		}
+
+		start := i
+		depth := 0
+		for ; i < len(tokens); i++ {
+			switch tokens[i] {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if depth != 0 {
+			return nil, fmt.Errorf("rule %s is not closed", name)
+		}
+		if err := checkYaraRuleBody(name, tokens[start+1:i]); err != nil {
+			return nil, err
+		}
+		i++
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("no rule found")
+	}
+	return names, nil
+}
+
+// checkYaraRuleBody reports the errors of libyara on the strings of a rule:
+// the strings are defined once with a value, and the condition uses all the
+// named strings and only them
+func checkYaraRuleBody(name string, body []string) error {
+	sections := map[string][]string{}
+	section := ""
+	depth := 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		case "meta", "strings", "condition":
+			if depth == 0 && i+1 < len(body) && body[i+1] == ":" {
+				section = body[i]
+				sections[section] = []string{}
+				i++
+				continue
+			}
+		}
+		if section == "" {
+			return fmt.Errorf("unexpected %q in rule %s", body[i], name)
+		}
+		sections[section] = append(sections[section], body[i])
+	}
+	condition, has := sections["condition"]
+	if !has || len(condition) == 0 {
+		return fmt.Errorf("rule %s has no condition", name)
+	}
+
+	defined := []string{}
+	strs := sections["strings"]
+	depth = 0
+	for i := 0; i < len(strs); i++ {
+		switch strs[i] {
+		case "{":
+			depth++
+			continue
+		case "}", ")":
+			depth--
+			continue
+		case "(":
+			depth++
+			continue
+		}
+		if depth != 0 || !strings.HasPrefix(strs[i], "$") {
+			continue
+		}
+		if i+2 >= len(strs) || strs[i+1] != "=" {
+			return fmt.Errorf("string %s of rule %s has no value", strs[i], name)
+		}
+		if value := strs[i+2]; value != "{" && !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("string %s of rule %s has no value", strs[i], name)
+		}
+		if strs[i] != "$" {
+			if slices.Contains(defined, strs[i]) {
+				return fmt.Errorf("duplicate string %s in rule %s", strs[i], name)
+			}
+			defined = append(defined, strs[i])
+		}
+		i++
+	}
+
+	used := map[string]bool{}
+	for _, token := range condition {
+		if token == "them" {
+			for _, s := range defined {
+				used[s] = true
+			}
+			continue
+		}
+		if len(token) < 2 || !strings.ContainsRune("$#@!", rune(token[0])) {
+			continue
+		}
+		ref := "$" + token[1:]
+		if prefix, wildcard := strings.CutSuffix(ref, "*"); wildcard {
+			for _, s := range defined {
+				if strings.HasPrefix(s, prefix) {
+					used[s] = true
+				}
+			}
+			continue
+		}
+		if !slices.Contains(defined, ref) {
+			return fmt.Errorf("rule %s uses undefined string %s", name, ref)
+		}
+		used[ref] = true
+	}
+	for _, s := range defined {
+		if !used[s] {
+			return fmt.Errorf("unreferenced string %s in rule %s", s, name)
+		}
+	}
+	return nil
+}
+
+// yaraTokens splits YARA source into identifiers, punctuation and literals,
+// dropping the comments
+func yaraTokens(src string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end == -1 {
+				return tokens, nil
+			}
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || (c == '/' && regexAllowed(tokens)):
+			end := literalEnd(src, i)
+			if end == -1 {
+				return nil, errors.New("unterminated string or regular expression")
+			}
+			tokens = append(tokens, src[i:end])
+			i = end
+		case c == '_' || c == '$' || c == '#' || c == '@' || c == '!' && i+1 < len(src) && isWordByte(src[i+1]) ||
+			isWordByte(c):
+			j := i + 1
+			for j < len(src) && (isWordByte(src[j]) || src[j] == '*') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// regexAllowed tells if a slash starts a regular expression rather than a
+// division, after an assignment of the strings section or matches
+func regexAllowed(tokens []string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last == "=" || last == "matches"
+}
+
+// literalEnd returns the index after the string or regular expression at i,
+// -1 when it is not terminated on the line
+func literalEnd(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '\n':
+			return -1
+		case quote:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -53,6 +53,7 @@ require (
 	k8s.io/client-go v0.29.0
 	k8s.io/metrics v0.29.0
 	k8s.io/utils v0.0.0-20240102154912-e7106e64919e
+	sigs.k8s.io/yaml v1.3.0
 
 )
 
@@ -152,5 +153,4 @@ require (
 	k8s.io/kube-openapi v0.0.0-20231010175941-2dd684a91f00 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.4.1 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
@@ -26,7 +26,18 @@ func (h *Handler) GetAgentControls(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	actions, errs := controls.GetAgentActions(ctx, agentID.NodeID, agentID.AvailableWorkload)
+	err = checkAgentNodeID(ctx, agentID.NodeID)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	err = controls.UpdateAgentHealth(ctx, agentID.NodeID, agentID.Health)
+	if err != nil {
+		log.Warn().Msgf("Cannot update health of %s: %v", agentID.NodeID, err)
+	}
+
+	actions, errs := controls.GetAgentActions(ctx, agentID.NodeID, agentID.AvailableWorkload, agentID.CustomRulesVersion)
 	for _, err := range errs {
 		if err != nil {
 			log.Warn().Msgf("Cannot process some actions for %s: %v, skipping",
@@ -63,16 +74,35 @@ func (h *Handler) GetAgentInitControls(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = checkAgentNodeID(ctx, agentID.NodeID)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
 	err = controls.CompleteAgentUpgrade(ctx, agentID.Version, agentID.NodeID)
 	if err != nil {
 		respondWith(ctx, w, http.StatusInternalServerError, err)
 		return
 	}
 
-	actions, err := controls.GetPendingAgentScans(ctx, agentID.NodeID, agentID.AvailableWorkload)
+	// Resource limits first, before the scans start
+	actions, err := controls.GetAgentResourceLimits(ctx, agentID.NodeID)
+	if err != nil {
+		log.Warn().Msgf("Cannot get resource limits: %s, skipping", err)
+	}
+
+	rulesActions, err := controls.GetCustomRules(ctx, agentID.NodeID, agentID.CustomRulesVersion)
+	if err != nil {
+		log.Warn().Msgf("Cannot get custom rules: %s, skipping", err)
+	}
+	actions = append(actions, rulesActions...)
+
+	scanActions, err := controls.GetPendingAgentScans(ctx, agentID.NodeID, agentID.AvailableWorkload)
 	if err != nil {
 		log.Warn().Msgf("Cannot get actions: %s, skipping", err)
 	}
+	actions = append(actions, scanActions...)
 
 	res := ctl.AgentControls{
 		BeatRateSec: 30 * ingesters.PushBack.Load(),
@@ -212,3 +242,29 @@ func (h *Handler) ScheduleAgentPluginsDisable(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func (h *Handler) ScheduleAgentResourceLimits(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentResourceLimitsReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	err = controls.ScheduleAgentResourceLimits(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Cannot schedule agent resource limits: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventAgentResourceLimits, ActionUpdate, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
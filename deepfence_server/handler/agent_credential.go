@@ -0,0 +1,420 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/go-chi/jwtauth/v5"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+)
+
+const (
+	RevokedAgentCredentialPrefix = "Revoked-AgentCredential-"
+	RevokedAgentNodePrefix       = "Revoked-AgentNode-"
+
+	// the setting is checked on every request of the agents
+	requireAgentCredentialsExpiry = time.Minute
+)
+
+var (
+	errAgentNodeMismatch      = ForbiddenError{errors.New("credential is bound to another agent")}
+	errAgentRevoked           = ForbiddenError{errors.New("agent is revoked")}
+	errAgentCannotEnroll      = ForbiddenError{errors.New("agent credentials cannot enroll agents")}
+	errAgentCredentialRevoked = ForbiddenError{errors.New("agent credential is revoked")}
+	errAgentCredentialMissing = ForbiddenError{errors.New("agents must use their credential in place of the API key")}
+)
+
+// requireAgentCredentials caches the setting of each namespace
+var requireAgentCredentials sync.Map
+
+type requireAgentCredentialsEntry struct {
+	required  bool
+	expiresAt time.Time
+}
+
+func (h *Handler) EnrollAgent(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentEnrollReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	ctx := r.Context()
+	if agentNodeID(ctx) != "" {
+		h.respondError(&errAgentCannotEnroll, w)
+		return
+	}
+	user, statusCode, _, err := h.GetUserFromJWT(ctx)
+	if err != nil {
+		h.respondWithErrorCode(err, w, statusCode)
+		return
+	}
+
+	cred, err := controls.EnrollAgent(ctx, req.NodeID, req.EnrollmentKey, user.ID)
+	if errors.Is(err, controls.ErrAgentRevoked) {
+		h.respondError(&ForbiddenError{err}, w)
+		return
+	} else if errors.Is(err, controls.ErrAgentEnrollmentPending) {
+		h.AuditUserActivity(r, EventAgentCredential, ActionCreate, model.AgentEnrollReq{NodeID: req.NodeID}, false)
+		err = httpext.JSON(w, http.StatusAccepted, model.AgentEnrollResp{Pending: true})
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot enroll agent %s: %v", req.NodeID, err)
+		h.respondError(err, w)
+		return
+	}
+	credUUID, err := uuid.Parse(cred.ID)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventAgentCredential, ActionCreate, model.AgentEnrollReq{NodeID: req.NodeID}, true)
+
+	err = httpext.JSON(w, http.StatusOK, model.AgentEnrollResp{
+		Credential: model.GetAPIToken(user.CompanyNamespace, credUUID),
+	})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ListAgentCredentials(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	creds, err := controls.ListAgentCredentials(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list agent credentials: %v", err)
+		h.respondError(err, w)
+		return
+	}
+	enrollments, err := controls.ListAgentEnrollments(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list agent enrollments: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.ListAgentCredentialsResp{
+		Credentials:        creds,
+		PendingEnrollments: enrollments,
+	})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ApproveAgentEnrollments(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentCredentialsReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	err = controls.ApproveAgentEnrollments(r.Context(), req.NodeIDs)
+	if errors.Is(err, controls.ErrAgentEnrollmentNotFound) {
+		h.respondError(&NotFoundError{err}, w)
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot approve agent enrollments: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventAgentCredential, ActionUpdate, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RevokeAgentCredentials(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentCredentialsReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	ctx := r.Context()
+	credIDs, err := controls.RevokeAgentCredentials(ctx, req.NodeIDs)
+	if err != nil {
+		log.Error().Msgf("Cannot revoke agent credentials: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+	// tokens already issued for the credentials expire with their refresh token
+	for _, id := range credIDs {
+		err = redisClient.Set(ctx, RevokedAgentCredentialPrefix+id, true, model.RefreshTokenExpiry).Err()
+		if err != nil {
+			h.respondError(err, w)
+			return
+		}
+	}
+	// the API key cannot be used for these agents either
+	for _, nodeID := range req.NodeIDs {
+		err = redisClient.Set(ctx, RevokedAgentNodePrefix+nodeID, true, 0).Err()
+		if err != nil {
+			h.respondError(err, w)
+			return
+		}
+	}
+
+	h.AuditUserActivity(r, EventAgentCredential, ActionDelete, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ReinstateAgents(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentCredentialsReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	ctx := r.Context()
+	err = controls.ReinstateAgents(ctx, req.NodeIDs)
+	if err != nil {
+		log.Error().Msgf("Cannot reinstate agents: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+	keys := make([]string, 0, len(req.NodeIDs))
+	for _, nodeID := range req.NodeIDs {
+		keys = append(keys, RevokedAgentNodePrefix+nodeID)
+	}
+	err = redisClient.Del(ctx, keys...).Err()
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventAgentCredential, ActionEnable, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// agentCredentialAccessToken issues the tokens of an agent credential, the
+// API key of the agents when they are enrolled
+func (h *Handler) agentCredentialAccessToken(ctx context.Context, credID string) (*model.ResponseAccessToken, error) {
+	cred, err := controls.UseAgentCredential(ctx, credID)
+	if errors.Is(err, controls.ErrAgentCredentialRevoked) {
+		return nil, &errAgentCredentialRevoked
+	} else if err != nil {
+		return nil, err
+	}
+	user, _, _, err := model.GetUserByID(ctx, cred.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, &errUserInactive
+	}
+	return user.GetAgentAccessToken(h.TokenAuth, cred)
+}
+
+// agentNodeID returns the node the token is bound to, empty when the token
+// was not issued for an agent credential
+func agentNodeID(ctx context.Context) string {
+	_, claims, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return ""
+	}
+	nodeID, _ := claims[model.AgentNodeIDClaim].(string)
+	return nodeID
+}
+
+// isAgentCredentialRevoked tells if the token was issued for an agent
+// credential revoked since
+func isAgentCredentialRevoked(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	credID, has := claims[model.AgentCredentialIDClaim].(string)
+	if !has {
+		return false, nil
+	}
+	return isRevokedKey(ctx, RevokedAgentCredentialPrefix+credID)
+}
+
+// checkAgentToken rejects the tokens not issued for an agent credential when
+// the agents have to use theirs
+func checkAgentToken(ctx context.Context) error {
+	if agentNodeID(ctx) != "" {
+		return nil
+	}
+	required, err := agentCredentialsRequired(ctx)
+	if err != nil {
+		return err
+	}
+	if required {
+		return &errAgentCredentialMissing
+	}
+	return nil
+}
+
+func agentCredentialsRequired(ctx context.Context) (bool, error) {
+	ns, err := directory.ExtractNamespace(ctx)
+	if err != nil {
+		return false, err
+	}
+	if entry, has := requireAgentCredentials.Load(ns); has {
+		if e := entry.(requireAgentCredentialsEntry); time.Now().Before(e.expiresAt) {
+			return e.required, nil
+		}
+	}
+	pgClient, err := directory.PostgresClient(ctx)
+	if err != nil {
+		return false, err
+	}
+	required, err := model.GetRequireAgentCredentials(ctx, pgClient)
+	if err != nil {
+		return false, err
+	}
+	requireAgentCredentials.Store(ns, requireAgentCredentialsEntry{
+		required:  required,
+		expiresAt: time.Now().Add(requireAgentCredentialsExpiry),
+	})
+	return required, nil
+}
+
+// checkAgentNodeID rejects the agents calling for another node than the one
+// of their credential, the ones without credential when it is required and
+// the revoked agents
+func checkAgentNodeID(ctx context.Context, nodeID string) error {
+	bound := agentNodeID(ctx)
+	if bound != "" && bound != nodeID {
+		return &errAgentNodeMismatch
+	}
+	if err := checkAgentToken(ctx); err != nil {
+		return err
+	}
+	revoked, err := isRevokedKey(ctx, RevokedAgentNodePrefix+nodeID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return &errAgentRevoked
+	}
+	return nil
+}
+
+// checkRevokedNodes rejects the nodes of revoked agents, whatever the token
+// they were reported with
+func checkRevokedNodes(ctx context.Context, nodeIDs []string) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		keys = append(keys, RevokedAgentNodePrefix+nodeID)
+	}
+	vals, err := redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+	for i, val := range vals {
+		if val != nil {
+			return fmt.Errorf("%w: %s", errAgentRevoked.err, nodeIDs[i])
+		}
+	}
+	return nil
+}
+
+// checkAgentScanIDs rejects the scan results posted without an agent
+// credential when it is required, the ones an agent posts for the scans of
+// other nodes than its own and the ones it hosts, and the results of the
+// nodes of revoked agents. The cloud scanners are not agents.
+func checkAgentScanIDs(ctx context.Context, scanType utils.Neo4jScanType, scanIDs []string) error {
+	if scanType == utils.NEO4JCloudComplianceScan {
+		return nil
+	}
+	if err := checkAgentToken(ctx); err != nil {
+		return err
+	}
+	if len(scanIDs) == 0 {
+		return nil
+	}
+	scanIDs = lo.Uniq(scanIDs)
+	if nodeID := agentNodeID(ctx); nodeID != "" {
+		count, err := controls.CountAgentScans(ctx, nodeID, scanType, scanIDs)
+		if err != nil {
+			return err
+		}
+		if count != len(scanIDs) {
+			return &errAgentNodeMismatch
+		}
+	}
+	nodeIDs, err := controls.ScannedNodeIDs(ctx, scanType, scanIDs)
+	if err != nil {
+		return err
+	}
+	return checkRevokedNodes(ctx, nodeIDs)
+}
+
+func isRevokedKey(ctx context.Context, key string) (bool, error) {
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		return false, err
+	}
+	val, err := redisClient.Get(ctx, key).Bool()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return val, nil
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+func (h *Handler) GetAgentsHealth(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentHealthReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	agents, err := controls.GetAgentsHealth(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Cannot get agents health: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.AgentHealthResp{Agents: agents})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	httpext "github.com/go-playground/pkg/v5/net/http"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/deepfence/ThreatMapper/deepfence_server/ingesters"
 	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/scope/report"
@@ -19,8 +23,23 @@ import (
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 )
 
+const (
+	reportSequencePrefix = "report-sequence-"
+	// longer than the agents keep their offline buffer
+	reportSequenceExpiry = 48 * time.Hour
+)
+
 var agentReportIngesters sync.Map
 
+// only moves the sequence of a report source forward
+var setReportSequence = redis.NewScript(`
+local last = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tonumber(ARGV[1]) > last then
+	redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
+end
+return 0
+`)
+
 func init() {
 	agentReportIngesters = sync.Map{}
 }
@@ -67,6 +86,24 @@ func (h *Handler) IngestAgentReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = checkReportSource(ctx, r)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	res := controls.AgentBeat{
+		BeatRateSec: 30 * ingesters.PushBack.Load(),
+	}
+
+	if isReplayedReport(ctx, r) {
+		err = httpext.JSON(w, http.StatusOK, res)
+		if err != nil {
+			log.Error().Msgf("Cannot send beat: %v", err)
+		}
+		return
+	}
+
 	buffer := bufferPool.Get().(*bytes.Buffer)
 	buffer.Reset()
 	_, err = io.Copy(buffer, r.Body)
@@ -84,21 +121,26 @@ func (h *Handler) IngestAgentReport(w http.ResponseWriter, r *http.Request) {
 		respondWith(ctx, w, http.StatusBadRequest, err)
 		return
 	}
+	check, err := reportHostsCheck(ctx)
+	if err != nil {
+		bufferPool.Put(buffer)
+		respondWith(ctx, w, http.StatusBadRequest, err)
+		return
+	}
 	decoder := json.NewDecoder(gzr)
 	if err := (*ingester).Ingest(ctx, report.CompressedReport{
 		Decoder: decoder,
 		Cleanup: func() {
 			bufferPool.Put(buffer)
 		},
+		Check: check,
 	}); err != nil {
 		bufferPool.Put(buffer)
 		respondWith(ctx, w, http.StatusServiceUnavailable, err)
 		return
 	}
+	markReportIngested(ctx, r)
 
-	res := controls.AgentBeat{
-		BeatRateSec: 30 * ingesters.PushBack.Load(),
-	}
 	err = httpext.JSON(w, http.StatusOK, res)
 
 	if err != nil {
@@ -120,6 +162,17 @@ func (h *Handler) IngestSyncAgentReport(w http.ResponseWriter, r *http.Request)
 		respondWith(ctx, w, http.StatusBadRequest, err)
 		return
 	}
+
+	err = checkReportSource(ctx, r)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+	if isReplayedReport(ctx, r) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	var rpt ingesters.ReportIngestionData
 
 	err = jsoniter.Unmarshal(data, &rpt)
@@ -139,6 +192,102 @@ func (h *Handler) IngestSyncAgentReport(w http.ResponseWriter, r *http.Request)
 	//      respondWith(ctx, w, http.StatusInternalServerError, err)
 	//      return
 	//}
+	markReportIngested(ctx, r)
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// checkReportSource only accepts the reports of their own node from the
+// agents with a credential. The header only tells the node of the agents
+// using the API key, the hosts in the reports are checked once decoded.
+func checkReportSource(ctx context.Context, r *http.Request) error {
+	source := r.Header.Get(controls.ReportSourceHeader)
+	if bound := agentNodeID(ctx); bound != "" && source == "" {
+		source = bound
+	}
+	if source == "" {
+		return checkAgentToken(ctx)
+	}
+	return checkAgentNodeID(ctx, source)
+}
+
+// reportHostsCheck rejects the reports of agents with a credential describing
+// other hosts than their node, and the reports of revoked agents whatever the
+// token they were sent with
+func reportHostsCheck(ctx context.Context) (func(*report.Report) error, error) {
+	ns, err := directory.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bound := agentNodeID(ctx)
+	return func(rpt *report.Report) error {
+		hosts := rpt.HostIDs()
+		for _, host := range hosts {
+			if bound != "" && host != bound {
+				return fmt.Errorf("report of agent %s describes host %s", bound, host)
+			}
+		}
+		return checkRevokedNodes(directory.NewContextWithNameSpace(ns), hosts)
+	}, nil
+}
+
+// reportSequence is only kept for the agents with a credential, so that the
+// node of the sequence is the one the token was issued to and not the one the
+// client claims in its header
+func reportSequence(ctx context.Context, r *http.Request) (string, int64, bool) {
+	source := agentNodeID(ctx)
+	if source == "" {
+		return "", 0, false
+	}
+	if header := r.Header.Get(controls.ReportSourceHeader); header != "" && header != source {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseInt(r.Header.Get(controls.ReportSequenceHeader), 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return source, seq, true
+}
+
+// isReplayedReport tells if the agent already published the report, it was
+// ingested but the agent did not get the response and kept it in its buffer
+func isReplayedReport(ctx context.Context, r *http.Request) bool {
+	source, seq, ok := reportSequence(ctx, r)
+	if !ok {
+		return false
+	}
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		log.Error().Msgf("Cannot check report sequence: %v", err)
+		return false
+	}
+	last, err := redisClient.Get(ctx, reportSequencePrefix+source).Int64()
+	if err == redis.Nil {
+		return false
+	} else if err != nil {
+		log.Error().Msgf("Cannot check report sequence: %v", err)
+		return false
+	}
+	if seq <= last {
+		log.Debug().Msgf("Skip replayed report %d of %s", seq, source)
+		return true
+	}
+	return false
+}
+
+func markReportIngested(ctx context.Context, r *http.Request) {
+	source, seq, ok := reportSequence(ctx, r)
+	if !ok {
+		return
+	}
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		log.Error().Msgf("Cannot set report sequence: %v", err)
+		return
+	}
+	err = setReportSequence.Run(ctx, redisClient, []string{reportSequencePrefix + source},
+		seq, int64(reportSequenceExpiry.Seconds())).Err()
+	if err != nil {
+		log.Error().Msgf("Cannot set report sequence: %v", err)
+	}
+}
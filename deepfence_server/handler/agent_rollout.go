@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+func (h *Handler) StartAgentRollout(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentRolloutRequest
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	rolloutID, err := controls.StartAgentRollout(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Cannot start agent rollout: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventAgentRollout, ActionStart, map[string]interface{}{
+		"rollout_id": rolloutID,
+		"version":    req.Version,
+		"node_ids":   req.NodeIDs,
+	}, true)
+
+	err = httpext.JSON(w, http.StatusOK, model.AgentRolloutIDReq{RolloutID: rolloutID})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ListAgentRollouts(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	rollouts, err := controls.ListAgentRollouts(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list agent rollouts: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.ListAgentRolloutsResp{Rollouts: rollouts})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) CancelAgentRollout(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AgentRolloutIDReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	err = controls.CancelAgentRollout(r.Context(), req.RolloutID)
+	if errors.Is(err, controls.ErrRolloutNotFound) {
+		h.respondError(&NotFoundError{err}, w)
+		return
+	} else if errors.Is(err, controls.ErrRolloutNotRunning) {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot cancel agent rollout: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventAgentRollout, ActionStop, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,7 +3,6 @@ package handler
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,7 +17,6 @@ import (
 	"github.com/deepfence/ThreatMapper/deepfence_server/model"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
-	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
 	httpext "github.com/go-playground/pkg/v5/net/http"
 	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
 	"golang.org/x/mod/semver"
@@ -176,12 +174,18 @@ func CleanUpAgentVersion(ctx context.Context, tagsToKeep []string) error {
 	return tx.Commit()
 }
 
+// ScheduleAutoUpgradeForPatchChanges starts a staged rollout of the latest
+// patch of each major.minor to the agents running an older patch, with the
+// default waves: a 10% canary group then waves of 25%, each soaking 30
+// minutes. The agents are no longer all upgraded at once, an agent can be
+// upgraded right away with the agent-upgrade control. Agents already part
+// of a running rollout of the patch, or rolled back from it, are left out.
 func ScheduleAutoUpgradeForPatchChanges(ctx context.Context, latest map[string]string) error {
 	nc, err := directory.Neo4jClient(ctx)
 	if err != nil {
 		return err
 	}
-	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
 	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(15 * time.Second))
@@ -190,39 +194,49 @@ func ScheduleAutoUpgradeForPatchChanges(ctx context.Context, latest map[string]s
 	}
 	defer tx.Close()
 
-	tagsToIngest := []map[string]string{}
+	rollouts := []model.AgentRolloutRequest{}
 	for k, v := range latest {
-		action, err := controls.PrepareAgentUpgradeAction(ctx, v)
+		res, err := tx.Run(`
+			MATCH (v:AgentVersion) <-[:VERSIONED]- (n:Node)
+			WHERE v.node_id STARTS WITH $major_minor
+			AND v.node_id <> $latest
+			OPTIONAL MATCH (r:AgentRollout{version: $latest}) -[:ROLLOUT_TARGET]-> (n)
+			WHERE r.status IN $statuses
+			WITH n, r
+			WHERE r IS NULL
+			RETURN DISTINCT n.node_id`,
+			map[string]interface{}{
+				"major_minor": k,
+				"latest":      v,
+				"statuses":    []string{model.AgentRolloutRunning, model.AgentRolloutRolledBack},
+			})
 		if err != nil {
-			log.Error().Msg(err.Error())
-			continue
+			return err
 		}
-
-		actionStr, err := json.Marshal(action)
+		recs, err := res.Collect()
 		if err != nil {
 			return err
 		}
-		tagsToIngest = append(tagsToIngest,
-			map[string]string{
-				"major_minor": k,
-				"latest":      v,
-				"action":      string(actionStr)})
+		if len(recs) == 0 {
+			continue
+		}
+		nodeIDs := make([]string, 0, len(recs))
+		for _, rec := range recs {
+			nodeIDs = append(nodeIDs, rec.Values[0].(string))
+		}
+		rollouts = append(rollouts, model.AgentRolloutRequest{Version: v, NodeIDs: nodeIDs})
 	}
 
-	if _, err = tx.Run(`
-		UNWIND $batch as row
-		MATCH (vnew:AgentVersion{node_id: row.latest})
-		MATCH (v:AgentVersion) <-[:VERSIONED]- (n:Node)
-		WHERE v.node_id STARTS WITH row.major_minor
-		AND v.node_id <> row.latest
-		MERGE (vnew) -[:SCHEDULED{status: $status, retries: 0, trigger_action: row.action, updated_at: TIMESTAMP()}]-> (n)`,
-		map[string]interface{}{
-			"status": utils.ScanStatusStarting,
-			"batch":  tagsToIngest}); err != nil {
-		return err
+	for _, req := range rollouts {
+		rolloutID, err := controls.StartAgentRollout(ctx, req)
+		if err != nil {
+			log.Error().Msgf("Cannot start agent rollout of %s: %v", req.Version, err)
+			continue
+		}
+		log.Info().Msgf("Started agent rollout %s to %d nodes", rolloutID, len(req.NodeIDs))
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func GetLatestVersionByMajorMinor(versions map[string]*bytes.Buffer) map[string]string {
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+const maxAssetTagsCSVSize = 10 * 1024 * 1024
+
+func (h *Handler) UpdateAssetTags(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AssetTagsUpdateRequest
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+	resp, err := model.UpdateAssetTags(r.Context(), req.AssetTags())
+	if err != nil {
+		log.Error().Msgf("%v", err)
+		h.respondError(&InternalServerError{err}, w)
+		return
+	}
+	h.AuditUserActivity(r, EventAssetTags, ActionUpdate, req, true)
+	err = httpext.JSON(w, http.StatusOK, resp)
+	if err != nil {
+		log.Error().Msgf("%v", err)
+	}
+}
+
+func (h *Handler) ImportAssetTags(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if err := r.ParseMultipartForm(maxAssetTagsCSVSize); err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	file, fileHeader, err := r.FormFile("csv")
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	defer file.Close()
+
+	tags, err := model.ParseAssetTagsCSV(file)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	resp, err := model.UpdateAssetTags(r.Context(), tags)
+	if err != nil {
+		log.Error().Msgf("%v", err)
+		h.respondError(&InternalServerError{err}, w)
+		return
+	}
+	h.AuditUserActivity(r, EventAssetTags, ActionBulk,
+		map[string]interface{}{"file": fileHeader.Filename, "rows": len(tags)}, true)
+	err = httpext.JSON(w, http.StatusOK, resp)
+	if err != nil {
+		log.Error().Msgf("%v", err)
+	}
+}
@@ -23,12 +23,20 @@ const (
 	EventVulnerabilityScan       = string(utils.NEO4JVulnerabilityScan)
 	EventSecretScan              = string(utils.NEO4JSecretScan)
 	EventMalwareScan             = string(utils.NEO4JMalwareScan)
+	EventIaCScan                 = string(utils.NEO4JIaCScan)
 	EventIntegration             = "integration"
 	EventGenerativeAIIntegration = "generative-ai-integration"
 	EventAuth                    = "auth"
 	EventReports                 = "reports"
 	EventSettings                = "settings"
 	EventRegistry                = "registry"
+	EventAgentRollout            = "agent-rollout"
+	EventAgentResourceLimits     = "agent-resource-limits"
+	EventScanWindow              = "scan-window"
+	EventAgentCredential         = "agent-credential"
+	EventScanProfile             = "scan-profile"
+	EventCustomRule              = "custom-rule"
+	EventAssetTags               = "asset-tags"
 	ActionStart                  = "start"
 	ActionStop                   = "stop"
 	ActionLogout                 = "logout"
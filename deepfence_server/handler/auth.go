@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
 	"github.com/deepfence/ThreatMapper/deepfence_server/model"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
@@ -55,7 +56,22 @@ func (h *Handler) APIAuthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	apiToken := &model.APIToken{APIToken: parsedUUID}
 	user, err := apiToken.GetUser(ctx, pgClient)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
+		// enrolled agents authenticate with their credential
+		accessTokenResponse, err := h.agentCredentialAccessToken(ctx, parsedUUID.String())
+		if errors.Is(err, controls.ErrAgentCredentialNotFound) {
+			h.respondError(&NotFoundError{err}, w)
+			return
+		} else if err != nil {
+			h.respondError(err, w)
+			return
+		}
+		err = httpext.JSON(w, http.StatusOK, accessTokenResponse)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+		return
+	} else if err != nil {
 		h.respondError(err, w)
 		return
 	}
@@ -84,7 +100,14 @@ func (h *Handler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	accessTokenResponse, err := user.GetAccessToken(h.TokenAuth, grantType)
+	var accessTokenResponse *model.ResponseAccessToken
+	if grantType == model.GrantTypeAgentCredential {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		credID, _ := claims[model.AgentCredentialIDClaim].(string)
+		accessTokenResponse, err = h.agentCredentialAccessToken(r.Context(), credID)
+	} else {
+		accessTokenResponse, err = user.GetAccessToken(h.TokenAuth, grantType)
+	}
 	if err != nil {
 		h.respondError(err, w)
 		return
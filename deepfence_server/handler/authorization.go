@@ -13,6 +13,15 @@ func (h *Handler) AuthHandler(resource, permission string, handlerFunc http.Hand
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		revoked, err := isAgentCredentialRevoked(r.Context(), claims)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 		enforce, err := h.AuthEnforcer.Enforce([]interface{}{claims["role"].(string), resource, permission}...)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
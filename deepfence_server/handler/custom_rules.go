@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/go-chi/chi/v5"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+func (h *Handler) AddCustomRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.CustomRuleReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+	err = controls.ValidateCustomRule(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err, skipOverwriteErrorMessage: true}, w)
+		return
+	}
+
+	rule, err := controls.AddCustomRule(r.Context(), req)
+	if err != nil {
+		h.respondCustomRuleError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventCustomRule, ActionCreate, rule, true)
+
+	err = httpext.JSON(w, http.StatusOK, rule)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) UpdateCustomRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.UpdateCustomRuleReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	req.ID = chi.URLParam(r, "id")
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+	err = controls.ValidateCustomRule(req.CustomRuleReq)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err, skipOverwriteErrorMessage: true}, w)
+		return
+	}
+
+	rule, err := controls.UpdateCustomRule(r.Context(), req)
+	if err != nil {
+		h.respondCustomRuleError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventCustomRule, ActionUpdate, rule, true)
+
+	err = httpext.JSON(w, http.StatusOK, rule)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) DeleteCustomRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	req := model.CustomRuleIDReq{ID: chi.URLParam(r, "id")}
+
+	err := controls.DeleteCustomRule(r.Context(), req.ID)
+	if err != nil {
+		h.respondCustomRuleError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventCustomRule, ActionDelete, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListCustomRules(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	res, err := controls.ListCustomRules(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list custom rules: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, res)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ListCustomRulesBundles(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	bundles, err := controls.ListCustomRulesBundles(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list custom rules bundles: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.ListCustomRulesBundlesResp{Bundles: bundles})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+// respondCustomRuleError reports the rules clashing with the other rules of
+// the bundle as invalid
+func (h *Handler) respondCustomRuleError(err error, w http.ResponseWriter) {
+	var invalid *controls.InvalidCustomRuleError
+	switch {
+	case errors.As(err, &invalid):
+		h.respondError(&ValidatorError{err: invalid, skipOverwriteErrorMessage: true}, w)
+	case errors.Is(err, controls.ErrCustomRuleNotFound):
+		h.respondError(&NotFoundError{err}, w)
+	default:
+		log.Error().Msgf("Cannot update custom rules: %v", err)
+		h.respondError(err, w)
+	}
+}
@@ -54,6 +54,11 @@ func (h *Handler) UpdateAgentDiagnosticLogsStatus(w http.ResponseWriter, r *http
 		h.respondError(&ValidatorError{err: err}, w)
 		return
 	}
+	err = checkAgentNodeID(r.Context(), req.NodeID)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
 	err = agentdiagnosis.UpdateAgentDiagnosticLogsStatus(r.Context(), req)
 	if err != nil {
 		h.respondError(err, w)
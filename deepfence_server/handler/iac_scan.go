@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/iac"
+	reportersScan "github.com/deepfence/ThreatMapper/deepfence_server/reporters/scan"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+const MaxIaCUploadSize = 20 * 1000000 // 20 MB
+
+func iacFindingID(nodeName string, f iac.Finding) string {
+	key := strings.Join([]string{nodeName, f.File, f.HelmTemplate, f.Resource(), f.ID}, "|")
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+func (h *Handler) StartIaCScanHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.IaCScanRequest
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxIaCUploadSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	files := make([]iac.File, 0, len(req.Files))
+	for _, f := range req.Files {
+		files = append(files, iac.File{Path: f.Path, Content: f.Content})
+	}
+	results, err := iac.Evaluate(files)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+
+	findings := make([]model.IaCFinding, 0, len(results))
+	severityCounts := map[string]int32{}
+	for _, f := range results {
+		findings = append(findings, model.IaCFinding{
+			TestCategory:        f.Category,
+			TestNumber:          f.ID,
+			TestInfo:            f.Title,
+			TestRationale:       f.Description,
+			Remediation:         f.Remediation,
+			Resource:            f.Resource(),
+			TestSeverity:        f.Severity,
+			Status:              model.IaCStatusAlarm,
+			ComplianceCheckType: model.IaCKubernetesManifest,
+			FilePath:            f.File,
+			HelmTemplate:        f.HelmTemplate,
+			DocumentIndex:       f.Document,
+			Kind:                f.Kind,
+			Namespace:           f.Namespace,
+			Name:                f.Name,
+			Container:           f.Container,
+			NodeID:              iacFindingID(req.NodeName, f),
+		})
+		severityCounts[f.Severity]++
+	}
+
+	scanID := scanID(model.NodeIdentifier{NodeID: req.NodeName, NodeType: model.IaCSourceNodeType})
+	err = reportersScan.SaveIaCScan(r.Context(), scanID, req.NodeName, findings)
+	if err != nil {
+		log.Error().Msgf("Error SaveIaCScan: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventIaCScan, ActionStart, map[string]interface{}{
+		"scan_id":   scanID,
+		"node_name": req.NodeName,
+		"files":     len(req.Files),
+	}, true)
+
+	err = httpext.JSON(w, http.StatusOK, model.IaCScanResult{
+		ScanResultsCommon: model.ScanResultsCommon{
+			NodeID:    req.NodeName,
+			NodeName:  req.NodeName,
+			NodeType:  model.IaCSourceNodeType,
+			ScanID:    scanID,
+			UpdatedAt: time.Now().UnixMilli(),
+			CreatedAt: time.Now().UnixMilli(),
+		},
+		Findings:       findings,
+		SeverityCounts: severityCounts,
+	})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ListIaCScansHandler(w http.ResponseWriter, r *http.Request) {
+	h.listScansHandler(w, r, utils.NEO4JIaCScan)
+}
+
+func (h *Handler) ListIaCScanResultsHandler(w http.ResponseWriter, r *http.Request) {
+	entries, common, err := listScanResultsHandler[model.IaCFinding](w, r, utils.NEO4JIaCScan)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	counts, err := reportersScan.GetSevCounts(r.Context(), utils.NEO4JIaCScan, common.ScanID)
+	if err != nil {
+		log.Error().Err(err).Msg("Counts computation issue")
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.IaCScanResult{Findings: entries, ScanResultsCommon: common, SeverityCounts: counts})
+	if err != nil {
+		log.Error().Msgf("%v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	reporters_scan "github.com/deepfence/ThreatMapper/deepfence_server/reporters/scan"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+func (h *Handler) GetImageScanStatus(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.ImageScanStatusRequest
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	status, err := reporters_scan.GetImageScanStatus(r.Context(), req.Images)
+	if err != nil {
+		log.Error().Msgf("Error GetImageScanStatus: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, status)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
@@ -28,6 +28,12 @@ func (h *Handler) GetKubernetesClusterControls(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	err = checkAgentNodeID(ctx, kubernetesClusterID.NodeID)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
 	actions, errs := controls.GetKubernetesClusterActions(ctx, kubernetesClusterID.NodeID, kubernetesClusterID.AvailableWorkload)
 	for _, err := range errs {
 		if err != nil {
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	reporters_posture "github.com/deepfence/ThreatMapper/deepfence_server/reporters/posture"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+func (h *Handler) GetKubernetesPosture(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req reporters_posture.KubernetesPostureFilter
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	posture, err := reporters_posture.GetKubernetesPosture(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Error GetKubernetesPosture: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, posture)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
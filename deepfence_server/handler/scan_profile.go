@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	ctl "github.com/deepfence/ThreatMapper/deepfence_utils/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/go-chi/chi/v5"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+// scanProfileIDArg is the bin arg of the profile a scan was triggered with
+const scanProfileIDArg = "scan_profile_id"
+
+func (h *Handler) AddScanProfile(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AddScanProfileReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	profile, err := controls.AddScanProfile(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Cannot add scan profile: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventScanProfile, ActionCreate, profile, true)
+
+	err = httpext.JSON(w, http.StatusOK, profile)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ListScanProfiles(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	profiles, err := controls.ListScanProfiles(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list scan profiles: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.ListScanProfilesResp{Profiles: profiles})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) DeleteScanProfile(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	req := model.ScanProfileIDReq{ID: chi.URLParam(r, "id")}
+
+	err := controls.DeleteScanProfile(r.Context(), req.ID)
+	if errors.Is(err, controls.ErrScanProfileNotFound) {
+		h.respondError(&NotFoundError{err}, w)
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot delete scan profile: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventScanProfile, ActionDelete, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AssignScanProfile(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AssignScanProfileReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	err = controls.AssignScanProfile(r.Context(), req)
+	if errors.Is(err, controls.ErrScanProfileNotFound) {
+		h.respondError(&NotFoundError{err}, w)
+		return
+	} else if errors.Is(err, controls.ErrScanProfileHostOnly) {
+		h.respondError(&ValidatorError{err: fmt.Errorf("node_ids:%w", err), skipOverwriteErrorMessage: true}, w)
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot assign scan profile: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventScanProfile, ActionUpdate, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scanProfileBinArgs(profileID string) map[string]string {
+	if profileID == "" {
+		return nil
+	}
+	return map[string]string{scanProfileIDArg: profileID}
+}
+
+// agentScanProfile returns the profile the secret and malware scans of the
+// node run with
+func agentScanProfile(ctx context.Context, nodeID string, binArgs map[string]string) (*ctl.ScanProfile, error) {
+	profile, err := controls.NodeScanProfile(ctx, nodeID, binArgs[scanProfileIDArg])
+	if err != nil {
+		log.Error().Msgf("Cannot get scan profile of %s: %v", nodeID, err)
+	}
+	return profile, err
+}
+
+// scanProfileError tells the errors of the profile a scan was triggered with
+// apart from the failures to start the scan
+func scanProfileError(err error) error {
+	switch {
+	case errors.Is(err, controls.ErrScanProfileNotFound):
+		return &NotFoundError{err}
+	case errors.Is(err, controls.ErrScanProfileHostOnly):
+		return &ValidatorError{err: fmt.Errorf("scan_profile_id:%w", err), skipOverwriteErrorMessage: true}
+	}
+	return err
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/minio/minio-go/v7"
 	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
 	"github.com/samber/lo"
+	"github.com/samber/mo"
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
@@ -194,9 +195,17 @@ func StartScanActionBuilder(ctx context.Context, scanType controls.ActionID, add
 		case controls.StartVulnerabilityScan:
 			internalReq = controls.StartVulnerabilityScanRequest{NodeID: req.NodeID, NodeType: nodeTypeInternal, BinArgs: binArgs}
 		case controls.StartSecretScan:
-			internalReq = controls.StartSecretScanRequest{NodeID: req.NodeID, NodeType: nodeTypeInternal, BinArgs: binArgs}
+			profile, err := agentScanProfile(ctx, req.NodeID, binArgs)
+			if err != nil {
+				return controls.Action{}, err
+			}
+			internalReq = controls.StartSecretScanRequest{NodeID: req.NodeID, NodeType: nodeTypeInternal, BinArgs: binArgs, Profile: profile}
 		case controls.StartMalwareScan:
-			internalReq = controls.StartMalwareScanRequest{NodeID: req.NodeID, NodeType: nodeTypeInternal, BinArgs: binArgs}
+			profile, err := agentScanProfile(ctx, req.NodeID, binArgs)
+			if err != nil {
+				return controls.Action{}, err
+			}
+			internalReq = controls.StartMalwareScanRequest{NodeID: req.NodeID, NodeType: nodeTypeInternal, BinArgs: binArgs, Profile: profile}
 		}
 
 		b, err := json.Marshal(internalReq)
@@ -372,7 +381,7 @@ func (h *Handler) StartSecretScanHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	actionBuilder := StartScanActionBuilder(r.Context(), controls.StartSecretScan, nil)
+	actionBuilder := StartScanActionBuilder(r.Context(), controls.StartSecretScan, scanProfileBinArgs(reqs.ScanProfileID))
 
 	scanIDs, bulkID, err := StartMultiScan(r.Context(), true, utils.NEO4JSecretScan, reqs.ScanTriggerCommon, actionBuilder)
 	if err != nil {
@@ -381,7 +390,7 @@ func (h *Handler) StartSecretScanHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		log.Error().Msgf("%v", err)
-		h.respondError(err, w)
+		h.respondError(scanProfileError(err), w)
 		return
 	}
 
@@ -491,7 +500,7 @@ func (h *Handler) StartMalwareScanHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	actionBuilder := StartScanActionBuilder(r.Context(), controls.StartMalwareScan, nil)
+	actionBuilder := StartScanActionBuilder(r.Context(), controls.StartMalwareScan, scanProfileBinArgs(reqs.ScanProfileID))
 
 	scanIDs, bulkID, err := StartMultiScan(r.Context(), true, utils.NEO4JMalwareScan, reqs.ScanTriggerCommon, actionBuilder)
 	if err != nil {
@@ -500,7 +509,7 @@ func (h *Handler) StartMalwareScanHandler(w http.ResponseWriter, r *http.Request
 			return
 		}
 		log.Error().Msgf("%v", err)
-		h.respondError(err, w)
+		h.respondError(scanProfileError(err), w)
 		return
 	}
 
@@ -630,6 +639,12 @@ func (h *Handler) IngestSbomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = checkAgentScanIDs(r.Context(), utils.NEO4JVulnerabilityScan, []string{params.ScanID})
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
 	mc, err := directory.MinioClient(r.Context())
 	if err != nil {
 		log.Error().Msg(err.Error())
@@ -688,63 +703,64 @@ func (h *Handler) IngestSbomHandler(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) IngestVulnerabilityReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewVulnerabilityIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JVulnerabilityScan, h.IngestChan)
 }
 
 func (h *Handler) IngestVulnerabilityScanStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewVulnerabilityStatusIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JVulnerabilityScan, h.IngestChan)
 }
 
 func (h *Handler) IngestSecretReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewSecretIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JSecretScan, h.IngestChan)
 }
 
 func (h *Handler) IngestSecretScanStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewSecretScanStatusIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JSecretScan, h.IngestChan)
 }
 
 func (h *Handler) IngestMalwareScanStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewMalwareScanStatusIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JMalwareScan, h.IngestChan)
 }
 
 func (h *Handler) IngestComplianceReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewComplianceIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JComplianceScan, h.IngestChan)
 }
 
 func (h *Handler) IngestComplianceScanStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewComplianceScanStatusIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JComplianceScan, h.IngestChan)
 }
 
 func (h *Handler) IngestCloudComplianceReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewCloudComplianceIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JCloudComplianceScan, h.IngestChan)
 }
 
 func (h *Handler) IngestMalwareReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewMalwareIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JMalwareScan, h.IngestChan)
 }
 
 func (h *Handler) IngestMalwareScanStatusReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewMalwareScanStatusIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JMalwareScan, h.IngestChan)
 }
 
 func (h *Handler) IngestCloudComplianceScanStatusReportHandler(w http.ResponseWriter, r *http.Request) {
 	ingester := ingesters.NewCloudComplianceScanStatusIngester()
-	ingestScanReportKafka(w, r, ingester, h.IngestChan)
+	ingestScanReportKafka(w, r, ingester, utils.NEO4JCloudComplianceScan, h.IngestChan)
 }
 
 func ingestScanReportKafka[T any](
 	respWrite http.ResponseWriter,
 	req *http.Request,
 	ingester ingesters.KafkaIngester[T],
+	scanType utils.Neo4jScanType,
 	ingestChan chan *kgo.Record) {
 
 	defer req.Body.Close()
@@ -768,6 +784,26 @@ func ingestScanReportKafka[T any](
 		http.Error(respWrite, "Error processing request body", http.StatusInternalServerError)
 		return
 	}
+
+	// agents only post the results of the scans of their node
+	var scans []struct {
+		ScanID string `json:"scan_id"`
+	}
+	err = json.Unmarshal(body, &scans)
+	if err != nil {
+		http.Error(respWrite, "Error processing request body", http.StatusBadRequest)
+		return
+	}
+	scanIDs := make([]string, 0, len(scans))
+	for _, scan := range scans {
+		scanIDs = append(scanIDs, scan.ScanID)
+	}
+	err = checkAgentScanIDs(ctx, scanType, scanIDs)
+	if err != nil {
+		log.Warn().Msgf("Scan results rejected: %v", err)
+		http.Error(respWrite, err.Error(), http.StatusForbidden)
+		return
+	}
 	err = ingester.Ingest(ctx, data, ingestChan)
 	if err != nil {
 		log.Error().Msgf("error: %+v", err)
@@ -1484,6 +1520,48 @@ func getNodeIDs(tx neo4j.Transaction, ids []model.NodeIdentifier, neo4jNode cont
 	return res, nil
 }
 
+// filterNodesByAssetTags keeps the nodes matching the asset tags filter
+func filterNodesByAssetTags(ctx context.Context, nodes []model.NodeIdentifier, filter model.AssetFilter) ([]model.NodeIdentifier, error) {
+	fieldsFilters := reporters.FieldsFilters{}
+	filter.Apply(&fieldsFilters)
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	res, err := session.Run(`
+		MATCH (n)
+		WHERE n.node_id IN $ids
+		`+reporters.ParseFieldFilters2CypherWhereConditions("n", mo.Some(fieldsFilters), false)+`
+		RETURN n.node_id`,
+		map[string]interface{}{"ids": reportersScan.NodeIdentifierToIDList(nodes)},
+		neo4j.WithTxTimeout(30*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return nil, err
+	}
+	matched := map[string]struct{}{}
+	for _, rec := range recs {
+		if id, ok := rec.Values[0].(string); ok {
+			matched[id] = struct{}{}
+		}
+	}
+
+	filtered := []model.NodeIdentifier{}
+	for _, n := range nodes {
+		if _, has := matched[n.NodeID]; has {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered, nil
+}
+
 func (h *Handler) scanResultMaskHandler(w http.ResponseWriter, r *http.Request, action string) {
 	defer r.Body.Close()
 	var req model.ScanResultsMaskRequest
@@ -1633,6 +1711,17 @@ func getScanResults(ctx context.Context, scanID, scanType string) (model.Downloa
 		resp.ScanResults = []interface{}{result}
 		return resp, nil
 
+	case "IaCScan":
+		result, common, err := reportersScan.GetScanResults[model.IaCFinding](
+			ctx, utils.StringToNeo4jScanType(scanType), scanID,
+			reporters.FieldsFilters{}, model.FetchWindow{})
+		if err != nil {
+			return resp, err
+		}
+		resp.ScanInfo = common
+		resp.ScanResults = []interface{}{result}
+		return resp, nil
+
 	default:
 		return resp, errIncorrectScanType
 	}
@@ -2104,6 +2193,13 @@ func StartMultiScan(ctx context.Context,
 		reqs = append(reqs, podContainerNodes...)
 	}
 
+	if !req.Filters.AssetFilter.IsEmpty() {
+		reqs, err = filterNodesByAssetTags(ctx, reqs, req.Filters.AssetFilter)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
 	driver, err := directory.Neo4jClient(ctx)
 
 	if err != nil {
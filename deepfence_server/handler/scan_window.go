@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/go-chi/chi/v5"
+	httpext "github.com/go-playground/pkg/v5/net/http"
+)
+
+func (h *Handler) AddScanWindow(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.AddScanWindowReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	window, err := controls.AddScanWindow(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Cannot add scan window: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventScanWindow, ActionCreate, window, true)
+
+	err = httpext.JSON(w, http.StatusOK, window)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) ListScanWindows(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	windows, err := controls.ListScanWindows(r.Context())
+	if err != nil {
+		log.Error().Msgf("Cannot list scan windows: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, model.ListScanWindowsResp{Windows: windows})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) DeleteScanWindow(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	req := model.ScanWindowIDReq{ID: chi.URLParam(r, "id")}
+
+	err := controls.DeleteScanWindow(r.Context(), req.ID)
+	if errors.Is(err, controls.ErrScanWindowNotFound) {
+		h.respondError(&NotFoundError{err}, w)
+		return
+	} else if err != nil {
+		log.Error().Msgf("Cannot delete scan window: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventScanWindow, ActionDelete, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) SetClusterScanLimit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.ClusterScanLimitReq
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	err = controls.SetClusterScanLimit(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Cannot set cluster scan limit: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	h.AuditUserActivity(r, EventScanWindow, ActionUpdate, req, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
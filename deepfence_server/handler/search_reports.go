@@ -180,6 +180,14 @@ func (h *Handler) SearchPods(w http.ResponseWriter, r *http.Request) {
 	SearchHandler[model.Pod](w, r, h)
 }
 
+func (h *Handler) SearchDrifts(w http.ResponseWriter, r *http.Request) {
+	SearchHandler[model.Drift](w, r, h)
+}
+
+func (h *Handler) SearchImageConfigFindings(w http.ResponseWriter, r *http.Request) {
+	SearchHandler[model.ImageConfigFinding](w, r, h)
+}
+
 func (h *Handler) SearchCompliances(w http.ResponseWriter, r *http.Request) {
 	SearchHandler[model.Compliance](w, r, h)
 }
@@ -192,6 +200,10 @@ func (h *Handler) SearchMalwareRules(w http.ResponseWriter, r *http.Request) {
 	SearchHandler[model.MalwareRule](w, r, h)
 }
 
+func (h *Handler) SearchIaCFindings(w http.ResponseWriter, r *http.Request) {
+	SearchHandler[model.IaCFinding](w, r, h)
+}
+
 func (h *Handler) SearchComplianceRules(w http.ResponseWriter, r *http.Request) {
 	SearchHandler[model.ComplianceRule](w, r, h)
 }
@@ -216,6 +228,10 @@ func (h *Handler) SearchComplianceScans(w http.ResponseWriter, r *http.Request)
 	h.SearchScans(w, r, utils.NEO4JComplianceScan)
 }
 
+func (h *Handler) SearchIaCScans(w http.ResponseWriter, r *http.Request) {
+	h.SearchScans(w, r, utils.NEO4JIaCScan)
+}
+
 func (h *Handler) SearchCloudComplianceScans(w http.ResponseWriter, r *http.Request) {
 	h.SearchScans(w, r, utils.NEO4JCloudComplianceScan)
 }
@@ -272,6 +288,14 @@ func (h *Handler) SearchPodsCount(w http.ResponseWriter, r *http.Request) {
 	SearchCountHandler[model.Pod](w, r, h)
 }
 
+func (h *Handler) SearchDriftsCount(w http.ResponseWriter, r *http.Request) {
+	SearchCountHandler[model.Drift](w, r, h)
+}
+
+func (h *Handler) SearchImageConfigFindingsCount(w http.ResponseWriter, r *http.Request) {
+	SearchCountHandler[model.ImageConfigFinding](w, r, h)
+}
+
 func (h *Handler) SearchCloudAccountCount(w http.ResponseWriter, r *http.Request) {
 	SearchCloudNodeCountHandler[model.CloudNodeAccountInfo](w, r, h)
 }
@@ -288,6 +312,10 @@ func (h *Handler) SearchMalwareRulesCount(w http.ResponseWriter, r *http.Request
 	SearchCountHandler[model.MalwareRule](w, r, h)
 }
 
+func (h *Handler) SearchIaCFindingsCount(w http.ResponseWriter, r *http.Request) {
+	SearchCountHandler[model.IaCFinding](w, r, h)
+}
+
 func (h *Handler) SearchComplianceRulesCount(w http.ResponseWriter, r *http.Request) {
 	SearchCountHandler[model.ComplianceRule](w, r, h)
 }
@@ -312,6 +340,10 @@ func (h *Handler) SearchComplianceScansCount(w http.ResponseWriter, r *http.Requ
 	h.SearchScansCount(w, r, utils.NEO4JComplianceScan)
 }
 
+func (h *Handler) SearchIaCScansCount(w http.ResponseWriter, r *http.Request) {
+	h.SearchScansCount(w, r, utils.NEO4JIaCScan)
+}
+
 func (h *Handler) SearchCloudComplianceScansCount(w http.ResponseWriter, r *http.Request) {
 	h.SearchScansCount(w, r, utils.NEO4JCloudComplianceScan)
 }
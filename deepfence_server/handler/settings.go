@@ -23,6 +23,7 @@ var (
 	errInvalidID              = BadDecoding{err: errors.New("invalid id")}
 	errInvalidURL             = BadDecoding{err: errors.New("invalid url")}
 	errInvalidInteger         = BadDecoding{err: errors.New("must be integer")}
+	errInvalidBoolean         = BadDecoding{err: errors.New("must be true or false")}
 	errInvalidEmailConfigType = ValidatorError{
 		err: fmt.Errorf("email_provider:must be %s or %s", model.EmailSettingSMTP, model.EmailSettingSES), skipOverwriteErrorMessage: true}
 )
@@ -216,6 +217,12 @@ func (h *Handler) UpdateGlobalSettings(w http.ResponseWriter, r *http.Request) {
 			h.respondError(&errInvalidInteger, w)
 			return
 		}
+	case model.RequireAgentCredentialsKey:
+		value, err = strconv.ParseBool(strings.TrimSpace(req.Value))
+		if err != nil {
+			h.respondError(&errInvalidBoolean, w)
+			return
+		}
 	default:
 		value = req.Value
 	}
@@ -237,3 +244,50 @@ func (h *Handler) UpdateGlobalSettings(w http.ResponseWriter, r *http.Request) {
 	h.AuditUserActivity(r, EventSettings, ActionUpdate, setting, true)
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *Handler) GetThreatScoringModel(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	ctx := r.Context()
+	pgClient, err := directory.PostgresClient(ctx)
+	if err != nil {
+		h.respondError(&InternalServerError{err}, w)
+		return
+	}
+	scoring, err := model.GetThreatScoringModel(ctx, pgClient)
+	if err != nil {
+		h.respondError(&InternalServerError{err}, w)
+		return
+	}
+	err = httpext.JSON(w, http.StatusOK, scoring)
+	if err != nil {
+		log.Error().Msgf("%v", err)
+	}
+}
+
+func (h *Handler) UpdateThreatScoringModel(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req model.ThreatScoringModel
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+	ctx := r.Context()
+	pgClient, err := directory.PostgresClient(ctx)
+	if err != nil {
+		h.respondError(&InternalServerError{err}, w)
+		return
+	}
+	err = req.Save(ctx, pgClient)
+	if err != nil {
+		h.respondError(&InternalServerError{err}, w)
+		return
+	}
+	h.AuditUserActivity(r, EventSettings, ActionUpdate, req, true)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -105,3 +105,25 @@ func (h *Handler) GetIndividualThreatGraph(w http.ResponseWriter, r *http.Reques
 	}
 	_ = httpext.JSON(w, http.StatusOK, individualThreatGraph)
 }
+
+func (h *Handler) GetAttackPaths(w http.ResponseWriter, r *http.Request) {
+	var req reporters_graph.AttackPathsRequest
+	defer r.Body.Close()
+	err := httpext.DecodeJSON(r, httpext.NoQueryParams, MaxPostRequestSize, &req)
+	if err != nil {
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+	err = h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+	paths, err := reporters_graph.GetAttackPaths(r.Context(), req)
+	if err != nil {
+		log.Error().Msgf("Error GetAttackPaths: %v", err)
+		h.respondError(err, w)
+		return
+	}
+	_ = httpext.JSON(w, http.StatusOK, paths)
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -89,6 +90,86 @@ func (h *Handler) GetTopologyPodsGraph(w http.ResponseWriter, req *http.Request)
 	})
 }
 
+func (h *Handler) ExportTopologyGraph(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	exportReq := reportersGraph.TopologyExportRequest{
+		Filters: reportersGraph.TopologyFilters{
+			CloudFilter:      []string{},
+			RegionFilter:     []string{},
+			KubernetesFilter: []string{},
+			HostFilter:       []string{},
+			PodFilter:        []string{},
+		},
+	}
+	err := httpext.DecodeJSON(req, httpext.NoQueryParams, MaxPostRequestSize, &exportReq)
+	if err != nil {
+		log.Error().Msgf("Failed to DecodeJSON: %v", err)
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+
+	err = h.Validator.Struct(exportReq)
+	if err != nil {
+		log.Error().Msgf("Failed to validate the request: %v", err)
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	ctx := req.Context()
+	reporter, err := getTopologyReporter(ctx)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	var graph reportersGraph.RenderedGraph
+	filters := exportReq.Filters
+	switch exportReq.Root {
+	case "hosts":
+		graph, err = reporter.HostGraph(ctx, filters)
+	case "kubernetes":
+		graph, err = reporter.KubernetesGraph(ctx, filters)
+	case "containers":
+		graph, err = reporter.ContainerGraph(ctx, filters)
+	case "pods":
+		graph, err = reporter.PodGraph(ctx, filters)
+	default:
+		graph, err = reporter.Graph(ctx, filters)
+	}
+	if err != nil {
+		log.Error().Msgf("Error getGraph: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	nodes, edges := graphToSummaries(graph, filters.CloudFilter, filters.RegionFilter, filters.KubernetesFilter, filters.HostFilter)
+	content, err := reportersGraph.ExportTopology(model.GraphResult{Nodes: nodes, Edges: edges}, exportReq.Format)
+	if err != nil {
+		log.Error().Msgf("Error exporting topology: %v", err)
+		h.respondError(err, w)
+		return
+	}
+
+	// plain file download, e.g. for Gephi or yEd
+	contentType := reportersGraph.ExportContentType(exportReq.Format)
+	if req.Header.Get("Accept") == contentType {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", "attachment; filename=topology."+exportReq.Format)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(content)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, reportersGraph.TopologyExportResponse{
+		Format:      exportReq.Format,
+		ContentType: contentType,
+		Content:     string(content),
+	})
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
 func (h *Handler) GetTopologyDelta(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	var deltaReq model.TopologyDeltaReq
@@ -120,6 +201,78 @@ func (h *Handler) GetTopologyDelta(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (h *Handler) GetNetworkPolicies(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	var policyReq reportersGraph.NetworkPolicyRequest
+	err := httpext.DecodeJSON(req, httpext.NoQueryParams, MaxPostRequestSize, &policyReq)
+	if err != nil {
+		log.Error().Msgf("Failed to DecodeJSON: %v", err)
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+
+	err = h.Validator.Struct(policyReq)
+	if err != nil {
+		log.Error().Msgf("Failed to validate the request: %v", err)
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	policies, err := reportersGraph.GetNetworkPolicies(req.Context(), policyReq)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		h.respondError(err, w)
+		return
+	}
+
+	// plain manifest download, ready for kubectl apply
+	if req.Header.Get("Accept") == "application/yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("Content-Disposition", "attachment; filename=network-policies.yaml")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(policies.YAML))
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, policies)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
+func (h *Handler) GetBlastRadius(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	var blastReq reportersGraph.BlastRadiusRequest
+	err := httpext.DecodeJSON(req, httpext.NoQueryParams, MaxPostRequestSize, &blastReq)
+	if err != nil {
+		log.Error().Msgf("Failed to DecodeJSON: %v", err)
+		h.respondError(&BadDecoding{err}, w)
+		return
+	}
+
+	err = h.Validator.Struct(blastReq)
+	if err != nil {
+		log.Error().Msgf("Failed to validate the request: %v", err)
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	blastRadius, err := reportersGraph.GetBlastRadius(req.Context(), blastReq)
+	if errors.Is(err, reportersGraph.ErrBlastRadiusNodeNotFound) {
+		h.respondError(&NotFoundError{err}, w)
+		return
+	} else if err != nil {
+		log.Error().Msg(err.Error())
+		h.respondError(err, w)
+		return
+	}
+
+	err = httpext.JSON(w, http.StatusOK, blastRadius)
+	if err != nil {
+		log.Error().Msg(err.Error())
+	}
+}
+
 func (h *Handler) getTopologyGraph(w http.ResponseWriter, req *http.Request, getGraph func(context.Context, reportersGraph.TopologyFilters, reportersGraph.TopologyReporter) (reportersGraph.RenderedGraph, error)) {
 
 	ctx := req.Context()
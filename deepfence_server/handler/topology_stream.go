@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const topologyStreamKeepAlive = 30 * time.Second
+
+type topologyStreamFilter struct {
+	clusters  map[string]struct{}
+	hosts     map[string]struct{}
+	nodeTypes map[string]struct{}
+	// hosts of the filtered clusters, to filter the connections
+	clusterHosts map[string]struct{}
+}
+
+func toSet(values []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		res[v] = struct{}{}
+	}
+	return res
+}
+
+func newTopologyStreamFilter(ctx context.Context, req model.TopologyStreamRequest) (*topologyStreamFilter, error) {
+	f := &topologyStreamFilter{
+		clusters:     toSet(req.KubernetesClusterIDs),
+		hosts:        toSet(req.HostNames),
+		nodeTypes:    toSet(req.NodeTypes),
+		clusterHosts: map[string]struct{}{},
+	}
+	if len(f.clusters) == 0 {
+		return f, nil
+	}
+
+	client, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := client.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	res, err := session.Run(`
+		MATCH (c:KubernetesCluster) -[:HOSTS]-> (n:Node)
+		WHERE c.node_id IN $ids
+		RETURN n.node_id`,
+		map[string]interface{}{"ids": req.KubernetesClusterIDs}, neo4j.WithTxTimeout(30*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		if id, ok := rec.Values[0].(string); ok {
+			f.clusterHosts[id] = struct{}{}
+		}
+	}
+	return f, nil
+}
+
+func (f *topologyStreamFilter) match(e utils.TopologyEvent) bool {
+	isConnection := e.Type == utils.TopologyConnectionAdded || e.Type == utils.TopologyConnectionRemoved
+	if isConnection {
+		if len(f.nodeTypes) > 0 {
+			if _, has := f.nodeTypes[utils.NodeTypeHost]; !has {
+				return false
+			}
+		}
+		if len(f.clusters) > 0 && !f.matchAny(f.clusterHosts, e.Source, e.Target) {
+			return false
+		}
+		if len(f.hosts) > 0 && !f.matchAny(f.hosts, e.Source, e.Target) {
+			return false
+		}
+		return true
+	}
+
+	if len(f.nodeTypes) > 0 {
+		if _, has := f.nodeTypes[e.NodeType]; !has {
+			return false
+		}
+	}
+	if len(f.clusters) > 0 {
+		if _, has := f.clusters[e.KubernetesClusterID]; !has {
+			return false
+		}
+		// new hosts of the cluster
+		if e.NodeType == utils.NodeTypeHost {
+			f.clusterHosts[e.NodeID] = struct{}{}
+		}
+	}
+	if len(f.hosts) > 0 && !f.matchAny(f.hosts, e.HostName, e.NodeID) {
+		return false
+	}
+	return true
+}
+
+func (f *topologyStreamFilter) matchAny(set map[string]struct{}, values ...string) bool {
+	for _, v := range values {
+		if _, has := set[v]; has {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamTopology pushes the topology changes as Server-Sent Events, as soon
+// as the agent reports are committed in the graph
+func (h *Handler) StreamTopology(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	req := model.TopologyStreamRequest{
+		KubernetesClusterIDs: query["kubernetes_cluster_id"],
+		HostNames:            query["host_name"],
+		NodeTypes:            query["node_type"],
+	}
+	err := h.Validator.Struct(req)
+	if err != nil {
+		h.respondError(&ValidatorError{err: err}, w)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.respondError(ErrStreamUnsupported, w)
+		return
+	}
+
+	filter, err := newTopologyStreamFilter(ctx, req)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+
+	ns, err := directory.ExtractNamespace(ctx)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+	redisClient, err := directory.RedisClient(ctx)
+	if err != nil {
+		h.respondError(err, w)
+		return
+	}
+	sub := redisClient.Subscribe(ctx, utils.TopologyEventsChannel(string(ns)))
+	defer sub.Close()
+	messages := sub.Channel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(topologyStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case msg, open := <-messages:
+			if !open {
+				return
+			}
+			var events []utils.TopologyEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &events); err != nil {
+				log.Warn().Msgf("Invalid topology events: %v", err)
+				continue
+			}
+			for _, e := range events {
+				if !filter.match(e) {
+					continue
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
 	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/integration/jira"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
@@ -214,6 +215,26 @@ func NewValidator() (*validator.Validate, ut.Translator, error) {
 				return t
 			},
 		},
+		{
+			tag: "asset_tag",
+			customRegisFunc: func(ut ut.Translator) error {
+				return ut.Add("asset_tag", "{0}:should only contain alphabets, numbers and _.:/=@+- characters", true)
+			},
+			customTransFunc: func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("asset_tag", utils.ToSnakeCase(fe.Field()))
+				return t
+			},
+		},
+		{
+			tag: "scan_profile_path",
+			customRegisFunc: func(ut ut.Translator) error {
+				return ut.Add("scan_profile_path", "{0}:should be an absolute path, only ending with /** as a glob", true)
+			},
+			customTransFunc: func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("scan_profile_path", utils.ToSnakeCase(fe.Field()))
+				return t
+			},
+		},
 		{
 			tag: "jira_auth_key",
 			customRegisFunc: func(ut ut.Translator) error {
@@ -266,6 +287,14 @@ func NewValidator() (*validator.Validate, ut.Translator, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	err = apiValidator.RegisterValidation("asset_tag", ValidateAssetTag)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = apiValidator.RegisterValidation("scan_profile_path", ValidateScanProfilePath)
+	if err != nil {
+		return nil, nil, err
+	}
 	return apiValidator, trans, nil
 }
 
@@ -333,6 +362,20 @@ func ValidateAPIToken(fl validator.FieldLevel) bool {
 	return APITokenRegex.MatchString(fl.Field().String())
 }
 
+func ValidateAssetTag(fl validator.FieldLevel) bool {
+	return model.AssetTagRegex.MatchString(fl.Field().String())
+}
+
+// ValidateScanProfilePath only accepts the paths the scanners of the agents
+// can exclude, which are path prefixes
+func ValidateScanProfilePath(fl validator.FieldLevel) bool {
+	prefix := strings.TrimSuffix(fl.Field().String(), "/**")
+	if prefix == "" {
+		return fl.Field().String() == "/**"
+	}
+	return strings.HasPrefix(prefix, "/") && !strings.ContainsAny(prefix, "*?[")
+}
+
 func ValidatePassword(fl validator.FieldLevel) bool {
 	var (
 		isUpper       bool
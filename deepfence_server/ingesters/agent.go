@@ -186,6 +186,7 @@ type neo4jIngester struct {
 	numIngested     atomic.Int32
 	numReceived     atomic.Int32
 	numProcessed    atomic.Int32
+	topologyEvents  *topologyEventsPublisher
 }
 
 type ReportIngestionData struct {
@@ -195,6 +196,8 @@ type ReportIngestionData struct {
 	PodBatch               []map[string]interface{} `json:"pod_batch" required:"true"`
 	ContainerImageBatch    []map[string]interface{} `json:"container_image_batch" required:"true"`
 	KubernetesClusterBatch []map[string]interface{} `json:"kubernetes_cluster_batch" required:"true"`
+	RuntimeEventBatch      []map[string]interface{} `json:"runtime_event_batch" required:"true"`
+	RoleBindingBatch       []map[string]interface{} `json:"role_binding_batch" required:"true"`
 
 	ProcessEdgesBatch          []map[string]interface{} `json:"process_edges_batch" required:"true"`
 	ContainerEdgesBatch        []map[string]interface{} `json:"container_edges_batch" required:"true"`
@@ -202,8 +205,12 @@ type ReportIngestionData struct {
 	PodEdgesBatch              []map[string]interface{} `json:"pod_edges_batch" required:"true"`
 	PodHostEdgesBatch          []map[string]interface{} `json:"pod_host_edges_batch" required:"true"`
 	EndpointEdgesBatch         []map[string]interface{} `json:"endpoint_edges_batch" required:"true"`
+	LocalEndpointEdgesBatch    []map[string]interface{} `json:"local_endpoint_edges_batch" required:"true"`
 	ContainerImageEdgeBatch    []map[string]interface{} `json:"container_image_edge_batch" required:"true"`
 	KubernetesClusterEdgeBatch []map[string]interface{} `json:"kubernetes_cluster_edge_batch" required:"true"`
+	RuntimeEventEdgesBatch     []map[string]interface{} `json:"runtime_event_edges_batch" required:"true"`
+	ContainerRuntimeEdgesBatch []map[string]interface{} `json:"container_runtime_edges_batch" required:"true"`
+	RoleBindingEdgesBatch      []map[string]interface{} `json:"role_binding_edges_batch" required:"true"`
 
 	// Endpoint_batch []map[string]string
 	// Endpoint_edges []map[string]string
@@ -250,8 +257,14 @@ func mergeIngestionData(other []ReportIngestionData) ReportIngestionData {
 	sizeContainerEdgesBatch := 0
 	sizePodEdgesBatch := 0
 	sizeEndpointEdgesBatch := 0
+	sizeLocalEndpointEdgesBatch := 0
 	sizeContainerImageEdgeBatch := 0
 	sizeKubernetesClusterEdgeBatch := 0
+	sizeRuntimeEventBatch := 0
+	sizeRuntimeEventEdgesBatch := 0
+	sizeContainerRuntimeEdgesBatch := 0
+	sizeRoleBindingBatch := 0
+	sizeRoleBindingEdgesBatch := 0
 	sizeHosts := 0
 
 	for i := range other {
@@ -266,8 +279,14 @@ func mergeIngestionData(other []ReportIngestionData) ReportIngestionData {
 		sizeContainerEdgesBatch += len(other[i].ContainerEdgesBatch)
 		sizePodEdgesBatch += len(other[i].PodEdgesBatch)
 		sizeEndpointEdgesBatch += len(other[i].EndpointEdgesBatch)
+		sizeLocalEndpointEdgesBatch += len(other[i].LocalEndpointEdgesBatch)
 		sizeContainerImageEdgeBatch += len(other[i].ContainerImageEdgeBatch)
 		sizeKubernetesClusterEdgeBatch += len(other[i].KubernetesClusterEdgeBatch)
+		sizeRuntimeEventBatch += len(other[i].RuntimeEventBatch)
+		sizeRuntimeEventEdgesBatch += len(other[i].RuntimeEventEdgesBatch)
+		sizeContainerRuntimeEdgesBatch += len(other[i].ContainerRuntimeEdgesBatch)
+		sizeRoleBindingBatch += len(other[i].RoleBindingBatch)
+		sizeRoleBindingEdgesBatch += len(other[i].RoleBindingEdgesBatch)
 		sizeHosts += len(other[i].HostBatch)
 	}
 
@@ -282,8 +301,14 @@ func mergeIngestionData(other []ReportIngestionData) ReportIngestionData {
 	containerEdgesBatch := make([]map[string]interface{}, 0, sizeContainerEdgesBatch)
 	podEdgesBatch := make([]map[string]interface{}, 0, sizePodEdgesBatch)
 	endpointEdgesBatch := make([]map[string]interface{}, 0, sizeEndpointEdgesBatch)
+	localEndpointEdgesBatch := make([]map[string]interface{}, 0, sizeLocalEndpointEdgesBatch)
 	containerImageEdgeBatch := make([]map[string]interface{}, 0, sizeContainerImageEdgeBatch)
 	kubernetesClusterEdgeBatch := make([]map[string]interface{}, 0, sizeKubernetesClusterEdgeBatch)
+	runtimeEventBatch := make([]map[string]interface{}, 0, sizeRuntimeEventBatch)
+	runtimeEventEdgesBatch := make([]map[string]interface{}, 0, sizeRuntimeEventEdgesBatch)
+	containerRuntimeEdgesBatch := make([]map[string]interface{}, 0, sizeContainerRuntimeEdgesBatch)
+	roleBindingBatch := make([]map[string]interface{}, 0, sizeRoleBindingBatch)
+	roleBindingEdgesBatch := make([]map[string]interface{}, 0, sizeRoleBindingEdgesBatch)
 	hosts := make([]map[string]interface{}, 0, sizeHosts)
 
 	for i := range other {
@@ -298,8 +323,14 @@ func mergeIngestionData(other []ReportIngestionData) ReportIngestionData {
 		containerEdgesBatch = append(containerEdgesBatch, other[i].ContainerEdgesBatch...)
 		podEdgesBatch = append(podEdgesBatch, other[i].PodEdgesBatch...)
 		endpointEdgesBatch = append(endpointEdgesBatch, other[i].EndpointEdgesBatch...)
+		localEndpointEdgesBatch = append(localEndpointEdgesBatch, other[i].LocalEndpointEdgesBatch...)
 		containerImageEdgeBatch = append(containerImageEdgeBatch, other[i].ContainerImageEdgeBatch...)
 		kubernetesClusterEdgeBatch = append(kubernetesClusterEdgeBatch, other[i].KubernetesClusterEdgeBatch...)
+		runtimeEventBatch = append(runtimeEventBatch, other[i].RuntimeEventBatch...)
+		runtimeEventEdgesBatch = append(runtimeEventEdgesBatch, other[i].RuntimeEventEdgesBatch...)
+		containerRuntimeEdgesBatch = append(containerRuntimeEdgesBatch, other[i].ContainerRuntimeEdgesBatch...)
+		roleBindingBatch = append(roleBindingBatch, other[i].RoleBindingBatch...)
+		roleBindingEdgesBatch = append(roleBindingEdgesBatch, other[i].RoleBindingEdgesBatch...)
 		// nd.Endpoint_batch = append(nd.Endpoint_batch, other[i].Endpoint_batch...)
 		// nd.Endpoint_edges = append(nd.Endpoint_edges, other[i].Endpoint_edges...)
 		hosts = append(hosts, other[i].Hosts...)
@@ -317,8 +348,14 @@ func mergeIngestionData(other []ReportIngestionData) ReportIngestionData {
 		ContainerEdgesBatch:        containerEdgesBatch,
 		PodEdgesBatch:              podEdgesBatch,
 		EndpointEdgesBatch:         endpointEdgesBatch,
+		LocalEndpointEdgesBatch:    localEndpointEdgesBatch,
 		ContainerImageEdgeBatch:    containerImageEdgeBatch,
 		KubernetesClusterEdgeBatch: kubernetesClusterEdgeBatch,
+		RuntimeEventBatch:          runtimeEventBatch,
+		RuntimeEventEdgesBatch:     runtimeEventEdgesBatch,
+		ContainerRuntimeEdgesBatch: containerRuntimeEdgesBatch,
+		RoleBindingBatch:           roleBindingBatch,
+		RoleBindingEdgesBatch:      roleBindingEdgesBatch,
 		Hosts:                      hosts,
 		NumMerged:                  len(other),
 	}
@@ -468,8 +505,14 @@ func NewReportIngestionData() ReportIngestionData {
 		PodEdgesBatch:              []map[string]interface{}{},
 		PodHostEdgesBatch:          []map[string]interface{}{},
 		EndpointEdgesBatch:         []map[string]interface{}{},
+		LocalEndpointEdgesBatch:    []map[string]interface{}{},
 		ContainerImageEdgeBatch:    []map[string]interface{}{},
 		KubernetesClusterEdgeBatch: []map[string]interface{}{},
+		RuntimeEventBatch:          []map[string]interface{}{},
+		RuntimeEventEdgesBatch:     []map[string]interface{}{},
+		ContainerRuntimeEdgesBatch: []map[string]interface{}{},
+		RoleBindingBatch:           []map[string]interface{}{},
+		RoleBindingEdgesBatch:      []map[string]interface{}{},
 		Hosts:                      []map[string]interface{}{},
 		NumMerged:                  1,
 	}
@@ -485,14 +528,20 @@ func prepareNeo4jIngestion(rpt *report.Report, resolvers *EndpointResolversCache
 		PodBatch:                   make([]map[string]interface{}, 0, len(rpt.Pod)),
 		ContainerImageBatch:        make([]map[string]interface{}, 0, len(rpt.ContainerImage)),
 		KubernetesClusterBatch:     make([]map[string]interface{}, 0, len(rpt.KubernetesCluster)),
+		RuntimeEventBatch:          make([]map[string]interface{}, 0, len(rpt.RuntimeEvent)),
+		RoleBindingBatch:           make([]map[string]interface{}, 0, len(rpt.RoleBinding)),
 		ProcessEdgesBatch:          nil,
 		ContainerEdgesBatch:        nil,
 		ContainerProcessEdgesBatch: nil,
 		PodEdgesBatch:              nil,
 		PodHostEdgesBatch:          nil,
 		EndpointEdgesBatch:         nil,
+		LocalEndpointEdgesBatch:    nil,
 		ContainerImageEdgeBatch:    nil,
 		KubernetesClusterEdgeBatch: nil,
+		RuntimeEventEdgesBatch:     nil,
+		ContainerRuntimeEdgesBatch: nil,
+		RoleBindingEdgesBatch:      nil,
 		NumMerged:                  1,
 	}
 
@@ -539,7 +588,12 @@ func prepareNeo4jIngestion(rpt *report.Report, resolvers *EndpointResolversCache
 
 	ttl := time.Now().Add(-mapTTL)
 	connections := []Connection{}
-	localMemoization := map[string]struct{}{}
+	// connections between the processes of a host, only the pod flows are
+	// derived from them
+	localConnections := []Connection{}
+	// local memoization of the hosts of the ips, skipping the sync.Map
+	// and redis lookups of the ips met again (91% reduction)
+	hostMemoization := map[string]string{}
 	for _, n := range rpt.Endpoint {
 		nodeIP, _ := extractIPPortFromEndpointID(n.Metadata.NodeID)
 		if nodeIP == localhostIP {
@@ -574,13 +628,25 @@ func prepareNeo4jIngestion(rpt *report.Report, resolvers *EndpointResolversCache
 				if n.Metadata.NodeID != i {
 					ip, port := extractIPPortFromEndpointID(i)
 					portint, _ := strconv.Atoi(port)
-					// local memoization is used to skip redis access (91% reduction)
-					if _, has := localMemoization[ip]; has {
-						continue
+					host, has := hostMemoization[ip]
+					if !has {
+						host, _ = resolvers.getHost(ip, ttl)
+						hostMemoization[ip] = host
 					}
-					if host, ok := resolvers.getHost(ip, ttl); ok {
+					if host != "" {
 						if n.Metadata.HostName == host {
-							localMemoization[ip] = struct{}{}
+							if pid == -1 {
+								continue
+							}
+							if rightIPPID, ok := resolvers.getIPPID(ip+port, ttl); ok {
+								localConnections = append(localConnections, Connection{
+									source:      host,
+									destination: host,
+									leftPID:     pid,
+									rightPID:    extractPidFromNodeID(rightIPPID),
+									localPort:   portint,
+								})
+							}
 							continue
 						}
 						rightIPPID, ok := resolvers.getIPPID(ip+port, ttl)
@@ -608,6 +674,7 @@ func prepareNeo4jIngestion(rpt *report.Report, resolvers *EndpointResolversCache
 		}
 	}
 	res.EndpointEdgesBatch = connections2maps(connections, buf)
+	res.LocalEndpointEdgesBatch = connections2maps(localConnections, buf)
 
 	processEdgesBatch := map[string][]string{}
 	containerProcessEdgesBatch := map[string][]string{}
@@ -656,11 +723,33 @@ func prepareNeo4jIngestion(rpt *report.Report, resolvers *EndpointResolversCache
 				n.Metadata.HostName = val
 			}
 		}
-		res.PodBatch = append(res.PodBatch, metadataToMap(n.Metadata))
+		res.PodBatch = append(res.PodBatch, podToMap(n.Metadata))
 		podEdgesBatch[n.Metadata.KubernetesClusterID] = append(podEdgesBatch[n.Metadata.KubernetesClusterID], n.Metadata.NodeID)
 		podHostEdgesBatch[n.Metadata.HostName] = append(podHostEdgesBatch[n.Metadata.HostName], n.Metadata.NodeID)
 	}
 
+	runtimeEventEdgesBatch := map[string][]string{}
+	containerRuntimeEdgesBatch := map[string][]string{}
+	for _, n := range rpt.RuntimeEvent {
+		if n.Metadata.HostName == "" {
+			continue
+		}
+		res.RuntimeEventBatch = append(res.RuntimeEventBatch, metadataToMap(n.Metadata))
+		runtimeEventEdgesBatch[n.Metadata.HostName] = append(runtimeEventEdgesBatch[n.Metadata.HostName], n.Metadata.NodeID)
+		if n.Parents != nil && len(n.Parents.Container) != 0 {
+			containerRuntimeEdgesBatch[n.Parents.Container] = append(containerRuntimeEdgesBatch[n.Parents.Container], n.Metadata.NodeID)
+		}
+	}
+
+	roleBindingEdgesBatch := map[string][]string{}
+	for _, n := range rpt.RoleBinding {
+		if n.Metadata.KubernetesClusterID == "" {
+			continue
+		}
+		res.RoleBindingBatch = append(res.RoleBindingBatch, metadataToMap(n.Metadata))
+		roleBindingEdgesBatch[n.Metadata.KubernetesClusterID] = append(roleBindingEdgesBatch[n.Metadata.KubernetesClusterID], n.Metadata.NodeID)
+	}
+
 	res.ProcessEdgesBatch = concatMaps(processEdgesBatch)
 	res.ContainerEdgesBatch = concatMaps(containerEdgesBatch)
 	res.ContainerProcessEdgesBatch = concatMaps(containerProcessEdgesBatch)
@@ -668,6 +757,9 @@ func prepareNeo4jIngestion(rpt *report.Report, resolvers *EndpointResolversCache
 	res.PodHostEdgesBatch = concatMaps(podHostEdgesBatch)
 	res.ContainerImageEdgeBatch = concatMaps(containerImageEdgesBatch)
 	res.KubernetesClusterEdgeBatch = concatMaps(kubernetesEdgesBatch)
+	res.RuntimeEventEdgesBatch = concatMaps(runtimeEventEdgesBatch)
+	res.ContainerRuntimeEdgesBatch = concatMaps(containerRuntimeEdgesBatch)
+	res.RoleBindingEdgesBatch = concatMaps(roleBindingEdgesBatch)
 
 	return res
 }
@@ -757,6 +849,19 @@ func (nc *neo4jIngester) PushToDBSeq(batches ReportIngestionData, session neo4j.
 		return err
 	}
 
+	if _, err := tx.Run(`
+		UNWIND $batch as row
+		MATCH (n:Node{node_id: row.source})
+		MERGE (n)-[r:LOCAL_CONNECTS]->(n)
+		WITH n, r, row.pids as rpids
+		UNWIND rpids as pids
+		SET r.left_pids = coalesce(r.left_pids, []) + pids.left,
+		    r.right_pids = coalesce(r.right_pids, []) + pids.right,
+			r.local_ports = coalesce(r.local_ports, []) + pids.local_port`,
+		map[string]interface{}{"batch": batches.LocalEndpointEdgesBatch}); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -840,7 +945,8 @@ func (nc *neo4jIngester) PushToDB(batches ReportIngestionData, session neo4j.Ses
 		MATCH (n:Node{node_id: row.node_id})
 		OPTIONAL MATCH (n:Node{node_id: 'in-the-internet'}) -[ri:CONNECTS]-> (n)
 		OPTIONAL MATCH (n) -[r:CONNECTS]-> (:Node)
-		DELETE r, ri`,
+		OPTIONAL MATCH (n) -[l:LOCAL_CONNECTS]-> (n)
+		DELETE r, ri, l`,
 		map[string]interface{}{"batch": batches.Hosts}); err != nil {
 		return err
 	}
@@ -900,6 +1006,55 @@ func (nc *neo4jIngester) PushToDB(batches ReportIngestionData, session neo4j.Ses
 		return err
 	}
 
+	if _, err := tx.Run(`
+		UNWIND $batch as row
+		MERGE (n:RuntimeEvent{node_id:row.node_id})
+		SET n+= row, n.updated_at = TIMESTAMP()`,
+		map[string]interface{}{"batch": batches.RuntimeEventBatch}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Run(`
+		UNWIND $batch as row
+		MATCH (n:Node{node_id: row.source})
+		WITH n, row
+		UNWIND row.destinations as dest
+		MATCH (m:RuntimeEvent{node_id: dest})
+		MERGE (n)-[:RECORDED]->(m)`,
+		map[string]interface{}{"batch": batches.RuntimeEventEdgesBatch}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Run(`
+		UNWIND $batch as row
+		MATCH (n:Container{node_id: row.source})
+		WITH n, row
+		UNWIND row.destinations as dest
+		MATCH (m:RuntimeEvent{node_id: dest})
+		MERGE (n)-[:RECORDED]->(m)`,
+		map[string]interface{}{"batch": batches.ContainerRuntimeEdgesBatch}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Run(`
+		UNWIND $batch as row
+		MERGE (n:KubernetesRoleBinding{node_id:row.node_id})
+		SET n+= row, n.updated_at = TIMESTAMP(), n.active = true`,
+		map[string]interface{}{"batch": batches.RoleBindingBatch}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Run(`
+		UNWIND $batch as row
+		MATCH (n:KubernetesCluster{node_id: row.source})
+		WITH n, row
+		UNWIND row.destinations as dest
+		MATCH (m:KubernetesRoleBinding{node_id: dest})
+		MERGE (n)-[:HOSTS]->(m)`,
+		map[string]interface{}{"batch": batches.RoleBindingEdgesBatch}); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -912,6 +1067,12 @@ func (nc *neo4jIngester) runIngester() {
 			continue
 		}
 		crpt.Cleanup()
+		if crpt.Check != nil {
+			if err := crpt.Check(&rpt); err != nil {
+				log.Warn().Msgf("Report rejected: %v", err)
+				continue
+			}
+		}
 		nc.preparersInput <- rpt
 	}
 	log.Info().Msgf("runIngester ended")
@@ -1007,6 +1168,9 @@ func (nc *neo4jIngester) runDBPusher(
 			span.End()
 			if dbPusherSeq == nil {
 				nc.numIngested.Add(int32(batches.NumMerged))
+				if nc.topologyEvents != nil {
+					nc.topologyEvents.publish(batches)
+				}
 			} else {
 				dbPusherSeq <- batches
 			}
@@ -1062,6 +1226,11 @@ func NewNeo4jCollector(ctx context.Context) (Ingester[report.CompressedReport],
 		numProcessed:    atomic.Int32{},
 	}
 
+	nc.topologyEvents, err = newTopologyEventsPublisher(collectorCtx, done)
+	if err != nil {
+		log.Warn().Msgf("Topology events disabled: %v", err)
+	}
+
 	for i := 0; i < uncompressWorkersNum; i++ {
 		go nc.runIngester()
 	}
@@ -1238,6 +1407,27 @@ func metadataToMap(n report.Metadata) map[string]interface{} {
 	return utils.StructToMap(n)
 }
 
+// pod security context fields are omitted from reports when unset,
+// they are reset explicitly so that fixed pods do not keep stale values
+var podSecurityDefaults = map[string]interface{}{
+	"kubernetes_privileged":      false,
+	"kubernetes_host_pid":        false,
+	"kubernetes_host_paths":      []string{},
+	"kubernetes_capabilities":    []string{},
+	"kubernetes_run_as_root":     false,
+	"kubernetes_automount_token": false,
+}
+
+func podToMap(n report.Metadata) map[string]interface{} {
+	res := metadataToMap(n)
+	for k, v := range podSecurityDefaults {
+		if _, has := res[k]; !has {
+			res[k] = v
+		}
+	}
+	return res
+}
+
 // TODO: improve syncro across multiple servers
 func UpdatePushBack(ctx context.Context, newValue *atomic.Int32, prev int32) error {
 
@@ -0,0 +1,46 @@
+package ingesters
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/scope/report"
+	"gotest.tools/assert"
+)
+
+func TestPrepareNeo4jIngestionLocalConnections(t *testing.T) {
+	rpt := report.MakeReport()
+	for id, container := range map[string]string{"host-1;100": "frontend", "host-1;200": "backend"} {
+		rpt.Process.AddNode(report.TopologyNode{
+			Metadata: report.Metadata{NodeID: id, HostName: "host-1"},
+			Parents:  &report.Parent{Container: container},
+		})
+	}
+	rpt.Endpoint.AddNode(report.TopologyNode{
+		Metadata:  report.Metadata{NodeID: ";10.0.0.5;43210", HostName: "host-1", Pid: 100},
+		Adjacency: report.MakeIDList(";10.0.0.6;8080", ";10.1.0.9;443"),
+	})
+
+	resolvers := &EndpointResolversCache{}
+	now := time.Now()
+	resolvers.netCache.Store("10.0.0.6", CacheEntry{value: "host-1", lastUpdated: now})
+	resolvers.netCache.Store("10.1.0.9", CacheEntry{value: "host-2", lastUpdated: now})
+	resolvers.pidCache.Store("10.0.0.68080", CacheEntry{value: "10.0.0.6;200", lastUpdated: now})
+	resolvers.pidCache.Store("10.1.0.9443", CacheEntry{value: "10.1.0.9;300", lastUpdated: now})
+
+	res := prepareNeo4jIngestion(&rpt, resolvers, &bytes.Buffer{})
+
+	// pods of a same node connect to each other, the flows are derived from
+	// the local edges
+	assert.Equal(t, len(res.LocalEndpointEdgesBatch), 1)
+	local := res.LocalEndpointEdgesBatch[0]
+	assert.Equal(t, local["source"], "host-1")
+	assert.Equal(t, local["destination"], "host-1")
+	assert.DeepEqual(t, local["pids"], []map[string]int{{"left": 100, "right": 200, "local_port": 8080}})
+
+	assert.Equal(t, len(res.EndpointEdgesBatch), 1)
+	remote := res.EndpointEdgesBatch[0]
+	assert.Equal(t, remote["source"], "host-1")
+	assert.Equal(t, remote["destination"], "host-2")
+}
@@ -0,0 +1,361 @@
+package ingesters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+const (
+	// fingerprint of nodes known before the ingester started, their
+	// first report only records the fingerprint
+	unknownFingerprint = 0
+	maxTrackedNodes    = 500_000
+	publishTimeout     = 5 * time.Second
+	// batches waiting to be compared, the DB pushers do not wait for them
+	topologyEventsQueueSize = 64
+	// nodes not reported for that long are forgotten, in case their
+	// removal was missed
+	topologyNodeTTL       = time.Hour
+	topologyPruneInterval = 5 * time.Minute
+)
+
+// metadata changing on every report, ignored to detect node updates
+var volatileNodeFields = map[string]struct{}{
+	"timestamp":                    {},
+	"uptime":                       {},
+	"cpu_max":                      {},
+	"cpu_usage":                    {},
+	"memory_max":                   {},
+	"memory_usage":                 {},
+	"open_files_count":             {},
+	"open_files":                   {},
+	"threads":                      {},
+	"connection_count":             {},
+	"docker_container_state_human": {},
+}
+
+// topologyEventsPublisher compares the committed batches with the
+// previously committed ones and publishes the node and connection
+// changes on the redis channel of the namespace. The batches are queued by
+// the DB pushers and compared by a single goroutine, which also forgets the
+// nodes removed by the clean up of the worker, published on the same channel.
+type topologyEventsPublisher struct {
+	rdb     *redis2.Client
+	channel string
+	batches chan ReportIngestionData
+	nodes   map[string]trackedNode
+	// connections by reporting host
+	connections map[string]map[string]struct{}
+}
+
+type trackedNode struct {
+	fingerprint uint64
+	seen        int64
+}
+
+func newTopologyEventsPublisher(ctx context.Context, done <-chan struct{}) (*topologyEventsPublisher, error) {
+	ns, err := directory.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rdb, err := directory.RedisClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &topologyEventsPublisher{
+		rdb:         rdb,
+		channel:     utils.TopologyEventsChannel(string(ns)),
+		batches:     make(chan ReportIngestionData, topologyEventsQueueSize),
+		nodes:       map[string]trackedNode{},
+		connections: map[string]map[string]struct{}{},
+	}
+	if err := p.preload(ctx); err != nil {
+		log.Warn().Msgf("Failed to preload topology: %v", err)
+	}
+	go p.run(ctx, done)
+	return p, nil
+}
+
+// preload loads the active nodes and connections so that a restart of
+// the console is not reported as new nodes
+func (p *topologyEventsPublisher) preload(ctx context.Context) error {
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	res, err := session.Run(`
+		MATCH (n)
+		WHERE (n:Node OR n:Container OR n:Pod OR n:KubernetesCluster)
+		AND n.active = true
+		RETURN n.node_id`,
+		map[string]interface{}{}, neo4j.WithTxTimeout(30*time.Second))
+	if err != nil {
+		return err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	for _, rec := range recs {
+		if id, ok := rec.Values[0].(string); ok {
+			p.nodes[id] = trackedNode{fingerprint: unknownFingerprint, seen: now}
+		}
+	}
+
+	res, err = session.Run(`
+		MATCH (n:Node) -[:CONNECTS]-> (m:Node)
+		RETURN n.node_id, m.node_id`,
+		map[string]interface{}{}, neo4j.WithTxTimeout(30*time.Second))
+	if err != nil {
+		return err
+	}
+	recs, err = res.Collect()
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		source, _ := rec.Values[0].(string)
+		target, _ := rec.Values[1].(string)
+		p.addConnection(source, target)
+	}
+	return nil
+}
+
+func connectionOwner(source, target string) string {
+	if source == "in-the-internet" {
+		return target
+	}
+	return source
+}
+
+func connectionKey(source, target string) string {
+	return source + ";;;" + target
+}
+
+func (p *topologyEventsPublisher) addConnection(source, target string) bool {
+	owner := connectionOwner(source, target)
+	conns, has := p.connections[owner]
+	if !has {
+		conns = map[string]struct{}{}
+		p.connections[owner] = conns
+	}
+	key := connectionKey(source, target)
+	if _, has := conns[key]; has {
+		return false
+	}
+	conns[key] = struct{}{}
+	return true
+}
+
+func nodeFingerprint(node map[string]interface{}) uint64 {
+	keys := make([]string, 0, len(node))
+	for k := range node {
+		if _, has := volatileNodeFields[k]; has {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%v;", k, node[k])
+	}
+	// never collide with the unknown fingerprint
+	return h.Sum64() | 1
+}
+
+func (p *topologyEventsPublisher) nodeEvents(batch []map[string]interface{}, nodeType string, now int64) []utils.TopologyEvent {
+	events := []utils.TopologyEvent{}
+	for _, node := range batch {
+		id, _ := node["node_id"].(string)
+		if id == "" {
+			continue
+		}
+		fingerprint := nodeFingerprint(node)
+		previous, has := p.nodes[id]
+		p.nodes[id] = trackedNode{fingerprint: fingerprint, seen: now}
+
+		var eventType string
+		switch {
+		case !has:
+			eventType = utils.TopologyNodeAdded
+		case previous.fingerprint == unknownFingerprint || previous.fingerprint == fingerprint:
+			continue
+		default:
+			eventType = utils.TopologyNodeUpdated
+		}
+		hostName, _ := node["host_name"].(string)
+		clusterID, _ := node["kubernetes_cluster_id"].(string)
+		events = append(events, utils.TopologyEvent{
+			Type:                eventType,
+			NodeID:              id,
+			NodeType:            nodeType,
+			HostName:            hostName,
+			KubernetesClusterID: clusterID,
+			Timestamp:           now,
+		})
+	}
+	return events
+}
+
+func (p *topologyEventsPublisher) connectionEvents(batches ReportIngestionData, now int64) []utils.TopologyEvent {
+	events := []utils.TopologyEvent{}
+
+	current := map[string]map[string][2]string{}
+	for _, host := range batches.Hosts {
+		if id, ok := host["node_id"].(string); ok {
+			current[id] = map[string][2]string{}
+		}
+	}
+	for _, edge := range batches.EndpointEdgesBatch {
+		source, _ := edge["source"].(string)
+		target, _ := edge["destination"].(string)
+		owner := connectionOwner(source, target)
+		if _, has := current[owner]; !has {
+			current[owner] = map[string][2]string{}
+		}
+		current[owner][connectionKey(source, target)] = [2]string{source, target}
+	}
+
+	for owner, conns := range current {
+		for _, st := range conns {
+			if p.addConnection(st[0], st[1]) {
+				events = append(events, utils.TopologyEvent{
+					Type:      utils.TopologyConnectionAdded,
+					HostName:  owner,
+					Source:    st[0],
+					Target:    st[1],
+					Timestamp: now,
+				})
+			}
+		}
+		for key := range p.connections[owner] {
+			if _, has := conns[key]; has {
+				continue
+			}
+			delete(p.connections[owner], key)
+			source, target, _ := strings.Cut(key, ";;;")
+			events = append(events, utils.TopologyEvent{
+				Type:      utils.TopologyConnectionRemoved,
+				HostName:  owner,
+				Source:    source,
+				Target:    target,
+				Timestamp: now,
+			})
+		}
+	}
+	return events
+}
+
+// publish queues the committed batches, dropped when the comparisons lag
+// behind
+func (p *topologyEventsPublisher) publish(batches ReportIngestionData) {
+	select {
+	case p.batches <- batches:
+	default:
+		log.Warn().Msgf("Topology events lagging, skip batches")
+	}
+}
+
+func (p *topologyEventsPublisher) run(ctx context.Context, done <-chan struct{}) {
+	sub := p.rdb.Subscribe(ctx, p.channel)
+	defer sub.Close()
+	removed := sub.Channel()
+
+	prune := time.NewTicker(topologyPruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case batches := <-p.batches:
+			p.publishBatches(batches)
+		case msg, ok := <-removed:
+			if !ok {
+				removed = nil
+				continue
+			}
+			var events []utils.TopologyEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &events); err != nil {
+				continue
+			}
+			p.removeNodes(events)
+		case <-prune.C:
+			p.prune(time.Now().Add(-topologyNodeTTL).UnixMilli())
+		case <-done:
+			return
+		}
+	}
+}
+
+// removeNodes forgets the nodes removed from the graph and the connections
+// of the removed hosts
+func (p *topologyEventsPublisher) removeNodes(events []utils.TopologyEvent) {
+	for _, event := range events {
+		if event.Type != utils.TopologyNodeRemoved {
+			continue
+		}
+		delete(p.nodes, event.NodeID)
+		delete(p.connections, event.NodeID)
+	}
+}
+
+// prune forgets the nodes not reported since before, and the connections of
+// the hosts not reported
+func (p *topologyEventsPublisher) prune(before int64) {
+	for id, node := range p.nodes {
+		if node.seen < before {
+			delete(p.nodes, id)
+		}
+	}
+	for owner := range p.connections {
+		if _, has := p.nodes[owner]; !has {
+			delete(p.connections, owner)
+		}
+	}
+}
+
+// publishBatches sends the changes of the committed batches to the
+// subscribers
+func (p *topologyEventsPublisher) publishBatches(batches ReportIngestionData) {
+	if len(p.nodes) > maxTrackedNodes {
+		log.Warn().Msgf("Too many tracked topology nodes, reset")
+		p.nodes = map[string]trackedNode{}
+	}
+
+	now := time.Now().UnixMilli()
+	events := []utils.TopologyEvent{}
+	events = append(events, p.nodeEvents(batches.KubernetesClusterBatch, utils.NodeTypeKubernetesCluster, now)...)
+	events = append(events, p.nodeEvents(batches.HostBatch, utils.NodeTypeHost, now)...)
+	events = append(events, p.nodeEvents(batches.PodBatch, utils.NodeTypePod, now)...)
+	events = append(events, p.nodeEvents(batches.ContainerBatch, utils.NodeTypeContainer, now)...)
+	events = append(events, p.connectionEvents(batches, now)...)
+	if len(events) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(events)
+	if err != nil {
+		log.Error().Msgf("Failed to marshal topology events: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := p.rdb.Publish(ctx, p.channel, payload).Err(); err != nil {
+		log.Error().Msgf("Failed to publish topology events: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package ingesters
+
+import (
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"gotest.tools/assert"
+)
+
+func TestTopologyEventsForgetRemovedNodes(t *testing.T) {
+	p := &topologyEventsPublisher{
+		nodes:       map[string]trackedNode{},
+		connections: map[string]map[string]struct{}{},
+	}
+	batches := ReportIngestionData{
+		HostBatch: []map[string]interface{}{
+			{"node_id": "host-1"},
+			{"node_id": "host-2"},
+		},
+		Hosts: []map[string]interface{}{
+			{"node_id": "host-1"},
+			{"node_id": "host-2"},
+		},
+		EndpointEdgesBatch: []map[string]interface{}{
+			{"source": "host-1", "destination": "host-2"},
+		},
+	}
+
+	events := p.nodeEvents(batches.HostBatch, utils.NodeTypeHost, 1000)
+	assert.Equal(t, len(events), 2)
+	events = p.connectionEvents(batches, 1000)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Type, utils.TopologyConnectionAdded)
+
+	p.removeNodes([]utils.TopologyEvent{
+		{Type: utils.TopologyNodeAdded, NodeID: "host-2"},
+		{Type: utils.TopologyNodeRemoved, NodeID: "host-1"},
+	})
+	_, has := p.nodes["host-1"]
+	assert.Assert(t, !has)
+	_, has = p.connections["host-1"]
+	assert.Assert(t, !has)
+	_, has = p.nodes["host-2"]
+	assert.Assert(t, has)
+
+	// a removed node coming back is added again
+	events = p.nodeEvents(batches.HostBatch[:1], utils.NodeTypeHost, 2000)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Type, utils.TopologyNodeAdded)
+
+	// the nodes not reported since are forgotten
+	p.prune(1500)
+	_, has = p.nodes["host-2"]
+	assert.Assert(t, !has)
+	_, has = p.nodes["host-1"]
+	assert.Assert(t, has)
+}
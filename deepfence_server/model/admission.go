@@ -0,0 +1,84 @@
+package model
+
+import (
+	"strings"
+)
+
+const (
+	dockerHubRegistry = "docker.io/"
+	dockerHubLibrary  = "library/"
+)
+
+type ImageScanStatusRequest struct {
+	Images []string `json:"images" validate:"required,min=1,max=100,dive,required" required:"true"`
+}
+
+type ImageScanSummary struct {
+	ScanID         string           `json:"scan_id" required:"true"`
+	NodeID         string           `json:"node_id" required:"true"`
+	UpdatedAt      int64            `json:"updated_at" required:"true"`
+	SeverityCounts map[string]int32 `json:"severity_counts" required:"true"`
+}
+
+type ImageScanStatus struct {
+	Image string `json:"image" required:"true"`
+	// Found is false when no image with this name and tag was reported
+	// by agents or registries
+	Found bool `json:"found" required:"true"`
+	// LatestScans holds the latest completed scan per scan type
+	// (vulnerability, secret, malware), missing if never scanned
+	LatestScans map[string]ImageScanSummary `json:"latest_scans" required:"true"`
+}
+
+// ImageReferenceDigest returns the digest, without algorithm, of an image
+// reference pinned by digest, else an empty string
+func ImageReferenceDigest(image string) string {
+	i := strings.Index(image, "@")
+	if i < 0 {
+		return ""
+	}
+	digest := strings.TrimSpace(image[i+1:])
+	if j := strings.Index(digest, ":"); j >= 0 {
+		digest = digest[j+1:]
+	}
+	return digest
+}
+
+// ImageReferenceCandidates lists the name:tag forms an image reference
+// can be stored as. Docker Hub references are expanded and shortened and
+// a missing tag defaults to latest. References pinned by digest have none,
+// the tag may point to another image: they are matched by digest only.
+func ImageReferenceCandidates(image string) []string {
+	image = strings.TrimSpace(image)
+	if strings.Contains(image, "@") {
+		return []string{}
+	}
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+
+	short := strings.TrimPrefix(name, dockerHubRegistry)
+	short = strings.TrimPrefix(short, dockerHubLibrary)
+	names := []string{name}
+	if !strings.Contains(short, "/") {
+		names = append(names, short, dockerHubLibrary+short, dockerHubRegistry+dockerHubLibrary+short)
+	} else if strings.HasPrefix(name, dockerHubRegistry) || !isRegistryHost(strings.Split(short, "/")[0]) {
+		names = append(names, short, dockerHubRegistry+short)
+	}
+
+	res := []string{}
+	seen := map[string]struct{}{}
+	for _, n := range names {
+		if _, has := seen[n]; has || n == "" {
+			continue
+		}
+		seen[n] = struct{}{}
+		res = append(res, n+":"+tag)
+	}
+	return res
+}
+
+func isRegistryHost(s string) bool {
+	return strings.ContainsAny(s, ".:") || s == "localhost"
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_image_reference_candidates(t *testing.T) {
+	assert.DeepEqual(t, ImageReferenceCandidates("nginx"), []string{
+		"nginx:latest", "library/nginx:latest", "docker.io/library/nginx:latest",
+	})
+	assert.DeepEqual(t, ImageReferenceCandidates("docker.io/library/nginx:1.25"), []string{
+		"docker.io/library/nginx:1.25", "nginx:1.25", "library/nginx:1.25",
+	})
+	assert.DeepEqual(t, ImageReferenceCandidates("docker.io/library/nginx:1.25@sha256:abcd"), []string{})
+	assert.DeepEqual(t, ImageReferenceCandidates("nginx@sha256:abcd"), []string{})
+	assert.DeepEqual(t, ImageReferenceCandidates("deepfenceio/agent:2.1"), []string{
+		"deepfenceio/agent:2.1", "docker.io/deepfenceio/agent:2.1",
+	})
+	assert.DeepEqual(t, ImageReferenceCandidates("localhost:5000/app"), []string{"localhost:5000/app:latest"})
+	assert.DeepEqual(t, ImageReferenceCandidates("ghcr.io/org/app:v1"), []string{"ghcr.io/org/app:v1"})
+}
+
+func Test_image_reference_digest(t *testing.T) {
+	assert.Equal(t, ImageReferenceDigest("nginx@sha256:abcd"), "abcd")
+	assert.Equal(t, ImageReferenceDigest("docker.io/library/nginx:1.25@sha256:abcd"), "abcd")
+	assert.Equal(t, ImageReferenceDigest("nginx:1.25"), "")
+}
@@ -8,8 +8,12 @@ type InitAgentReq struct {
 }
 
 type AgentID struct {
-	NodeID            string `json:"node_id" required:"true"`
-	AvailableWorkload int    `json:"available_workload" required:"true"`
+	NodeID            string             `json:"node_id" required:"true"`
+	AvailableWorkload int                `json:"available_workload" required:"true"`
+	Health            *AgentHealthReport `json:"health,omitempty"`
+	// CustomRulesVersion is the version of the custom rules the scanners of
+	// the agent run with, 0 when none
+	CustomRulesVersion *int64 `json:"custom_rules_version,omitempty"`
 }
 
 type AgentUpgrade struct {
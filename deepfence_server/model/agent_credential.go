@@ -0,0 +1,115 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+const (
+	// AgentRole is the role of the tokens issued for agent credentials, only
+	// allowed to poll controls and ingest reports
+	AgentRole                = "agent"
+	GrantTypeAgentCredential = "agent_credential"
+
+	AgentNodeIDClaim       = "agent_node_id"
+	AgentCredentialIDClaim = "agent_credential_id"
+)
+
+// AgentEnrollReq exchanges the API key of an agent for a credential bound to
+// its node. The enrollment key is the secret the agent generated on its first
+// enrollment, the node is bound to it.
+type AgentEnrollReq struct {
+	NodeID        string `json:"node_id" validate:"required" required:"true"`
+	EnrollmentKey string `json:"enrollment_key" validate:"required,min=32" required:"true"`
+}
+
+// AgentEnrollResp has no credential while the enrollment waits for the
+// approval of an admin
+type AgentEnrollResp struct {
+	Credential string `json:"credential"`
+	Pending    bool   `json:"pending" required:"true"`
+}
+
+// AgentEnrollment is an enrollment waiting for approval, of a node already
+// enrolled or with another enrollment key than the one it is bound to
+type AgentEnrollment struct {
+	NodeID      string `json:"node_id" required:"true"`
+	UserID      int64  `json:"user_id" required:"true"`
+	RequestedAt int64  `json:"requested_at" required:"true"`
+	Approved    bool   `json:"approved" required:"true"`
+}
+
+type AgentCredential struct {
+	ID        string `json:"id" required:"true"`
+	NodeID    string `json:"node_id" required:"true"`
+	UserID    int64  `json:"user_id" required:"true"`
+	CreatedAt int64  `json:"created_at" required:"true"`
+	LastUsed  int64  `json:"last_used" required:"true"`
+	Revoked   bool   `json:"revoked" required:"true"`
+	RevokedAt int64  `json:"revoked_at" required:"true"`
+}
+
+type ListAgentCredentialsResp struct {
+	Credentials        []AgentCredential `json:"credentials" required:"true"`
+	PendingEnrollments []AgentEnrollment `json:"pending_enrollments" required:"true"`
+}
+
+// AgentCredentialsReq revokes the credentials of the agents, which cannot
+// enroll again until reinstated
+type AgentCredentialsReq struct {
+	NodeIDs []string `json:"node_ids" validate:"required,min=1" required:"true"`
+}
+
+// HashEnrollmentKey is the form the enrollment keys are stored in
+func HashEnrollmentKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetAgentAccessToken issues tokens bound to the node of the credential, with
+// the agent role
+func (u *User) GetAgentAccessToken(tokenAuth *jwtauth.JWTAuth, cred AgentCredential) (*ResponseAccessToken, error) {
+	accessTokenID := utils.NewUUIDString()
+	claims := map[string]interface{}{
+		"id":                   accessTokenID,
+		"user_id":              u.ID,
+		"first_name":           u.FirstName,
+		"last_name":            u.LastName,
+		"role":                 AgentRole,
+		"role_id":              u.RoleID,
+		"company_id":           u.CompanyID,
+		"company":              u.Company,
+		"email":                u.Email,
+		"is_active":            u.IsActive,
+		"grant_type":           GrantTypeAgentCredential,
+		AgentNodeIDClaim:       cred.NodeID,
+		AgentCredentialIDClaim: cred.ID,
+		directory.NamespaceKey: u.CompanyNamespace,
+	}
+	jwtauth.SetIssuedNow(claims)
+	jwtauth.SetExpiryIn(claims, AccessTokenExpiry)
+	_, accessToken, err := tokenAuth.Encode(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshClaims := map[string]interface{}{
+		"token_id":             accessTokenID,
+		"user":                 u.ID,
+		"type":                 "refresh_token",
+		"grant_type":           GrantTypeAgentCredential,
+		AgentCredentialIDClaim: cred.ID,
+		directory.NamespaceKey: u.CompanyNamespace,
+	}
+	jwtauth.SetIssuedNow(refreshClaims)
+	jwtauth.SetExpiryIn(refreshClaims, RefreshTokenExpiry)
+	_, refreshToken, err := tokenAuth.Encode(refreshClaims)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseAccessToken{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
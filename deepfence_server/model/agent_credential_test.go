@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/go-chi/jwtauth/v5"
+	"gotest.tools/assert"
+)
+
+func TestGetAgentAccessToken(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	user := User{ID: 1, Role: AdminRole, CompanyNamespace: "default"}
+
+	res, err := user.GetAgentAccessToken(tokenAuth, AgentCredential{ID: "cred", NodeID: "host-1"})
+	assert.NilError(t, err)
+
+	token, err := tokenAuth.Decode(res.AccessToken)
+	assert.NilError(t, err)
+	claims := token.PrivateClaims()
+	assert.Equal(t, claims["role"], AgentRole)
+	assert.Equal(t, claims[AgentNodeIDClaim], "host-1")
+	assert.Equal(t, claims[AgentCredentialIDClaim], "cred")
+
+	token, err = tokenAuth.Decode(res.RefreshToken)
+	assert.NilError(t, err)
+	claims = token.PrivateClaims()
+	assert.Equal(t, claims["grant_type"], GrantTypeAgentCredential)
+	assert.Equal(t, claims[AgentCredentialIDClaim], "cred")
+}
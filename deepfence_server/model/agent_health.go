@@ -0,0 +1,77 @@
+package model
+
+const (
+	AgentHealthy  = "healthy"
+	AgentDegraded = "degraded"
+	AgentOffline  = "offline"
+
+	AgentPluginRunning = "running"
+	AgentPluginStopped = "stopped"
+	AgentPluginCrashed = "crashed"
+
+	AgentPluginHealthOK           = "ok"
+	AgentPluginHealthCrashLooping = "crash_looping"
+	AgentPluginHealthStuck        = "stuck"
+	AgentPluginHealthUnhealthy    = "unhealthy"
+
+	AgentPluginCheckNone    = "none"
+	AgentPluginCheckPassing = "passing"
+	AgentPluginCheckFailing = "failing"
+)
+
+// AgentPluginStatus is the state of a config.ini process as seen by the
+// bootstrapper. RecentRestarts only counts the restarts of the last 10
+// minutes. CrashReason and LastLogLines describe the last failure.
+type AgentPluginStatus struct {
+	Name           string   `json:"name" required:"true"`
+	Status         string   `json:"status" required:"true" enum:"running,stopped,crashed"`
+	Restarts       int      `json:"restarts" required:"true"`
+	RecentRestarts int      `json:"recent_restarts" required:"true"`
+	LastExitCode   int      `json:"last_exit_code" required:"true"`
+	HealthCheck    string   `json:"health_check" enum:"none,passing,failing"`
+	CrashReason    string   `json:"crash_reason"`
+	LastLogLines   []string `json:"last_log_lines"`
+	Since          int64    `json:"since" required:"true"`
+}
+
+type AgentCgroupUsage struct {
+	Name              string `json:"name" required:"true"`
+	CPUUsageMillicore int64  `json:"cpu_usage_millicore" required:"true"`
+	MemoryUsage       int64  `json:"memory_usage" required:"true"`
+	MemoryLimit       int64  `json:"memory_limit" required:"true"`
+}
+
+// AgentHealthReport is sent by the bootstrapper along with the controls polling
+type AgentHealthReport struct {
+	Plugins []AgentPluginStatus `json:"plugins" required:"true"`
+	Cgroups []AgentCgroupUsage  `json:"cgroups" required:"true"`
+}
+
+type AgentHealthReq struct {
+	NodeIDs  []string    `json:"node_ids" required:"true"`
+	Versions []string    `json:"versions" required:"true"`
+	Statuses []string    `json:"statuses" validate:"omitempty,dive,oneof=healthy degraded offline" required:"true" enum:"healthy,degraded,offline"`
+	Window   FetchWindow `json:"window" required:"true"`
+}
+
+type AgentPluginHealth struct {
+	AgentPluginStatus
+	Health string `json:"health" required:"true" enum:"ok,crash_looping,stuck,unhealthy"`
+}
+
+type AgentHealth struct {
+	NodeID              string              `json:"node_id" required:"true"`
+	NodeName            string              `json:"node_name" required:"true"`
+	Version             string              `json:"version" required:"true"`
+	Status              string              `json:"status" required:"true" enum:"healthy,degraded,offline"`
+	Issues              []string            `json:"issues" required:"true"`
+	LastHeartbeat       int64               `json:"last_heartbeat" required:"true"`
+	IngestionLagSeconds int64               `json:"ingestion_lag_seconds" required:"true"`
+	PendingActions      int64               `json:"pending_actions" required:"true"`
+	Plugins             []AgentPluginHealth `json:"plugins" required:"true"`
+	Cgroups             []AgentCgroupUsage  `json:"cgroups" required:"true"`
+}
+
+type AgentHealthResp struct {
+	Agents []AgentHealth `json:"agents" required:"true"`
+}
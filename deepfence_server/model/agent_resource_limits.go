@@ -0,0 +1,20 @@
+package model
+
+// AgentResourceLimitsReq pushes the resource profile of the plugins to the
+// agents of node_ids and of the kubernetes_cluster_ids. The agents of the
+// clusters joining later get the cluster profile. Empty limits remove the
+// profile.
+type AgentResourceLimitsReq struct {
+	NodeIDs              []string                    `json:"node_ids" required:"true"`
+	KubernetesClusterIDs []string                    `json:"kubernetes_cluster_ids" required:"true"`
+	Limits               []AgentPluginResourceLimits `json:"limits" validate:"dive" required:"true"`
+}
+
+type AgentPluginResourceLimits struct {
+	PluginName        string `json:"plugin_name" validate:"required" required:"true"`
+	CPUPercentage     int    `json:"cpu_percentage" validate:"min=0,max=100"`
+	MemoryMB          int64  `json:"memory_mb" validate:"min=0"`
+	IOWeight          int    `json:"io_weight" validate:"min=0,max=10000"`
+	ScanCPUPercentage int    `json:"scan_cpu_percentage" validate:"min=0,max=100"`
+	ScanMemoryMB      int64  `json:"scan_memory_mb" validate:"min=0"`
+}
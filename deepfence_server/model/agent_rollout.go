@@ -0,0 +1,62 @@
+package model
+
+const (
+	AgentRolloutRunning    = "running"
+	AgentRolloutCompleted  = "completed"
+	AgentRolloutRolledBack = "rolled_back"
+	AgentRolloutCancelled  = "cancelled"
+
+	AgentRolloutNodePending    = "pending"
+	AgentRolloutNodeUpgrading  = "upgrading"
+	AgentRolloutNodeHealthy    = "healthy"
+	AgentRolloutNodeFailed     = "failed"
+	AgentRolloutNodeRolledBack = "rolled_back"
+	AgentRolloutNodeSkipped    = "skipped"
+)
+
+// AgentRolloutRequest upgrades the agents of node_ids in waves. The first
+// wave is the canary group, either the named canary_node_ids or
+// canary_percentage of the nodes, the others hold wave_percentage of the
+// nodes each. A wave starts once the previous one reported healthy for
+// soak_period_minutes, the upgraded agents are rolled back when more than
+// max_failure_percentage of a wave fails. Zero values use the defaults.
+// Nodes inactive before getting the upgrade are skipped.
+type AgentRolloutRequest struct {
+	Version              string   `json:"version" validate:"required" required:"true"`
+	NodeIDs              []string `json:"node_ids" validate:"required,min=1" required:"true"`
+	CanaryNodeIDs        []string `json:"canary_node_ids"`
+	CanaryPercentage     int      `json:"canary_percentage" validate:"min=0,max=100"`
+	WavePercentage       int      `json:"wave_percentage" validate:"min=0,max=100"`
+	SoakPeriodMinutes    int      `json:"soak_period_minutes" validate:"min=0,max=10080"`
+	MaxFailurePercentage int      `json:"max_failure_percentage" validate:"min=0,max=100"`
+}
+
+type AgentRolloutIDReq struct {
+	RolloutID string `json:"rollout_id" validate:"required" required:"true"`
+}
+
+type AgentRolloutNode struct {
+	NodeID          string `json:"node_id" required:"true"`
+	Wave            int    `json:"wave" required:"true"`
+	PreviousVersion string `json:"previous_version" required:"true"`
+	Status          string `json:"status" required:"true" enum:"pending,upgrading,healthy,failed,rolled_back,skipped"`
+}
+
+type AgentRollout struct {
+	RolloutID            string             `json:"rollout_id" required:"true"`
+	Version              string             `json:"version" required:"true"`
+	Status               string             `json:"status" required:"true" enum:"running,completed,rolled_back,cancelled"`
+	StatusMessage        string             `json:"status_message" required:"true"`
+	CurrentWave          int                `json:"current_wave" required:"true"`
+	TotalWaves           int                `json:"total_waves" required:"true"`
+	SoakPeriodMinutes    int                `json:"soak_period_minutes" required:"true"`
+	MaxFailurePercentage int                `json:"max_failure_percentage" required:"true"`
+	WaveStartedAt        int64              `json:"wave_started_at" required:"true"`
+	CreatedAt            int64              `json:"created_at" required:"true"`
+	UpdatedAt            int64              `json:"updated_at" required:"true"`
+	Nodes                []AgentRolloutNode `json:"nodes" required:"true"`
+}
+
+type ListAgentRolloutsResp struct {
+	Rollouts []AgentRollout `json:"rollouts" required:"true"`
+}
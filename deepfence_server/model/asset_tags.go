@@ -0,0 +1,339 @@
+package model
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/reporters"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const (
+	AssetTagsSourceUser    = "user"
+	AssetTagsSourceDerived = "derived"
+
+	assetTagsCSVSeparator = ";"
+)
+
+var (
+	AssetTagRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.:/=@+-]{0,127}$`)
+
+	// neo4j labels of the nodes accepting asset tags
+	AssetTagsNodeTypes = map[string]string{
+		"host":            "Node",
+		"container":       "Container",
+		"image":           "ContainerImage",
+		"container_image": "ContainerImage",
+		"pod":             "Pod",
+		"cluster":         "KubernetesCluster",
+		"cloud_resource":  "CloudResource",
+	}
+
+	assetTagsCSVHeader = []string{"node_id", "node_type", "owner", "asset_criticality", "user_tags"}
+
+	// kubernetes labels and cloud tags used to derive the asset tags,
+	// lower cased as cloud tags are usually capitalized
+	assetOwnerKeys       = []string{"owner", "team", "app.kubernetes.io/owner", "app.kubernetes.io/team"}
+	assetCriticalityKeys = []string{"criticality", "asset-criticality", "asset_criticality", "deepfence.io/criticality"}
+	assetTagKeys         = []string{"env", "environment", "tier", "app.kubernetes.io/part-of"}
+)
+
+type AssetNode struct {
+	NodeID   string `json:"node_id" validate:"required" required:"true"`
+	NodeType string `json:"node_type" validate:"required,oneof=host container image container_image pod cluster cloud_resource" required:"true" enum:"host,container,image,container_image,pod,cluster,cloud_resource"`
+}
+
+type AssetTags struct {
+	AssetNode
+	UserTags         []string `json:"user_tags"`
+	Owner            string   `json:"owner"`
+	AssetCriticality string   `json:"asset_criticality"`
+}
+
+// AssetTagsUpdateRequest replaces the asset tags of the nodes, empty
+// values clear them
+type AssetTagsUpdateRequest struct {
+	Nodes            []AssetNode `json:"nodes" validate:"required,min=1,dive" required:"true"`
+	UserTags         []string    `json:"user_tags" validate:"omitempty,max=32,dive,asset_tag"`
+	Owner            string      `json:"owner" validate:"omitempty,asset_tag"`
+	AssetCriticality string      `json:"asset_criticality" validate:"omitempty,oneof=critical high medium low" enum:"critical,high,medium,low"`
+}
+
+type AssetTagsImportRequest struct {
+	CSV multipart.File `formData:"csv" json:"csv" validate:"required" required:"true"`
+}
+
+type AssetTagsUpdateResponse struct {
+	UpdatedCount int      `json:"updated_count" required:"true"`
+	NotFound     []string `json:"not_found" required:"true"`
+}
+
+// AssetFilter selects the nodes by their asset tags
+type AssetFilter struct {
+	UserTags         []string `json:"user_tags"`
+	Owners           []string `json:"owners"`
+	AssetCriticality []string `json:"asset_criticality" validate:"omitempty,dive,oneof=critical high medium low" enum:"critical,high,medium,low"`
+}
+
+func (f AssetFilter) IsEmpty() bool {
+	return len(f.UserTags) == 0 && len(f.Owners) == 0 && len(f.AssetCriticality) == 0
+}
+
+// Apply adds the asset tags conditions to the node filters
+func (f AssetFilter) Apply(filters *reporters.FieldsFilters) {
+	if len(f.UserTags) > 0 {
+		if filters.ContainsInArrayFilter.FieldsValues == nil {
+			filters.ContainsInArrayFilter.FieldsValues = map[string][]interface{}{}
+		}
+		filters.ContainsInArrayFilter.FieldsValues["user_tags"] = toInterfaces(f.UserTags)
+	}
+	if len(f.Owners) > 0 || len(f.AssetCriticality) > 0 {
+		if filters.ContainsFilter.FieldsValues == nil {
+			filters.ContainsFilter.FieldsValues = map[string][]interface{}{}
+		}
+		if len(f.Owners) > 0 {
+			filters.ContainsFilter.FieldsValues["owner"] = toInterfaces(f.Owners)
+		}
+		if len(f.AssetCriticality) > 0 {
+			filters.ContainsFilter.FieldsValues["asset_criticality"] = toInterfaces(f.AssetCriticality)
+		}
+	}
+}
+
+func toInterfaces(values []string) []interface{} {
+	res := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		res = append(res, v)
+	}
+	return res
+}
+
+func (r AssetTagsUpdateRequest) AssetTags() []AssetTags {
+	tags := make([]AssetTags, 0, len(r.Nodes))
+	for _, n := range r.Nodes {
+		tags = append(tags, AssetTags{
+			AssetNode:        n,
+			UserTags:         r.UserTags,
+			Owner:            r.Owner,
+			AssetCriticality: r.AssetCriticality,
+		})
+	}
+	return tags
+}
+
+func validateAssetTags(t AssetTags) error {
+	if t.NodeID == "" {
+		return errors.New("missing node_id")
+	}
+	if _, has := AssetTagsNodeTypes[t.NodeType]; !has {
+		return fmt.Errorf("invalid node_type %q", t.NodeType)
+	}
+	if t.Owner != "" && !AssetTagRegex.MatchString(t.Owner) {
+		return fmt.Errorf("invalid owner %q", t.Owner)
+	}
+	switch t.AssetCriticality {
+	case "", AssetCriticalityCritical, AssetCriticalityHigh, AssetCriticalityMedium, AssetCriticalityLow:
+	default:
+		return fmt.Errorf("invalid asset_criticality %q", t.AssetCriticality)
+	}
+	for _, tag := range t.UserTags {
+		if !AssetTagRegex.MatchString(tag) {
+			return fmt.Errorf("invalid user tag %q", tag)
+		}
+	}
+	return nil
+}
+
+// ParseAssetTagsCSV reads the asset tags from a CSV file with the
+// node_id,node_type,owner,asset_criticality,user_tags header, the user
+// tags being separated by semicolons
+func ParseAssetTagsCSV(r io.Reader) ([]AssetTags, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(assetTagsCSVHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+	for i := range assetTagsCSVHeader {
+		if strings.ToLower(strings.TrimSpace(header[i])) != assetTagsCSVHeader[i] {
+			return nil, fmt.Errorf("invalid csv header, expected %s", strings.Join(assetTagsCSVHeader, ","))
+		}
+	}
+
+	res := []AssetTags{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		tags := AssetTags{
+			AssetNode: AssetNode{
+				NodeID:   strings.TrimSpace(record[0]),
+				NodeType: strings.TrimSpace(record[1]),
+			},
+			Owner:            strings.TrimSpace(record[2]),
+			AssetCriticality: strings.ToLower(strings.TrimSpace(record[3])),
+			UserTags:         []string{},
+		}
+		for _, tag := range strings.Split(record[4], assetTagsCSVSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags.UserTags = append(tags.UserTags, tag)
+			}
+		}
+		if err := validateAssetTags(tags); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		res = append(res, tags)
+	}
+	return res, nil
+}
+
+// UpdateAssetTags sets the asset tags set by users on the nodes, they
+// are no longer derived from the kubernetes labels or cloud tags
+func UpdateAssetTags(ctx context.Context, tags []AssetTags) (AssetTagsUpdateResponse, error) {
+	res := AssetTagsUpdateResponse{NotFound: []string{}}
+
+	rowsByLabel := map[string][]map[string]interface{}{}
+	requested := map[string]struct{}{}
+	for _, t := range tags {
+		label, has := AssetTagsNodeTypes[t.NodeType]
+		if !has {
+			return res, fmt.Errorf("invalid node_type %q", t.NodeType)
+		}
+		userTags := t.UserTags
+		if userTags == nil {
+			userTags = []string{}
+		}
+		rowsByLabel[label] = append(rowsByLabel[label], map[string]interface{}{
+			"node_id":           t.NodeID,
+			"user_tags":         userTags,
+			"owner":             t.Owner,
+			"asset_criticality": t.AssetCriticality,
+		})
+		requested[t.NodeID] = struct{}{}
+	}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(60 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	updated := map[string]struct{}{}
+	for label, rows := range rowsByLabel {
+		r, err := tx.Run(`
+			UNWIND $batch as row
+			MATCH (n:`+label+`{node_id: row.node_id})
+			SET n.user_tags = row.user_tags,
+				n.owner = CASE WHEN row.owner = '' THEN null ELSE row.owner END,
+				n.asset_criticality = CASE WHEN row.asset_criticality = '' THEN null ELSE row.asset_criticality END,
+				n.asset_tags_source = $source
+			RETURN n.node_id`,
+			map[string]interface{}{"batch": rows, "source": AssetTagsSourceUser})
+		if err != nil {
+			return res, err
+		}
+		recs, err := r.Collect()
+		if err != nil {
+			return res, err
+		}
+		for _, rec := range recs {
+			if id, ok := rec.Values[0].(string); ok {
+				updated[id] = struct{}{}
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return res, err
+	}
+
+	res.UpdatedCount = len(updated)
+	for id := range requested {
+		if _, has := updated[id]; !has {
+			res.NotFound = append(res.NotFound, id)
+		}
+	}
+	sort.Strings(res.NotFound)
+	return res, nil
+}
+
+// DeriveAssetTags extracts the owner, criticality and tags from
+// kubernetes labels or cloud tags
+func DeriveAssetTags(labels map[string]string) (owner, criticality string, tags []string) {
+	lowered := make(map[string]string, len(labels))
+	for k, v := range labels {
+		lowered[strings.ToLower(k)] = strings.TrimSpace(v)
+	}
+	for _, k := range assetOwnerKeys {
+		if v := lowered[k]; v != "" && AssetTagRegex.MatchString(v) {
+			owner = v
+			break
+		}
+	}
+	for _, k := range assetCriticalityKeys {
+		switch v := strings.ToLower(lowered[k]); v {
+		case AssetCriticalityCritical, AssetCriticalityHigh, AssetCriticalityMedium, AssetCriticalityLow:
+			criticality = v
+		}
+		if criticality != "" {
+			break
+		}
+	}
+	tags = []string{}
+	for _, k := range assetTagKeys {
+		if v := lowered[k]; v != "" {
+			if tag := k + ":" + v; AssetTagRegex.MatchString(tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return owner, criticality, tags
+}
+
+// ParseResourceLabels reads the kubernetes labels or cloud tags stored as
+// json, either as an object or as a list of Key/Value objects
+func ParseResourceLabels(raw string) map[string]string {
+	labels := map[string]string{}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &object); err == nil {
+		for k, v := range object {
+			if str, ok := v.(string); ok {
+				labels[k] = str
+			}
+		}
+		return labels
+	}
+	var list []map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &list); err == nil {
+		for _, entry := range list {
+			k, _ := entry["Key"].(string)
+			v, _ := entry["Value"].(string)
+			if k != "" {
+				labels[k] = v
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/reporters"
+	"gotest.tools/assert"
+)
+
+func Test_parse_asset_tags_csv(t *testing.T) {
+	tags, err := ParseAssetTagsCSV(strings.NewReader(
+		"node_id,node_type,owner,asset_criticality,user_tags\n" +
+			"host-1,host,payments,Critical,env:prod; pci\n" +
+			"img-1,image,,,\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(tags), 2)
+	assert.Equal(t, tags[0].AssetCriticality, AssetCriticalityCritical)
+	assert.DeepEqual(t, tags[0].UserTags, []string{"env:prod", "pci"})
+	assert.Equal(t, len(tags[1].UserTags), 0)
+
+	_, err = ParseAssetTagsCSV(strings.NewReader("id,type,owner,criticality,tags\n"))
+	assert.ErrorContains(t, err, "invalid csv header")
+
+	_, err = ParseAssetTagsCSV(strings.NewReader(
+		"node_id,node_type,owner,asset_criticality,user_tags\n" +
+			"host-1,host,payments,urgent,\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func Test_derive_asset_tags(t *testing.T) {
+	owner, criticality, tags := DeriveAssetTags(ParseResourceLabels(
+		`{"Team":"payments","deepfence.io/criticality":"High","env":"prod","app":"api"}`))
+	assert.Equal(t, owner, "payments")
+	assert.Equal(t, criticality, AssetCriticalityHigh)
+	assert.DeepEqual(t, tags, []string{"env:prod"})
+
+	owner, criticality, tags = DeriveAssetTags(ParseResourceLabels(
+		`[{"Key":"Owner","Value":"infra"},{"Key":"Criticality","Value":"unknown"}]`))
+	assert.Equal(t, owner, "infra")
+	assert.Equal(t, criticality, "")
+	assert.Equal(t, len(tags), 0)
+}
+
+func Test_asset_filter_apply(t *testing.T) {
+	filters := reporters.FieldsFilters{}
+	assert.Assert(t, AssetFilter{}.IsEmpty())
+	AssetFilter{UserTags: []string{"pci"}, AssetCriticality: []string{"critical"}}.Apply(&filters)
+	assert.DeepEqual(t, filters.ContainsInArrayFilter.FieldsValues["user_tags"], []interface{}{"pci"})
+	assert.DeepEqual(t, filters.ContainsFilter.FieldsValues["asset_criticality"], []interface{}{"critical"})
+	_, has := filters.ContainsFilter.FieldsValues["owner"]
+	assert.Assert(t, !has)
+}
@@ -0,0 +1,66 @@
+package model
+
+const (
+	CustomRuleSecret  = "secret"
+	CustomRuleMalware = "malware"
+)
+
+// CustomRule is an organization rule scanned along with the bundled ones. A
+// secret rule matches its regex against the part of the files, a malware
+// rule holds the source of YARA rules.
+type CustomRule struct {
+	ID          string `json:"id" required:"true"`
+	Name        string `json:"name" required:"true"`
+	Type        string `json:"type" required:"true" enum:"secret,malware"`
+	Description string `json:"description" required:"true"`
+	Part        string `json:"part" required:"true"`
+	Regex       string `json:"regex" required:"true"`
+	Severity    string `json:"severity" required:"true"`
+	Content     string `json:"content" required:"true"`
+	Enabled     bool   `json:"enabled" required:"true"`
+	CreatedAt   int64  `json:"created_at" required:"true"`
+	UpdatedAt   int64  `json:"updated_at" required:"true"`
+}
+
+type CustomRuleReq struct {
+	Name        string `json:"name" validate:"required,max=128" required:"true"`
+	Type        string `json:"type" validate:"required,oneof=secret malware" required:"true" enum:"secret,malware"`
+	Description string `json:"description" validate:"max=1024"`
+	Part        string `json:"part" validate:"required_if=Type secret,omitempty,oneof=contents filename extension path" enum:"contents,filename,extension,path"`
+	Regex       string `json:"regex" validate:"required_if=Type secret"`
+	Severity    string `json:"severity" validate:"required_if=Type secret,omitempty,oneof=low medium high critical" enum:"low,medium,high,critical"`
+	Content     string `json:"content" validate:"required_if=Type malware,max=1048576"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type UpdateCustomRuleReq struct {
+	ID string `path:"id" validate:"required" required:"true"`
+	CustomRuleReq
+}
+
+type CustomRuleIDReq struct {
+	ID string `path:"id" validate:"required" required:"true"`
+}
+
+// ListCustomRulesResp lists the rules along with the version of the bundles
+// the scanners use and the latest published one, released once the worker
+// validated it. ValidationError is why the latest version was rejected.
+type ListCustomRulesResp struct {
+	Rules            []CustomRule `json:"rules" required:"true"`
+	Version          int64        `json:"version" required:"true"`
+	PublishedVersion int64        `json:"published_version" required:"true"`
+	ValidationError  string       `json:"validation_error" required:"true"`
+}
+
+// CustomRulesBundle is a version of the rules of a type, kept in the file
+// server
+type CustomRulesBundle struct {
+	Type      string `json:"type" required:"true" enum:"secret,malware"`
+	Version   int64  `json:"version" required:"true"`
+	Size      int64  `json:"size" required:"true"`
+	CreatedAt int64  `json:"created_at" required:"true"`
+}
+
+type ListCustomRulesBundlesResp struct {
+	Bundles []CustomRulesBundle `json:"bundles" required:"true"`
+}
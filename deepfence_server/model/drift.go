@@ -0,0 +1,56 @@
+package model
+
+const (
+	DriftSourceProcess      = "process"
+	DriftSourceRuntimeEvent = "runtime_event"
+
+	// executables missing from the image
+	DriftCategoryNewExecutable = "new_executable"
+	// executables in the image not owned by any of its packages, copied in
+	// at build time, they are reported with a lower severity
+	DriftCategoryUnownedFile = "unowned_file"
+
+	DriftSeverityHigh = "high"
+	DriftSeverityLow  = "low"
+
+	// Resource of integrations notified with new drifts
+	DriftNotificationType = "Drift"
+)
+
+// Drift is an executable run in a container which is not part of the
+// files of its image, as listed by the image SBOM, or which is in the
+// image but not owned by any package
+type Drift struct {
+	NodeID         string   `json:"node_id" required:"true"`
+	ContainerID    string   `json:"container_id" required:"true"`
+	ContainerName  string   `json:"docker_container_name" required:"true"`
+	ImageID        string   `json:"docker_image_id" required:"true"`
+	ImageName      string   `json:"docker_image_name" required:"true"`
+	HostName       string   `json:"host_name" required:"true"`
+	ExecutablePath string   `json:"executable_path" required:"true"`
+	Cmdline        string   `json:"cmdline" required:"true"`
+	Lineage        []string `json:"lineage" required:"true"`
+	Source         string   `json:"source" required:"true" enum:"process,runtime_event"`
+	Category       string   `json:"category" required:"true" enum:"new_executable,unowned_file"`
+	Severity       string   `json:"severity" required:"true" enum:"high,low"`
+	SBOMScanID     string   `json:"sbom_scan_id" required:"true"`
+	FirstSeenAt    int64    `json:"first_seen_at" required:"true"`
+	LastSeenAt     int64    `json:"last_seen_at" required:"true"`
+	Masked         bool     `json:"masked" required:"true"`
+}
+
+func (Drift) NodeType() string {
+	return "Drift"
+}
+
+func (Drift) ExtendedField() string {
+	return ""
+}
+
+func (d Drift) GetCategory() string {
+	return d.Source
+}
+
+func (Drift) GetJSONCategory() string {
+	return "source"
+}
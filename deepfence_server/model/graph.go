@@ -21,3 +21,9 @@ type TopologyDeltaResponse struct {
 	AdditionTimestamp int64            `json:"addition_timestamp" format:"int64"`
 	DeletionTimestamp int64            `json:"deletion_timestamp" format:"int64"`
 }
+
+type TopologyStreamRequest struct {
+	KubernetesClusterIDs []string `query:"kubernetes_cluster_id"`
+	HostNames            []string `query:"host_name"`
+	NodeTypes            []string `query:"node_type" validate:"omitempty,dive,oneof=Node Container Pod KubernetesCluster" enum:"Node,Container,Pod,KubernetesCluster"`
+}
@@ -0,0 +1,66 @@
+package model
+
+const (
+	IaCSourceNodeType     = "iac_source"
+	IaCKubernetesManifest = "kubernetes_manifest"
+	IaCStatusAlarm        = "alarm"
+)
+
+type IaCFile struct {
+	Path    string `json:"path" validate:"required,max=1024" required:"true"`
+	Content string `json:"content" validate:"required" required:"true"`
+}
+
+// IaCScanRequest holds Kubernetes manifests, or helm template output, of a
+// source like a repository or a chart. Scans of the same node_name are
+// grouped under the same source node.
+type IaCScanRequest struct {
+	NodeName string    `json:"node_name" validate:"required,max=256" required:"true"`
+	Files    []IaCFile `json:"files" validate:"required,min=1,max=1000,dive" required:"true"`
+}
+
+type IaCScanResult struct {
+	ScanResultsCommon
+	Findings       []IaCFinding     `json:"findings" required:"true"`
+	SeverityCounts map[string]int32 `json:"severity_counts" required:"true"`
+}
+
+// IaCFinding shares the field names of Compliance so both can be listed
+// together
+type IaCFinding struct {
+	TestCategory        string `json:"test_category" required:"true"`
+	TestNumber          string `json:"test_number" required:"true"`
+	TestInfo            string `json:"description" required:"true"`
+	TestRationale       string `json:"test_rationale" required:"true"`
+	Remediation         string `json:"remediation" required:"true"`
+	Resource            string `json:"resource" required:"true"`
+	TestSeverity        string `json:"test_severity" required:"true"`
+	Status              string `json:"status" required:"true"`
+	ComplianceCheckType string `json:"compliance_check_type" required:"true"`
+	FilePath            string `json:"file_path" required:"true"`
+	HelmTemplate        string `json:"helm_template" required:"true"`
+	DocumentIndex       int    `json:"document_index" required:"true"`
+	Kind                string `json:"kind" required:"true"`
+	Namespace           string `json:"namespace" required:"true"`
+	Name                string `json:"name" required:"true"`
+	Container           string `json:"container" required:"true"`
+	NodeID              string `json:"node_id" required:"true"`
+	Masked              bool   `json:"masked" required:"true"`
+	UpdatedAt           int64  `json:"updated_at" required:"true"`
+}
+
+func (IaCFinding) NodeType() string {
+	return "IaCFinding"
+}
+
+func (IaCFinding) ExtendedField() string {
+	return ""
+}
+
+func (v IaCFinding) GetCategory() string {
+	return v.TestSeverity
+}
+
+func (IaCFinding) GetJSONCategory() string {
+	return "test_severity"
+}
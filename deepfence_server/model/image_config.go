@@ -0,0 +1,34 @@
+package model
+
+// ImageConfigFinding is an insecure setting of the config of an image, like
+// running as root or secrets set in ENV, found during its SBOM scan
+type ImageConfigFinding struct {
+	NodeID      string `json:"node_id" required:"true"`
+	ImageID     string `json:"docker_image_id" required:"true"`
+	ImageName   string `json:"docker_image_name" required:"true"`
+	RuleID      string `json:"rule_id" required:"true"`
+	Title       string `json:"title" required:"true"`
+	Description string `json:"description" required:"true"`
+	Remediation string `json:"remediation" required:"true"`
+	Severity    string `json:"severity" required:"true" enum:"critical,high,medium,low"`
+	Evidence    string `json:"evidence" required:"true"`
+	ScanID      string `json:"scan_id" required:"true"`
+	Masked      bool   `json:"masked" required:"true"`
+	UpdatedAt   int64  `json:"updated_at" required:"true"`
+}
+
+func (ImageConfigFinding) NodeType() string {
+	return "ImageConfigFinding"
+}
+
+func (ImageConfigFinding) ExtendedField() string {
+	return ""
+}
+
+func (f ImageConfigFinding) GetCategory() string {
+	return f.Severity
+}
+
+func (ImageConfigFinding) GetJSONCategory() string {
+	return "severity"
+}
@@ -32,6 +32,7 @@ type IntegrationFilters struct {
 	FieldsFilters  reporters.FieldsFilters `json:"fields_filters"`
 	NodeIds        []NodeIdentifier        `json:"node_ids" required:"true"`
 	ContainerNames []string                `json:"container_names" required:"false"`
+	AssetFilter    AssetFilter             `json:"asset_filter"`
 }
 
 func (i *IntegrationAddReq) IntegrationExists(ctx context.Context, pgClient *postgresqlDb.Queries) (bool, error) {
@@ -15,10 +15,13 @@ type PresentationContext struct {
 type Metadata map[string]interface{}
 
 type KubernetesCluster struct {
-	ID           string `json:"node_id" required:"true"`
-	Name         string `json:"node_name" required:"true"`
-	AgentRunning bool   `json:"agent_running" required:"true"`
-	Hosts        []Host `json:"hosts" required:"true"`
+	ID               string   `json:"node_id" required:"true"`
+	Name             string   `json:"node_name" required:"true"`
+	AgentRunning     bool     `json:"agent_running" required:"true"`
+	Hosts            []Host   `json:"hosts" required:"true"`
+	UserTags         []string `json:"user_tags"`
+	Owner            string   `json:"owner"`
+	AssetCriticality string   `json:"asset_criticality"`
 }
 
 func (KubernetesCluster) NodeType() string {
@@ -110,6 +113,9 @@ type Host struct {
 	CloudWarnAlarmCount             int64            `json:"cloud_warn_alarm_count" required:"true"`
 	InboundConnections              []Connection     `json:"inbound_connections" required:"true"`
 	OutboundConnections             []Connection     `json:"outbound_connections" required:"true"`
+	UserTags                        []string         `json:"user_tags"`
+	Owner                           string           `json:"owner"`
+	AssetCriticality                string           `json:"asset_criticality"`
 }
 
 func (Host) NodeType() string {
@@ -178,11 +184,15 @@ type Pod struct {
 	KubernetesState           string                 `json:"kubernetes_state" required:"true"`
 	KubernetesIP              string                 `json:"kubernetes_ip" required:"true"`
 	KubernetesIsInHostNetwork bool                   `json:"kubernetes_is_in_host_network" required:"true"`
+	KubernetesServiceAccount  string                 `json:"kubernetes_service_account"`
 	KubernetesLabels          map[string]interface{} `json:"kubernetes_labels" required:"true" nested_json:"true"`
 	KubernetesCreated         string                 `json:"kubernetes_created" required:"true"`
 	MalwareScanStatus         string                 `json:"malware_scan_status" required:"true"`
 	SecretScanStatus          string                 `json:"secret_scan_status" required:"true"`
 	VulnerabilityScanStatus   string                 `json:"vulnerability_scan_status" required:"true"`
+	UserTags                  []string               `json:"user_tags"`
+	Owner                     string                 `json:"owner"`
+	AssetCriticality          string                 `json:"asset_criticality"`
 }
 
 func (Pod) NodeType() string {
@@ -221,6 +231,15 @@ type Container struct {
 	DockerContainerIps         []interface{}          `json:"docker_container_ips" required:"true"`
 	DockerContainerCreated     string                 `json:"docker_container_created" required:"true"`
 	DockerContainerPorts       string                 `json:"docker_container_ports" required:"true"`
+	ContainerRuntime           string                 `json:"container_runtime"`
+	ContainerRuntimeClass      string                 `json:"container_runtime_class"`
+	ContainerNamespace         string                 `json:"container_namespace"`
+	ContainerMounts            []string               `json:"container_mounts"`
+	ContainerPrivileged        bool                   `json:"container_privileged"`
+	ContainerCapabilities      []string               `json:"container_capabilities"`
+	ContainerRunAsRoot         bool                   `json:"container_run_as_root"`
+	ContainerReadOnlyRootfs    bool                   `json:"container_read_only_rootfs"`
+	ImageDigest                string                 `json:"image_digest"`
 	Uptime                     int                    `json:"uptime" required:"true"`
 	CPUMax                     float64                `json:"cpu_max" required:"true"`
 	CPUUsage                   float64                `json:"cpu_usage" required:"true"`
@@ -235,6 +254,9 @@ type Container struct {
 	MalwaresCount              int64                  `json:"malwares_count" required:"true"`
 	MalwareScanStatus          string                 `json:"malware_scan_status" required:"true"`
 	MalwareLatestScanID        string                 `json:"malware_latest_scan_id" required:"true"`
+	UserTags                   []string               `json:"user_tags"`
+	Owner                      string                 `json:"owner"`
+	AssetCriticality           string                 `json:"asset_criticality"`
 }
 
 func (Container) NodeType() string {
@@ -332,6 +354,7 @@ type ContainerImage struct {
 	DockerImageVirtualSize    string                 `json:"docker_image_virtual_size" required:"true"`
 	DockerImageID             string                 `json:"docker_image_id" required:"true"`
 	DockerImageTagList        []string               `json:"docker_image_tag_list" required:"true"`
+	ImageDigest               string                 `json:"image_digest"`
 	Metadata                  map[string]interface{} `json:"metadata" nested_json:"true"`
 	VulnerabilitiesCount      int64                  `json:"vulnerabilities_count" required:"true"`
 	VulnerabilityScanStatus   string                 `json:"vulnerability_scan_status" required:"true"`
@@ -343,6 +366,9 @@ type ContainerImage struct {
 	MalwareScanStatus         string                 `json:"malware_scan_status" required:"true"`
 	MalwareLatestScanID       string                 `json:"malware_latest_scan_id" required:"true"`
 	Containers                []Container            `json:"containers" required:"true"`
+	UserTags                  []string               `json:"user_tags"`
+	Owner                     string                 `json:"owner"`
+	AssetCriticality          string                 `json:"asset_criticality"`
 }
 
 func (ContainerImage) NodeType() string {
@@ -366,16 +392,19 @@ func (ci ContainerImage) id() string {
 }
 
 type CloudResource struct {
-	ID                          string `json:"node_id" required:"true"`
-	Name                        string `json:"node_name" required:"true"`
-	Type                        string `json:"node_type" required:"true"`
-	TypeLabel                   string `json:"type_label" required:"true"`
-	AccountID                   string `json:"account_id" required:"true"`
-	CloudProvider               string `json:"cloud_provider" required:"true"`
-	CloudRegion                 string `json:"cloud_region" required:"true"`
-	CloudCompliancesCount       int64  `json:"cloud_compliances_count" required:"true"`
-	CloudComplianceScanStatus   string `json:"cloud_compliance_scan_status" required:"true"`
-	CloudComplianceLatestScanID string `json:"cloud_compliance_latest_scan_id" required:"true"`
+	ID                          string   `json:"node_id" required:"true"`
+	Name                        string   `json:"node_name" required:"true"`
+	Type                        string   `json:"node_type" required:"true"`
+	TypeLabel                   string   `json:"type_label" required:"true"`
+	AccountID                   string   `json:"account_id" required:"true"`
+	CloudProvider               string   `json:"cloud_provider" required:"true"`
+	CloudRegion                 string   `json:"cloud_region" required:"true"`
+	CloudCompliancesCount       int64    `json:"cloud_compliances_count" required:"true"`
+	CloudComplianceScanStatus   string   `json:"cloud_compliance_scan_status" required:"true"`
+	CloudComplianceLatestScanID string   `json:"cloud_compliance_latest_scan_id" required:"true"`
+	UserTags                    []string `json:"user_tags"`
+	Owner                       string   `json:"owner"`
+	AssetCriticality            string   `json:"asset_criticality"`
 }
 
 func (CloudResource) NodeType() string {
@@ -5,7 +5,7 @@ import (
 )
 
 type GenerateReportReq struct {
-	ReportType string              `json:"report_type" validate:"required" required:"true" enum:"pdf,xlsx,sbom"`
+	ReportType string              `json:"report_type" validate:"required" required:"true" enum:"pdf,xlsx,sbom,coverage,attack_paths"`
 	Duration   int                 `json:"duration" enum:"0,1,7,30,60,90,180"`
 	Filters    utils.ReportFilters `json:"filters"`
 	Options    utils.ReportOptions `json:"options" validate:"omitempty"`
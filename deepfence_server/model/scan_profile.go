@@ -0,0 +1,45 @@
+package model
+
+// ScanProfile scopes the files the secret and malware scans read. The
+// default profile applies to the nodes without a profile of their own, the
+// profile given when triggering a scan overrides both.
+type ScanProfile struct {
+	ID                     string   `json:"id" required:"true"`
+	Name                   string   `json:"name" required:"true"`
+	IncludePaths           []string `json:"include_paths" required:"true"`
+	ExcludePaths           []string `json:"exclude_paths" required:"true"`
+	MaxFileSizeMB          int64    `json:"max_file_size_mb" required:"true"`
+	SkipMountedVolumes     bool     `json:"skip_mounted_volumes" required:"true"`
+	SkipNetworkFilesystems bool     `json:"skip_network_filesystems" required:"true"`
+	IsDefault              bool     `json:"is_default" required:"true"`
+	NodeIDs                []string `json:"node_ids" required:"true"`
+	CreatedAt              int64    `json:"created_at" required:"true"`
+}
+
+// AddScanProfileReq only takes the options the scanners of the agents
+// support: paths are prefixes, optionally ending with /**, the include paths
+// only apply to the scans of hosts and the file size is not limited yet.
+type AddScanProfileReq struct {
+	Name                   string   `json:"name" validate:"required,max=128" required:"true"`
+	IncludePaths           []string `json:"include_paths" validate:"omitempty,dive,required,scan_profile_path"`
+	ExcludePaths           []string `json:"exclude_paths" validate:"omitempty,dive,required,scan_profile_path"`
+	MaxFileSizeMB          int64    `json:"max_file_size_mb" validate:"max=0"`
+	SkipMountedVolumes     bool     `json:"skip_mounted_volumes"`
+	SkipNetworkFilesystems bool     `json:"skip_network_filesystems"`
+	IsDefault              bool     `json:"is_default"`
+}
+
+type ScanProfileIDReq struct {
+	ID string `path:"id" validate:"required" required:"true"`
+}
+
+type ListScanProfilesResp struct {
+	Profiles []ScanProfile `json:"profiles" required:"true"`
+}
+
+// AssignScanProfileReq sets the profile of the nodes, an empty profile_id
+// puts them back on the default profile
+type AssignScanProfileReq struct {
+	ProfileID string   `json:"profile_id"`
+	NodeIDs   []string `json:"node_ids" validate:"required,min=1" required:"true"`
+}
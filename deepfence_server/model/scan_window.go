@@ -0,0 +1,47 @@
+package model
+
+// ScanWindow restricts the agent scans of its scan_types to the time
+// between start_time and end_time, in the timezone, on the days of the week
+// (0 is Sunday). It applies to the hosts, the hosts of the clusters and the
+// hosts tagged with any of the tags. Empty scan_types or days mean all of
+// them, a window ending before its start ends the next day.
+type ScanWindow struct {
+	ID                   string   `json:"id" required:"true"`
+	Name                 string   `json:"name" required:"true"`
+	HostIDs              []string `json:"host_ids" required:"true"`
+	KubernetesClusterIDs []string `json:"kubernetes_cluster_ids" required:"true"`
+	Tags                 []string `json:"tags" required:"true"`
+	ScanTypes            []string `json:"scan_types" required:"true"`
+	Days                 []int    `json:"days" required:"true"`
+	StartTime            string   `json:"start_time" required:"true"`
+	EndTime              string   `json:"end_time" required:"true"`
+	Timezone             string   `json:"timezone" required:"true"`
+	CreatedAt            int64    `json:"created_at" required:"true"`
+}
+
+type AddScanWindowReq struct {
+	Name                 string   `json:"name" validate:"required,max=128" required:"true"`
+	HostIDs              []string `json:"host_ids"`
+	KubernetesClusterIDs []string `json:"kubernetes_cluster_ids"`
+	Tags                 []string `json:"tags"`
+	ScanTypes            []string `json:"scan_types" validate:"omitempty,dive,oneof=SecretScan MalwareScan VulnerabilityScan ComplianceScan" enum:"SecretScan,MalwareScan,VulnerabilityScan,ComplianceScan"`
+	Days                 []int    `json:"days" validate:"omitempty,dive,min=0,max=6"`
+	StartTime            string   `json:"start_time" validate:"required,datetime=15:04" required:"true"`
+	EndTime              string   `json:"end_time" validate:"required,datetime=15:04" required:"true"`
+	Timezone             string   `json:"timezone" validate:"omitempty,timezone"`
+}
+
+type ScanWindowIDReq struct {
+	ID string `path:"id" validate:"required" required:"true"`
+}
+
+type ListScanWindowsResp struct {
+	Windows []ScanWindow `json:"windows" required:"true"`
+}
+
+// ClusterScanLimitReq caps the scans running at once on the hosts of the
+// clusters, 0 removes the cap
+type ClusterScanLimitReq struct {
+	KubernetesClusterIDs []string `json:"kubernetes_cluster_ids" validate:"required,min=1" required:"true"`
+	MaxConcurrentScans   int      `json:"max_concurrent_scans" validate:"min=0,max=1000" required:"true"`
+}
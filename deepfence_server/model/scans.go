@@ -20,10 +20,12 @@ type VulnerabilityScanTriggerReq struct {
 
 type SecretScanTriggerReq struct {
 	ScanTriggerCommon
+	ScanProfileID string `json:"scan_profile_id"`
 }
 
 type MalwareScanTriggerReq struct {
 	ScanTriggerCommon
+	ScanProfileID string `json:"scan_profile_id"`
 }
 
 type ComplianceScanTriggerReq struct {
@@ -54,6 +56,7 @@ type ScanFilter struct {
 	HostScanFilter              reporters.ContainsFilter `json:"host_scan_filter" required:"true"`
 	CloudAccountScanFilter      reporters.ContainsFilter `json:"cloud_account_scan_filter" required:"true"`
 	KubernetesClusterScanFilter reporters.ContainsFilter `json:"kubernetes_cluster_scan_filter" required:"true"`
+	AssetFilter                 AssetFilter              `json:"asset_filter"`
 }
 
 type ScanTriggerCommon struct {
@@ -163,7 +166,7 @@ type StopScanRequest struct {
 
 type ScanActionRequest struct {
 	ScanID   string `path:"scan_id" validate:"required" required:"true"`
-	ScanType string `path:"scan_type" validate:"required,oneof=SecretScan VulnerabilityScan MalwareScan ComplianceScan CloudComplianceScan" required:"true" enum:"SecretScan,VulnerabilityScan,MalwareScan,ComplianceScan,CloudComplianceScan"`
+	ScanType string `path:"scan_type" validate:"required,oneof=SecretScan VulnerabilityScan MalwareScan ComplianceScan CloudComplianceScan IaCScan" required:"true" enum:"SecretScan,VulnerabilityScan,MalwareScan,ComplianceScan,CloudComplianceScan,IaCScan"`
 	// utils.Neo4jScanType
 }
 
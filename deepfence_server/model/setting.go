@@ -23,6 +23,7 @@ const (
 	EmailSettingSMTP                  = "smtp"
 	InactiveNodesDeleteScanResultsKey = "inactive_delete_scan_results"
 	ConsoleIDKey                      = "console_id"
+	RequireAgentCredentialsKey        = "require_agent_credentials"
 )
 
 type GetAuditLogsRow struct {
@@ -64,7 +65,7 @@ type GetAuditLogsRequest struct {
 
 type SettingUpdateRequest struct {
 	ID    int64  `path:"id" validate:"required" required:"true"`
-	Key   string `json:"key" validate:"required,oneof=console_url inactive_delete_scan_results" required:"true" enum:"console_url,inactive_delete_scan_results"`
+	Key   string `json:"key" validate:"required,oneof=console_url inactive_delete_scan_results require_agent_credentials" required:"true" enum:"console_url,inactive_delete_scan_results,require_agent_credentials"`
 	Value string `json:"value" validate:"required" required:"true"`
 }
 
@@ -173,6 +174,42 @@ func SetScanResultsDeletionSetting(ctx context.Context, pgClient *postgresqlDb.Q
 	return nil
 }
 
+func SetRequireAgentCredentialsSetting(ctx context.Context, pgClient *postgresqlDb.Queries) error {
+	_, err := pgClient.GetSetting(ctx, RequireAgentCredentialsKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		s := Setting{
+			Key: RequireAgentCredentialsKey,
+			Value: &SettingValue{
+				Label:       "Require Agent Credentials",
+				Value:       false,
+				Description: "Reject the agents using the API key in place of a credential bound to their node",
+			},
+			IsVisibleOnUI: true,
+		}
+		_, err = s.Create(ctx, pgClient)
+		if err != nil {
+			return err
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetRequireAgentCredentials tells if the agents have to use their
+// credential, false when not set
+func GetRequireAgentCredentials(ctx context.Context, pgClient *postgresqlDb.Queries) (bool, error) {
+	setting, err := GetSettingByKey(ctx, pgClient, RequireAgentCredentialsKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	required, _ := setting.Value.Value.(bool)
+	return required, nil
+}
+
 func SetConsoleIDSetting(ctx context.Context, pgClient *postgresqlDb.Queries) error {
 	_, err := pgClient.GetSetting(ctx, ConsoleIDKey)
 	if errors.Is(err, sql.ErrNoRows) {
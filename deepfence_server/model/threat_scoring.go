@@ -0,0 +1,130 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	postgresqlDb "github.com/deepfence/ThreatMapper/deepfence_utils/postgresql/postgresql-db"
+)
+
+const (
+	ThreatScoringModelKey = "threat_scoring_model"
+
+	AssetCriticalityCritical = "critical"
+	AssetCriticalityHigh     = "high"
+	AssetCriticalityMedium   = "medium"
+	AssetCriticalityLow      = "low"
+)
+
+type ThreatScoringWeights struct {
+	Vulnerability   float64 `json:"vulnerability" validate:"min=0,max=100" required:"true"`
+	Secret          float64 `json:"secret" validate:"min=0,max=100" required:"true"`
+	Malware         float64 `json:"malware" validate:"min=0,max=100" required:"true"`
+	Compliance      float64 `json:"compliance" validate:"min=0,max=100" required:"true"`
+	CloudCompliance float64 `json:"cloud_compliance" validate:"min=0,max=100" required:"true"`
+}
+
+// ThreatScoringModel defines which findings are counted as exploitable in
+// the threat graph and how the risk score of the nodes is computed:
+// the weighted sum of the exploitable findings, multiplied for nodes
+// reachable from the internet and by the criticality of the asset.
+type ThreatScoringModel struct {
+	Weights                           ThreatScoringWeights `json:"weights" required:"true"`
+	VulnerabilityExploitabilityScores []int64              `json:"vulnerability_exploitability_scores" validate:"required,min=1,dive,min=0,max=3" required:"true"`
+	SecretSeverities                  []string             `json:"secret_severities" validate:"required,min=1,dive,oneof=critical high medium low" required:"true" enum:"critical,high,medium,low"`
+	MalwareSeverities                 []string             `json:"malware_severities" validate:"required,min=1,dive,oneof=critical high medium low" required:"true" enum:"critical,high,medium,low"`
+	ComplianceStatuses                []string             `json:"compliance_statuses" validate:"required,min=1,dive,oneof=alarm warn info note ok pass skip" required:"true" enum:"alarm,warn,info,note,ok,pass,skip"`
+	CloudComplianceStatuses           []string             `json:"cloud_compliance_statuses" validate:"required,min=1,dive,oneof=alarm warn info note ok pass skip" required:"true" enum:"alarm,warn,info,note,ok,pass,skip"`
+	InternetExposureMultiplier        float64              `json:"internet_exposure_multiplier" validate:"min=1,max=100" required:"true"`
+	AssetCriticalityMultipliers       map[string]float64   `json:"asset_criticality_multipliers" validate:"dive,keys,oneof=critical high medium low,endkeys,min=0,max=100" required:"true"`
+}
+
+// DefaultThreatScoringModel matches the criteria used before the model
+// was configurable
+func DefaultThreatScoringModel() ThreatScoringModel {
+	return ThreatScoringModel{
+		Weights: ThreatScoringWeights{
+			Vulnerability:   1,
+			Secret:          1,
+			Malware:         1,
+			Compliance:      1,
+			CloudCompliance: 1,
+		},
+		VulnerabilityExploitabilityScores: []int64{1, 2, 3},
+		SecretSeverities:                  []string{"critical", "high"},
+		MalwareSeverities:                 []string{"critical", "high"},
+		ComplianceStatuses:                []string{"warn", "alarm"},
+		CloudComplianceStatuses:           []string{"warn", "alarm"},
+		InternetExposureMultiplier:        1,
+		AssetCriticalityMultipliers: map[string]float64{
+			AssetCriticalityCritical: 1,
+			AssetCriticalityHigh:     1,
+			AssetCriticalityMedium:   1,
+			AssetCriticalityLow:      1,
+		},
+	}
+}
+
+// GetThreatScoringModel returns the configured model or the default one
+// if it was never configured
+func GetThreatScoringModel(ctx context.Context, pgClient *postgresqlDb.Queries) (ThreatScoringModel, error) {
+	setting, err := pgClient.GetSetting(ctx, ThreatScoringModelKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultThreatScoringModel(), nil
+	} else if err != nil {
+		return ThreatScoringModel{}, err
+	}
+	var scoring ThreatScoringModel
+	err = json.Unmarshal(setting.Value, &scoring)
+	if err != nil {
+		return ThreatScoringModel{}, err
+	}
+	return scoring, nil
+}
+
+func (t *ThreatScoringModel) Save(ctx context.Context, pgClient *postgresqlDb.Queries) error {
+	settingVal, err := json.Marshal(*t)
+	if err != nil {
+		return err
+	}
+	_, err = pgClient.GetSetting(ctx, ThreatScoringModelKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = pgClient.CreateSetting(ctx, postgresqlDb.CreateSettingParams{
+			Key:           ThreatScoringModelKey,
+			Value:         settingVal,
+			IsVisibleOnUi: false,
+		})
+		return err
+	} else if err != nil {
+		return err
+	}
+	return pgClient.UpdateSetting(ctx, postgresqlDb.UpdateSettingParams{
+		Key:           ThreatScoringModelKey,
+		Value:         settingVal,
+		IsVisibleOnUi: false,
+	})
+}
+
+// CypherParams returns the model as parameters of the threat graph queries
+func (t *ThreatScoringModel) CypherParams() map[string]interface{} {
+	criticality := map[string]interface{}{}
+	for level, multiplier := range t.AssetCriticalityMultipliers {
+		criticality[level] = multiplier
+	}
+	return map[string]interface{}{
+		"vulnerability_weight":                t.Weights.Vulnerability,
+		"secret_weight":                       t.Weights.Secret,
+		"malware_weight":                      t.Weights.Malware,
+		"compliance_weight":                   t.Weights.Compliance,
+		"cloud_compliance_weight":             t.Weights.CloudCompliance,
+		"vulnerability_exploitability_scores": t.VulnerabilityExploitabilityScores,
+		"secret_severities":                   t.SecretSeverities,
+		"malware_severities":                  t.MalwareSeverities,
+		"compliance_statuses":                 t.ComplianceStatuses,
+		"cloud_compliance_statuses":           t.CloudComplianceStatuses,
+		"internet_exposure_multiplier":        t.InternetExposureMultiplier,
+		"asset_criticality_multipliers":       criticality,
+	}
+}
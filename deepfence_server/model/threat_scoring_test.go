@@ -0,0 +1,32 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"gotest.tools/assert"
+)
+
+func Test_threat_scoring_model_validation(t *testing.T) {
+	validate := validator.New()
+
+	scoring := DefaultThreatScoringModel()
+	assert.NilError(t, validate.Struct(scoring))
+
+	scoring.InternetExposureMultiplier = 0.5
+	assert.Assert(t, validate.Struct(scoring) != nil)
+
+	scoring = DefaultThreatScoringModel()
+	scoring.SecretSeverities = []string{}
+	assert.Assert(t, validate.Struct(scoring) != nil)
+
+	scoring = DefaultThreatScoringModel()
+	scoring.AssetCriticalityMultipliers["unknown"] = 2
+	assert.Assert(t, validate.Struct(scoring) != nil)
+
+	scoring = DefaultThreatScoringModel()
+	scoring.AssetCriticalityMultipliers[AssetCriticalityCritical] = 3
+	params := scoring.CypherParams()
+	assert.Equal(t, params["asset_criticality_multipliers"].(map[string]interface{})[AssetCriticalityCritical], float64(3))
+	assert.DeepEqual(t, params["vulnerability_exploitability_scores"], []int64{1, 2, 3})
+}
@@ -0,0 +1,183 @@
+package iac
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const helmSourcePrefix = "# Source: "
+
+// File is a Kubernetes manifest, possibly holding several YAML documents
+// as rendered by helm template
+type File struct {
+	Path    string
+	Content string
+}
+
+// Finding is a rule failed by a workload of a manifest
+type Finding struct {
+	Rule
+	File string
+	// HelmTemplate is the chart template the document was rendered from
+	HelmTemplate string
+	Document     int
+	Kind         string
+	Namespace    string
+	Name         string
+	Container    string
+}
+
+// Resource identifies the workload, and container when the rule applies
+// to one
+func (f Finding) Resource() string {
+	res := fmt.Sprintf("%s/%s/%s", f.Kind, f.Namespace, f.Name)
+	if f.Container != "" {
+		res += " (container " + f.Container + ")"
+	}
+	return res
+}
+
+type object struct {
+	Kind     string            `json:"kind"`
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     json.RawMessage   `json:"spec"`
+	Items    []json.RawMessage `json:"items"`
+}
+
+type podTemplateSpec struct {
+	Template corev1.PodTemplateSpec `json:"template"`
+}
+
+type cronJobSpec struct {
+	JobTemplate struct {
+		Spec podTemplateSpec `json:"spec"`
+	} `json:"jobTemplate"`
+}
+
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+	spec      corev1.PodSpec
+}
+
+// Evaluate runs all the rules against the workloads found in the files.
+// Documents which are not workloads are skipped.
+func Evaluate(files []File) ([]Finding, error) {
+	findings := []Finding{}
+	for _, file := range files {
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(file.Content)))
+		for document := 1; ; document++ {
+			doc, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Path, err)
+			}
+			workloads, err := parseWorkloads(doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: document %d: %w", file.Path, document, err)
+			}
+			template := helmTemplate(doc)
+			for _, w := range workloads {
+				for _, rule := range rules {
+					for _, container := range rule.check(w) {
+						findings = append(findings, Finding{
+							Rule:         rule,
+							File:         file.Path,
+							HelmTemplate: template,
+							Document:     document,
+							Kind:         w.kind,
+							Namespace:    w.namespace,
+							Name:         w.name,
+							Container:    container,
+						})
+					}
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+func helmTemplate(doc []byte) string {
+	for _, line := range bytes.Split(doc, []byte("\n")) {
+		if s := string(bytes.TrimSpace(line)); strings.HasPrefix(s, helmSourcePrefix) {
+			return strings.TrimPrefix(s, helmSourcePrefix)
+		}
+	}
+	return ""
+}
+
+func parseWorkloads(doc []byte) ([]workload, error) {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return nil, nil
+	}
+	raw, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	return decodeWorkloads(raw)
+}
+
+func decodeWorkloads(raw []byte) ([]workload, error) {
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	var obj object
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+
+	if obj.Kind == "List" {
+		res := []workload{}
+		for _, item := range obj.Items {
+			items, err := decodeWorkloads(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, items...)
+		}
+		return res, nil
+	}
+	if len(obj.Spec) == 0 {
+		return nil, nil
+	}
+
+	namespace := obj.Metadata.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	w := workload{kind: obj.Kind, namespace: namespace, name: obj.Metadata.Name}
+
+	var err error
+	switch obj.Kind {
+	case "Pod":
+		err = json.Unmarshal(obj.Spec, &w.spec)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		var spec podTemplateSpec
+		err = json.Unmarshal(obj.Spec, &spec)
+		w.spec = spec.Template.Spec
+	case "CronJob":
+		var spec cronJobSpec
+		err = json.Unmarshal(obj.Spec, &spec)
+		w.spec = spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", obj.Kind, obj.Metadata.Name, err)
+	}
+	return []workload{w}, nil
+}
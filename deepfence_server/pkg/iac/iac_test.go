@@ -0,0 +1,129 @@
+package iac
+
+import (
+	"sort"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const rendered = `---
+# Source: web/templates/serviceaccount.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+      containers:
+      - name: app
+        image: registry.local:5000/shop/web:1.4.2
+        securityContext:
+          allowPrivilegeEscalation: false
+          readOnlyRootFilesystem: true
+        resources:
+          limits:
+            cpu: 500m
+            memory: 256Mi
+      - name: debug
+        image: busybox
+        securityContext:
+          privileged: true
+          capabilities:
+            add: ["sys_admin"]
+`
+
+const cronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          hostNetwork: true
+          volumes:
+          - name: data
+            hostPath:
+              path: /var/lib/data
+          containers:
+          - name: backup
+            image: backup@sha256:0000
+            securityContext:
+              runAsUser: 0
+              allowPrivilegeEscalation: false
+              readOnlyRootFilesystem: true
+            resources:
+              requests:
+                cpu: 100m
+                memory: 64Mi
+`
+
+func ruleIDs(findings []Finding, resource string) []string {
+	ids := []string{}
+	for _, f := range findings {
+		if f.Resource() == resource {
+			ids = append(ids, f.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestEvaluate(t *testing.T) {
+	findings, err := Evaluate([]File{
+		{Path: "rendered.yaml", Content: rendered},
+		{Path: "cronjob.yaml", Content: cronJob},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, ruleIDs(findings, "Deployment/shop/web (container app)"), []string{})
+	assert.DeepEqual(t, ruleIDs(findings, "Deployment/shop/web (container debug)"), []string{
+		"k8s_dangerous_capabilities",
+		"k8s_image_latest_tag",
+		"k8s_missing_resource_limits",
+		"k8s_missing_resource_requests",
+		"k8s_privilege_escalation",
+		"k8s_privileged_container",
+		"k8s_writable_root_filesystem",
+	})
+	assert.DeepEqual(t, ruleIDs(findings, "CronJob/default/backup"), []string{
+		"k8s_host_namespaces",
+		"k8s_host_path_volume",
+	})
+	assert.DeepEqual(t, ruleIDs(findings, "CronJob/default/backup (container backup)"), []string{
+		"k8s_missing_resource_limits",
+		"k8s_run_as_root",
+	})
+
+	for _, f := range findings {
+		if f.Kind == "Deployment" {
+			assert.Equal(t, f.File, "rendered.yaml")
+			assert.Equal(t, f.HelmTemplate, "web/templates/deployment.yaml")
+			assert.Equal(t, f.Document, 2)
+		}
+	}
+
+	_, err = Evaluate([]File{{Path: "bad.yaml", Content: "kind: Pod\nspec: [\n"}})
+	assert.ErrorContains(t, err, "bad.yaml: document 1")
+}
+
+func TestUnpinnedImage(t *testing.T) {
+	assert.Assert(t, unpinnedImage("nginx"))
+	assert.Assert(t, unpinnedImage("nginx:latest"))
+	assert.Assert(t, unpinnedImage("registry.local:5000/nginx"))
+	assert.Assert(t, !unpinnedImage("registry.local:5000/nginx:1.25"))
+	assert.Assert(t, !unpinnedImage("nginx@sha256:abcd"))
+}
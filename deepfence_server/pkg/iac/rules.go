@@ -0,0 +1,224 @@
+package iac
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Rule is a misconfiguration check. check returns the names of the
+// failing containers, or a single empty name when the pod spec fails.
+type Rule struct {
+	ID          string
+	Category    string
+	Title       string
+	Description string
+	Remediation string
+	Severity    string
+
+	check func(w workload) []string
+}
+
+var (
+	dangerousCapabilities = map[corev1.Capability]struct{}{
+		"ALL":        {},
+		"SYS_ADMIN":  {},
+		"SYS_MODULE": {},
+		"SYS_PTRACE": {},
+		"SYS_RAWIO":  {},
+		"NET_ADMIN":  {},
+		"BPF":        {},
+	}
+
+	rules = []Rule{
+		{
+			ID:          "k8s_privileged_container",
+			Category:    "Pod Security",
+			Title:       "Privileged container",
+			Description: "Privileged containers have full access to the host devices and kernel capabilities.",
+			Remediation: "Set securityContext.privileged to false.",
+			Severity:    SeverityCritical,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				return c.SecurityContext != nil && isTrue(c.SecurityContext.Privileged)
+			}),
+		},
+		{
+			ID:          "k8s_host_namespaces",
+			Category:    "Pod Security",
+			Title:       "Host namespaces shared",
+			Description: "Pods sharing the host network, PID or IPC namespace can observe and interfere with the host processes and traffic.",
+			Remediation: "Set hostNetwork, hostPID and hostIPC to false.",
+			Severity:    SeverityHigh,
+			check: podCheck(func(spec *corev1.PodSpec) bool {
+				return spec.HostNetwork || spec.HostPID || spec.HostIPC
+			}),
+		},
+		{
+			ID:          "k8s_dangerous_capabilities",
+			Category:    "Pod Security",
+			Title:       "Dangerous capabilities added",
+			Description: "Capabilities like SYS_ADMIN, NET_ADMIN or SYS_PTRACE allow escaping the container.",
+			Remediation: "Remove the capability from securityContext.capabilities.add.",
+			Severity:    SeverityHigh,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				if c.SecurityContext == nil || c.SecurityContext.Capabilities == nil {
+					return false
+				}
+				for _, capability := range c.SecurityContext.Capabilities.Add {
+					if _, has := dangerousCapabilities[corev1.Capability(strings.ToUpper(string(capability)))]; has {
+						return true
+					}
+				}
+				return false
+			}),
+		},
+		{
+			ID:          "k8s_host_path_volume",
+			Category:    "Pod Security",
+			Title:       "Host path volume mounted",
+			Description: "hostPath volumes expose the host filesystem to the pod.",
+			Remediation: "Use persistent volumes, configMaps or emptyDir instead of hostPath.",
+			Severity:    SeverityMedium,
+			check: podCheck(func(spec *corev1.PodSpec) bool {
+				for _, volume := range spec.Volumes {
+					if volume.HostPath != nil {
+						return true
+					}
+				}
+				return false
+			}),
+		},
+		{
+			ID:          "k8s_privilege_escalation",
+			Category:    "Pod Security",
+			Title:       "Privilege escalation allowed",
+			Description: "Without allowPrivilegeEscalation set to false a process can gain more privileges than its parent.",
+			Remediation: "Set securityContext.allowPrivilegeEscalation to false.",
+			Severity:    SeverityMedium,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				return c.SecurityContext == nil || c.SecurityContext.AllowPrivilegeEscalation == nil ||
+					*c.SecurityContext.AllowPrivilegeEscalation
+			}),
+		},
+		{
+			ID:          "k8s_run_as_root",
+			Category:    "Pod Security",
+			Title:       "Container may run as root",
+			Description: "Containers running as root make a container breakout far more damaging.",
+			Remediation: "Set runAsNonRoot to true and runAsUser to a non zero user.",
+			Severity:    SeverityMedium,
+			check:       containerCheck(runsAsRoot),
+		},
+		{
+			ID:          "k8s_missing_resource_limits",
+			Category:    "Resource Management",
+			Title:       "Missing CPU or memory limits",
+			Description: "Containers without limits can exhaust the node resources and starve other workloads.",
+			Remediation: "Set resources.limits.cpu and resources.limits.memory.",
+			Severity:    SeverityMedium,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				return c.Resources.Limits.Cpu().IsZero() || c.Resources.Limits.Memory().IsZero()
+			}),
+		},
+		{
+			ID:          "k8s_missing_resource_requests",
+			Category:    "Resource Management",
+			Title:       "Missing CPU or memory requests",
+			Description: "Containers without requests cannot be scheduled reliably.",
+			Remediation: "Set resources.requests.cpu and resources.requests.memory.",
+			Severity:    SeverityLow,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				// requests default to the limits when only those are set
+				cpu := c.Resources.Requests.Cpu().IsZero() && c.Resources.Limits.Cpu().IsZero()
+				memory := c.Resources.Requests.Memory().IsZero() && c.Resources.Limits.Memory().IsZero()
+				return cpu || memory
+			}),
+		},
+		{
+			ID:          "k8s_writable_root_filesystem",
+			Category:    "Pod Security",
+			Title:       "Writable root filesystem",
+			Description: "A writable root filesystem lets an attacker drop and run binaries in the container.",
+			Remediation: "Set securityContext.readOnlyRootFilesystem to true.",
+			Severity:    SeverityLow,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				return c.SecurityContext == nil || !isTrue(c.SecurityContext.ReadOnlyRootFilesystem)
+			}),
+		},
+		{
+			ID:          "k8s_image_latest_tag",
+			Category:    "Supply Chain",
+			Title:       "Image without a pinned tag",
+			Description: "Images using the latest tag, or no tag, change without notice and cannot be traced to a scan.",
+			Remediation: "Pin the image to a version tag or digest.",
+			Severity:    SeverityLow,
+			check: containerCheck(func(_ *corev1.PodSpec, c *corev1.Container) bool {
+				return unpinnedImage(c.Image)
+			}),
+		},
+	}
+)
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+func podCheck(check func(spec *corev1.PodSpec) bool) func(w workload) []string {
+	return func(w workload) []string {
+		if check(&w.spec) {
+			return []string{""}
+		}
+		return nil
+	}
+}
+
+func containerCheck(check func(spec *corev1.PodSpec, c *corev1.Container) bool) func(w workload) []string {
+	return func(w workload) []string {
+		failed := []string{}
+		for _, containers := range [][]corev1.Container{w.spec.InitContainers, w.spec.Containers} {
+			for i := range containers {
+				if check(&w.spec, &containers[i]) {
+					failed = append(failed, containers[i].Name)
+				}
+			}
+		}
+		return failed
+	}
+}
+
+func runsAsRoot(spec *corev1.PodSpec, c *corev1.Container) bool {
+	var runAsNonRoot *bool
+	var runAsUser *int64
+	if spec.SecurityContext != nil {
+		runAsNonRoot = spec.SecurityContext.RunAsNonRoot
+		runAsUser = spec.SecurityContext.RunAsUser
+	}
+	if c.SecurityContext != nil {
+		if c.SecurityContext.RunAsNonRoot != nil {
+			runAsNonRoot = c.SecurityContext.RunAsNonRoot
+		}
+		if c.SecurityContext.RunAsUser != nil {
+			runAsUser = c.SecurityContext.RunAsUser
+		}
+	}
+	if runAsUser != nil {
+		return *runAsUser == 0
+	}
+	return !isTrue(runAsNonRoot)
+}
+
+func unpinnedImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i < 0 || name[i+1:] == "latest"
+}
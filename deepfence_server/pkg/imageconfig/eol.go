@@ -0,0 +1,92 @@
+package imageconfig
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+// end of security support of the releases, including LTS where offered
+var endOfLifeDates = map[string]map[string]time.Time{
+	"alpine": {
+		"3.15": date("2023-11-01"),
+		"3.16": date("2024-05-23"),
+		"3.17": date("2024-11-22"),
+		"3.18": date("2025-05-09"),
+		"3.19": date("2025-11-01"),
+		"3.20": date("2026-04-01"),
+		"3.21": date("2026-11-01"),
+		"3.22": date("2027-05-01"),
+	},
+	"debian": {
+		"8":  date("2020-06-30"),
+		"9":  date("2022-06-30"),
+		"10": date("2024-06-30"),
+		"11": date("2026-08-31"),
+		"12": date("2028-06-30"),
+	},
+	"ubuntu": {
+		"14.04": date("2019-04-25"),
+		"16.04": date("2021-04-30"),
+		"18.04": date("2023-05-31"),
+		"20.04": date("2025-05-29"),
+		"22.04": date("2027-06-01"),
+		"24.04": date("2029-05-31"),
+	},
+	"centos": {
+		"6": date("2020-11-30"),
+		"7": date("2024-06-30"),
+		"8": date("2021-12-31"),
+	},
+}
+
+// endOfLife reports whether the distro release is past its end of life.
+// Releases older than any in the table are past it, newer or unlisted
+// ones are unknown.
+func endOfLife(distro Distro, now time.Time) (eol bool, known bool) {
+	releases, has := endOfLifeDates[distro.ID]
+	if !has || distro.VersionID == "" {
+		return false, false
+	}
+	version := distro.VersionID
+	if distro.ID == "alpine" {
+		// 3.18.4 is supported as long as 3.18
+		parts := strings.SplitN(version, ".", 3)
+		if len(parts) >= 2 {
+			version = parts[0] + "." + parts[1]
+		}
+	} else if distro.ID == "debian" || distro.ID == "centos" {
+		version, _, _ = strings.Cut(version, ".")
+	}
+
+	if eolDate, has := releases[version]; has {
+		return now.After(eolDate), true
+	}
+	for release := range releases {
+		if !olderRelease(version, release) {
+			return false, false
+		}
+	}
+	return true, true
+}
+
+// olderRelease compares dotted numeric versions, a before b
+func olderRelease(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, errX := strconv.Atoi(as[i])
+		y, errY := strconv.Atoi(bs[i])
+		if errX != nil || errY != nil {
+			return false
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return len(as) < len(bs)
+}
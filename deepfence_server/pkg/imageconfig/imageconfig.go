@@ -0,0 +1,230 @@
+package imageconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+
+	// images built longer ago than this miss the base image fixes
+	staleAfter = 365 * 24 * time.Hour
+)
+
+// Finding is a misconfiguration of an image, Evidence holds the offending
+// part of the config with secrets masked
+type Finding struct {
+	ID          string
+	Title       string
+	Description string
+	Remediation string
+	Severity    string
+	Evidence    string
+}
+
+// Distro is the OS release of the image, as detected by the SBOM
+type Distro struct {
+	ID        string
+	VersionID string
+}
+
+// config is the subset of the OCI image config which is analyzed
+type config struct {
+	Created time.Time `json:"created"`
+	Config  struct {
+		User         string              `json:"User"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Env          []string            `json:"Env"`
+		Labels       map[string]string   `json:"Labels"`
+		Healthcheck  *struct {
+			Test []string `json:"Test"`
+		} `json:"Healthcheck"`
+	} `json:"config"`
+	History []struct {
+		CreatedBy string `json:"created_by"`
+	} `json:"history"`
+}
+
+var (
+	sensitivePorts = map[string]string{
+		"22":    "ssh",
+		"23":    "telnet",
+		"2375":  "docker",
+		"2376":  "docker",
+		"3306":  "mysql",
+		"3389":  "rdp",
+		"5432":  "postgres",
+		"5900":  "vnc",
+		"6379":  "redis",
+		"9200":  "elasticsearch",
+		"11211": "memcached",
+		"27017": "mongodb",
+	}
+
+	secretKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|access_?key|private_?key|credential)`)
+	// keys pointing to where the secret is mounted, not holding it
+	secretReferenceKey = regexp.MustCompile(`(?i)_(file|path|dir)$`)
+
+	secretValues = []*regexp.Regexp{
+		regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+		regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),
+		regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36}`),
+		regexp.MustCompile(`xox[baprs]-[A-Za-z0-9-]{10,}`),
+	}
+
+	remoteAdd = regexp.MustCompile(`\bADD\s+(?:--\S+\s+)*(https?://\S+)`)
+)
+
+// Analyze checks the raw OCI image config for insecure defaults. distro is
+// used to detect base images past their end of life at now.
+func Analyze(raw []byte, distro Distro, now time.Time) ([]Finding, error) {
+	var c config
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("invalid image config: %w", err)
+	}
+
+	findings := []Finding{}
+
+	if runsAsRoot(c.Config.User) {
+		findings = append(findings, Finding{
+			ID:          "image_root_user",
+			Title:       "Image runs as root",
+			Description: "Containers of the image run as root unless the runtime overrides the user.",
+			Remediation: "Add a USER instruction with a non root user to the Dockerfile.",
+			Severity:    SeverityMedium,
+			Evidence:    "USER " + c.Config.User,
+		})
+	}
+
+	if c.Config.Healthcheck == nil || len(c.Config.Healthcheck.Test) == 0 || c.Config.Healthcheck.Test[0] == "NONE" {
+		findings = append(findings, Finding{
+			ID:          "image_missing_healthcheck",
+			Title:       "No HEALTHCHECK defined",
+			Description: "Without a health check a hung container is only noticed by its users.",
+			Remediation: "Add a HEALTHCHECK instruction to the Dockerfile.",
+			Severity:    SeverityLow,
+		})
+	}
+
+	ports := make([]string, 0, len(c.Config.ExposedPorts))
+	for port := range c.Config.ExposedPorts {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		service, has := sensitivePorts[strings.SplitN(port, "/", 2)[0]]
+		if !has {
+			continue
+		}
+		findings = append(findings, Finding{
+			ID:          "image_sensitive_port",
+			Title:       "Sensitive port exposed",
+			Description: fmt.Sprintf("The image exposes the %s port, which is a common target when reachable.", service),
+			Remediation: "Remove the EXPOSE instruction and reach the service over a private network.",
+			Severity:    SeverityMedium,
+			Evidence:    "EXPOSE " + port,
+		})
+	}
+
+	for _, h := range c.History {
+		if m := remoteAdd.FindStringSubmatch(h.CreatedBy); m != nil {
+			findings = append(findings, Finding{
+				ID:          "image_remote_add",
+				Title:       "ADD from a remote URL",
+				Description: "Files added from a URL are not verified and can change between builds.",
+				Remediation: "Download with curl or wget, verify the checksum, or use COPY.",
+				Severity:    SeverityMedium,
+				Evidence:    "ADD " + m[1],
+			})
+		}
+	}
+
+	for _, env := range c.Config.Env {
+		key, value, _ := strings.Cut(env, "=")
+		if looksLikeSecret(key, value) {
+			findings = append(findings, Finding{
+				ID:          "image_secret_in_env",
+				Title:       "Secret in environment variable",
+				Description: "ENV values are stored in the image config and readable by anyone pulling the image.",
+				Remediation: "Pass the secret at runtime or mount it, and rotate the exposed value.",
+				Severity:    SeverityHigh,
+				Evidence:    "ENV " + key + "=" + mask(value),
+			})
+		}
+	}
+
+	labels := make([]string, 0, len(c.Config.Labels))
+	for key := range c.Config.Labels {
+		labels = append(labels, key)
+	}
+	sort.Strings(labels)
+	for _, key := range labels {
+		if looksLikeSecret(key, c.Config.Labels[key]) {
+			findings = append(findings, Finding{
+				ID:          "image_secret_in_label",
+				Title:       "Secret in label",
+				Description: "Labels are stored in the image config and readable by anyone pulling the image.",
+				Remediation: "Remove the label and rotate the exposed value.",
+				Severity:    SeverityHigh,
+				Evidence:    "LABEL " + key + "=" + mask(c.Config.Labels[key]),
+			})
+		}
+	}
+
+	if eol, known := endOfLife(distro, now); known && eol {
+		findings = append(findings, Finding{
+			ID:          "image_eol_base",
+			Title:       "Base image past end of life",
+			Description: fmt.Sprintf("%s %s no longer receives security updates.", distro.ID, distro.VersionID),
+			Remediation: "Rebuild the image on a supported release of the base image.",
+			Severity:    SeverityHigh,
+			Evidence:    distro.ID + " " + distro.VersionID,
+		})
+	}
+
+	if !c.Created.IsZero() && now.Sub(c.Created) > staleAfter {
+		findings = append(findings, Finding{
+			ID:          "image_stale_build",
+			Title:       "Image not rebuilt for over a year",
+			Description: "Old builds miss the fixes published for the base image and packages since.",
+			Remediation: "Rebuild the image regularly to pick up base image updates.",
+			Severity:    SeverityLow,
+			Evidence:    "created " + c.Created.UTC().Format(time.RFC3339),
+		})
+	}
+
+	return findings, nil
+}
+
+func runsAsRoot(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "root" || name == "0"
+}
+
+func looksLikeSecret(key, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range secretValues {
+		if r.MatchString(value) {
+			return true
+		}
+	}
+	return secretKey.MatchString(key) && !secretReferenceKey.MatchString(key) &&
+		!strings.HasPrefix(value, "/")
+}
+
+func mask(value string) string {
+	if len(value) <= 4 {
+		return "****"
+	}
+	return value[:4] + "****"
+}
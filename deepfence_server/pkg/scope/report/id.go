@@ -78,6 +78,11 @@ func MakeProcessNodeID(hostID, pid string) string {
 	return hostID + ScopeDelim + pid
 }
 
+// MakeRuntimeEventNodeID produces a runtime event node ID from its composite parts.
+func MakeRuntimeEventNodeID(hostID, pid, eventType string, timestamp int64) string {
+	return hostID + ScopeDelim + pid + ScopeDelim + eventType + ScopeDelim + strconv.FormatInt(timestamp, 10)
+}
+
 // MakeOverlayNodeID produces an overlay topology node ID from a router peer's
 // prefix and name, which is assumed to be globally unique.
 func MakeOverlayNodeID(peerPrefix, peerName string) string {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -21,6 +22,8 @@ const (
 	Host              = "host"
 	Overlay           = "overlay"
 	KubernetesCluster = "kubernetes_cluster"
+	RuntimeEvent      = "runtime_event"
+	RoleBinding       = "kubernetes_role_binding"
 
 	// Shapes used for different nodes
 	Circle         = "circle"
@@ -245,6 +248,8 @@ var topologyNames = []string{
 	Namespace,
 	Host,
 	Overlay,
+	RuntimeEvent,
+	RoleBinding,
 }
 
 type TopologyNode struct {
@@ -427,6 +432,8 @@ type Parent struct {
 type CompressedReport struct {
 	Decoder *json.Decoder
 	Cleanup func()
+	// Check rejects the report once decoded when set
+	Check func(*Report) error
 }
 
 func (cr *CompressedReport) FillReport(rpt *Report) error {
@@ -499,6 +506,18 @@ type Report struct {
 	// their status endpoints. Edges are present.
 	Overlay Topology
 
+	// RuntimeEvent nodes are process exec/exit and sensitive file write
+	// events traced on hosts running probes. Metadata includes things like
+	// event type, process lineage and modified file path. Edges are not
+	// present.
+	RuntimeEvent Topology
+
+	// RoleBinding nodes are the RoleBindings and ClusterRoleBindings
+	// of the cluster, resolved against the Role or ClusterRole they bind.
+	// Metadata includes things like subjects and granted permissions. Edges
+	// are not present.
+	RoleBinding Topology
+
 	DNS DNSRecords `json:"DNS,omitempty" deepequal:"nil==empty"`
 	// Backwards-compatibility for an accident in commit 951629a / release 1.11.6.
 	BugDNS DNSRecords `json:"nodes,omitempty"`
@@ -537,6 +556,8 @@ func MakeReport() Report {
 		ContainerImage:    MakeTopology(),
 		Host:              MakeTopology(),
 		Overlay:           MakeTopology(),
+		RuntimeEvent:      MakeTopology(),
+		RoleBinding:       MakeTopology(),
 		DNS:               DNSRecords{},
 		Window:            0,
 		ID:                fmt.Sprintf("%d", rand.Int63()),
@@ -580,6 +601,42 @@ func (r *Report) Clear() {
 	for k := range r.Overlay {
 		delete(r.Overlay, k)
 	}
+	for k := range r.RuntimeEvent {
+		delete(r.RuntimeEvent, k)
+	}
+	for k := range r.RoleBinding {
+		delete(r.RoleBinding, k)
+	}
+}
+
+// HostIDs returns the nodes the report describes: its hosts and kubernetes
+// clusters, the hosts of its containers, images and processes and the
+// clusters of its pods. The cluster agents describe their cluster.
+func (r *Report) HostIDs() []string {
+	seen := map[string]struct{}{}
+	for _, t := range []Topology{r.Host, r.KubernetesCluster} {
+		for id := range t {
+			seen[id] = struct{}{}
+		}
+	}
+	for _, t := range []Topology{r.Container, r.ContainerImage, r.Process} {
+		for _, n := range t {
+			if n.Metadata.HostName != "" {
+				seen[n.Metadata.HostName] = struct{}{}
+			}
+		}
+	}
+	for _, n := range r.Pod {
+		if n.Metadata.KubernetesClusterID != "" {
+			seen[n.Metadata.KubernetesClusterID] = struct{}{}
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
 }
 
 // Copy returns a value copy of the report.
@@ -672,6 +729,10 @@ func (r *Report) topology(name string) *Topology {
 		return &r.Host
 	case Overlay:
 		return &r.Overlay
+	case RuntimeEvent:
+		return &r.RuntimeEvent
+	case RoleBinding:
+		return &r.RoleBinding
 	}
 	return nil
 }
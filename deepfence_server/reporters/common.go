@@ -15,6 +15,7 @@ var (
 		utils.NEO4JMalwareScan:         "Malware",
 		utils.NEO4JComplianceScan:      "Compliance",
 		utils.NEO4JCloudComplianceScan: "CloudCompliance",
+		utils.NEO4JIaCScan:             "IaCFinding",
 	}
 	ScanResultIDField = map[utils.Neo4jScanType]string{
 		utils.NEO4JVulnerabilityScan:   "cve_id",
@@ -22,6 +23,7 @@ var (
 		utils.NEO4JMalwareScan:         "node_id",
 		utils.NEO4JComplianceScan:      "node_id",
 		utils.NEO4JCloudComplianceScan: "node_id",
+		utils.NEO4JIaCScan:             "node_id",
 	}
 )
 
@@ -0,0 +1,471 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j/dbtype"
+)
+
+const (
+	defaultAttackPathsLimit = 10
+	maxAttackPathsLimit     = 50
+	// findings listed per hop
+	attackPathTopFindings = 5
+)
+
+type AttackPathsRequest struct {
+	IssueType string   `json:"issue_type" validate:"omitempty,oneof=all vulnerability secret malware compliance" enum:"all,vulnerability,secret,malware,compliance"`
+	NodeIDs   []string `json:"node_ids"`
+	Limit     int      `json:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+// AttackPath is the shortest chain of connections from the internet to a
+// node with exploitable issues
+type AttackPath struct {
+	NodeID      string          `json:"node_id" required:"true"`
+	NodeName    string          `json:"node_name" required:"true"`
+	RiskScore   float64         `json:"risk_score" required:"true"`
+	Hops        []AttackPathHop `json:"hops" required:"true"`
+	Explanation string          `json:"explanation" required:"true"`
+}
+
+type AttackPathHop struct {
+	NodeID          string                    `json:"node_id" required:"true"`
+	NodeName        string                    `json:"node_name" required:"true"`
+	NodeType        string                    `json:"node_type" required:"true"`
+	Exposures       []AttackPathExposure      `json:"exposures" required:"true"`
+	Services        []AttackPathService       `json:"services" required:"true"`
+	Vulnerabilities []AttackPathVulnerability `json:"vulnerabilities" required:"true"`
+	Secrets         []AttackPathSecret        `json:"secrets" required:"true"`
+	Explanation     string                    `json:"explanation" required:"true"`
+}
+
+// AttackPathExposure is a security group rule or public cloud resource
+// letting the internet traffic reach the hop
+type AttackPathExposure struct {
+	ResourceID   string `json:"resource_id" required:"true"`
+	ResourceType string `json:"resource_type" required:"true"`
+	Name         string `json:"name" required:"true"`
+	CidrIpv4     string `json:"cidr_ipv4" required:"true"`
+}
+
+// AttackPathService is a port reached on the hop, with the process
+// listening on it when known
+type AttackPathService struct {
+	Port    int64  `json:"port" required:"true"`
+	Process string `json:"process" required:"true"`
+}
+
+type AttackPathVulnerability struct {
+	CveID               string  `json:"cve_id" required:"true"`
+	CveSeverity         string  `json:"cve_severity" required:"true"`
+	CveCausedByPackage  string  `json:"cve_caused_by_package" required:"true"`
+	CveCVSSScore        float64 `json:"cve_cvss_score" required:"true"`
+	ExploitabilityScore int64   `json:"exploitability_score" required:"true"`
+	HasLiveConnection   bool    `json:"has_live_connection" required:"true"`
+}
+
+type AttackPathSecret struct {
+	NodeID       string `json:"node_id" required:"true"`
+	Name         string `json:"name" required:"true"`
+	Level        string `json:"level" required:"true"`
+	FullFilename string `json:"full_filename" required:"true"`
+}
+
+func attackPathTargetCondition(issueType string) string {
+	switch issueType {
+	case "vulnerability":
+		return "m.exploitable_vulnerabilities_count > 0"
+	case "secret":
+		return "m.exploitable_secrets_count > 0"
+	case "malware":
+		return "m.exploitable_malwares_count > 0"
+	case "compliance":
+		return "m.warn_alarm_count > 0"
+	}
+	return `(m.exploitable_vulnerabilities_count > 0
+		OR m.exploitable_secrets_count > 0
+		OR m.exploitable_malwares_count > 0
+		OR m.warn_alarm_count > 0)`
+}
+
+// GetAttackPaths returns the attack paths of the riskiest nodes reachable
+// from the internet, along with the findings explaining each hop
+func GetAttackPaths(ctx context.Context, req AttackPathsRequest) ([]AttackPath, error) {
+	paths := []AttackPath{}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAttackPathsLimit
+	} else if limit > maxAttackPathsLimit {
+		limit = maxAttackPathsLimit
+	}
+	nodeIDs := req.NodeIDs
+	if nodeIDs == nil {
+		nodeIDs = []string{}
+	}
+
+	scoring := model.DefaultThreatScoringModel()
+	if pgClient, err := directory.PostgresClient(ctx); err == nil {
+		if s, err := model.GetThreatScoringModel(ctx, pgClient); err == nil {
+			scoring = s
+		} else {
+			log.Warn().Msgf("Failed to get threat scoring model: %v", err)
+		}
+	}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return paths, err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(60 * time.Second))
+	if err != nil {
+		return paths, err
+	}
+	defer tx.Close()
+
+	res, err := tx.Run(`
+		MATCH (m:Node)
+		WHERE m.depth IS NOT NULL
+		AND NOT m.node_id IN ['in-the-internet', 'out-the-internet']
+		AND (size($node_ids) = 0 OR m.node_id IN $node_ids)
+		AND `+attackPathTargetCondition(req.IssueType)+`
+		WITH m
+		ORDER BY COALESCE(m.risk_score, 0) DESC
+		LIMIT $limit
+		MATCH p=shortestPath((n:Node{node_id:'in-the-internet'}) -[:CONNECTS*1..3]-> (m))
+		RETURN m.node_id, COALESCE(m.risk_score, 0.0), p`,
+		map[string]interface{}{"node_ids": nodeIDs, "limit": limit})
+	if err != nil {
+		return paths, err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return paths, err
+	}
+
+	hops := map[string]*AttackPathHop{}
+	type edge struct{ source, target string }
+	edges := []edge{}
+	for _, rec := range recs {
+		p, ok := rec.Values[2].(dbtype.Path)
+		if !ok {
+			continue
+		}
+		path := AttackPath{
+			NodeID:    rec.Values[0].(string),
+			RiskScore: toFloat64(rec.Values[1]),
+			Hops:      []AttackPathHop{},
+		}
+		// the first node is the internet
+		for i, node := range p.Nodes[1:] {
+			id, _ := node.Props["node_id"].(string)
+			if _, has := hops[id]; !has {
+				name, _ := node.Props["node_name"].(string)
+				nodeType, _ := node.Props["node_type"].(string)
+				hops[id] = &AttackPathHop{
+					NodeID:          id,
+					NodeName:        name,
+					NodeType:        nodeType,
+					Exposures:       []AttackPathExposure{},
+					Services:        []AttackPathService{},
+					Vulnerabilities: []AttackPathVulnerability{},
+					Secrets:         []AttackPathSecret{},
+				}
+			}
+			source, _ := p.Nodes[i].Props["node_id"].(string)
+			edges = append(edges, edge{source: source, target: id})
+			path.Hops = append(path.Hops, AttackPathHop{NodeID: id})
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return paths, nil
+	}
+
+	hopIDs := make([]string, 0, len(hops))
+	for id := range hops {
+		hopIDs = append(hopIDs, id)
+	}
+	edgeRows := make([]map[string]interface{}, 0, len(edges))
+	for _, e := range edges {
+		edgeRows = append(edgeRows, map[string]interface{}{"source": e.source, "target": e.target})
+	}
+
+	// security group rules opened to the internet, the hosts are linked to
+	// their cloud instances by the link cloud resources task
+	res, err = tx.Run(`
+		UNWIND $node_ids as id
+		MATCH (n:Node{node_id: id}) -[:IS]-> (c:CloudResource)
+		OPTIONAL MATCH (r:CloudResource{id: 'aws_vpc_security_group_rule'}) -[:SECURED]-> (c)
+		WHERE NOT r.is_egress
+		WITH n, c, r
+		WHERE r IS NOT NULL OR exists((:Node{node_id:'in-the-internet'}) -[:PUBLIC]-> (c))
+		RETURN n.node_id,
+			COALESCE(r.node_id, c.node_id),
+			COALESCE(r.node_type, c.node_type, ''),
+			COALESCE(r.group_id, c.name, ''),
+			COALESCE(r.cidr_ipv4, '')`,
+		map[string]interface{}{"node_ids": hopIDs})
+	if err != nil {
+		return paths, err
+	}
+	recs, err = res.Collect()
+	if err != nil {
+		return paths, err
+	}
+	for _, rec := range recs {
+		hop := hops[rec.Values[0].(string)]
+		hop.Exposures = append(hop.Exposures, toAttackPathExposure(rec.Values[1:]))
+	}
+
+	// ports reached on the hops, the right pids are the listening processes
+	res, err = tx.Run(`
+		UNWIND $edges as edge
+		MATCH (:Node{node_id: edge.source}) -[r:CONNECTS]-> (n:Node{node_id: edge.target})
+		WHERE r.local_ports IS NOT NULL
+		UNWIND range(0, size(r.local_ports) - 1) AS i
+		WITH n, r.local_ports[i] AS port,
+			CASE WHEN r.right_pids IS NULL THEN null ELSE r.right_pids[i] END AS pid
+		OPTIONAL MATCH (p:Process{node_id: n.node_id + ';' + toString(pid)})
+		RETURN DISTINCT n.node_id, port, COALESCE(p.short_name, p.node_name, '')`,
+		map[string]interface{}{"edges": edgeRows})
+	if err != nil {
+		return paths, err
+	}
+	recs, err = res.Collect()
+	if err != nil {
+		return paths, err
+	}
+	for _, rec := range recs {
+		hop := hops[rec.Values[0].(string)]
+		port, ok := rec.Values[1].(int64)
+		if !ok {
+			continue
+		}
+		process, _ := rec.Values[2].(string)
+		hop.Services = append(hop.Services, AttackPathService{Port: port, Process: process})
+	}
+
+	// top exploitable vulnerabilities of the hosts and their containers
+	res, err = tx.Run(`
+		UNWIND $node_ids as id
+		MATCH (n:Node{node_id: id})
+		OPTIONAL MATCH (n) -[:HOSTS]-> (c:Container)
+		WITH n, collect(c) + [n] as targets
+		UNWIND targets as t
+		MATCH (t) <-[:SCANNED]- (s:VulnerabilityScan)
+		WHERE s.status = $success
+		WITH n, t, max(s.updated_at) as latest
+		MATCH (t) <-[:SCANNED]- (:VulnerabilityScan{updated_at: latest}) -[:DETECTED]-> (v:Vulnerability)
+		WHERE v.exploitability_score IN $scores
+		OPTIONAL MATCH (v) -[:IS]-> (r:VulnerabilityStub)
+		WITH DISTINCT n, v, COALESCE(r.cve_cvss_score, 0.0) as cvss
+		ORDER BY v.exploitability_score DESC, cvss DESC
+		WITH n, collect([v.cve_id, v.cve_severity, v.cve_caused_by_package, cvss,
+			v.exploitability_score, COALESCE(v.has_live_connection, false)])[..$top] as vulns
+		RETURN n.node_id, vulns`,
+		map[string]interface{}{
+			"node_ids": hopIDs,
+			"success":  utils.ScanStatusSuccess,
+			"scores":   scoring.VulnerabilityExploitabilityScores,
+			"top":      attackPathTopFindings,
+		})
+	if err != nil {
+		return paths, err
+	}
+	recs, err = res.Collect()
+	if err != nil {
+		return paths, err
+	}
+	for _, rec := range recs {
+		hop := hops[rec.Values[0].(string)]
+		vulns, _ := rec.Values[1].([]interface{})
+		for _, v := range vulns {
+			fields, ok := v.([]interface{})
+			if !ok || len(fields) != 6 {
+				continue
+			}
+			vuln := AttackPathVulnerability{CveCVSSScore: toFloat64(fields[3])}
+			vuln.CveID, _ = fields[0].(string)
+			vuln.CveSeverity, _ = fields[1].(string)
+			vuln.CveCausedByPackage, _ = fields[2].(string)
+			vuln.ExploitabilityScore, _ = fields[4].(int64)
+			vuln.HasLiveConnection, _ = fields[5].(bool)
+			hop.Vulnerabilities = append(hop.Vulnerabilities, vuln)
+		}
+	}
+
+	// top secrets of the hosts and their containers
+	res, err = tx.Run(`
+		UNWIND $node_ids as id
+		MATCH (n:Node{node_id: id})
+		OPTIONAL MATCH (n) -[:HOSTS]-> (c:Container)
+		WITH n, collect(c) + [n] as targets
+		UNWIND targets as t
+		MATCH (t) <-[:SCANNED]- (s:SecretScan)
+		WHERE s.status = $success
+		WITH n, t, max(s.updated_at) as latest
+		MATCH (t) <-[:SCANNED]- (:SecretScan{updated_at: latest}) -[:DETECTED]-> (v:Secret)
+		WHERE v.level IN $severities
+		OPTIONAL MATCH (v) -[:IS]-> (r:SecretRule)
+		WITH DISTINCT n, v, COALESCE(r.name, '') as name,
+			CASE v.level WHEN 'critical' THEN 4 WHEN 'high' THEN 3 WHEN 'medium' THEN 2 ELSE 1 END as rank
+		ORDER BY rank DESC
+		WITH n, collect([v.node_id, name, v.level, COALESCE(v.full_filename, '')])[..$top] as secrets
+		RETURN n.node_id, secrets`,
+		map[string]interface{}{
+			"node_ids":   hopIDs,
+			"success":    utils.ScanStatusSuccess,
+			"severities": scoring.SecretSeverities,
+			"top":        attackPathTopFindings,
+		})
+	if err != nil {
+		return paths, err
+	}
+	recs, err = res.Collect()
+	if err != nil {
+		return paths, err
+	}
+	for _, rec := range recs {
+		hop := hops[rec.Values[0].(string)]
+		secrets, _ := rec.Values[1].([]interface{})
+		for _, s := range secrets {
+			fields, ok := s.([]interface{})
+			if !ok || len(fields) != 4 {
+				continue
+			}
+			secret := AttackPathSecret{}
+			secret.NodeID, _ = fields[0].(string)
+			secret.Name, _ = fields[1].(string)
+			secret.Level, _ = fields[2].(string)
+			secret.FullFilename, _ = fields[3].(string)
+			hop.Secrets = append(hop.Secrets, secret)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return paths, err
+	}
+
+	for i := range paths {
+		for j := range paths[i].Hops {
+			hop := *hops[paths[i].Hops[j].NodeID]
+			sort.Slice(hop.Services, func(a, b int) bool { return hop.Services[a].Port < hop.Services[b].Port })
+			hop.Explanation = explainAttackPathHop(hop, j == 0)
+			paths[i].Hops[j] = hop
+		}
+		if n := len(paths[i].Hops); n > 0 {
+			paths[i].NodeName = paths[i].Hops[n-1].NodeName
+		}
+		paths[i].Explanation = explainAttackPath(paths[i])
+	}
+	return paths, nil
+}
+
+// toAttackPathExposure reads the resource id, type, name and cidr of an
+// exposure row
+func toAttackPathExposure(values []interface{}) AttackPathExposure {
+	e := AttackPathExposure{}
+	e.ResourceID, _ = values[0].(string)
+	e.ResourceType, _ = values[1].(string)
+	e.Name, _ = values[2].(string)
+	e.CidrIpv4, _ = values[3].(string)
+	return e
+}
+
+func hopName(hop AttackPathHop) string {
+	if hop.NodeName != "" {
+		return hop.NodeName
+	}
+	return hop.NodeID
+}
+
+// explainAttackPathHop describes how the hop is reached and what an
+// attacker can exploit on it
+func explainAttackPathHop(hop AttackPathHop, fromInternet bool) string {
+	var sb strings.Builder
+	sb.WriteString(hopName(hop))
+	if fromInternet {
+		sb.WriteString(" is reachable from the internet")
+	} else {
+		sb.WriteString(" is reachable from the previous hop")
+	}
+
+	if len(hop.Services) > 0 {
+		services := make([]string, 0, len(hop.Services))
+		for _, s := range hop.Services {
+			if s.Process != "" {
+				services = append(services, fmt.Sprintf("%d (%s)", s.Port, s.Process))
+			} else {
+				services = append(services, fmt.Sprintf("%d", s.Port))
+			}
+		}
+		sb.WriteString(" on port " + strings.Join(services, ", "))
+	}
+
+	if fromInternet && len(hop.Exposures) > 0 {
+		exposures := make([]string, 0, len(hop.Exposures))
+		for _, e := range hop.Exposures {
+			name := e.Name
+			if name == "" {
+				name = e.ResourceID
+			}
+			if e.CidrIpv4 != "" {
+				name += " allowing " + e.CidrIpv4
+			}
+			exposures = append(exposures, name)
+		}
+		sb.WriteString(" through " + strings.Join(exposures, ", "))
+	}
+	sb.WriteString(".")
+
+	if len(hop.Vulnerabilities) > 0 {
+		cves := make([]string, 0, len(hop.Vulnerabilities))
+		for _, v := range hop.Vulnerabilities {
+			cves = append(cves, fmt.Sprintf("%s (%s)", v.CveID, v.CveSeverity))
+		}
+		fmt.Fprintf(&sb, " Exploitable vulnerabilities: %s.", strings.Join(cves, ", "))
+	}
+	if len(hop.Secrets) > 0 {
+		secrets := make([]string, 0, len(hop.Secrets))
+		for _, s := range hop.Secrets {
+			name := s.Name
+			if name == "" {
+				name = s.FullFilename
+			}
+			secrets = append(secrets, fmt.Sprintf("%s (%s)", name, s.Level))
+		}
+		fmt.Fprintf(&sb, " Exposed secrets: %s.", strings.Join(secrets, ", "))
+	}
+	return sb.String()
+}
+
+func explainAttackPath(path AttackPath) string {
+	names := make([]string, 0, len(path.Hops)+1)
+	names = append(names, "internet")
+	vulns, secrets := 0, 0
+	for _, hop := range path.Hops {
+		names = append(names, hopName(hop))
+		vulns += len(hop.Vulnerabilities)
+		secrets += len(hop.Secrets)
+	}
+	target := path.NodeName
+	if target == "" {
+		target = path.NodeID
+	}
+	return fmt.Sprintf("%s can be reached from the internet in %d hop(s) (%s), exposing %d exploitable vulnerabilities and %d secrets along the path, risk score %.2f.",
+		target, len(path.Hops), strings.Join(names, " -> "), vulns, secrets, path.RiskScore)
+}
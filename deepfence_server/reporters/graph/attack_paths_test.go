@@ -0,0 +1,51 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_explain_attack_path(t *testing.T) {
+	entry := AttackPathHop{
+		NodeID:    "lb-host",
+		NodeName:  "lb",
+		Services:  []AttackPathService{{Port: 443, Process: "nginx"}},
+		Exposures: []AttackPathExposure{{ResourceID: "sgr-1", Name: "sg-1", CidrIpv4: "0.0.0.0/0"}},
+	}
+	target := AttackPathHop{
+		NodeID:          "db-host",
+		Services:        []AttackPathService{{Port: 5432}},
+		Vulnerabilities: []AttackPathVulnerability{{CveID: "CVE-2023-1", CveSeverity: "critical"}},
+		Secrets:         []AttackPathSecret{{FullFilename: "/etc/key.pem", Level: "high"}},
+	}
+	assert.Equal(t, explainAttackPathHop(entry, true),
+		"lb is reachable from the internet on port 443 (nginx) through sg-1 allowing 0.0.0.0/0.")
+	assert.Equal(t, explainAttackPathHop(target, false),
+		"db-host is reachable from the previous hop on port 5432."+
+			" Exploitable vulnerabilities: CVE-2023-1 (critical). Exposed secrets: /etc/key.pem (high).")
+
+	path := AttackPath{NodeID: "db-host", RiskScore: 3, Hops: []AttackPathHop{entry, target}}
+	assert.Equal(t, explainAttackPath(path),
+		"db-host can be reached from the internet in 2 hop(s) (internet -> lb -> db-host),"+
+			" exposing 1 exploitable vulnerabilities and 1 secrets along the path, risk score 3.00.")
+}
+
+func Test_attack_path_exposure(t *testing.T) {
+	// security group rule securing the cloud instance of the hop
+	e := toAttackPathExposure([]interface{}{"sgr-1", "aws_vpc_security_group_rule", "sg-1", "0.0.0.0/0"})
+	assert.DeepEqual(t, e, AttackPathExposure{
+		ResourceID:   "sgr-1",
+		ResourceType: "aws_vpc_security_group_rule",
+		Name:         "sg-1",
+		CidrIpv4:     "0.0.0.0/0",
+	})
+
+	// public cloud instance without rule
+	e = toAttackPathExposure([]interface{}{"i-1", "aws_ec2_instance", "web", ""})
+	assert.Equal(t, e.ResourceID, "i-1")
+	assert.Equal(t, e.CidrIpv4, "")
+
+	hop := AttackPathHop{NodeID: "web-host", Exposures: []AttackPathExposure{e}}
+	assert.Equal(t, explainAttackPathHop(hop, true), "web-host is reachable from the internet through web.")
+}
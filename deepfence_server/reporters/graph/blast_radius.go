@@ -0,0 +1,417 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const (
+	defaultBlastRadiusHops  = 3
+	defaultBlastRadiusLimit = 100
+	// pods followed from each pod through its namespace or service
+	// account, the riskiest first
+	blastRadiusPeersLimit = 50
+
+	BlastRadiusViaConnects       = "connects"
+	BlastRadiusViaNamespace      = "namespace"
+	BlastRadiusViaServiceAccount = "service_account"
+	BlastRadiusViaSecurityGroup  = "security_group"
+	BlastRadiusViaCloud          = "cloud_relationship"
+	BlastRadiusViaHosted         = "hosted"
+)
+
+var ErrBlastRadiusNodeNotFound = errors.New("node not found")
+
+// neo4j labels of the blast radius node types
+var blastRadiusLabels = map[string]string{
+	"host":           "Node",
+	"container":      "Container",
+	"pod":            "Pod",
+	"cloud_resource": "CloudResource",
+}
+
+type BlastRadiusRequest struct {
+	NodeID   string `json:"node_id" validate:"required" required:"true"`
+	NodeType string `json:"node_type" validate:"required,oneof=host container pod cloud_resource" required:"true" enum:"host,container,pod,cloud_resource"`
+	MaxHops  int    `json:"max_hops" validate:"omitempty,min=1,max=5"`
+	Limit    int    `json:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// BlastRadiusAsset is an asset reachable from the compromised node, Via
+// lists the kinds of relationships it was reached through
+type BlastRadiusAsset struct {
+	NodeID                          string   `json:"node_id" required:"true"`
+	NodeName                        string   `json:"node_name" required:"true"`
+	NodeType                        string   `json:"node_type" required:"true"`
+	Hops                            int      `json:"hops" required:"true"`
+	Via                             []string `json:"via" required:"true"`
+	VulnerabilitiesCount            int64    `json:"vulnerabilities_count" required:"true"`
+	ExploitableVulnerabilitiesCount int64    `json:"exploitable_vulnerabilities_count" required:"true"`
+	SecretsCount                    int64    `json:"secrets_count" required:"true"`
+	ExploitableSecretsCount         int64    `json:"exploitable_secrets_count" required:"true"`
+	RiskScore                       float64  `json:"risk_score" required:"true"`
+}
+
+type BlastRadius struct {
+	NodeID   string             `json:"node_id" required:"true"`
+	NodeType string             `json:"node_type" required:"true"`
+	MaxHops  int                `json:"max_hops" required:"true"`
+	Total    int                `json:"total" required:"true"`
+	Assets   []BlastRadiusAsset `json:"assets" required:"true"`
+}
+
+// blastRadiusFrontier holds the node ids to expand, by neo4j label
+type blastRadiusFrontier map[string][]string
+
+func (f blastRadiusFrontier) params() map[string]interface{} {
+	params := map[string]interface{}{"peers_limit": blastRadiusPeersLimit}
+	for _, label := range blastRadiusLabels {
+		ids := f[label]
+		if ids == nil {
+			ids = []string{}
+		}
+		params[label] = ids
+	}
+	return params
+}
+
+func (f blastRadiusFrontier) isEmpty() bool {
+	for _, ids := range f {
+		if len(ids) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+const blastRadiusReturn = `
+	RETURN DISTINCT m.node_id,
+		COALESCE(m.node_name, m.name, m.node_id),
+		labels(m),
+		COALESCE(m.vulnerabilities_count, 0),
+		COALESCE(m.exploitable_vulnerabilities_count, 0),
+		COALESCE(m.secrets_count, 0),
+		COALESCE(m.exploitable_secrets_count, 0),
+		COALESCE(m.risk_score, 0.0)`
+
+// relationships followed on each hop
+var blastRadiusHopQueries = []struct {
+	via   string
+	query string
+}{
+	{
+		via: BlastRadiusViaConnects,
+		query: `
+		MATCH (n:Node) -[:CONNECTS]-> (m:Node)
+		WHERE n.node_id IN $Node
+		AND NOT m.node_id IN ['in-the-internet', 'out-the-internet']`,
+	},
+	{
+		via: BlastRadiusViaConnects,
+		query: `
+		MATCH (n:Node) -[:FLOWS]-> (m)
+		WHERE n.node_id IN $Node
+		AND NOT m.node_id IN ['in-the-internet', 'out-the-internet']`,
+	},
+	{
+		via: BlastRadiusViaConnects,
+		query: `
+		MATCH (n:Pod) -[:FLOWS]-> (m)
+		WHERE n.node_id IN $Pod
+		AND NOT m.node_id IN ['in-the-internet', 'out-the-internet']`,
+	},
+	{
+		via: BlastRadiusViaServiceAccount,
+		query: `
+		MATCH (n:Pod)
+		WHERE n.node_id IN $Pod
+		AND n.kubernetes_service_account IS NOT NULL
+		MATCH (m:Pod)
+		WHERE m.kubernetes_cluster_id = n.kubernetes_cluster_id
+		AND m.kubernetes_namespace = n.kubernetes_namespace
+		AND m.kubernetes_service_account = n.kubernetes_service_account
+		AND m.active = true
+		AND m <> n
+		WITH n, m
+		ORDER BY COALESCE(m.risk_score, 0.0) DESC
+		WITH n, collect(m)[..$peers_limit] as peers
+		UNWIND peers as m
+		WITH m`,
+	},
+	{
+		via: BlastRadiusViaNamespace,
+		query: `
+		MATCH (n:Pod)
+		WHERE n.node_id IN $Pod
+		MATCH (m:Pod)
+		WHERE m.kubernetes_cluster_id = n.kubernetes_cluster_id
+		AND m.kubernetes_namespace = n.kubernetes_namespace
+		AND m.active = true
+		AND m <> n
+		WITH n, m
+		ORDER BY COALESCE(m.risk_score, 0.0) DESC
+		WITH n, collect(m)[..$peers_limit] as peers
+		UNWIND peers as m
+		WITH m`,
+	},
+	{
+		via: BlastRadiusViaSecurityGroup,
+		query: `
+		MATCH (n:CloudResource) <-[:SECURED]- (:CloudResource) -[:SECURED]-> (m:CloudResource)
+		WHERE n.node_id IN $CloudResource
+		AND m <> n`,
+	},
+	{
+		via: BlastRadiusViaCloud,
+		query: `
+		MATCH (n:CloudResource) -[]-> (m:CloudResource)
+		WHERE n.node_id IN $CloudResource
+		AND m.id <> 'aws_vpc_security_group_rule'`,
+	},
+}
+
+// assets sharing the host or instance of the reached ones, they are
+// counted at the same distance. The hosts are linked to their instances by
+// the link cloud resources task.
+var blastRadiusHostedQueries = []string{
+	`
+	MATCH (n:Node) -[:HOSTS]-> (m)
+	WHERE n.node_id IN $Node
+	AND (m:Container OR m:Pod)
+	AND m.active = true`,
+	`
+	MATCH (n:Node) -[:IS]-> (m:CloudResource)
+	WHERE n.node_id IN $Node`,
+	`
+	MATCH (n:CloudResource) <-[:IS]- (m:Node)
+	WHERE n.node_id IN $CloudResource`,
+}
+
+// other layers of the compromised node, as host, pod and cloud resource ids
+var blastRadiusSeedQueries = map[string]string{
+	"host": `
+		MATCH (n:Node{node_id: $id})
+		OPTIONAL MATCH (n) -[:IS]-> (c:CloudResource)
+		RETURN [], [], collect(c.node_id)`,
+	"container": `
+		MATCH (c:Container{node_id: $id})
+		OPTIONAL MATCH (h:Node) -[:HOSTS]-> (c)
+		OPTIONAL MATCH (p:Pod{node_id: c.pod_id})
+		RETURN collect(DISTINCT h.node_id), collect(DISTINCT p.node_id), []`,
+	"pod": `
+		MATCH (p:Pod{node_id: $id})
+		OPTIONAL MATCH (h:Node) -[:HOSTS]-> (p)
+		RETURN collect(h.node_id), [], []`,
+	"cloud_resource": `
+		MATCH (c:CloudResource{node_id: $id})
+		OPTIONAL MATCH (n:Node) -[:IS]-> (c)
+		RETURN collect(n.node_id), [], []`,
+}
+
+func blastRadiusNodeType(labels []interface{}) (string, string) {
+	for _, l := range labels {
+		label, _ := l.(string)
+		for nodeType, nodeLabel := range blastRadiusLabels {
+			if nodeLabel == label {
+				return nodeType, label
+			}
+		}
+	}
+	return "", ""
+}
+
+func toInt64(v interface{}) int64 {
+	switch i := v.(type) {
+	case int64:
+		return i
+	case float64:
+		return int64(i)
+	}
+	return 0
+}
+
+// GetBlastRadius lists the assets reachable from a compromised node,
+// ranked by their exploitable findings
+func GetBlastRadius(ctx context.Context, req BlastRadiusRequest) (BlastRadius, error) {
+	maxHops := req.MaxHops
+	if maxHops <= 0 {
+		maxHops = defaultBlastRadiusHops
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultBlastRadiusLimit
+	}
+	res := BlastRadius{
+		NodeID:   req.NodeID,
+		NodeType: req.NodeType,
+		MaxHops:  maxHops,
+		Assets:   []BlastRadiusAsset{},
+	}
+
+	label, has := blastRadiusLabels[req.NodeType]
+	if !has {
+		return res, fmt.Errorf("unsupported node type %q", req.NodeType)
+	}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(60 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	// the compromised node is expanded from all its layers: the host and
+	// pod of a container, the instance of a host and the other way around
+	r, err := tx.Run(blastRadiusSeedQueries[req.NodeType],
+		map[string]interface{}{"id": req.NodeID})
+	if err != nil {
+		return res, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return res, ErrBlastRadiusNodeNotFound
+	}
+	seeds := map[string]struct{}{req.NodeID: {}}
+	frontier := blastRadiusFrontier{label: {req.NodeID}}
+	for i, seedLabel := range []string{"Node", "Pod", "CloudResource"} {
+		ids, _ := rec.Values[i].([]interface{})
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				if _, has := seeds[s]; !has {
+					seeds[s] = struct{}{}
+					frontier[seedLabel] = append(frontier[seedLabel], s)
+				}
+			}
+		}
+	}
+
+	assets := map[string]*BlastRadiusAsset{}
+	addAsset := func(next blastRadiusFrontier, values []interface{}, via string, hops int) {
+		id, _ := values[0].(string)
+		if _, seed := seeds[id]; seed || id == "" {
+			return
+		}
+		if asset, has := assets[id]; has {
+			if asset.Hops == hops && !containsString(asset.Via, via) {
+				asset.Via = append(asset.Via, via)
+			}
+			return
+		}
+		labels, _ := values[2].([]interface{})
+		nodeType, nodeLabel := blastRadiusNodeType(labels)
+		if nodeType == "" {
+			return
+		}
+		name, _ := values[1].(string)
+		assets[id] = &BlastRadiusAsset{
+			NodeID:                          id,
+			NodeName:                        name,
+			NodeType:                        nodeType,
+			Hops:                            hops,
+			Via:                             []string{via},
+			VulnerabilitiesCount:            toInt64(values[3]),
+			ExploitableVulnerabilitiesCount: toInt64(values[4]),
+			SecretsCount:                    toInt64(values[5]),
+			ExploitableSecretsCount:         toInt64(values[6]),
+			RiskScore:                       toFloat64(values[7]),
+		}
+		next[nodeLabel] = append(next[nodeLabel], id)
+	}
+
+	for hops := 1; hops <= maxHops && !frontier.isEmpty(); hops++ {
+		next := blastRadiusFrontier{}
+		for _, q := range blastRadiusHopQueries {
+			r, err := tx.Run(q.query+blastRadiusReturn, frontier.params())
+			if err != nil {
+				return res, err
+			}
+			recs, err := r.Collect()
+			if err != nil {
+				return res, err
+			}
+			for _, rec := range recs {
+				addAsset(next, rec.Values, q.via, hops)
+			}
+		}
+
+		// hosted assets are expanded until no new one is found, hosts
+		// are both reached through their instances and the other way
+		hosted := next
+		for !hosted.isEmpty() {
+			found := blastRadiusFrontier{}
+			for _, q := range blastRadiusHostedQueries {
+				r, err := tx.Run(q+blastRadiusReturn, hosted.params())
+				if err != nil {
+					return res, err
+				}
+				recs, err := r.Collect()
+				if err != nil {
+					return res, err
+				}
+				for _, rec := range recs {
+					addAsset(found, rec.Values, BlastRadiusViaHosted, hops)
+				}
+			}
+			for l, ids := range found {
+				next[l] = append(next[l], ids...)
+			}
+			hosted = found
+		}
+		frontier = next
+	}
+
+	res.Assets = rankBlastRadiusAssets(assets)
+	res.Total = len(res.Assets)
+	if len(res.Assets) > limit {
+		res.Assets = res.Assets[:limit]
+	}
+	return res, nil
+}
+
+// rankBlastRadiusAssets sorts the assets by exploitable findings first,
+// then by all findings and distance
+func rankBlastRadiusAssets(assets map[string]*BlastRadiusAsset) []BlastRadiusAsset {
+	res := make([]BlastRadiusAsset, 0, len(assets))
+	for _, a := range assets {
+		sort.Strings(a.Via)
+		res = append(res, *a)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		ei := res[i].ExploitableVulnerabilitiesCount + res[i].ExploitableSecretsCount
+		ej := res[j].ExploitableVulnerabilitiesCount + res[j].ExploitableSecretsCount
+		if ei != ej {
+			return ei > ej
+		}
+		ti := res[i].VulnerabilitiesCount + res[i].SecretsCount
+		tj := res[j].VulnerabilitiesCount + res[j].SecretsCount
+		if ti != tj {
+			return ti > tj
+		}
+		if res[i].Hops != res[j].Hops {
+			return res[i].Hops < res[j].Hops
+		}
+		return res[i].NodeID < res[j].NodeID
+	})
+	return res
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
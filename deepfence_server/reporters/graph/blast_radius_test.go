@@ -0,0 +1,43 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_rank_blast_radius_assets(t *testing.T) {
+	ranked := rankBlastRadiusAssets(map[string]*BlastRadiusAsset{
+		"far-exploitable": {NodeID: "far-exploitable", Hops: 3, ExploitableVulnerabilitiesCount: 2, Via: []string{BlastRadiusViaConnects}},
+		"near-secrets":    {NodeID: "near-secrets", Hops: 1, ExploitableSecretsCount: 1, SecretsCount: 4},
+		"near-clean":      {NodeID: "near-clean", Hops: 1},
+		"far-clean":       {NodeID: "far-clean", Hops: 2},
+		"near-vulnerable": {NodeID: "near-vulnerable", Hops: 1, VulnerabilitiesCount: 10,
+			Via: []string{BlastRadiusViaNamespace, BlastRadiusViaConnects}},
+	})
+
+	ids := []string{}
+	for _, a := range ranked {
+		ids = append(ids, a.NodeID)
+	}
+	assert.DeepEqual(t, ids, []string{"far-exploitable", "near-secrets", "near-vulnerable", "near-clean", "far-clean"})
+	assert.DeepEqual(t, ranked[2].Via, []string{BlastRadiusViaConnects, BlastRadiusViaNamespace})
+}
+
+func Test_blast_radius_frontier_params(t *testing.T) {
+	params := blastRadiusFrontier{"Pod": {"pod-1"}}.params()
+	assert.Equal(t, params["peers_limit"], blastRadiusPeersLimit)
+	assert.DeepEqual(t, params["Pod"], []string{"pod-1"})
+	assert.DeepEqual(t, params["Node"], []string{})
+
+	// every hop query gets all its parameters
+	for _, q := range blastRadiusHopQueries {
+		for _, name := range []string{"$Node", "$Pod", "$CloudResource", "$peers_limit"} {
+			if strings.Contains(q.query, name) {
+				_, has := params[strings.TrimPrefix(name, "$")]
+				assert.Assert(t, has, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	coreV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultNetworkPolicyWindow = 24 * time.Hour
+	namespaceNameLabel         = "kubernetes.io/metadata.name"
+	internetCIDR               = "0.0.0.0/0"
+)
+
+// labels set by controllers on every pod, they differ between replicas
+// and can't be used in selectors
+var volatilePodLabels = map[string]struct{}{
+	"pod-template-hash":                  {},
+	"controller-revision-hash":           {},
+	"pod-template-generation":            {},
+	"statefulset.kubernetes.io/pod-name": {},
+	"controller-uid":                     {},
+	"batch.kubernetes.io/controller-uid": {},
+	"job-name":                           {},
+	"batch.kubernetes.io/job-name":       {},
+}
+
+var policyNameReplacer = regexp.MustCompile(`[^a-z0-9-]+`)
+
+type NetworkPolicyRequest struct {
+	KubernetesClusterID string `json:"kubernetes_cluster_id" validate:"required" required:"true"`
+	Namespace           string `json:"namespace"`
+	WindowMinutes       int    `json:"window_minutes" validate:"omitempty,min=1,max=10080"`
+}
+
+type NetworkPolicyResponse struct {
+	Namespaces  []string `json:"namespaces" required:"true"`
+	PolicyCount int      `json:"policy_count" required:"true"`
+	YAML        string   `json:"yaml" required:"true"`
+}
+
+// flowPeer is one end of an observed flow: a pod, a host or the internet
+type flowPeer struct {
+	nodeID    string
+	isPod     bool
+	clusterID string
+	namespace string
+	labels    map[string]string
+	ips       []string
+}
+
+type podFlow struct {
+	src  flowPeer
+	dst  flowPeer
+	port int
+}
+
+// GetNetworkPolicies derives least privilege NetworkPolicies for the pods
+// of a cluster, or of one of its namespaces, from the flows observed
+// between pods, hosts and the internet during the requested window.
+func GetNetworkPolicies(ctx context.Context, req NetworkPolicyRequest) (NetworkPolicyResponse, error) {
+	res := NetworkPolicyResponse{Namespaces: []string{}}
+
+	window := defaultNetworkPolicyWindow
+	if req.WindowMinutes > 0 {
+		window = time.Duration(req.WindowMinutes) * time.Minute
+	}
+
+	flows, err := getPodFlows(ctx, req.KubernetesClusterID, time.Now().Add(-window))
+	if err != nil {
+		return res, err
+	}
+
+	policies := buildNetworkPolicies(flows, req.KubernetesClusterID, req.Namespace)
+
+	namespaces := map[string]struct{}{}
+	var buf bytes.Buffer
+	for i := range policies {
+		out, err := yaml.Marshal(policies[i])
+		if err != nil {
+			return res, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(out)
+		namespaces[policies[i].Namespace] = struct{}{}
+	}
+	for ns := range namespaces {
+		res.Namespaces = append(res.Namespaces, ns)
+	}
+	sort.Strings(res.Namespaces)
+	res.PolicyCount = len(policies)
+	res.YAML = buf.String()
+	return res, nil
+}
+
+func getPodFlows(ctx context.Context, clusterID string, since time.Time) ([]podFlow, error) {
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (s) -[f:FLOWS]-> (d)
+		WHERE f.last_seen_at >= $since
+		AND ((s:Pod AND s.kubernetes_cluster_id = $cluster_id)
+			OR (d:Pod AND d.kubernetes_cluster_id = $cluster_id))
+		RETURN s.node_id, s:Pod, s.kubernetes_cluster_id, s.kubernetes_namespace,
+			CASE WHEN s:Pod THEN s.kubernetes_labels ELSE null END,
+			CASE WHEN s:Pod THEN [s.kubernetes_ip] ELSE s.private_ip END,
+			d.node_id, d:Pod, d.kubernetes_cluster_id, d.kubernetes_namespace,
+			CASE WHEN d:Pod THEN d.kubernetes_labels ELSE null END,
+			CASE WHEN d:Pod THEN [d.kubernetes_ip] ELSE d.private_ip END,
+			f.port`,
+		map[string]interface{}{
+			"cluster_id": clusterID,
+			"since":      since.UnixMilli(),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := r.Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	flows := make([]podFlow, 0, len(records))
+	for _, rec := range records {
+		port, _ := rec.Values[12].(int64)
+		flows = append(flows, podFlow{
+			src:  recordToFlowPeer(rec.Values[0:6]),
+			dst:  recordToFlowPeer(rec.Values[6:12]),
+			port: int(port),
+		})
+	}
+	return flows, nil
+}
+
+func recordToFlowPeer(values []interface{}) flowPeer {
+	p := flowPeer{labels: map[string]string{}, ips: []string{}}
+	p.nodeID, _ = values[0].(string)
+	p.isPod, _ = values[1].(bool)
+	p.clusterID, _ = values[2].(string)
+	p.namespace, _ = values[3].(string)
+	if labels, ok := values[4].(string); ok && labels != "" {
+		if err := json.Unmarshal([]byte(labels), &p.labels); err != nil {
+			log.Warn().Msgf("invalid labels on pod %s: %v", p.nodeID, err)
+		}
+	}
+	if ips, ok := values[5].([]interface{}); ok {
+		for _, ip := range ips {
+			if s, ok := ip.(string); ok && s != "" {
+				p.ips = append(p.ips, s)
+			}
+		}
+	}
+	return p
+}
+
+// selectorLabels keeps the labels shared by all the replicas of a workload
+func selectorLabels(labels map[string]string) map[string]string {
+	res := map[string]string{}
+	for k, v := range labels {
+		if _, has := volatilePodLabels[k]; has {
+			continue
+		}
+		res[k] = v
+	}
+	return res
+}
+
+func selectorKey(namespace string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteString(namespace)
+	for _, k := range keys {
+		buf.WriteByte(';')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(labels[k])
+	}
+	return buf.String()
+}
+
+type workloadRules struct {
+	namespace string
+	selector  map[string]string
+	ingress   map[string]networkingV1.NetworkPolicyIngressRule
+	egress    map[string]networkingV1.NetworkPolicyEgressRule
+}
+
+// buildNetworkPolicies groups the pods by workload, using their stable
+// labels, and allows only the observed ingress and egress flows of each
+// group. DNS egress is always allowed as UDP flows are not tracked.
+func buildNetworkPolicies(flows []podFlow, clusterID, namespace string) []networkingV1.NetworkPolicy {
+	workloads := map[string]*workloadRules{}
+
+	getWorkload := func(p flowPeer) *workloadRules {
+		if !p.isPod || p.clusterID != clusterID {
+			return nil
+		}
+		if namespace != "" && p.namespace != namespace {
+			return nil
+		}
+		selector := selectorLabels(p.labels)
+		if len(selector) == 0 {
+			log.Debug().Msgf("pod %s has no labels to select it", p.nodeID)
+			return nil
+		}
+		key := selectorKey(p.namespace, selector)
+		w, has := workloads[key]
+		if !has {
+			w = &workloadRules{
+				namespace: p.namespace,
+				selector:  selector,
+				ingress:   map[string]networkingV1.NetworkPolicyIngressRule{},
+				egress:    map[string]networkingV1.NetworkPolicyEgressRule{},
+			}
+			workloads[key] = w
+		}
+		return w
+	}
+
+	for _, f := range flows {
+		if w := getWorkload(f.src); w != nil {
+			if peers := policyPeers(f.dst, clusterID, w.namespace); len(peers) > 0 {
+				key := peersKey(f.dst, f.port)
+				w.egress[key] = networkingV1.NetworkPolicyEgressRule{
+					To:    peers,
+					Ports: policyPorts(f.port),
+				}
+			}
+		}
+		if w := getWorkload(f.dst); w != nil {
+			if peers := policyPeers(f.src, clusterID, w.namespace); len(peers) > 0 {
+				key := peersKey(f.src, f.port)
+				w.ingress[key] = networkingV1.NetworkPolicyIngressRule{
+					From:  peers,
+					Ports: policyPorts(f.port),
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(workloads))
+	for k := range workloads {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]networkingV1.NetworkPolicy, 0, len(workloads))
+	for _, k := range keys {
+		w := workloads[k]
+		policy := networkingV1.NetworkPolicy{
+			TypeMeta: metaV1.TypeMeta{
+				APIVersion: "networking.k8s.io/v1",
+				Kind:       "NetworkPolicy",
+			},
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      policyName(k, w.selector),
+				Namespace: w.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "deepfence"},
+			},
+			Spec: networkingV1.NetworkPolicySpec{
+				PodSelector: metaV1.LabelSelector{MatchLabels: w.selector},
+				PolicyTypes: []networkingV1.PolicyType{
+					networkingV1.PolicyTypeIngress,
+					networkingV1.PolicyTypeEgress,
+				},
+				Ingress: []networkingV1.NetworkPolicyIngressRule{},
+				Egress:  []networkingV1.NetworkPolicyEgressRule{dnsEgressRule()},
+			},
+		}
+		for _, rk := range sortedKeys(w.ingress) {
+			policy.Spec.Ingress = append(policy.Spec.Ingress, w.ingress[rk])
+		}
+		for _, rk := range sortedKeys(w.egress) {
+			policy.Spec.Egress = append(policy.Spec.Egress, w.egress[rk])
+		}
+		res = append(res, policy)
+	}
+	return res
+}
+
+// policyPeers converts the other end of a flow into policy peers. Pods of
+// the same cluster are selected by labels, anything else by IP.
+func policyPeers(p flowPeer, clusterID, namespace string) []networkingV1.NetworkPolicyPeer {
+	switch {
+	case p.nodeID == "in-the-internet" || p.nodeID == "out-the-internet":
+		return []networkingV1.NetworkPolicyPeer{
+			{IPBlock: &networkingV1.IPBlock{CIDR: internetCIDR}},
+		}
+	case p.isPod && p.clusterID == clusterID:
+		peer := networkingV1.NetworkPolicyPeer{}
+		if selector := selectorLabels(p.labels); len(selector) > 0 {
+			peer.PodSelector = &metaV1.LabelSelector{MatchLabels: selector}
+		}
+		if p.namespace != namespace || peer.PodSelector == nil {
+			peer.NamespaceSelector = &metaV1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabel: p.namespace},
+			}
+		}
+		return []networkingV1.NetworkPolicyPeer{peer}
+	}
+	res := make([]networkingV1.NetworkPolicyPeer, 0, len(p.ips))
+	for _, ip := range p.ips {
+		cidr := ip + "/32"
+		if strings.Contains(ip, ":") {
+			cidr = ip + "/128"
+		}
+		res = append(res, networkingV1.NetworkPolicyPeer{IPBlock: &networkingV1.IPBlock{CIDR: cidr}})
+	}
+	return res
+}
+
+func peersKey(p flowPeer, port int) string {
+	if p.isPod {
+		return fmt.Sprintf("%s;%d", selectorKey(p.namespace, selectorLabels(p.labels)), port)
+	}
+	return fmt.Sprintf("%s;%d", p.nodeID, port)
+}
+
+func policyPorts(port int) []networkingV1.NetworkPolicyPort {
+	if port <= 0 {
+		return nil
+	}
+	protocol := coreV1.ProtocolTCP
+	p := intstr.FromInt32(int32(port))
+	return []networkingV1.NetworkPolicyPort{{Protocol: &protocol, Port: &p}}
+}
+
+func dnsEgressRule() networkingV1.NetworkPolicyEgressRule {
+	udp := coreV1.ProtocolUDP
+	tcp := coreV1.ProtocolTCP
+	port := intstr.FromInt32(53)
+	return networkingV1.NetworkPolicyEgressRule{
+		To: []networkingV1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: &metaV1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: "kube-system"},
+				},
+				PodSelector: &metaV1.LabelSelector{
+					MatchLabels: map[string]string{"k8s-app": "kube-dns"},
+				},
+			},
+		},
+		Ports: []networkingV1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &port},
+			{Protocol: &tcp, Port: &port},
+		},
+	}
+}
+
+func policyName(key string, selector map[string]string) string {
+	name := selector["app.kubernetes.io/name"]
+	if name == "" {
+		name = selector["app"]
+	}
+	name = strings.Trim(policyNameReplacer.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	h := sha256.Sum256([]byte(key))
+	suffix := hex.EncodeToString(h[:])[:8]
+	if name == "" {
+		return "deepfence-" + suffix
+	}
+	return "deepfence-" + name + "-" + suffix
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,112 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_build_network_policies(t *testing.T) {
+
+	frontend := flowPeer{
+		nodeID:    "pod-1",
+		isPod:     true,
+		clusterID: "cluster",
+		namespace: "shop",
+		labels:    map[string]string{"app": "frontend", "pod-template-hash": "abc"},
+	}
+	frontend2 := frontend
+	frontend2.nodeID = "pod-2"
+	frontend2.labels = map[string]string{"app": "frontend", "pod-template-hash": "def"}
+	db := flowPeer{
+		nodeID:    "pod-3",
+		isPod:     true,
+		clusterID: "cluster",
+		namespace: "data",
+		labels:    map[string]string{"app": "db"},
+	}
+	internet := flowPeer{nodeID: "in-the-internet"}
+	host := flowPeer{nodeID: "host-1", ips: []string{"10.0.0.1"}}
+
+	flows := []podFlow{
+		{src: internet, dst: frontend, port: 443},
+		{src: internet, dst: frontend2, port: 443},
+		{src: frontend, dst: db, port: 5432},
+		{src: frontend2, dst: host, port: 9100},
+	}
+
+	policies := buildNetworkPolicies(flows, "cluster", "shop")
+	assert.Equal(t, len(policies), 1)
+
+	p := policies[0]
+	assert.Equal(t, p.Namespace, "shop")
+	assert.DeepEqual(t, p.Spec.PodSelector.MatchLabels, map[string]string{"app": "frontend"})
+
+	assert.Equal(t, len(p.Spec.Ingress), 1)
+	assert.Equal(t, p.Spec.Ingress[0].From[0].IPBlock.CIDR, internetCIDR)
+	assert.Equal(t, p.Spec.Ingress[0].Ports[0].Port.IntValue(), 443)
+
+	// dns + db + host
+	assert.Equal(t, len(p.Spec.Egress), 3)
+	found := map[string]bool{}
+	for _, rule := range p.Spec.Egress[1:] {
+		peer := rule.To[0]
+		switch {
+		case peer.IPBlock != nil:
+			assert.Equal(t, peer.IPBlock.CIDR, "10.0.0.1/32")
+			found["host"] = true
+		case peer.PodSelector != nil:
+			assert.DeepEqual(t, peer.PodSelector.MatchLabels, map[string]string{"app": "db"})
+			assert.DeepEqual(t, peer.NamespaceSelector.MatchLabels, map[string]string{namespaceNameLabel: "data"})
+			assert.Equal(t, rule.Ports[0].Port.IntValue(), 5432)
+			found["db"] = true
+		}
+	}
+	assert.Equal(t, len(found), 2)
+
+	all := buildNetworkPolicies(flows, "cluster", "")
+	assert.Equal(t, len(all), 2)
+}
+
+func Test_build_network_policies_same_node(t *testing.T) {
+
+	// the flow of two pods scheduled on the same node, recorded from the
+	// local connections of the host
+	api := flowPeer{
+		nodeID:    "pod-api",
+		isPod:     true,
+		clusterID: "cluster",
+		namespace: "shop",
+		labels:    map[string]string{"app": "api"},
+	}
+	cache := flowPeer{
+		nodeID:    "pod-cache",
+		isPod:     true,
+		clusterID: "cluster",
+		namespace: "shop",
+		labels:    map[string]string{"app": "cache"},
+	}
+
+	policies := buildNetworkPolicies([]podFlow{{src: api, dst: cache, port: 6379}}, "cluster", "shop")
+	assert.Equal(t, len(policies), 2)
+
+	byApp := map[string]int{}
+	for i, p := range policies {
+		byApp[p.Spec.PodSelector.MatchLabels["app"]] = i
+	}
+
+	apiPolicy := policies[byApp["api"]]
+	assert.Equal(t, len(apiPolicy.Spec.Ingress), 0)
+	// dns + cache
+	assert.Equal(t, len(apiPolicy.Spec.Egress), 2)
+	egress := apiPolicy.Spec.Egress[1]
+	assert.DeepEqual(t, egress.To[0].PodSelector.MatchLabels, map[string]string{"app": "cache"})
+	assert.Assert(t, egress.To[0].NamespaceSelector == nil)
+	assert.Equal(t, egress.Ports[0].Port.IntValue(), 6379)
+
+	cachePolicy := policies[byApp["cache"]]
+	assert.Equal(t, len(cachePolicy.Spec.Ingress), 1)
+	ingress := cachePolicy.Spec.Ingress[0]
+	assert.DeepEqual(t, ingress.From[0].PodSelector.MatchLabels, map[string]string{"app": "api"})
+	assert.Equal(t, ingress.Ports[0].Port.IntValue(), 6379)
+}
@@ -70,6 +70,7 @@ func (tc *ThreatGraphReporter) GetThreatGraph(ctx context.Context, filter Threat
 					CloudComplianceCount:            nodeInfo[index].CloudComplianceCount,
 					WarnAlarmCount:                  nodeInfo[index].WarnAlarmCount,
 					CloudWarnAlarmCount:             nodeInfo[index].CloudWarnAlarmCount,
+					RiskScore:                       nodeInfo[index].RiskScore,
 					Count:                           nodeInfo[index].Count,
 					NodeType:                        nodeInfo[index].NodeType,
 					AttackPath:                      paths,
@@ -77,6 +78,10 @@ func (tc *ThreatGraphReporter) GetThreatGraph(ctx context.Context, filter Threat
 				resources = append(resources, entry)
 			}
 		}
+		// most at risk resources first
+		sort.SliceStable(resources, func(i, j int) bool {
+			return resources[i].RiskScore > resources[j].RiskScore
+		})
 	end:
 		all[cp] = ProviderThreatGraph{
 			Resources:                       resources,
@@ -247,8 +252,8 @@ func (tc *ThreatGraphReporter) GetRawThreatGraph(ctx context.Context, filters Th
 		case cloudProvider != CloudPrivate:
 			res, err = tx.Run(`
 				CALL apoc.nodes.group(['ThreatCloudResource','ThreatNode'], ['node_type', 'depth', 'cloud_provider'],
-				[{`+"`*`"+`: 'count', sum_cve: 'sum', sum_exploitable_cve: 'sum', sum_secrets: 'sum', sum_exploitable_secrets: 'sum', sum_compliance: 'sum', sum_cloud_compliance: 'sum', sum_warn_alarm: 'sum', sum_cloud_warn_alarm: 'sum',
-				node_id:'collect', vulnerabilities_count: 'collect', exploitable_vulnerabilities_count: 'collect', secrets_count:'collect', exploitable_secrets_count: 'collect', compliances_count:'collect', cloud_compliances_count: 'collect', warn_alarm_count: 'collect', cloud_warn_alarm_count: 'collect'},
+				[{`+"`*`"+`: 'count', sum_cve: 'sum', sum_exploitable_cve: 'sum', sum_secrets: 'sum', sum_exploitable_secrets: 'sum', sum_compliance: 'sum', sum_cloud_compliance: 'sum', sum_warn_alarm: 'sum', sum_cloud_warn_alarm: 'sum', sum_risk_score: 'sum',
+				node_id:'collect', vulnerabilities_count: 'collect', exploitable_vulnerabilities_count: 'collect', secrets_count:'collect', exploitable_secrets_count: 'collect', compliances_count:'collect', cloud_compliances_count: 'collect', warn_alarm_count: 'collect', cloud_warn_alarm_count: 'collect', risk_score: 'collect'},
 				{`+"`*`"+`: 'count'}], {selfRels: false})
 				YIELD node, relationships
 				WHERE apoc.any.property(node, 'cloud_provider') = '`+cloudProvider+`'
@@ -257,8 +262,8 @@ func (tc *ThreatGraphReporter) GetRawThreatGraph(ctx context.Context, filters Th
 		case !filters.CloudResourceOnly:
 			res, err = tx.Run(`
 				CALL apoc.nodes.group(['ThreatNode'], ['node_type', 'depth', 'cloud_provider'],
-				[{`+"`*`"+`: 'count', sum_cve: 'sum', sum_exploitable_cve: 'sum', sum_secrets: 'sum', sum_exploitable_secrets: 'sum', sum_compliance: 'sum', sum_cloud_compliance: 'sum', sum_warn_alarm: 'sum', sum_cloud_warn_alarm: 'sum',
-				node_id:'collect', vulnerabilities_count: 'collect', exploitable_vulnerabilities_count: 'collect', secrets_count:'collect', exploitable_secrets_count: 'collect', compliances_count:'collect', cloud_compliances_count:'collect', warn_alarm_count: 'collect', cloud_warn_alarm_count: 'collect'},
+				[{`+"`*`"+`: 'count', sum_cve: 'sum', sum_exploitable_cve: 'sum', sum_secrets: 'sum', sum_exploitable_secrets: 'sum', sum_compliance: 'sum', sum_cloud_compliance: 'sum', sum_warn_alarm: 'sum', sum_cloud_warn_alarm: 'sum', sum_risk_score: 'sum',
+				node_id:'collect', vulnerabilities_count: 'collect', exploitable_vulnerabilities_count: 'collect', secrets_count:'collect', exploitable_secrets_count: 'collect', compliances_count:'collect', cloud_compliances_count:'collect', warn_alarm_count: 'collect', cloud_warn_alarm_count: 'collect', risk_score: 'collect'},
 				{`+"`*`"+`: 'count'}], {selfRels: false})
 				YIELD node, relationships
 				WHERE NOT apoc.any.property(node, 'cloud_provider') IN ['aws', 'gcp', 'azure']
@@ -326,6 +331,7 @@ func record2struct(node dbtype.Node) AttackPathData {
 	sumSumCloudCompliance := record["sum_sum_cloud_compliance"]
 	sumSumWarnAlarm := record["sum_sum_warn_alarm"]
 	sumSumCloudWarnAlarm := record["sum_sum_cloud_warn_alarm"]
+	sumSumRiskScore := record["sum_sum_risk_score"]
 	nodeCount := record["count_*"]
 	collectNodeID := record["collect_node_id"]
 	collectNumCVE := record["collect_vulnerabilities_count"]
@@ -336,6 +342,7 @@ func record2struct(node dbtype.Node) AttackPathData {
 	collectNumCloudCompliance := record["collect_cloud_compliances_count"]
 	collectNumWarnAlarm := record["collect_warn_alarm_count"]
 	collectNumCloudWarnAlarm := record["collect_cloud_warn_alarm_count"]
+	collectRiskScore := record["collect_risk_score"]
 
 	collectNodeIDs := []string{}
 	for _, v := range collectNodeID.([]interface{}) {
@@ -414,6 +421,13 @@ func record2struct(node dbtype.Node) AttackPathData {
 		sumSumCloudWarnAlarmRes = sumSumCloudWarnAlarm.(int64)
 	}
 
+	collectRiskScoreRes := []float64{}
+	if collectRiskScore != nil {
+		for _, v := range collectRiskScore.([]interface{}) {
+			collectRiskScoreRes = append(collectRiskScoreRes, toFloat64(v))
+		}
+	}
+
 	return AttackPathData{
 		identity:                     node.Id,
 		NodeType:                     nodeType.(string),
@@ -437,6 +451,8 @@ func record2struct(node dbtype.Node) AttackPathData {
 		collectNumCloudWarnAlarm:     collectNumCloudWarnAlarmRes,
 		sumSumWarnAlarm:              sumSumWarnAlarmRes,
 		sumSumCloudWarnAlarm:         sumSumCloudWarnAlarmRes,
+		sumSumRiskScore:              toFloat64(sumSumRiskScore),
+		collectRiskScore:             collectRiskScoreRes,
 	}
 }
 
@@ -463,6 +479,20 @@ type AttackPathData struct {
 	collectNumCloudCompliance    []int64
 	collectNumWarnAlarm          []int64
 	collectNumCloudWarnAlarm     []int64
+	sumSumRiskScore              float64
+	collectRiskScore             []float64
+}
+
+// toFloat64 reads the risk scores, aggregated as integers when the
+// weights are integers
+func toFloat64(v interface{}) float64 {
+	switch f := v.(type) {
+	case float64:
+		return f
+	case int64:
+		return float64(f)
+	}
+	return 0
 }
 
 func getThreatNodeID(apd AttackPathData) string {
@@ -528,6 +558,10 @@ func (ap AttackPaths) getNodeInfos() map[int64]ThreatNodeInfo {
 			if len(v.collectNumCloudWarnAlarm) == len(v.collectNodeID) {
 				cloudWarnAlarmCount = v.collectNumCloudWarnAlarm[i]
 			}
+			riskScore := float64(0)
+			if len(v.collectRiskScore) == len(v.collectNodeID) {
+				riskScore = v.collectRiskScore[i]
+			}
 
 			Nodes[nodeID] = NodeInfo{
 				NodeID:                          nodeID,
@@ -540,6 +574,7 @@ func (ap AttackPaths) getNodeInfos() map[int64]ThreatNodeInfo {
 				CloudComplianceCount:            cloudComplianceCount,
 				WarnAlarmCount:                  warnAlarmCount,
 				CloudWarnAlarmCount:             cloudWarnAlarmCount,
+				RiskScore:                       riskScore,
 			}
 		}
 		res[v.identity] = ThreatNodeInfo{
@@ -554,6 +589,7 @@ func (ap AttackPaths) getNodeInfos() map[int64]ThreatNodeInfo {
 			CloudComplianceCount:            v.sumSumCloudCompliance,
 			WarnAlarmCount:                  v.sumSumWarnAlarm,
 			CloudWarnAlarmCount:             v.sumSumCloudWarnAlarm,
+			RiskScore:                       v.sumSumRiskScore,
 			Count:                           int64(len(v.collectNodeID)),
 			NodeType:                        v.NodeType,
 			AttackPath:                      [][]string{},
@@ -581,15 +617,16 @@ type ThreatNodeInfo struct {
 	ID    string              `json:"id" required:"true"`
 	Nodes map[string]NodeInfo `json:"nodes" required:"true"`
 
-	VulnerabilityCount              int64 `json:"vulnerability_count" required:"true"`
-	ExploitableVulnerabilitiesCount int64 `json:"exploitable_vulnerabilities_count" required:"true"`
-	SecretsCount                    int64 `json:"secrets_count" required:"true"`
-	ExploitableSecretsCount         int64 `json:"exploitable_secrets_count" required:"true"`
-	ComplianceCount                 int64 `json:"compliance_count" required:"true"`
-	CloudComplianceCount            int64 `json:"cloud_compliance_count" required:"true"`
-	Count                           int64 `json:"count" required:"true"`
-	WarnAlarmCount                  int64 `json:"warn_alarm_count" required:"true"`
-	CloudWarnAlarmCount             int64 `json:"cloud_warn_alarm_count" required:"true"`
+	VulnerabilityCount              int64   `json:"vulnerability_count" required:"true"`
+	ExploitableVulnerabilitiesCount int64   `json:"exploitable_vulnerabilities_count" required:"true"`
+	SecretsCount                    int64   `json:"secrets_count" required:"true"`
+	ExploitableSecretsCount         int64   `json:"exploitable_secrets_count" required:"true"`
+	ComplianceCount                 int64   `json:"compliance_count" required:"true"`
+	CloudComplianceCount            int64   `json:"cloud_compliance_count" required:"true"`
+	Count                           int64   `json:"count" required:"true"`
+	WarnAlarmCount                  int64   `json:"warn_alarm_count" required:"true"`
+	CloudWarnAlarmCount             int64   `json:"cloud_warn_alarm_count" required:"true"`
+	RiskScore                       float64 `json:"risk_score" required:"true"`
 
 	NodeType string `json:"node_type" required:"true"`
 
@@ -597,14 +634,15 @@ type ThreatNodeInfo struct {
 }
 
 type NodeInfo struct {
-	NodeID                          string `json:"node_id" required:"true"`
-	Name                            string `json:"name" required:"true"`
-	VulnerabilityCount              int64  `json:"vulnerability_count" required:"true"`
-	ExploitableVulnerabilitiesCount int64  `json:"exploitable_vulnerabilities_count" required:"true"`
-	SecretsCount                    int64  `json:"secrets_count" required:"true"`
-	ExploitableSecretsCount         int64  `json:"exploitable_secrets_count" required:"true"`
-	ComplianceCount                 int64  `json:"compliance_count" required:"true"`
-	CloudComplianceCount            int64  `json:"cloud_compliance_count" required:"true"`
-	WarnAlarmCount                  int64  `json:"warn_alarm_count" required:"true"`
-	CloudWarnAlarmCount             int64  `json:"cloud_warn_alarm_count" required:"true"`
+	NodeID                          string  `json:"node_id" required:"true"`
+	Name                            string  `json:"name" required:"true"`
+	VulnerabilityCount              int64   `json:"vulnerability_count" required:"true"`
+	ExploitableVulnerabilitiesCount int64   `json:"exploitable_vulnerabilities_count" required:"true"`
+	SecretsCount                    int64   `json:"secrets_count" required:"true"`
+	ExploitableSecretsCount         int64   `json:"exploitable_secrets_count" required:"true"`
+	ComplianceCount                 int64   `json:"compliance_count" required:"true"`
+	CloudComplianceCount            int64   `json:"cloud_compliance_count" required:"true"`
+	WarnAlarmCount                  int64   `json:"warn_alarm_count" required:"true"`
+	CloudWarnAlarmCount             int64   `json:"cloud_warn_alarm_count" required:"true"`
+	RiskScore                       float64 `json:"risk_score" required:"true"`
 }
@@ -0,0 +1,265 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/scope/render/detailed"
+)
+
+const (
+	TopologyExportGraphML = "graphml"
+	TopologyExportDOT     = "dot"
+	TopologyExportJGF     = "jgf"
+
+	relationConnects = "CONNECTS"
+	relationHosts    = "HOSTS"
+)
+
+var ErrUnknownExportFormat = errors.New("unknown topology export format")
+
+type TopologyExportRequest struct {
+	Root    string          `json:"root" validate:"omitempty,oneof=hosts kubernetes containers pods" enum:"hosts,kubernetes,containers,pods"`
+	Format  string          `json:"format" validate:"required,oneof=graphml dot jgf" required:"true" enum:"graphml,dot,jgf"`
+	Filters TopologyFilters `json:"filters" required:"true"`
+}
+
+type TopologyExportResponse struct {
+	Format      string `json:"format" required:"true"`
+	ContentType string `json:"content_type" required:"true"`
+	Content     string `json:"content" required:"true"`
+}
+
+type exportEdge struct {
+	source   string
+	target   string
+	relation string
+}
+
+// ExportContentType returns the media type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case TopologyExportGraphML:
+		return "application/graphml+xml"
+	case TopologyExportDOT:
+		return "text/vnd.graphviz"
+	case TopologyExportJGF:
+		return "application/vnd.jgf+json"
+	}
+	return "application/octet-stream"
+}
+
+// ExportTopology renders a topology graph in a standard graph format.
+// Parent relationships are exported as HOSTS edges along with the
+// CONNECTS edges so that the hierarchy can be rebuilt by the tooling.
+func ExportTopology(graph model.GraphResult, format string) ([]byte, error) {
+	nodes, edges := exportElements(graph)
+	switch format {
+	case TopologyExportGraphML:
+		return exportGraphML(nodes, edges)
+	case TopologyExportDOT:
+		return exportDOT(nodes, edges), nil
+	case TopologyExportJGF:
+		return exportJGF(nodes, edges)
+	}
+	return nil, ErrUnknownExportFormat
+}
+
+// exportElements sorts the nodes and edges for stable outputs and adds
+// the nodes only referenced by edges, which most formats require
+func exportElements(graph model.GraphResult) ([]detailed.NodeSummary, []exportEdge) {
+	all := map[string]detailed.NodeSummary{}
+	for id, n := range graph.Nodes {
+		if n.ID == "" {
+			n.ID = id
+		}
+		all[n.ID] = n
+	}
+
+	edges := []exportEdge{}
+	for _, n := range graph.Nodes {
+		if n.ImmediateParentID != "" {
+			edges = append(edges, exportEdge{source: n.ImmediateParentID, target: n.ID, relation: relationHosts})
+		}
+	}
+	for _, e := range graph.Edges {
+		edges = append(edges, exportEdge{source: e.Source, target: e.Target, relation: relationConnects})
+	}
+	for _, e := range edges {
+		for _, id := range []string{e.source, e.target} {
+			if _, has := all[id]; !has {
+				all[id] = detailed.NodeSummary{ID: id, Label: id}
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].relation != edges[j].relation {
+			return edges[i].relation > edges[j].relation
+		}
+		if edges[i].source != edges[j].source {
+			return edges[i].source < edges[j].source
+		}
+		return edges[i].target < edges[j].target
+	})
+
+	nodes := make([]detailed.NodeSummary, 0, len(all))
+	for _, n := range all {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes, edges
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+func exportGraphML(nodes []detailed.NodeSummary, edges []exportEdge) ([]byte, error) {
+	doc := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "parent", For: "node", AttrName: "parent", AttrType: "string"},
+			{ID: "relation", For: "edge", AttrName: "relation", AttrType: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "topology",
+			EdgeDefault: "directed",
+			Nodes:       make([]graphMLNode, 0, len(nodes)),
+			Edges:       make([]graphMLEdge, 0, len(edges)),
+		},
+	}
+	for _, n := range nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: n.Label},
+				{Key: "type", Value: n.Type},
+				{Key: "parent", Value: n.ImmediateParentID},
+			},
+		})
+	}
+	for i, e := range edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: e.source,
+			Target: e.target,
+			Data:   []graphMLData{{Key: "relation", Value: e.relation}},
+		})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
+
+func exportDOT(nodes []detailed.NodeSummary, edges []exportEdge) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("digraph topology {\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&buf, "  %s [label=%s, type=%s, parent=%s];\n",
+			dotQuote(n.ID), dotQuote(n.Label), dotQuote(n.Type), dotQuote(n.ImmediateParentID))
+	}
+	for _, e := range edges {
+		style := "solid"
+		if e.relation == relationHosts {
+			style = "dashed"
+		}
+		fmt.Fprintf(&buf, "  %s -> %s [relation=%s, style=%s];\n",
+			dotQuote(e.source), dotQuote(e.target), dotQuote(e.relation), style)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+type jgfNode struct {
+	Label    string            `json:"label"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type jgfEdge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Relation string `json:"relation"`
+}
+
+type jgfGraph struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Directed bool               `json:"directed"`
+	Nodes    map[string]jgfNode `json:"nodes"`
+	Edges    []jgfEdge          `json:"edges"`
+}
+
+// exportJGF follows the JSON Graph Format v2 specification
+func exportJGF(nodes []detailed.NodeSummary, edges []exportEdge) ([]byte, error) {
+	g := jgfGraph{
+		ID:       "topology",
+		Type:     "topology",
+		Directed: true,
+		Nodes:    make(map[string]jgfNode, len(nodes)),
+		Edges:    make([]jgfEdge, 0, len(edges)),
+	}
+	for _, n := range nodes {
+		g.Nodes[n.ID] = jgfNode{
+			Label: n.Label,
+			Metadata: map[string]string{
+				"type":   n.Type,
+				"parent": n.ImmediateParentID,
+			},
+		}
+	}
+	for _, e := range edges {
+		g.Edges = append(g.Edges, jgfEdge{Source: e.source, Target: e.target, Relation: e.relation})
+	}
+	return json.MarshalIndent(map[string]jgfGraph{"graph": g}, "", "  ")
+}
@@ -0,0 +1,66 @@
+package reporters_graph //nolint:stylecheck
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/scope/render/detailed"
+	"gotest.tools/assert"
+)
+
+func testExportGraph() model.GraphResult {
+	return model.GraphResult{
+		Nodes: detailed.NodeSummaries{
+			"aws":    {ID: "aws", Label: "aws", Type: "cloud_provider"},
+			"host-1": {ID: "host-1", Label: `host "1"`, Type: "host", ImmediateParentID: "aws"},
+			"host-2": {ID: "host-2", Label: "host-2", Type: "host", ImmediateParentID: "aws"},
+		},
+		Edges: detailed.TopologyConnectionSummaries{
+			"host-1host-2":          {Source: "host-1", Target: "host-2"},
+			"in-the-internethost-1": {Source: "in-the-internet", Target: "host-1"},
+		},
+	}
+}
+
+func Test_export_topology_graphml(t *testing.T) {
+	out, err := ExportTopology(testExportGraph(), TopologyExportGraphML)
+	assert.NilError(t, err)
+
+	var doc graphMLDocument
+	assert.NilError(t, xml.Unmarshal(out, &doc))
+	// edge only nodes are added
+	assert.Equal(t, len(doc.Graph.Nodes), 4)
+	assert.Equal(t, len(doc.Graph.Edges), 4)
+	assert.Equal(t, doc.Graph.Edges[0].Data[0].Value, relationHosts)
+	assert.Equal(t, doc.Graph.Edges[3].Data[0].Value, relationConnects)
+}
+
+func Test_export_topology_dot(t *testing.T) {
+	out, err := ExportTopology(testExportGraph(), TopologyExportDOT)
+	assert.NilError(t, err)
+
+	dot := string(out)
+	assert.Assert(t, strings.HasPrefix(dot, "digraph topology {\n"))
+	assert.Assert(t, strings.Contains(dot, `"host-1" [label="host \"1\"", type="host", parent="aws"];`))
+	assert.Assert(t, strings.Contains(dot, `"host-1" -> "host-2" [relation="CONNECTS", style=solid];`))
+	assert.Assert(t, strings.Contains(dot, `"aws" -> "host-1" [relation="HOSTS", style=dashed];`))
+}
+
+func Test_export_topology_jgf(t *testing.T) {
+	out, err := ExportTopology(testExportGraph(), TopologyExportJGF)
+	assert.NilError(t, err)
+
+	var doc map[string]jgfGraph
+	assert.NilError(t, json.Unmarshal(out, &doc))
+	g := doc["graph"]
+	assert.Equal(t, g.Directed, true)
+	assert.Equal(t, len(g.Nodes), 4)
+	assert.Equal(t, g.Nodes["host-2"].Metadata["parent"], "aws")
+	assert.Equal(t, len(g.Edges), 4)
+
+	_, err = ExportTopology(testExportGraph(), "svg")
+	assert.Equal(t, err, ErrUnknownExportFormat)
+}
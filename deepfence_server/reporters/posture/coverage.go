@@ -0,0 +1,206 @@
+package reporters_posture //nolint:stylecheck
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const (
+	CoverageNeverScanned = "never_scanned"
+	CoverageStale        = "stale"
+
+	// Resource of integrations notified with scan coverage
+	CoverageNotificationType = "ScanCoverage"
+)
+
+var (
+	ErrUnsupportedCoverageScanType = errors.New("unsupported scan type for coverage")
+	ErrUnsupportedCoverageNodeType = errors.New("unsupported node type for coverage")
+)
+
+type CoverageFilter struct {
+	ScanTypes []string          `json:"scan_types" validate:"omitempty,dive,oneof=vulnerability secret malware compliance" enum:"vulnerability,secret,malware,compliance"`
+	NodeTypes []string          `json:"node_types" validate:"omitempty,dive,oneof=host container container_image kubernetes_cluster" enum:"host,container,container_image,kubernetes_cluster"`
+	StaleDays int               `json:"stale_days" validate:"min=0"`
+	Window    model.FetchWindow `json:"window"`
+}
+
+type CoverageNode struct {
+	NodeID        string `json:"node_id" required:"true"`
+	NodeName      string `json:"node_name" required:"true"`
+	NodeType      string `json:"node_type" required:"true"`
+	ScanType      string `json:"scan_type" required:"true"`
+	Reason        string `json:"reason" required:"true" enum:"never_scanned,stale"`
+	LastScannedAt int64  `json:"last_scanned_at" required:"true"`
+}
+
+type CoverageSummary struct {
+	ScanType           string  `json:"scan_type" required:"true"`
+	NodeType           string  `json:"node_type" required:"true"`
+	Total              int64   `json:"total" required:"true"`
+	NeverScanned       int64   `json:"never_scanned" required:"true"`
+	Stale              int64   `json:"stale" required:"true"`
+	CoveragePercentage float64 `json:"coverage_percentage" required:"true"`
+}
+
+type ScanCoverage struct {
+	Summary []CoverageSummary `json:"summary" required:"true"`
+	Nodes   []CoverageNode    `json:"nodes" required:"true"`
+}
+
+// GetScanCoverage lists, per scan type and node type, the active nodes
+// which were never successfully scanned or whose last completed scan is
+// older than StaleDays. StaleDays set to 0 only reports never scanned nodes.
+func GetScanCoverage(ctx context.Context, filter CoverageFilter) (ScanCoverage, error) {
+	res := ScanCoverage{Summary: []CoverageSummary{}, Nodes: []CoverageNode{}}
+
+	scanTypes, err := coverageScanTypes(filter.ScanTypes)
+	if err != nil {
+		return res, err
+	}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(60 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	threshold := int64(0)
+	if filter.StaleDays > 0 {
+		threshold = time.Now().Add(-time.Duration(filter.StaleDays) * 24 * time.Hour).UnixMilli()
+	}
+
+	for _, scanType := range scanTypes {
+		nodes, err := coverageNodeTypes(scanType, filter.NodeTypes)
+		if err != nil {
+			return res, err
+		}
+		for _, node := range nodes {
+			r, err := tx.Run(`
+				MATCH (n:`+node.label+`)
+				WHERE `+node.where+`
+				OPTIONAL MATCH (n) <-[:SCANNED]- (s:`+string(scanType)+`{status: $complete})
+				WITH n, max(s.updated_at) AS last_scanned_at
+				WITH count(n) AS total,
+					collect(CASE WHEN last_scanned_at IS NULL OR last_scanned_at < $threshold
+						THEN {node_id: n.node_id, node_name: COALESCE(n.node_name, n.node_id), last_scanned_at: last_scanned_at}
+					END) AS uncovered
+				RETURN total, uncovered`,
+				map[string]interface{}{
+					"complete":  utils.ScanStatusSuccess,
+					"threshold": threshold,
+				})
+			if err != nil {
+				return res, err
+			}
+			rec, err := r.Single()
+			if err != nil {
+				return res, err
+			}
+
+			summary := CoverageSummary{
+				ScanType: ScanTypeLabels[scanType],
+				NodeType: node.name,
+				Total:    rec.Values[0].(int64),
+			}
+			for _, entry := range rec.Values[1].([]interface{}) {
+				m := entry.(map[string]interface{})
+				cn := CoverageNode{
+					NodeID:   m["node_id"].(string),
+					NodeName: m["node_name"].(string),
+					NodeType: node.name,
+					ScanType: summary.ScanType,
+					Reason:   CoverageNeverScanned,
+				}
+				if lastScannedAt, ok := m["last_scanned_at"].(int64); ok {
+					cn.LastScannedAt = lastScannedAt
+					cn.Reason = CoverageStale
+					summary.Stale += 1
+				} else {
+					summary.NeverScanned += 1
+				}
+				res.Nodes = append(res.Nodes, cn)
+			}
+			summary.CoveragePercentage = 100.0
+			if summary.Total > 0 {
+				covered := summary.Total - summary.NeverScanned - summary.Stale
+				summary.CoveragePercentage = float64(covered) * 100.0 / float64(summary.Total)
+			}
+			res.Summary = append(res.Summary, summary)
+		}
+	}
+
+	if filter.Window.Size > 0 {
+		start := min(filter.Window.Offset, len(res.Nodes))
+		end := min(start+filter.Window.Size, len(res.Nodes))
+		res.Nodes = res.Nodes[start:end]
+	}
+
+	return res, nil
+}
+
+func coverageScanTypes(scanTypes []string) ([]utils.Neo4jScanType, error) {
+	if len(scanTypes) == 0 {
+		return []utils.Neo4jScanType{
+			utils.NEO4JVulnerabilityScan,
+			utils.NEO4JSecretScan,
+			utils.NEO4JMalwareScan,
+			utils.NEO4JComplianceScan,
+		}, nil
+	}
+	res := []utils.Neo4jScanType{}
+	for _, scanType := range scanTypes {
+		found := false
+		for neo4jScanType, label := range ScanTypeLabels {
+			if _, has := scannableNodes[neo4jScanType]; has && label == scanType {
+				res = append(res, neo4jScanType)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrUnsupportedCoverageScanType
+		}
+	}
+	return res, nil
+}
+
+func coverageNodeTypes(scanType utils.Neo4jScanType, nodeTypes []string) ([]scannableNode, error) {
+	if len(nodeTypes) == 0 {
+		return scannableNodes[scanType], nil
+	}
+	res := []scannableNode{}
+	for _, nodeType := range nodeTypes {
+		found := false
+		for _, node := range []scannableNode{hostNode, containerNode, imageNode, clusterNode} {
+			if node.name == nodeType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrUnsupportedCoverageNodeType
+		}
+		// node types not applicable to the scan type are skipped
+		for _, node := range scannableNodes[scanType] {
+			if node.name == nodeType {
+				res = append(res, node)
+			}
+		}
+	}
+	return res, nil
+}
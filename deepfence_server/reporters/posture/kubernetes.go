@@ -0,0 +1,466 @@
+package reporters_posture //nolint:stylecheck
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const (
+	KubernetesFindingPod         = "pod"
+	KubernetesFindingRoleBinding = "role_binding"
+
+	clusterAdminPermission = "*:*"
+)
+
+type KubernetesPostureRule struct {
+	ID          string `json:"id" required:"true"`
+	Title       string `json:"title" required:"true"`
+	Severity    string `json:"severity" required:"true" enum:"critical,high,medium,low"`
+	Description string `json:"description" required:"true"`
+}
+
+var (
+	ruleExposedClusterAdmin = KubernetesPostureRule{
+		ID:          "k8s_exposed_cluster_admin_service_account",
+		Title:       "Service account with cluster-admin mounted in internet exposed pod",
+		Severity:    "critical",
+		Description: "The pod receives connections from the internet and mounts the token of a service account bound to cluster wide admin permissions, compromising the pod compromises the cluster.",
+	}
+	ruleExposedPrivileged = KubernetesPostureRule{
+		ID:          "k8s_exposed_privileged_pod",
+		Title:       "Privileged container in internet exposed pod",
+		Severity:    "critical",
+		Description: "The pod receives connections from the internet and runs a privileged container, compromising the pod gives access to its host.",
+	}
+	rulePrivileged = KubernetesPostureRule{
+		ID:          "k8s_privileged_container",
+		Title:       "Privileged container",
+		Severity:    "high",
+		Description: "Privileged containers have all capabilities and access to the host devices.",
+	}
+	ruleCapabilities = KubernetesPostureRule{
+		ID:          "k8s_dangerous_capabilities",
+		Title:       "Dangerous capabilities added",
+		Severity:    "high",
+		Description: "Capabilities such as SYS_ADMIN or SYS_PTRACE allow escaping the container.",
+	}
+	ruleHostPath = KubernetesPostureRule{
+		ID:          "k8s_host_path_mount",
+		Title:       "Host path mounted",
+		Severity:    "medium",
+		Description: "Host path volumes expose the host filesystem to the pod.",
+	}
+	ruleHostNamespace = KubernetesPostureRule{
+		ID:          "k8s_host_namespace",
+		Title:       "Host network or PID namespace shared",
+		Severity:    "medium",
+		Description: "Pods sharing the host namespaces can see and reach the host processes and interfaces.",
+	}
+	ruleRunAsRoot = KubernetesPostureRule{
+		ID:          "k8s_run_as_root",
+		Title:       "Container may run as root",
+		Severity:    "low",
+		Description: "Neither runAsNonRoot nor a non root runAsUser is enforced.",
+	}
+	ruleClusterAdminBinding = KubernetesPostureRule{
+		ID:          "k8s_cluster_admin_binding",
+		Title:       "Wildcard permissions granted",
+		Severity:    "high",
+		Description: "The binding grants every verb on every resource to non system subjects.",
+	}
+	ruleSecretsAccess = KubernetesPostureRule{
+		ID:          "k8s_secrets_access",
+		Title:       "Read access to secrets granted",
+		Severity:    "medium",
+		Description: "The binding allows non system subjects to read secrets.",
+	}
+
+	// KubernetesPostureRules are all the rules evaluated against pods and role bindings
+	KubernetesPostureRules = []KubernetesPostureRule{
+		ruleExposedClusterAdmin,
+		ruleExposedPrivileged,
+		rulePrivileged,
+		ruleCapabilities,
+		ruleHostPath,
+		ruleHostNamespace,
+		ruleRunAsRoot,
+		ruleClusterAdminBinding,
+		ruleSecretsAccess,
+	}
+
+	dangerousCapabilities = map[string]struct{}{
+		"ALL": {}, "SYS_ADMIN": {}, "SYS_PTRACE": {}, "SYS_MODULE": {},
+		"NET_ADMIN": {}, "DAC_READ_SEARCH": {}, "SYS_RAWIO": {}, "BPF": {},
+	}
+
+	secretsPermissions = map[string]struct{}{
+		"get:secrets": {}, "list:secrets": {}, "watch:secrets": {}, "*:secrets": {},
+		"get:*": {}, "list:*": {}, "watch:*": {},
+	}
+
+	// severity order of the findings
+	kubernetesSeverityRank = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
+)
+
+type KubernetesPostureFilter struct {
+	KubernetesClusterIDs []string `json:"kubernetes_cluster_ids"`
+	Severities           []string `json:"severities" validate:"omitempty,dive,oneof=critical high medium low" enum:"critical,high,medium,low"`
+}
+
+type KubernetesPostureFinding struct {
+	RuleID              string `json:"rule_id" required:"true"`
+	Title               string `json:"title" required:"true"`
+	Severity            string `json:"severity" required:"true" enum:"critical,high,medium,low"`
+	Description         string `json:"description" required:"true"`
+	NodeID              string `json:"node_id" required:"true"`
+	NodeName            string `json:"node_name" required:"true"`
+	NodeType            string `json:"node_type" required:"true" enum:"pod,role_binding"`
+	KubernetesClusterID string `json:"kubernetes_cluster_id" required:"true"`
+	KubernetesNamespace string `json:"kubernetes_namespace" required:"true"`
+	Reason              string `json:"reason" required:"true"`
+}
+
+type KubernetesPosture struct {
+	Rules          []KubernetesPostureRule    `json:"rules" required:"true"`
+	SeverityCounts map[string]int             `json:"severity_counts" required:"true"`
+	Findings       []KubernetesPostureFinding `json:"findings" required:"true"`
+}
+
+type kubernetesPod struct {
+	nodeID          string
+	nodeName        string
+	clusterID       string
+	namespace       string
+	serviceAccount  string
+	privileged      bool
+	hostPID         bool
+	hostNetwork     bool
+	runAsRoot       bool
+	automountToken  bool
+	internetExposed bool
+	hostPaths       []string
+	capabilities    []string
+}
+
+type kubernetesRoleBinding struct {
+	nodeID      string
+	nodeName    string
+	clusterID   string
+	namespace   string
+	roleKind    string
+	roleName    string
+	subjects    []string
+	permissions []string
+}
+
+// GetKubernetesPosture evaluates the kubernetes posture rules against the
+// pods security contexts and the RBAC bindings reported by the agents
+func GetKubernetesPosture(ctx context.Context, filter KubernetesPostureFilter) (KubernetesPosture, error) {
+	res := KubernetesPosture{
+		Rules:          KubernetesPostureRules,
+		SeverityCounts: map[string]int{},
+		Findings:       []KubernetesPostureFinding{},
+	}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(60 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	clusterIDs := filter.KubernetesClusterIDs
+	if clusterIDs == nil {
+		clusterIDs = []string{}
+	}
+
+	r, err := tx.Run(`
+		MATCH (n:Pod)
+		WHERE n.active = true
+		AND (size($cluster_ids) = 0 OR n.kubernetes_cluster_id IN $cluster_ids)
+		RETURN {
+			node_id: n.node_id,
+			node_name: COALESCE(n.node_name, n.node_id),
+			cluster_id: n.kubernetes_cluster_id,
+			namespace: n.kubernetes_namespace,
+			service_account: n.kubernetes_service_account,
+			privileged: n.kubernetes_privileged,
+			host_pid: n.kubernetes_host_pid,
+			host_network: n.kubernetes_is_in_host_network,
+			run_as_root: n.kubernetes_run_as_root,
+			automount_token: n.kubernetes_automount_token,
+			host_paths: n.kubernetes_host_paths,
+			capabilities: n.kubernetes_capabilities,
+			internet_exposed: exists((:Node{node_id:'in-the-internet'}) -[:FLOWS]-> (n))
+				OR (COALESCE(n.kubernetes_is_in_host_network, false)
+				AND exists((:Node{node_id:'in-the-internet'}) -[:CONNECTS]-> (:Node) -[:HOSTS]-> (n)))
+		}`,
+		map[string]interface{}{"cluster_ids": clusterIDs})
+	if err != nil {
+		return res, err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+	pods := make([]kubernetesPod, 0, len(records))
+	for _, rec := range records {
+		m := rec.Values[0].(map[string]interface{})
+		pods = append(pods, kubernetesPod{
+			nodeID:          toString(m["node_id"]),
+			nodeName:        toString(m["node_name"]),
+			clusterID:       toString(m["cluster_id"]),
+			namespace:       toString(m["namespace"]),
+			serviceAccount:  toString(m["service_account"]),
+			privileged:      toBool(m["privileged"]),
+			hostPID:         toBool(m["host_pid"]),
+			hostNetwork:     toBool(m["host_network"]),
+			runAsRoot:       toBool(m["run_as_root"]),
+			automountToken:  toBool(m["automount_token"]),
+			internetExposed: toBool(m["internet_exposed"]),
+			hostPaths:       toStrings(m["host_paths"]),
+			capabilities:    toStrings(m["capabilities"]),
+		})
+	}
+
+	r, err = tx.Run(`
+		MATCH (n:KubernetesRoleBinding)
+		WHERE size($cluster_ids) = 0 OR n.kubernetes_cluster_id IN $cluster_ids
+		RETURN {
+			node_id: n.node_id,
+			node_name: COALESCE(n.node_name, n.node_id),
+			cluster_id: n.kubernetes_cluster_id,
+			namespace: n.kubernetes_namespace,
+			role_kind: n.kubernetes_role_kind,
+			role_name: n.kubernetes_role_name,
+			subjects: n.kubernetes_subjects,
+			permissions: n.kubernetes_permissions
+		}`,
+		map[string]interface{}{"cluster_ids": clusterIDs})
+	if err != nil {
+		return res, err
+	}
+	records, err = r.Collect()
+	if err != nil {
+		return res, err
+	}
+	bindings := make([]kubernetesRoleBinding, 0, len(records))
+	for _, rec := range records {
+		m := rec.Values[0].(map[string]interface{})
+		bindings = append(bindings, kubernetesRoleBinding{
+			nodeID:      toString(m["node_id"]),
+			nodeName:    toString(m["node_name"]),
+			clusterID:   toString(m["cluster_id"]),
+			namespace:   toString(m["namespace"]),
+			roleKind:    toString(m["role_kind"]),
+			roleName:    toString(m["role_name"]),
+			subjects:    toStrings(m["subjects"]),
+			permissions: toStrings(m["permissions"]),
+		})
+	}
+
+	severities := map[string]struct{}{}
+	for _, severity := range filter.Severities {
+		severities[severity] = struct{}{}
+	}
+	for _, finding := range evaluateKubernetesPosture(pods, bindings) {
+		if _, has := severities[finding.Severity]; len(severities) > 0 && !has {
+			continue
+		}
+		res.SeverityCounts[finding.Severity] += 1
+		res.Findings = append(res.Findings, finding)
+	}
+
+	return res, nil
+}
+
+func evaluateKubernetesPosture(pods []kubernetesPod, bindings []kubernetesRoleBinding) []KubernetesPostureFinding {
+	findings := []KubernetesPostureFinding{}
+
+	// service accounts bound cluster wide to wildcard permissions,
+	// keyed by cluster and subject
+	clusterAdmins := map[string]string{}
+	for _, b := range bindings {
+		if !containsPermission(b.permissions, clusterAdminPermission) {
+			continue
+		}
+		if b.namespace == "" {
+			for _, subject := range b.subjects {
+				clusterAdmins[b.clusterID+"|"+subject] = b.nodeName
+			}
+		}
+		if subjects := nonSystemSubjects(b.subjects); len(subjects) > 0 {
+			findings = append(findings, bindingFinding(ruleClusterAdminBinding, b,
+				b.roleKind+" "+b.roleName+" bound to "+strings.Join(subjects, ", ")))
+		}
+	}
+
+	for _, b := range bindings {
+		if containsPermission(b.permissions, clusterAdminPermission) {
+			continue
+		}
+		subjects := nonSystemSubjects(b.subjects)
+		if len(subjects) == 0 {
+			continue
+		}
+		for _, p := range b.permissions {
+			if _, has := secretsPermissions[p]; has {
+				findings = append(findings, bindingFinding(ruleSecretsAccess, b,
+					b.roleKind+" "+b.roleName+" grants "+p+" to "+strings.Join(subjects, ", ")))
+				break
+			}
+		}
+	}
+
+	for _, p := range pods {
+		if p.internetExposed && p.automountToken && p.serviceAccount != "" {
+			for _, subject := range serviceAccountSubjects(p.namespace, p.serviceAccount) {
+				if binding, has := clusterAdmins[p.clusterID+"|"+subject]; has {
+					findings = append(findings, podFinding(ruleExposedClusterAdmin, p,
+						"service account "+p.serviceAccount+" is bound to cluster-admin by "+binding))
+					break
+				}
+			}
+		}
+		if p.privileged {
+			if p.internetExposed {
+				findings = append(findings, podFinding(ruleExposedPrivileged, p, "privileged container receiving internet traffic"))
+			}
+			findings = append(findings, podFinding(rulePrivileged, p, "securityContext.privileged is true"))
+		}
+		capabilities := []string{}
+		for _, capability := range p.capabilities {
+			if _, has := dangerousCapabilities[strings.TrimPrefix(capability, "CAP_")]; has {
+				capabilities = append(capabilities, capability)
+			}
+		}
+		if len(capabilities) > 0 {
+			findings = append(findings, podFinding(ruleCapabilities, p, "added capabilities "+strings.Join(capabilities, ", ")))
+		}
+		if len(p.hostPaths) > 0 {
+			findings = append(findings, podFinding(ruleHostPath, p, "mounts "+strings.Join(p.hostPaths, ", ")))
+		}
+		if p.hostNetwork || p.hostPID {
+			namespaces := []string{}
+			if p.hostNetwork {
+				namespaces = append(namespaces, "network")
+			}
+			if p.hostPID {
+				namespaces = append(namespaces, "pid")
+			}
+			findings = append(findings, podFinding(ruleHostNamespace, p, "shares host "+strings.Join(namespaces, ", ")))
+		}
+		if p.runAsRoot {
+			findings = append(findings, podFinding(ruleRunAsRoot, p, "no non root user enforced"))
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return kubernetesSeverityRank[findings[i].Severity] < kubernetesSeverityRank[findings[j].Severity]
+		}
+		return findings[i].NodeID < findings[j].NodeID
+	})
+	return findings
+}
+
+func podFinding(rule KubernetesPostureRule, p kubernetesPod, reason string) KubernetesPostureFinding {
+	return KubernetesPostureFinding{
+		RuleID:              rule.ID,
+		Title:               rule.Title,
+		Severity:            rule.Severity,
+		Description:         rule.Description,
+		NodeID:              p.nodeID,
+		NodeName:            p.nodeName,
+		NodeType:            KubernetesFindingPod,
+		KubernetesClusterID: p.clusterID,
+		KubernetesNamespace: p.namespace,
+		Reason:              reason,
+	}
+}
+
+func bindingFinding(rule KubernetesPostureRule, b kubernetesRoleBinding, reason string) KubernetesPostureFinding {
+	return KubernetesPostureFinding{
+		RuleID:              rule.ID,
+		Title:               rule.Title,
+		Severity:            rule.Severity,
+		Description:         rule.Description,
+		NodeID:              b.nodeID,
+		NodeName:            b.nodeName,
+		NodeType:            KubernetesFindingRoleBinding,
+		KubernetesClusterID: b.clusterID,
+		KubernetesNamespace: b.namespace,
+		Reason:              reason,
+	}
+}
+
+// serviceAccountSubjects lists the binding subjects matching a service
+// account, including the groups every service account belongs to
+func serviceAccountSubjects(namespace, serviceAccount string) []string {
+	return []string{
+		"ServiceAccount:" + namespace + "/" + serviceAccount,
+		"Group:system:serviceaccounts:" + namespace,
+		"Group:system:serviceaccounts",
+		"Group:system:authenticated",
+	}
+}
+
+// nonSystemSubjects filters out the subjects managed by kubernetes itself.
+// Groups matching all service accounts or all users are kept on purpose.
+func nonSystemSubjects(subjects []string) []string {
+	res := []string{}
+	for _, s := range subjects {
+		switch {
+		case s == "Group:system:authenticated", s == "Group:system:unauthenticated",
+			strings.HasPrefix(s, "Group:system:serviceaccounts"):
+			res = append(res, s)
+		case strings.HasPrefix(s, "Group:system:"), strings.HasPrefix(s, "User:system:"),
+			strings.HasPrefix(s, "ServiceAccount:kube-system/"):
+		default:
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func containsPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func toBool(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+func toStrings(v interface{}) []string {
+	values, _ := v.([]interface{})
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
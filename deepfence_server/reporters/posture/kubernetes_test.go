@@ -0,0 +1,98 @@
+package reporters_posture //nolint:stylecheck
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func findingRules(findings []KubernetesPostureFinding, nodeID string) []string {
+	rules := []string{}
+	for _, f := range findings {
+		if f.NodeID == nodeID {
+			rules = append(rules, f.RuleID)
+		}
+	}
+	return rules
+}
+
+func TestEvaluateKubernetesPosture(t *testing.T) {
+	bindings := []kubernetesRoleBinding{
+		{
+			nodeID: "crb-admin", clusterID: "c1", roleKind: "ClusterRole", roleName: "cluster-admin",
+			subjects:    []string{"ServiceAccount:payments/api"},
+			permissions: []string{"*:*"},
+		},
+		{
+			nodeID: "crb-masters", clusterID: "c1", roleKind: "ClusterRole", roleName: "cluster-admin",
+			subjects:    []string{"Group:system:masters"},
+			permissions: []string{"*:*"},
+		},
+		{
+			nodeID: "rb-reader", clusterID: "c1", namespace: "payments", roleKind: "Role", roleName: "secret-reader",
+			subjects:    []string{"ServiceAccount:payments/worker"},
+			permissions: []string{"get:configmaps", "get:secrets"},
+		},
+		{
+			nodeID: "rb-system", clusterID: "c1", namespace: "kube-system", roleKind: "Role", roleName: "reader",
+			subjects:    []string{"ServiceAccount:kube-system/dns"},
+			permissions: []string{"get:secrets"},
+		},
+	}
+	pods := []kubernetesPod{
+		{
+			nodeID: "pod-api", clusterID: "c1", namespace: "payments", serviceAccount: "api",
+			automountToken: true, internetExposed: true,
+		},
+		{
+			// same service account in another cluster
+			nodeID: "pod-other", clusterID: "c2", namespace: "payments", serviceAccount: "api",
+			automountToken: true, internetExposed: true,
+		},
+		{
+			// token not mounted
+			nodeID: "pod-nomount", clusterID: "c1", namespace: "payments", serviceAccount: "api",
+			internetExposed: true,
+		},
+		{
+			nodeID: "pod-agent", clusterID: "c1", namespace: "payments", serviceAccount: "default",
+			privileged: true, hostPID: true, runAsRoot: true,
+			hostPaths:    []string{"/var/run/docker.sock"},
+			capabilities: []string{"NET_BIND_SERVICE", "SYS_ADMIN"},
+		},
+	}
+
+	findings := evaluateKubernetesPosture(pods, bindings)
+
+	assert.Equal(t, findings[0].RuleID, ruleExposedClusterAdmin.ID)
+	assert.Equal(t, findings[0].NodeID, "pod-api")
+	assert.Equal(t, findings[0].Severity, "critical")
+
+	assert.DeepEqual(t, findingRules(findings, "pod-api"), []string{ruleExposedClusterAdmin.ID})
+	assert.DeepEqual(t, findingRules(findings, "pod-other"), []string{})
+	assert.DeepEqual(t, findingRules(findings, "pod-nomount"), []string{})
+	assert.DeepEqual(t, findingRules(findings, "pod-agent"), []string{
+		rulePrivileged.ID, ruleCapabilities.ID, ruleHostPath.ID, ruleHostNamespace.ID, ruleRunAsRoot.ID,
+	})
+	assert.DeepEqual(t, findingRules(findings, "crb-admin"), []string{ruleClusterAdminBinding.ID})
+	assert.DeepEqual(t, findingRules(findings, "crb-masters"), []string{})
+	assert.DeepEqual(t, findingRules(findings, "rb-reader"), []string{ruleSecretsAccess.ID})
+	assert.DeepEqual(t, findingRules(findings, "rb-system"), []string{})
+
+	for _, f := range findings {
+		if f.RuleID == ruleCapabilities.ID {
+			assert.Equal(t, f.Reason, "added capabilities SYS_ADMIN")
+		}
+	}
+}
+
+func TestNonSystemSubjects(t *testing.T) {
+	assert.DeepEqual(t, nonSystemSubjects([]string{
+		"Group:system:masters",
+		"User:system:kube-scheduler",
+		"ServiceAccount:kube-system/coredns",
+		"Group:system:authenticated",
+		"Group:system:serviceaccounts:payments",
+		"User:alice",
+	}), []string{"Group:system:authenticated", "Group:system:serviceaccounts:payments", "User:alice"})
+}
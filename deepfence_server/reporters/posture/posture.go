@@ -0,0 +1,380 @@
+package reporters_posture //nolint:stylecheck
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	ingestersUtil "github.com/deepfence/ThreatMapper/deepfence_utils/utils/ingesters"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+const (
+	// Bounds on label values exported to prometheus, everything beyond
+	// the top entries is folded into OtherLabel
+	MaxClusterLabels   = 50
+	MaxBenchmarkLabels = 20
+
+	OtherLabel     = "other"
+	NoClusterLabel = "none"
+	UnknownLabel   = "unknown"
+)
+
+var (
+	// scan type label values
+	ScanTypeLabels = map[utils.Neo4jScanType]string{
+		utils.NEO4JVulnerabilityScan:   "vulnerability",
+		utils.NEO4JSecretScan:          "secret",
+		utils.NEO4JMalwareScan:         "malware",
+		utils.NEO4JComplianceScan:      "compliance",
+		utils.NEO4JCloudComplianceScan: "cloud_compliance",
+	}
+
+	// field holding the severity of a finding per scan type
+	severityField = map[utils.Neo4jScanType]string{
+		utils.NEO4JVulnerabilityScan:   "cve_severity",
+		utils.NEO4JSecretScan:          "level",
+		utils.NEO4JMalwareScan:         "file_severity",
+		utils.NEO4JComplianceScan:      "status",
+		utils.NEO4JCloudComplianceScan: "status",
+	}
+
+	// allowed severity label values, anything else is reported as unknown
+	severityLabels = map[string]struct{}{
+		"critical": {}, "high": {}, "medium": {}, "low": {},
+		"alarm": {}, "warn": {}, "info": {}, "ok": {}, "pass": {}, "skip": {}, "note": {},
+	}
+
+	// node types eligible for each scan type, with the match condition
+	// defining which nodes are expected to be scanned
+	scannableNodes = map[utils.Neo4jScanType][]scannableNode{
+		utils.NEO4JVulnerabilityScan: {hostNode, containerNode, imageNode},
+		utils.NEO4JSecretScan:        {hostNode, containerNode, imageNode},
+		utils.NEO4JMalwareScan:       {hostNode, containerNode, imageNode},
+		utils.NEO4JComplianceScan:    {hostNode, clusterNode},
+	}
+
+	hostNode      = scannableNode{label: utils.NodeTypeHost, name: "host", where: "n.active = true AND n.agent_running = true"}
+	containerNode = scannableNode{label: utils.NodeTypeContainer, name: "container", where: "n.active = true AND n.pseudo = false"}
+	imageNode     = scannableNode{label: utils.NodeTypeContainerImage, name: "container_image", where: "n.active = true AND n.pseudo = false"}
+	clusterNode   = scannableNode{label: utils.NodeTypeKubernetesCluster, name: "kubernetes_cluster", where: "n.active = true AND n.agent_running = true"}
+
+	linuxPassStatus = []string{"warn", "pass"}
+	kubePassStatus  = []string{"ok", "info", "skip"}
+	cloudPassStatus = []string{"ok", "info", "skip"}
+)
+
+type scannableNode struct {
+	label string
+	name  string
+	where string
+}
+
+type FindingsCount struct {
+	ScanType string
+	Severity string
+	Cluster  string
+	Count    int64
+}
+
+type NodesCount struct {
+	ScanType string
+	NodeType string
+	Count    int64
+}
+
+type CompliancePass struct {
+	ScanType   string
+	Benchmark  string
+	Percentage float64
+}
+
+type PostureMetrics struct {
+	Findings       []FindingsCount
+	NeverScanned   []NodesCount
+	ScansFailed    []NodesCount
+	CompliancePass []CompliancePass
+	AgentsOffline  []NodesCount
+}
+
+// GetPostureMetrics computes security posture gauges from the latest scan
+// of every active node. Label values are normalized and bounded so the
+// output can be exported as prometheus metrics.
+func GetPostureMetrics(ctx context.Context) (PostureMetrics, error) {
+	res := PostureMetrics{}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(60 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	res.Findings, err = getFindings(tx)
+	if err != nil {
+		return res, err
+	}
+
+	res.NeverScanned, err = getNeverScanned(tx)
+	if err != nil {
+		return res, err
+	}
+
+	res.ScansFailed, err = getScansFailed(tx)
+	if err != nil {
+		return res, err
+	}
+
+	res.CompliancePass, err = getCompliancePass(tx)
+	if err != nil {
+		return res, err
+	}
+
+	res.AgentsOffline, err = getAgentsOffline(tx)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func getFindings(tx neo4j.Transaction) ([]FindingsCount, error) {
+	findings := []FindingsCount{}
+	clusterTotals := map[string]int64{}
+
+	for _, scanType := range allScanTypes() {
+		r, err := tx.Run(`
+			MATCH (s:`+string(scanType)+`) -[:SCANNED]-> (n)
+			WHERE n.active = true
+			AND s.node_id = n.`+ingestersUtil.LatestScanIDField[scanType]+`
+			MATCH (s) -[d:DETECTED]-> (f)
+			WHERE COALESCE(d.masked, false) = false
+			RETURN COALESCE(n.kubernetes_cluster_id, '') AS cluster, toLower(COALESCE(f.`+severityField[scanType]+`, '')) AS severity, count(f) AS count`,
+			map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		recs, err := r.Collect()
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range recs {
+			cluster := rec.Values[0].(string)
+			if cluster == "" {
+				cluster = NoClusterLabel
+			}
+			count := rec.Values[2].(int64)
+			clusterTotals[cluster] += count
+			findings = append(findings, FindingsCount{
+				ScanType: ScanTypeLabels[scanType],
+				Severity: normalizeSeverity(rec.Values[1].(string)),
+				Cluster:  cluster,
+				Count:    count,
+			})
+		}
+	}
+
+	clusterLabels := BoundLabels(clusterTotals, MaxClusterLabels)
+
+	merged := map[FindingsCount]int64{}
+	for _, f := range findings {
+		key := FindingsCount{ScanType: f.ScanType, Severity: f.Severity, Cluster: clusterLabels[f.Cluster]}
+		merged[key] += f.Count
+	}
+
+	res := make([]FindingsCount, 0, len(merged))
+	for k, v := range merged {
+		k.Count = v
+		res = append(res, k)
+	}
+	return res, nil
+}
+
+func getNeverScanned(tx neo4j.Transaction) ([]NodesCount, error) {
+	res := []NodesCount{}
+	for _, scanType := range allScanTypes() {
+		for _, node := range scannableNodes[scanType] {
+			r, err := tx.Run(`
+				MATCH (n:`+node.label+`)
+				WHERE `+node.where+`
+				AND NOT exists((n)<-[:SCANNED]-(:`+string(scanType)+`))
+				RETURN count(n)`,
+				map[string]interface{}{})
+			if err != nil {
+				return nil, err
+			}
+			rec, err := r.Single()
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, NodesCount{
+				ScanType: ScanTypeLabels[scanType],
+				NodeType: node.name,
+				Count:    rec.Values[0].(int64),
+			})
+		}
+	}
+	return res, nil
+}
+
+func getScansFailed(tx neo4j.Transaction) ([]NodesCount, error) {
+	res := []NodesCount{}
+	for _, scanType := range allScanTypes() {
+		r, err := tx.Run(`
+			MATCH (s:`+string(scanType)+`) -[:SCANNED]-> (n)
+			WHERE s.status = $failed
+			AND n.active = true
+			AND s.node_id = n.`+ingestersUtil.LatestScanIDField[scanType]+`
+			RETURN count(n)`,
+			map[string]interface{}{"failed": utils.ScanStatusFailed})
+		if err != nil {
+			return nil, err
+		}
+		rec, err := r.Single()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, NodesCount{
+			ScanType: ScanTypeLabels[scanType],
+			Count:    rec.Values[0].(int64),
+		})
+	}
+	return res, nil
+}
+
+func getCompliancePass(tx neo4j.Transaction) ([]CompliancePass, error) {
+	res := []CompliancePass{}
+	for _, scanType := range []utils.Neo4jScanType{utils.NEO4JComplianceScan, utils.NEO4JCloudComplianceScan} {
+		r, err := tx.Run(`
+			MATCH (s:`+string(scanType)+`) -[:SCANNED]-> (n)
+			WHERE n.active = true
+			AND s.node_id = n.`+ingestersUtil.LatestScanIDField[scanType]+`
+			MATCH (s) -[d:DETECTED]-> (c)
+			WHERE COALESCE(d.masked, false) = false
+			WITH c, CASE WHEN n:CloudNode THEN $cloud_pass WHEN n:KubernetesCluster THEN $kube_pass ELSE $linux_pass END AS pass_status
+			RETURN toLower(COALESCE(c.compliance_check_type, '')) AS benchmark,
+				count(c) AS total,
+				sum(CASE WHEN c.status IN pass_status THEN 1 ELSE 0 END) AS passed`,
+			map[string]interface{}{
+				"cloud_pass": cloudPassStatus,
+				"kube_pass":  kubePassStatus,
+				"linux_pass": linuxPassStatus,
+			})
+		if err != nil {
+			return nil, err
+		}
+		recs, err := r.Collect()
+		if err != nil {
+			return nil, err
+		}
+
+		totals := map[string]int64{}
+		passed := map[string]int64{}
+		for _, rec := range recs {
+			benchmark := rec.Values[0].(string)
+			if benchmark == "" {
+				benchmark = UnknownLabel
+			}
+			totals[benchmark] += rec.Values[1].(int64)
+			passed[benchmark] += rec.Values[2].(int64)
+		}
+
+		benchmarkLabels := BoundLabels(totals, MaxBenchmarkLabels)
+		boundedTotals := map[string]int64{}
+		boundedPassed := map[string]int64{}
+		for benchmark, total := range totals {
+			boundedTotals[benchmarkLabels[benchmark]] += total
+			boundedPassed[benchmarkLabels[benchmark]] += passed[benchmark]
+		}
+
+		for benchmark, total := range boundedTotals {
+			if total == 0 {
+				continue
+			}
+			res = append(res, CompliancePass{
+				ScanType:   ScanTypeLabels[scanType],
+				Benchmark:  benchmark,
+				Percentage: float64(boundedPassed[benchmark]) * 100.0 / float64(total),
+			})
+		}
+	}
+	return res, nil
+}
+
+func getAgentsOffline(tx neo4j.Transaction) ([]NodesCount, error) {
+	res := []NodesCount{}
+	for _, node := range []scannableNode{hostNode, clusterNode} {
+		r, err := tx.Run(`
+			MATCH (n:`+node.label+`)
+			WHERE n.agent_running = true
+			AND n.active = false
+			RETURN count(n)`,
+			map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		rec, err := r.Single()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, NodesCount{
+			NodeType: node.name,
+			Count:    rec.Values[0].(int64),
+		})
+	}
+	return res, nil
+}
+
+// BoundLabels maps every label to itself if it is within the max entries
+// with the highest counts, otherwise to OtherLabel. Ties are broken by
+// label name so the mapping is stable between scrapes.
+func BoundLabels(counts map[string]int64, max int) map[string]string {
+	labels := make([]string, 0, len(counts))
+	for label := range counts {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if counts[labels[i]] != counts[labels[j]] {
+			return counts[labels[i]] > counts[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+
+	res := make(map[string]string, len(labels))
+	for i, label := range labels {
+		if i < max {
+			res[label] = label
+		} else {
+			res[label] = OtherLabel
+		}
+	}
+	return res
+}
+
+func normalizeSeverity(severity string) string {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if _, has := severityLabels[severity]; has {
+		return severity
+	}
+	return UnknownLabel
+}
+
+func allScanTypes() []utils.Neo4jScanType {
+	return []utils.Neo4jScanType{
+		utils.NEO4JVulnerabilityScan,
+		utils.NEO4JSecretScan,
+		utils.NEO4JMalwareScan,
+		utils.NEO4JComplianceScan,
+		utils.NEO4JCloudComplianceScan,
+	}
+}
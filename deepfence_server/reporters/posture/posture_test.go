@@ -0,0 +1,29 @@
+package reporters_posture //nolint:stylecheck
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestBoundLabels(t *testing.T) {
+	counts := map[string]int64{"a": 10, "b": 30, "c": 20, "d": 20}
+
+	labels := BoundLabels(counts, 2)
+	assert.Equal(t, labels["b"], "b")
+	assert.Equal(t, labels["c"], "c")
+	assert.Equal(t, labels["d"], OtherLabel)
+	assert.Equal(t, labels["a"], OtherLabel)
+
+	labels = BoundLabels(counts, 10)
+	for label := range counts {
+		assert.Equal(t, labels[label], label)
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	assert.Equal(t, normalizeSeverity("Critical "), "critical")
+	assert.Equal(t, normalizeSeverity("warn"), "warn")
+	assert.Equal(t, normalizeSeverity("bogus"), UnknownLabel)
+	assert.Equal(t, normalizeSeverity(""), UnknownLabel)
+}
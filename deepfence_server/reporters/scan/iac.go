@@ -0,0 +1,74 @@
+package reporters_scan //nolint:stylecheck
+
+import (
+	"context"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// SaveIaCScan stores a completed IaC scan of the source nodeName with its
+// findings. Findings keep their masked state across scans of the source.
+func SaveIaCScan(ctx context.Context, scanID, nodeName string, findings []model.IaCFinding) error {
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	if _, err = tx.Run(`
+		MERGE (n:IaCSource{node_id: $node_id})
+		SET n.node_name = $node_id,
+			n.node_type = $node_type,
+			n.active = true,
+			n.updated_at = TIMESTAMP()
+		MERGE (m:`+string(utils.NEO4JIaCScan)+`{node_id: $scan_id})
+		MERGE (m) -[:SCANNED]-> (n)
+		SET m.status = $status,
+			m.status_message = "",
+			m.retries = 0,
+			m.created_at = TIMESTAMP(),
+			m.updated_at = TIMESTAMP()`,
+		map[string]interface{}{
+			"node_id":   nodeName,
+			"node_type": model.IaCSourceNodeType,
+			"scan_id":   scanID,
+			"status":    utils.ScanStatusSuccess,
+		}); err != nil {
+		return err
+	}
+
+	batch := make([]map[string]interface{}, 0, len(findings))
+	for _, f := range findings {
+		row := utils.ToMap(f)
+		delete(row, "masked")
+		delete(row, "updated_at")
+		batch = append(batch, row)
+	}
+
+	if _, err = tx.Run(`
+		UNWIND $batch as row
+		MERGE (d:`+utils.ScanTypeDetectedNode[utils.NEO4JIaCScan]+`{node_id: row.node_id})
+		SET d += row,
+			d.masked = COALESCE(d.masked, false),
+			d.updated_at = TIMESTAMP()
+		WITH d
+		MATCH (m:`+string(utils.NEO4JIaCScan)+`{node_id: $scan_id})
+		MERGE (m) -[r:DETECTED]-> (d)
+		SET r.masked = false`,
+		map[string]interface{}{"batch": batch, "scan_id": scanID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
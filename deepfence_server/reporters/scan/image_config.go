@@ -0,0 +1,130 @@
+package reporters_scan //nolint:stylecheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// SaveImageConfigFindings replaces the config findings of the image scanned
+// by the vulnerability scan scanID, or of the image of the scanned
+// container. Findings found again keep their masked state.
+func SaveImageConfigFindings(ctx context.Context, scanID string, findings []model.ImageConfigFinding) error {
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (s:`+string(utils.NEO4JVulnerabilityScan)+`{node_id: $scan_id}) -[:SCANNED]-> (n)
+		WITH CASE WHEN n:ContainerImage THEN n.node_id ELSE n.docker_image_id END AS image_id
+		MATCH (img:ContainerImage{node_id: image_id})
+		RETURN img.node_id, COALESCE(img.docker_image_name + ':' + img.docker_image_tag, img.node_id)`,
+		map[string]interface{}{"scan_id": scanID})
+	if err != nil {
+		return err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return &NodeNotFoundError{nodeID: scanID}
+	}
+	imageID := rec.Values[0].(string)
+	imageName := rec.Values[1].(string)
+
+	ids := make([]string, 0, len(findings))
+	batch := make([]map[string]interface{}, 0, len(findings))
+	for _, f := range findings {
+		key := imageID + "|" + f.RuleID + "|" + f.Evidence
+		f.NodeID = fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+		f.ImageID = imageID
+		f.ImageName = imageName
+		f.ScanID = scanID
+		row := utils.ToMap(f)
+		delete(row, "masked")
+		delete(row, "updated_at")
+		ids = append(ids, f.NodeID)
+		batch = append(batch, row)
+	}
+
+	if _, err = tx.Run(`
+		MATCH (img:ContainerImage{node_id: $image_id}) -[:CONFIG_FINDING]-> (d:ImageConfigFinding)
+		WHERE NOT d.node_id IN $ids
+		DETACH DELETE d`,
+		map[string]interface{}{"image_id": imageID, "ids": ids}); err != nil {
+		return err
+	}
+
+	if _, err = tx.Run(`
+		MATCH (img:ContainerImage{node_id: $image_id})
+		SET img.image_config_findings_count = size($batch)
+		WITH img
+		UNWIND $batch as row
+		MERGE (d:ImageConfigFinding{node_id: row.node_id})
+		SET d += row,
+			d.masked = COALESCE(d.masked, false),
+			d.updated_at = TIMESTAMP()
+		MERGE (img) -[:CONFIG_FINDING]-> (d)`,
+		map[string]interface{}{"image_id": imageID, "batch": batch}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetImageConfigFindings returns the current config findings of the images
+// scanned by the vulnerability scans, keyed by scan id
+func GetImageConfigFindings(ctx context.Context, scanIDs []string) (map[string][]model.ImageConfigFinding, error) {
+	res := map[string][]model.ImageConfigFinding{}
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		MATCH (s:`+string(utils.NEO4JVulnerabilityScan)+`) -[:SCANNED]-> (n)
+		WHERE s.node_id IN $scan_ids
+		WITH s, CASE WHEN n:ContainerImage THEN n.node_id ELSE n.docker_image_id END AS image_id
+		MATCH (img:ContainerImage{node_id: image_id}) -[:CONFIG_FINDING]-> (d:ImageConfigFinding)
+		RETURN s.node_id, d{.*}
+		ORDER BY d.rule_id`,
+		map[string]interface{}{"scan_ids": scanIDs})
+	if err != nil {
+		return res, err
+	}
+	recs, err := r.Collect()
+	if err != nil {
+		return res, err
+	}
+
+	for _, rec := range recs {
+		var f model.ImageConfigFinding
+		utils.FromMap(rec.Values[1].(map[string]interface{}), &f)
+		scanID := rec.Values[0].(string)
+		res[scanID] = append(res[scanID], f)
+	}
+
+	return res, nil
+}
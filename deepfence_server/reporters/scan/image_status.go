@@ -0,0 +1,113 @@
+package reporters_scan //nolint:stylecheck
+
+import (
+	"context"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// scan types reported for images, keyed by the name used in responses
+var imageScanTypes = map[string]utils.Neo4jScanType{
+	"vulnerability": utils.NEO4JVulnerabilityScan,
+	"secret":        utils.NEO4JSecretScan,
+	"malware":       utils.NEO4JMalwareScan,
+}
+
+// imageReferenceCondition matches the images of a reference by name and
+// tag, or by digest when pinned: the repo digest reported by agents or the
+// manifest digest of registries. The image id of agents is the digest of the
+// image config, which references never pin.
+const imageReferenceCondition = `
+	CASE WHEN $digest = ''
+	THEN n.docker_image_name + ':' + n.docker_image_tag IN $candidates
+	ELSE coalesce(n.image_digest, '') ENDS WITH ':' + $digest OR n.node_id = $digest
+	END`
+
+// GetImageScanStatus returns, for each image reference, the latest
+// completed scan of every scan type across all the container images
+// known with the same name and tag, whether reported by an agent or
+// synced from a registry. References pinned by digest only match the
+// images with this repo or registry digest.
+func GetImageScanStatus(ctx context.Context, images []string) ([]model.ImageScanStatus, error) {
+	res := make([]model.ImageScanStatus, 0, len(images))
+
+	driver, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	session := driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return res, err
+	}
+	defer tx.Close()
+
+	for _, image := range images {
+		status := model.ImageScanStatus{
+			Image:       image,
+			LatestScans: map[string]model.ImageScanSummary{},
+		}
+		params := map[string]interface{}{
+			"candidates": model.ImageReferenceCandidates(image),
+			"digest":     model.ImageReferenceDigest(image),
+			"complete":   utils.ScanStatusSuccess,
+		}
+
+		r, err := tx.Run(`
+			MATCH (n:ContainerImage)
+			WHERE `+imageReferenceCondition+`
+			RETURN count(n) > 0`, params)
+		if err != nil {
+			return res, err
+		}
+		rec, err := r.Single()
+		if err != nil {
+			return res, err
+		}
+		status.Found = rec.Values[0].(bool)
+		if !status.Found {
+			res = append(res, status)
+			continue
+		}
+
+		for name, scanType := range imageScanTypes {
+			r, err := tx.Run(`
+				MATCH (s:`+string(scanType)+`{status: $complete}) -[:SCANNED]-> (n:ContainerImage)
+				WHERE `+imageReferenceCondition+`
+				RETURN s.node_id, n.node_id, s.updated_at
+				ORDER BY s.updated_at DESC
+				LIMIT 1`, params)
+			if err != nil {
+				return res, err
+			}
+			recs, err := r.Collect()
+			if err != nil {
+				return res, err
+			}
+			if len(recs) == 0 {
+				continue
+			}
+			scanID := recs[0].Values[0].(string)
+			counts, err := GetSevCounts(ctx, scanType, scanID)
+			if err != nil {
+				return res, err
+			}
+			status.LatestScans[name] = model.ImageScanSummary{
+				ScanID:         scanID,
+				NodeID:         recs[0].Values[1].(string),
+				UpdatedAt:      recs[0].Values[2].(int64),
+				SeverityCounts: counts,
+			}
+		}
+		res = append(res, status)
+	}
+
+	return res, nil
+}
@@ -475,6 +475,8 @@ func nodeType2Neo4jType(nodeType string) string {
 		return "KubernetesCluster"
 	case "cloud_account":
 		return "CloudNode"
+	case "iac_source":
+		return "IaCSource"
 	}
 	return "unknown"
 }
@@ -899,6 +901,8 @@ func scanResultIDField(scanType utils.Neo4jScanType) string {
 		return "test_number"
 	case utils.NEO4JCloudComplianceScan:
 		return "control_id"
+	case utils.NEO4JIaCScan:
+		return "test_number"
 	}
 	return ""
 }
@@ -915,6 +919,8 @@ func type2sevField(scanType utils.Neo4jScanType) string {
 		return "status"
 	case utils.NEO4JCloudComplianceScan:
 		return "status"
+	case utils.NEO4JIaCScan:
+		return "test_severity"
 	}
 	return "error_sev_field_unknown"
 }
@@ -1181,6 +1187,8 @@ func Labels2NodeType(labels []interface{}) string {
 			return "registry"
 		case "CloudNode":
 			return "cloud_account"
+		case "IaCSource":
+			return "iac_source"
 		}
 	}
 	return "unknown"
@@ -1,6 +1,11 @@
 package router
 
 import (
+	"context"
+	"sync"
+	"time"
+
+	reporters_posture "github.com/deepfence/ThreatMapper/deepfence_server/reporters/posture"
 	reporters_search "github.com/deepfence/ThreatMapper/deepfence_server/reporters/search"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
@@ -84,6 +89,107 @@ func (collector *Collector) Collect(ch chan<- prometheus.Metric) {
 
 }
 
+// postureMetricsTTL is how long the posture is reused across scrapes, its
+// queries go over the latest scans of every node
+const postureMetricsTTL = time.Minute
+
+type PostureCollector struct {
+	findings       *prometheus.Desc
+	neverScanned   *prometheus.Desc
+	scansFailed    *prometheus.Desc
+	compliancePass *prometheus.Desc
+	agentsOffline  *prometheus.Desc
+
+	cacheLock sync.Mutex
+	cached    *reporters_posture.PostureMetrics
+	cachedAt  time.Time
+}
+
+func newPostureCollector() *PostureCollector {
+	return &PostureCollector{
+		findings: prometheus.NewDesc(
+			"posture_findings",
+			"number of findings in latest scans by scan type, severity and kubernetes cluster",
+			[]string{"scan_type", "severity", "cluster", "namespace"}, nil,
+		),
+		neverScanned: prometheus.NewDesc(
+			"posture_nodes_never_scanned",
+			"number of active nodes never scanned by scan type and node type",
+			[]string{"scan_type", "node_type", "namespace"}, nil,
+		),
+		scansFailed: prometheus.NewDesc(
+			"posture_scans_failed",
+			"number of active nodes whose latest scan failed by scan type",
+			[]string{"scan_type", "namespace"}, nil,
+		),
+		compliancePass: prometheus.NewDesc(
+			"posture_compliance_pass_percentage",
+			"percentage of passing checks in latest compliance scans by benchmark",
+			[]string{"scan_type", "benchmark", "namespace"}, nil,
+		),
+		agentsOffline: prometheus.NewDesc(
+			"posture_agents_offline",
+			"number of agents not reporting to console",
+			[]string{"node_type", "namespace"}, nil,
+		),
+	}
+}
+
+func (collector *PostureCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.findings
+	ch <- collector.neverScanned
+	ch <- collector.scansFailed
+	ch <- collector.compliancePass
+	ch <- collector.agentsOffline
+}
+
+func (collector *PostureCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := directory.NewContextWithNameSpace(directory.NonSaaSDirKey)
+	ns := string(directory.NonSaaSDirKey)
+
+	posture, err := collector.posture(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to fetch posture for metrics")
+		return
+	}
+
+	for _, f := range posture.Findings {
+		ch <- prometheus.MustNewConstMetric(collector.findings, prometheus.GaugeValue,
+			float64(f.Count), f.ScanType, f.Severity, f.Cluster, ns)
+	}
+	for _, n := range posture.NeverScanned {
+		ch <- prometheus.MustNewConstMetric(collector.neverScanned, prometheus.GaugeValue,
+			float64(n.Count), n.ScanType, n.NodeType, ns)
+	}
+	for _, n := range posture.ScansFailed {
+		ch <- prometheus.MustNewConstMetric(collector.scansFailed, prometheus.GaugeValue,
+			float64(n.Count), n.ScanType, ns)
+	}
+	for _, c := range posture.CompliancePass {
+		ch <- prometheus.MustNewConstMetric(collector.compliancePass, prometheus.GaugeValue,
+			c.Percentage, c.ScanType, c.Benchmark, ns)
+	}
+	for _, n := range posture.AgentsOffline {
+		ch <- prometheus.MustNewConstMetric(collector.agentsOffline, prometheus.GaugeValue,
+			float64(n.Count), n.NodeType, ns)
+	}
+}
+
+func (collector *PostureCollector) posture(ctx context.Context) (*reporters_posture.PostureMetrics, error) {
+	collector.cacheLock.Lock()
+	defer collector.cacheLock.Unlock()
+	if collector.cached != nil && time.Since(collector.cachedAt) < postureMetricsTTL {
+		return collector.cached, nil
+	}
+	posture, err := reporters_posture.GetPostureMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collector.cached = &posture
+	collector.cachedAt = time.Now()
+	return collector.cached, nil
+}
+
 func NewMetrics() *prometheus.Registry {
 	// prometheus metrics
 	registry := prometheus.NewRegistry()
@@ -91,6 +197,7 @@ func NewMetrics() *prometheus.Registry {
 		collectors.NewGoCollector(),
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		newCollector(),
+		newPostureCollector(),
 	)
 
 	return registry
@@ -217,6 +217,8 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 				r.Post("/email", dfHandler.AuthHandler(ResourceSettings, PermissionWrite, dfHandler.AddEmailConfiguration))
 				r.Get("/email", dfHandler.AuthHandler(ResourceSettings, PermissionRead, dfHandler.GetEmailConfiguration))
 				r.Delete("/email/{config_id}", dfHandler.AuthHandler(ResourceSettings, PermissionDelete, dfHandler.DeleteEmailConfiguration))
+				r.Get("/threat-scoring", dfHandler.AuthHandler(ResourceSettings, PermissionRead, dfHandler.GetThreatScoringModel))
+				r.Put("/threat-scoring", dfHandler.AuthHandler(ResourceSettings, PermissionWrite, dfHandler.UpdateThreatScoringModel))
 				r.Put("/agent/version", dfHandler.AuthHandler(ResourceSettings, PermissionWrite, dfHandler.UploadAgentBinaries))
 				r.Get("/agent/versions", dfHandler.AuthHandler(ResourceSettings, PermissionWrite, dfHandler.ListAgentVersion))
 			})
@@ -229,11 +231,16 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 					r.Post("/containers", dfHandler.GetTopologyContainersGraph)
 					r.Post("/pods", dfHandler.GetTopologyPodsGraph)
 					r.Post("/delta", dfHandler.GetTopologyDelta)
+					r.Post("/export", dfHandler.ExportTopologyGraph)
+					r.Get("/stream", dfHandler.StreamTopology)
 				})
 				r.Route("/threat", func(r chi.Router) {
 					r.Post("/", dfHandler.GetThreatGraph)
 					r.Post("/individual", dfHandler.GetIndividualThreatGraph)
+					r.Post("/attack-paths", dfHandler.GetAttackPaths)
 				})
+				r.Post("/network-policies", dfHandler.GetNetworkPolicies)
+				r.Post("/blast-radius", dfHandler.GetBlastRadius)
 			})
 
 			r.Route("/lookup", func(r chi.Router) {
@@ -267,6 +274,7 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 				r.Post("/malwares", dfHandler.SearchMalwares)
 				r.Post("/cloud-compliances", dfHandler.SearchCloudCompliances)
 				r.Post("/compliances", dfHandler.SearchCompliances)
+				r.Post("/iac-findings", dfHandler.SearchIaCFindings)
 				r.Post("/secret-rules", dfHandler.SearchSecretRules)
 				r.Post("/malware-rules", dfHandler.SearchMalwareRules)
 				r.Post("/compliance-rules", dfHandler.SearchComplianceRules)
@@ -274,12 +282,15 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 				r.Post("/cloud-resources", dfHandler.SearchCloudResources)
 				r.Post("/kubernetes-clusters", dfHandler.SearchKubernetesClusters)
 				r.Post("/pods", dfHandler.SearchPods)
+				r.Post("/drifts", dfHandler.SearchDrifts)
+				r.Post("/image-config-findings", dfHandler.SearchImageConfigFindings)
 
 				r.Post("/vulnerability/scans", dfHandler.SearchVulnerabilityScans)
 				r.Post("/secret/scans", dfHandler.SearchSecretScans)
 				r.Post("/malware/scans", dfHandler.SearchMalwareScans)
 				r.Post("/compliance/scans", dfHandler.SearchComplianceScans)
 				r.Post("/cloud-compliance/scans", dfHandler.SearchCloudComplianceScans)
+				r.Post("/iac/scans", dfHandler.SearchIaCScans)
 
 				r.Post("/cloud-accounts", dfHandler.SearchCloudNodes)
 				r.Post("/registry-accounts", dfHandler.SearchRegistryAccounts)
@@ -298,9 +309,12 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 					r.Post("/compliance-rules", dfHandler.SearchComplianceRulesCount)
 					r.Post("/vulnerability-rules", dfHandler.SearchVulnerabilityRulesCount)
 					r.Post("/compliances", dfHandler.SearchCompliancesCount)
+					r.Post("/iac-findings", dfHandler.SearchIaCFindingsCount)
 					r.Post("/cloud-resources", dfHandler.SearchCloudResourcesCount)
 					r.Post("/kubernetes-clusters", dfHandler.SearchKubernetesClustersCount)
 					r.Post("/pods", dfHandler.SearchPodsCount)
+					r.Post("/drifts", dfHandler.SearchDriftsCount)
+					r.Post("/image-config-findings", dfHandler.SearchImageConfigFindingsCount)
 					r.Post("/cloud-accounts", dfHandler.SearchCloudAccountCount)
 					r.Post("/registry-accounts", dfHandler.SearchRegistryAccountsCount)
 
@@ -309,14 +323,55 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 					r.Post("/malware/scans", dfHandler.SearchMalwareScansCount)
 					r.Post("/compliance/scans", dfHandler.SearchComplianceScansCount)
 					r.Post("/cloud-compliance/scans", dfHandler.SearchCloudComplianceScansCount)
+					r.Post("/iac/scans", dfHandler.SearchIaCScansCount)
 				})
 			})
 
+			r.Route("/agents", func(r chi.Router) {
+				r.Post("/health", dfHandler.AuthHandler(ResourceScan, PermissionRead, dfHandler.GetAgentsHealth))
+				r.Post("/enroll", dfHandler.AuthHandler(ResourceAgentReport, PermissionIngest, dfHandler.EnrollAgent))
+				r.Route("/credentials", func(r chi.Router) {
+					r.Get("/", dfHandler.AuthHandler(ResourceAllUsers, PermissionRead, dfHandler.ListAgentCredentials))
+					r.Post("/approve", dfHandler.AuthHandler(ResourceAllUsers, PermissionWrite, dfHandler.ApproveAgentEnrollments))
+					r.Post("/revoke", dfHandler.AuthHandler(ResourceAllUsers, PermissionWrite, dfHandler.RevokeAgentCredentials))
+					r.Post("/reinstate", dfHandler.AuthHandler(ResourceAllUsers, PermissionWrite, dfHandler.ReinstateAgents))
+				})
+			})
+
+			r.Route("/scan-windows", func(r chi.Router) {
+				r.Get("/", dfHandler.AuthHandler(ResourceScan, PermissionRead, dfHandler.ListScanWindows))
+				r.Post("/", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.AddScanWindow))
+				r.Delete("/{id}", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.DeleteScanWindow))
+				r.Put("/cluster-limits", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.SetClusterScanLimit))
+			})
+
+			r.Route("/scan-profiles", func(r chi.Router) {
+				r.Get("/", dfHandler.AuthHandler(ResourceScan, PermissionRead, dfHandler.ListScanProfiles))
+				r.Post("/", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.AddScanProfile))
+				r.Delete("/{id}", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.DeleteScanProfile))
+				r.Put("/assign", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.AssignScanProfile))
+			})
+
+			r.Route("/custom-rules", func(r chi.Router) {
+				r.Get("/", dfHandler.AuthHandler(ResourceScan, PermissionRead, dfHandler.ListCustomRules))
+				r.Post("/", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.AddCustomRule))
+				r.Get("/bundles", dfHandler.AuthHandler(ResourceScan, PermissionRead, dfHandler.ListCustomRulesBundles))
+				r.Put("/{id}", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.UpdateCustomRule))
+				r.Delete("/{id}", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.DeleteCustomRule))
+			})
+
 			r.Route("/controls", func(r chi.Router) {
-				r.Post("/agent", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.GetAgentControls))
-				r.Post("/kubernetes-cluster", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.GetKubernetesClusterControls))
-				r.Post("/agent-init", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.GetAgentInitControls))
+				r.Post("/agent", dfHandler.AuthHandler(ResourceAgentReport, PermissionIngest, dfHandler.GetAgentControls))
+				r.Post("/kubernetes-cluster", dfHandler.AuthHandler(ResourceAgentReport, PermissionIngest, dfHandler.GetKubernetesClusterControls))
+				r.Post("/agent-init", dfHandler.AuthHandler(ResourceAgentReport, PermissionIngest, dfHandler.GetAgentInitControls))
 				r.Post("/agent-upgrade", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.ScheduleAgentUpgrade))
+				r.Route("/agent-rollouts", func(r chi.Router) {
+					r.Get("/", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.ListAgentRollouts))
+					r.Post("/start", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.StartAgentRollout))
+					r.Post("/cancel", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.CancelAgentRollout))
+				})
+
+				r.Post("/agent-resource-limits", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.ScheduleAgentResourceLimits))
 
 				r.Route("/agent-plugins", func(r chi.Router) {
 					r.Post("/enable", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.ScheduleAgentPluginsEnable))
@@ -357,6 +412,7 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 				r.Post("/secret", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.StartSecretScanHandler))
 				r.Post("/compliance", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.StartComplianceScanHandler))
 				r.Post("/malware", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.StartMalwareScanHandler))
+				r.Post("/iac", dfHandler.AuthHandler(ResourceScan, PermissionStart, dfHandler.StartIaCScanHandler))
 			})
 			r.Route("/scan/stop", func(r chi.Router) {
 				r.Post("/vulnerability", dfHandler.AuthHandler(ResourceScan, PermissionStop, dfHandler.StopVulnerabilityScanHandler))
@@ -377,6 +433,7 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 				r.Post("/compliance", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListComplianceScansHandler))
 				r.Post("/malware", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListMalwareScansHandler))
 				r.Post("/cloud-compliance", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListCloudComplianceScansHandler))
+				r.Post("/iac", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListIaCScansHandler))
 			})
 			r.Route("/scan/results", func(r chi.Router) {
 				r.Get("/fields", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.GetScanReportFields))
@@ -396,6 +453,7 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 
 				r.Post("/compliance", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListComplianceScanResultsHandler))
 				r.Post("/cloud-compliance", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListCloudComplianceScanResultsHandler))
+				r.Post("/iac", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ListIaCScanResultsHandler))
 
 				r.Route("/count", func(r chi.Router) {
 					r.Post("/vulnerability", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.CountVulnerabilityScanResultsHandler))
@@ -435,11 +493,19 @@ func SetupRoutes(r *chi.Mux, serverPort string, serveOpenapiDocs bool, ingestC c
 
 			r.Post("/scans/bulk/delete", dfHandler.AuthHandler(ResourceScanReport, PermissionDelete, dfHandler.BulkDeleteScans))
 
+			r.Route("/asset-tags", func(r chi.Router) {
+				r.Put("/", dfHandler.AuthHandler(ResourceScanReport, PermissionWrite, dfHandler.UpdateAssetTags))
+				r.Post("/import", dfHandler.AuthHandler(ResourceScanReport, PermissionWrite, dfHandler.ImportAssetTags))
+			})
+
 			r.Route("/scan/{scan_type}/{scan_id}", func(r chi.Router) {
 				r.Get("/download", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.ScanResultDownloadHandler))
 				r.Delete("/", dfHandler.AuthHandler(ResourceScanReport, PermissionDelete, dfHandler.ScanDeleteHandler))
 			})
 			r.Post("/scan/nodes-in-result", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.GetAllNodesInScanResultBulkHandler))
+			r.Post("/scan/coverage", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.GetScanCoverage))
+			r.Post("/scan/kubernetes-posture", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.GetKubernetesPosture))
+			r.Post("/scan/images/status", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.GetImageScanStatus))
 
 			r.Route("/scan/sbom", func(r chi.Router) {
 				r.Post("/", dfHandler.AuthHandler(ResourceScanReport, PermissionRead, dfHandler.GetSbomHandler))
@@ -20,6 +20,9 @@ const (
 	StopMalwareScan
 	StopVulnerabilityScan
 	StopComplianceScan
+	RollbackAgentUpgrade
+	UpdateAgentResourceLimits
+	UpdateCustomScanRules
 )
 
 type ScanResource int
@@ -106,6 +109,7 @@ type StartSecretScanRequest struct {
 	NodeID   string            `json:"node_id" required:"true"`
 	NodeType ScanResource      `json:"node_type" required:"true"`
 	BinArgs  map[string]string `json:"bin_args" required:"true"`
+	Profile  *ScanProfile      `json:"profile,omitempty"`
 }
 
 type StartComplianceScanRequest struct {
@@ -118,6 +122,17 @@ type StartMalwareScanRequest struct {
 	NodeID   string            `json:"node_id" required:"true"`
 	NodeType ScanResource      `json:"node_type" required:"true"`
 	BinArgs  map[string]string `json:"bin_args" required:"true"`
+	Profile  *ScanProfile      `json:"profile,omitempty"`
+}
+
+// ScanProfile scopes the files of a secret or malware scan. Paths are globs
+// relative to the root of the scanned host, container or image.
+type ScanProfile struct {
+	IncludePaths           []string `json:"include_paths"`
+	ExcludePaths           []string `json:"exclude_paths"`
+	MaxFileSizeMB          int64    `json:"max_file_size_mb"`
+	SkipMountedVolumes     bool     `json:"skip_mounted_volumes"`
+	SkipNetworkFilesystems bool     `json:"skip_network_filesystems"`
 }
 
 type StopSecretScanRequest StartSecretScanRequest
@@ -138,6 +153,37 @@ type StartAgentUpgradeRequest struct {
 	Version          string `json:"version" required:"true"`
 }
 
+// RollbackAgentUpgradeRequest restores the binaries backed up by the last
+// upgrade, or installs the tarball of the version when there is no backup
+type RollbackAgentUpgradeRequest StartAgentUpgradeRequest
+
+// PluginResourceLimits caps the resources of a config.ini process. CPU is a
+// percentage of all the CPUs of the node, memory is in MB and IO is a weight
+// between 1 and 10000, 0 means unlimited. The Scan limits apply while the
+// plugin is running a scan, they default to the idle ones.
+type PluginResourceLimits struct {
+	PluginName        string `json:"plugin_name" required:"true"`
+	CPUPercentage     int    `json:"cpu_percentage" required:"true"`
+	MemoryMB          int64  `json:"memory_mb" required:"true"`
+	IOWeight          int    `json:"io_weight" required:"true"`
+	ScanCPUPercentage int    `json:"scan_cpu_percentage" required:"true"`
+	ScanMemoryMB      int64  `json:"scan_memory_mb" required:"true"`
+}
+
+// UpdateAgentResourceLimitsRequest replaces the limits of all the plugins,
+// the plugins not listed are left unlimited
+type UpdateAgentResourceLimitsRequest struct {
+	Limits []PluginResourceLimits `json:"limits" required:"true"`
+}
+
+// UpdateCustomScanRulesRequest replaces the custom rules of the secret and
+// malware scanners with the bundles of the version, an empty URL removes them
+type UpdateCustomScanRulesRequest struct {
+	Version         int64  `json:"version" required:"true"`
+	SecretRulesURL  string `json:"secret_rules_url" required:"true"`
+	MalwareRulesURL string `json:"malware_rules_url" required:"true"`
+}
+
 type EnableAgentPluginRequest struct {
 	PluginName string `json:"plugin_name" required:"true"`
 	Version    string `json:"version" required:"true"`
@@ -157,6 +203,13 @@ type AgentBeat struct {
 	BeatRateSec int32 `json:"beatrate" required:"true"`
 }
 
+// Agents number the reports they publish so the console can drop the ones
+// replayed from their offline buffer it already ingested
+const (
+	ReportSourceHeader   = "X-Report-Source"
+	ReportSequenceHeader = "X-Report-Sequence"
+)
+
 type AgentControls struct {
 	BeatRateSec int32    `json:"beatrate" required:"true"`
 	Commands    []Action `json:"commands" required:"true"`
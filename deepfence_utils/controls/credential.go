@@ -0,0 +1,86 @@
+package controls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AgentCredential is the credential bound to its node the bootstrapper gets
+// when enrolling with the console. The agent processes use it in place of
+// the API key.
+type AgentCredential struct {
+	NodeID     string `json:"node_id"`
+	Credential string `json:"credential"`
+}
+
+func AgentCredentialPath() string {
+	return os.ExpandEnv("${DF_INSTALL_DIR}/etc/deepfence/agent-credential.json")
+}
+
+func ReadAgentCredential() (AgentCredential, error) {
+	var cred AgentCredential
+	data, err := os.ReadFile(AgentCredentialPath())
+	if err != nil {
+		return cred, err
+	}
+	err = json.Unmarshal(data, &cred)
+	return cred, err
+}
+
+func WriteAgentCredential(cred AgentCredential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	path := AgentCredentialPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func RemoveAgentCredential() error {
+	err := os.Remove(AgentCredentialPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func AgentEnrollmentKeyPath() string {
+	return os.ExpandEnv("${DF_INSTALL_DIR}/etc/deepfence/agent-enrollment-key")
+}
+
+// AgentEnrollmentKey returns the secret the node proves it is the one that
+// first enrolled with, generated on the first call
+func AgentEnrollmentKey() (string, error) {
+	path := AgentEnrollmentKeyPath()
+	data, err := os.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(key), 0600); err != nil {
+		return "", err
+	}
+	return key, os.Rename(tmp, path)
+}
@@ -57,6 +57,12 @@ const (
 	AutoFetchGenerativeAIIntegrations = "auto_fetch_generative_ai_integrations"
 	AsynqDeleteAllArchivedTasks       = "asynq_delete_all_archived_tasks"
 	RedisRewriteAOF                   = "redis_rewrite_aof"
+	ScanCoverageNotificationTask      = "tasks_scan_coverage_notification"
+	ComputeDriftTask                  = "compute_drift"
+	RecordPodFlowsTask                = "record_pod_flows"
+	DeriveAssetTagsTask               = "derive_asset_tags"
+	ProgressAgentRolloutsTask         = "progress_agent_rollouts"
+	ValidateCustomRulesTask           = "validate_custom_rules"
 )
 
 const (
@@ -84,6 +90,27 @@ const (
 	NodeTypeRegistryAccount   = "RegistryAccount"
 )
 
+// topology change events, published on the redis channel of the namespace
+const (
+	TopologyEventsChannelPrefix = "topology_events/"
+
+	TopologyNodeAdded         = "node_added"
+	TopologyNodeUpdated       = "node_updated"
+	TopologyNodeRemoved       = "node_removed"
+	TopologyConnectionAdded   = "connection_added"
+	TopologyConnectionRemoved = "connection_removed"
+)
+
+// custom scan rules bundles in the file server, one file per version named
+// after it, zero padded to sort in version order. The versions are tracked
+// by the CustomRulesVersion node of the graph.
+const (
+	CustomSecretRulesDir  = "custom-rules/secret"
+	CustomMalwareRulesDir = "custom-rules/malware"
+	CustomRulesFileFormat = "%020d"
+	CustomRulesVersionID  = "custom-rules"
+)
+
 type Neo4jScanType string
 
 const (
@@ -92,6 +119,7 @@ const (
 	NEO4JMalwareScan         Neo4jScanType = "MalwareScan"
 	NEO4JComplianceScan      Neo4jScanType = "ComplianceScan"
 	NEO4JCloudComplianceScan Neo4jScanType = "CloudComplianceScan"
+	NEO4JIaCScan             Neo4jScanType = "IaCScan"
 )
 
 func StringToNeo4jScanType(s string) Neo4jScanType {
@@ -106,6 +134,8 @@ func StringToNeo4jScanType(s string) Neo4jScanType {
 		return NEO4JComplianceScan
 	case "CloudComplianceScan":
 		return NEO4JCloudComplianceScan
+	case "IaCScan":
+		return NEO4JIaCScan
 	default:
 		return ""
 	}
@@ -118,6 +148,7 @@ var (
 		NEO4JMalwareScan:         "Malware",
 		NEO4JComplianceScan:      "Compliance",
 		NEO4JCloudComplianceScan: "CloudCompliance",
+		NEO4JIaCScan:             "IaCFinding",
 	}
 	DetectedNodeScanType = map[string]Neo4jScanType{
 		"Vulnerability":   NEO4JVulnerabilityScan,
@@ -125,6 +156,7 @@ var (
 		"Malware":         NEO4JMalwareScan,
 		"Compliance":      NEO4JComplianceScan,
 		"CloudCompliance": NEO4JCloudComplianceScan,
+		"IaCFinding":      NEO4JIaCScan,
 	}
 )
 
@@ -211,14 +243,22 @@ var Tasks = []string{
 	StopVulnerabilityScanTask,
 	UpdateCloudResourceScanStatusTask,
 	UpdatePodScanStatusTask,
+	ScanCoverageNotificationTask,
+	ComputeDriftTask,
+	RecordPodFlowsTask,
+	DeriveAssetTagsTask,
+	ProgressAgentRolloutsTask,
+	ValidateCustomRulesTask,
 }
 
 type ReportType string
 
 const (
-	ReportXLSX ReportType = "xlsx"
-	ReportPDF  ReportType = "pdf"
-	ReportSBOM ReportType = "sbom"
+	ReportXLSX        ReportType = "xlsx"
+	ReportPDF         ReportType = "pdf"
+	ReportSBOM        ReportType = "sbom"
+	ReportCoverage    ReportType = "coverage"
+	ReportAttackPaths ReportType = "attack_paths"
 )
 
 // mask_global : This is to mask gobally. (same as previous mask_across_hosts_and_images flag)
@@ -86,6 +86,10 @@ type ReportParams struct {
 type ReportOptions struct {
 	// SBOMFormat Applicable if ReportType is sbom
 	SBOMFormat string `json:"sbom_format" validate:"omitempty,oneof=syft-json@11.0.1 cyclonedx-json@1.5 spdx-json@2.3" enum:"syft-json@11.0.1,cyclonedx-json@1.5,spdx-json@2.3"`
+	// StaleDays Applicable if ReportType is coverage
+	StaleDays int `json:"stale_days,omitempty" validate:"omitempty,min=0"`
+	// NodeIDs Applicable if ReportType is attack_paths, riskiest nodes if empty
+	NodeIDs []string `json:"node_ids,omitempty"`
 }
 
 type ReportFilters struct {
@@ -111,6 +115,9 @@ type AdvancedReportFilters struct {
 	HostName              []string `json:"host_name,omitempty"`
 	AccountID             []string `json:"node_id,omitempty"`
 	KubernetesClusterName []string `json:"kubernetes_cluster_name,omitempty"`
+	UserTags              []string `json:"user_tags,omitempty"`
+	Owner                 []string `json:"owner,omitempty"`
+	AssetCriticality      []string `json:"asset_criticality,omitempty" enum:"critical,high,medium,low"`
 }
 
 func (r ReportFilters) String() string {
@@ -120,3 +127,19 @@ func (r ReportFilters) String() string {
 		return string(b)
 	}
 }
+
+// TopologyEvent is a node or connection change of the topology graph
+type TopologyEvent struct {
+	Type                string `json:"type" required:"true" enum:"node_added,node_updated,node_removed,connection_added,connection_removed"`
+	NodeID              string `json:"node_id,omitempty"`
+	NodeType            string `json:"node_type,omitempty"`
+	HostName            string `json:"host_name,omitempty"`
+	KubernetesClusterID string `json:"kubernetes_cluster_id,omitempty"`
+	Source              string `json:"source,omitempty"`
+	Target              string `json:"target,omitempty"`
+	Timestamp           int64  `json:"timestamp" required:"true" format:"int64"`
+}
+
+func TopologyEventsChannel(namespace string) string {
+	return TopologyEventsChannelPrefix + namespace
+}
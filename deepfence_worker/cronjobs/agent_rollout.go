@@ -0,0 +1,29 @@
+package cronjobs
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/controls"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/hibiken/asynq"
+)
+
+var agentRolloutsRunning atomic.Bool
+
+// ProgressAgentRollouts moves the running agent rollouts to their next wave
+// once the current one soaked, or rolls them back when too many agents of
+// the wave failed to come back on the new version.
+func ProgressAgentRollouts(ctx context.Context, task *asynq.Task) error {
+
+	if !agentRolloutsRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer agentRolloutsRunning.Store(false)
+
+	err := controls.ProgressAgentRollouts(ctx)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error progressing agent rollouts: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package cronjobs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/hibiken/asynq"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+var assetTagsRunning atomic.Bool
+
+// DeriveAssetTags sets the owner, criticality and tags of the pods and
+// cloud resources from their kubernetes labels and cloud tags, then
+// propagates them to the containers of the pods and the hosts of the
+// cloud instances. The derived tags no longer found are cleared, nodes
+// tagged by users are left untouched.
+func DeriveAssetTags(ctx context.Context, task *asynq.Task) error {
+
+	log := log.WithCtx(ctx)
+
+	if !assetTagsRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer assetTagsRunning.Store(false)
+
+	nc, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	txConfig := neo4j.WithTxTimeout(120 * time.Second)
+
+	if err = deriveLabelTags(session, "Pod", "kubernetes_labels", txConfig); err != nil {
+		log.Error().Msgf("Error deriving pod asset tags: %v", err)
+		return err
+	}
+	if err = deriveLabelTags(session, "CloudResource", "tags", txConfig); err != nil {
+		log.Error().Msgf("Error deriving cloud resource asset tags: %v", err)
+		return err
+	}
+
+	if _, err = session.Run(`
+		MATCH (c:Container)
+		WHERE c.pod_id IS NOT NULL
+		AND coalesce(c.asset_tags_source, $derived) = $derived
+		MATCH (p:Pod{node_id: c.pod_id})
+		WHERE p.asset_tags_source IS NOT NULL
+		SET c.user_tags = p.user_tags,
+			c.owner = p.owner,
+			c.asset_criticality = p.asset_criticality,
+			c.asset_tags_source = $derived`,
+		map[string]interface{}{"derived": model.AssetTagsSourceDerived}, txConfig); err != nil {
+		log.Error().Msgf("Error propagating pod asset tags: %v", err)
+		return err
+	}
+	if _, err = session.Run(`
+		MATCH (c:Container{asset_tags_source: $derived})
+		OPTIONAL MATCH (p:Pod{node_id: c.pod_id})
+		WHERE p.asset_tags_source IS NOT NULL
+		WITH c, count(p) as tagged
+		WHERE tagged = 0
+		REMOVE c.user_tags, c.owner, c.asset_criticality, c.asset_tags_source`,
+		map[string]interface{}{"derived": model.AssetTagsSourceDerived}, txConfig); err != nil {
+		log.Error().Msgf("Error clearing pod asset tags: %v", err)
+		return err
+	}
+
+	// the hosts are linked to their cloud instance by their agent cloud
+	// metadata or, when discovered by the cloud scanners, by their instance id
+	if _, err = session.Run(`
+		MATCH (n:Node)-[:IS]->(r:CloudResource)
+		WHERE coalesce(n.asset_tags_source, $derived) = $derived
+		AND r.asset_tags_source IS NOT NULL
+		WITH n, head(collect(r)) as r
+		SET n.user_tags = r.user_tags,
+			n.owner = r.owner,
+			n.asset_criticality = r.asset_criticality,
+			n.asset_tags_source = $derived`,
+		map[string]interface{}{"derived": model.AssetTagsSourceDerived}, txConfig); err != nil {
+		log.Error().Msgf("Error propagating cloud asset tags: %v", err)
+		return err
+	}
+	if _, err = session.Run(`
+		MATCH (n:Node{asset_tags_source: $derived})
+		OPTIONAL MATCH (n)-[:IS]->(r:CloudResource)
+		WHERE r.asset_tags_source IS NOT NULL
+		WITH n, count(r) as tagged
+		WHERE tagged = 0
+		REMOVE n.user_tags, n.owner, n.asset_criticality, n.asset_tags_source`,
+		map[string]interface{}{"derived": model.AssetTagsSourceDerived}, txConfig); err != nil {
+		log.Error().Msgf("Error clearing cloud asset tags: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// deriveLabelTags parses the labels stored as json in the given field
+// and writes back the derived asset tags. The tags derived before from
+// labels since removed are cleared.
+func deriveLabelTags(session neo4j.Session, label, field string, txConfig func(*neo4j.TransactionConfig)) error {
+	res, err := session.Run(`
+		MATCH (n:`+label+`)
+		WHERE n.active = true
+		AND (n.asset_tags_source = $derived
+			OR (n.asset_tags_source IS NULL AND n.`+field+` IS NOT NULL))
+		RETURN n.node_id, n.`+field+`, n.asset_tags_source`,
+		map[string]interface{}{"derived": model.AssetTagsSourceDerived}, txConfig)
+	if err != nil {
+		return err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return err
+	}
+
+	batch := make([]map[string]interface{}, 0, len(recs))
+	stale := []string{}
+	for _, rec := range recs {
+		id, _ := rec.Values[0].(string)
+		raw, _ := rec.Values[1].(string)
+		source, _ := rec.Values[2].(string)
+		if id == "" {
+			continue
+		}
+		owner, criticality, tags := "", "", []string{}
+		if raw != "" {
+			owner, criticality, tags = model.DeriveAssetTags(model.ParseResourceLabels(raw))
+		}
+		if owner == "" && criticality == "" && len(tags) == 0 {
+			if source == model.AssetTagsSourceDerived {
+				stale = append(stale, id)
+			}
+			continue
+		}
+		batch = append(batch, map[string]interface{}{
+			"node_id":           id,
+			"owner":             owner,
+			"asset_criticality": criticality,
+			"user_tags":         tags,
+		})
+	}
+
+	if len(batch) != 0 {
+		_, err = session.Run(`
+			UNWIND $batch as row
+			MATCH (n:`+label+`{node_id: row.node_id})
+			SET n.user_tags = row.user_tags,
+				n.owner = CASE WHEN row.owner = '' THEN null ELSE row.owner END,
+				n.asset_criticality = CASE WHEN row.asset_criticality = '' THEN null ELSE row.asset_criticality END,
+				n.asset_tags_source = $derived`,
+			map[string]interface{}{"batch": batch, "derived": model.AssetTagsSourceDerived}, txConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(stale) != 0 {
+		_, err = session.Run(`
+			MATCH (n:`+label+`{asset_tags_source: $derived})
+			WHERE n.node_id IN $ids
+			REMOVE n.user_tags, n.owner, n.asset_criticality, n.asset_tags_source`,
+			map[string]interface{}{"ids": stale, "derived": model.AssetTagsSourceDerived}, txConfig)
+	}
+	return err
+}
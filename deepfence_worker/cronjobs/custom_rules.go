@@ -0,0 +1,96 @@
+package cronjobs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/deepfence/ThreatMapper/deepfence_worker/tasks/malwarescan"
+	"github.com/deepfence/ThreatMapper/deepfence_worker/tasks/secretscan"
+	workerUtils "github.com/deepfence/ThreatMapper/deepfence_worker/utils"
+	"github.com/hibiken/asynq"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+var customRulesValidating atomic.Bool
+
+// ValidateCustomRules loads the latest custom rules published by the console
+// the way the scanners do, with hyperscan and libyara, along with the bundled
+// rules. The version is released to the scanners and the agents when they
+// load, else the error is kept on the version and the last released rules
+// stay in use.
+func ValidateCustomRules(ctx context.Context, task *asynq.Task) error {
+
+	log := log.WithCtx(ctx)
+
+	if !customRulesValidating.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer customRulesValidating.Store(false)
+
+	nc, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	txConfig := neo4j.WithTxTimeout(30 * time.Second)
+
+	r, err := session.Run(`
+		MATCH (v:CustomRulesVersion{node_id: $id})
+		WHERE v.published_version > COALESCE(v.validated_version, v.version, 0)
+		RETURN v.published_version`,
+		map[string]interface{}{"id": utils.CustomRulesVersionID}, txConfig)
+	if err != nil {
+		return err
+	}
+	records, err := r.Collect()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	version := records[0].Values[0].(int64)
+
+	secret, err := workerUtils.CustomRulesBundle(ctx, utils.CustomSecretRulesDir, version)
+	if err != nil {
+		return err
+	}
+	malware, err := workerUtils.CustomRulesBundle(ctx, utils.CustomMalwareRulesDir, version)
+	if err != nil {
+		return err
+	}
+
+	validationError := ""
+	if err := secretscan.ValidateCustomRules(secret); err != nil {
+		validationError = err.Error()
+	} else if err := malwarescan.ValidateCustomRules(malware); err != nil {
+		validationError = err.Error()
+	}
+
+	if _, err = session.Run(`
+		MATCH (v:CustomRulesVersion{node_id: $id})
+		WHERE v.published_version = $version
+		SET v.validated_version = $version,
+			v.validation_error = $error,
+			v.version = CASE WHEN $error = '' THEN $version ELSE v.version END`,
+		map[string]interface{}{
+			"id":      utils.CustomRulesVersionID,
+			"version": version,
+			"error":   validationError,
+		}, txConfig); err != nil {
+		return err
+	}
+
+	if validationError != "" {
+		log.Warn().Msgf("Custom rules version %d rejected: %s", version, validationError)
+	} else {
+		log.Info().Msgf("Custom rules version %d released", version)
+	}
+	return nil
+}
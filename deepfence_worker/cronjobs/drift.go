@@ -0,0 +1,384 @@
+package cronjobs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/integration"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	workerUtil "github.com/deepfence/ThreatMapper/deepfence_worker/utils"
+	"github.com/hibiken/asynq"
+	"github.com/minio/minio-go/v7"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// directories merged into /usr on most distributions, an executable
+// run from one of them can be listed in the image under the other
+var mergedUsrDirs = []string{"/bin/", "/sbin/", "/lib/", "/lib64/"}
+
+var driftRunning atomic.Bool
+
+type executed struct {
+	path    string
+	cmdline string
+	lineage []string
+	source  string
+}
+
+// ComputeDrift compares the executables run in the active containers, from
+// the process topology and the runtime exec events, with the files of their
+// image SBOM. Executables missing from the image are recorded as Drift
+// nodes and the new ones are sent to the integrations subscribed to drifts.
+// Executables in the image but not owned by any package are recorded with a
+// low severity only.
+func ComputeDrift(ctx context.Context, task *asynq.Task) error {
+
+	log := log.WithCtx(ctx)
+
+	if !driftRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer driftRunning.Store(false)
+
+	log.Info().Msgf("Compute drift Starting")
+	defer log.Info().Msgf("Compute drift Done")
+
+	nc, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	mc, err := directory.MinioClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	txConfig := neo4j.WithTxTimeout(300 * time.Second)
+
+	res, err := session.Run(`
+		MATCH (c:Container{active: true})
+		MATCH (:ContainerImage{node_id: c.docker_image_id}) <-[:SCANNED]- (s:VulnerabilityScan{status: $complete})
+		WITH c, s ORDER BY s.updated_at DESC
+		WITH c, collect(s.node_id)[0] AS scan_id
+		OPTIONAL MATCH (c) -[:HOSTS]-> (p:Process)
+		WITH c, scan_id, collect({path: split(p.cmdline, ' ')[0], cmdline: p.cmdline, lineage: [], source: $process}) AS procs
+		OPTIONAL MATCH (c) -[:RECORDED]-> (e:RuntimeEvent{event_type: $exec})
+		WITH c, scan_id, procs, collect({path: e.file_path, cmdline: e.cmdline, lineage: e.lineage, source: $runtime_event}) AS execs
+		RETURN c.node_id, c.docker_container_name, c.docker_image_id, c.docker_image_name, c.host_name, scan_id, procs + execs`,
+		map[string]interface{}{
+			"complete":      utils.ScanStatusSuccess,
+			"exec":          "exec",
+			"process":       model.DriftSourceProcess,
+			"runtime_event": model.DriftSourceRuntimeEvent,
+		}, txConfig)
+	if err != nil {
+		return err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return err
+	}
+
+	fileIndexes := map[string]*fileIndex{}
+	drifts := []model.Drift{}
+	for _, rec := range recs {
+		scanID := toString(rec.Values[5])
+		index, has := fileIndexes[scanID]
+		if !has {
+			index, err = loadFileIndex(ctx, mc, scanID)
+			if err != nil {
+				// scans done before file indexes were generated
+				log.Debug().Msgf("No sbom file index for scan %s: %v", scanID, err)
+			}
+			fileIndexes[scanID] = index
+		}
+		if index == nil {
+			continue
+		}
+
+		drifts = append(drifts, containerDrifts(driftContainer{
+			id:        toString(rec.Values[0]),
+			name:      toString(rec.Values[1]),
+			imageID:   toString(rec.Values[2]),
+			imageName: toString(rec.Values[3]),
+			hostName:  toString(rec.Values[4]),
+			scanID:    scanID,
+		}, toExecuted(rec.Values[6]), index)...)
+	}
+
+	if len(drifts) == 0 {
+		return nil
+	}
+
+	newDrifts, err := upsertDrifts(session, drifts, txConfig)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to record drifts")
+		return err
+	}
+	log.Info().Msgf("Drifts found: %d, new: %d", len(drifts), len(newDrifts))
+
+	notify := []model.Drift{}
+	for _, d := range newDrifts {
+		if d.Severity == model.DriftSeverityHigh {
+			notify = append(notify, d)
+		}
+	}
+	if len(notify) > 0 {
+		sendDriftNotifications(ctx, notify)
+	}
+
+	return nil
+}
+
+type driftContainer struct {
+	id        string
+	name      string
+	imageID   string
+	imageName string
+	hostName  string
+	scanID    string
+}
+
+// containerDrifts returns the executables run in the container which are
+// missing from its image or not owned by any of the image packages
+func containerDrifts(c driftContainer, execs []executed, index *fileIndex) []model.Drift {
+	drifts := []model.Drift{}
+	for _, e := range execs {
+		category, severity := model.DriftCategoryNewExecutable, model.DriftSeverityHigh
+		if imageContains(index.owned, e.path) {
+			continue
+		} else if imageContains(index.unowned, e.path) {
+			category, severity = model.DriftCategoryUnownedFile, model.DriftSeverityLow
+		}
+		drifts = append(drifts, model.Drift{
+			NodeID:         utils.SHA256sum([]byte(c.id + e.path)),
+			ContainerID:    c.id,
+			ContainerName:  c.name,
+			ImageID:        c.imageID,
+			ImageName:      c.imageName,
+			HostName:       c.hostName,
+			ExecutablePath: e.path,
+			Cmdline:        e.cmdline,
+			Lineage:        e.lineage,
+			Source:         e.source,
+			Category:       category,
+			Severity:       severity,
+			SBOMScanID:     c.scanID,
+		})
+	}
+	return drifts
+}
+
+// upsertDrifts records the drifts and returns the ones seen for the first time
+func upsertDrifts(session neo4j.Session, drifts []model.Drift, txConfig func(*neo4j.TransactionConfig)) ([]model.Drift, error) {
+	ids := make([]string, 0, len(drifts))
+	batch := make([]map[string]interface{}, 0, len(drifts))
+	for _, d := range drifts {
+		ids = append(ids, d.NodeID)
+		batch = append(batch, map[string]interface{}{
+			"node_id":               d.NodeID,
+			"container_id":          d.ContainerID,
+			"docker_container_name": d.ContainerName,
+			"docker_image_id":       d.ImageID,
+			"docker_image_name":     d.ImageName,
+			"host_name":             d.HostName,
+			"executable_path":       d.ExecutablePath,
+			"cmdline":               d.Cmdline,
+			"lineage":               d.Lineage,
+			"source":                d.Source,
+			"category":              d.Category,
+			"severity":              d.Severity,
+			"sbom_scan_id":          d.SBOMScanID,
+		})
+	}
+
+	res, err := session.Run(`
+		MATCH (d:Drift)
+		WHERE d.node_id IN $ids
+		RETURN d.node_id`,
+		map[string]interface{}{"ids": ids}, txConfig)
+	if err != nil {
+		return nil, err
+	}
+	recs, err := res.Collect()
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]struct{}{}
+	for _, rec := range recs {
+		existing[rec.Values[0].(string)] = struct{}{}
+	}
+
+	if _, err = session.Run(`
+		UNWIND $batch as row
+		MATCH (c:Container{node_id: row.container_id})
+		MERGE (d:Drift{node_id: row.node_id})
+		ON CREATE SET d += row, d.first_seen_at = TIMESTAMP(), d.masked = false
+		ON MATCH SET d.cmdline = row.cmdline, d.sbom_scan_id = row.sbom_scan_id,
+			d.category = row.category, d.severity = row.severity
+		SET d.last_seen_at = TIMESTAMP(), d.updated_at = TIMESTAMP()
+		MERGE (c) -[:DRIFTED]-> (d)`,
+		map[string]interface{}{"batch": batch}, txConfig); err != nil {
+		return nil, err
+	}
+
+	newDrifts := []model.Drift{}
+	for _, d := range drifts {
+		if _, has := existing[d.NodeID]; !has {
+			newDrifts = append(newDrifts, d)
+		}
+	}
+	return newDrifts, nil
+}
+
+func sendDriftNotifications(ctx context.Context, drifts []model.Drift) {
+
+	log := log.WithCtx(ctx)
+
+	pgClient, err := directory.PostgresClient(ctx)
+	if err != nil {
+		log.Error().Msgf("Error getting postgresCtx: %v", err)
+		return
+	}
+	integrations, err := pgClient.GetIntegrations(ctx)
+	if err != nil {
+		log.Error().Msgf("Error getting integrations: %v", err)
+		return
+	}
+
+	messages := []map[string]interface{}{}
+	for _, d := range drifts {
+		messages = append(messages, utils.ToMap(d))
+	}
+	messageByte, err := json.Marshal(messages)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to marshal drifts")
+		return
+	}
+
+	for _, integrationRow := range integrations {
+		if integrationRow.Resource != model.DriftNotificationType {
+			continue
+		}
+
+		iByte, err := json.Marshal(integrationRow)
+		if err != nil {
+			log.Error().Err(err).Msgf("integration id %d", integrationRow.ID)
+			continue
+		}
+
+		integrationModel, err := integration.GetIntegration(ctx, integrationRow.IntegrationType, iByte)
+		if err != nil {
+			log.Error().Err(err).Msgf("integration id %d", integrationRow.ID)
+			continue
+		}
+
+		extras := map[string]interface{}{
+			"scan_type": model.DriftNotificationType,
+		}
+		err = integrationModel.SendNotification(ctx, string(messageByte), extras)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to send drifts using %s id %d",
+				integrationRow.IntegrationType, integrationRow.ID)
+			continue
+		}
+		log.Info().Msgf("Notification sent %d drifts using %s id %d",
+			len(drifts), integrationRow.IntegrationType, integrationRow.ID)
+	}
+}
+
+type fileIndex struct {
+	owned   map[string]struct{}
+	unowned map[string]struct{}
+}
+
+func loadFileIndex(ctx context.Context, mc directory.FileManager, scanID string) (*fileIndex, error) {
+	buff, err := mc.DownloadFileContexts(ctx, workerUtil.GetSbomFileIndexPath(scanID), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	files := workerUtil.SbomFileIndex{}
+	if err := json.Unmarshal(buff, &files); err != nil {
+		// indexes of the earlier scans list all the files together
+		if err := json.Unmarshal(buff, &files.Owned); err != nil {
+			return nil, err
+		}
+	}
+	return &fileIndex{
+		owned:   toSet(files.Owned),
+		unowned: toSet(files.Unowned),
+	}, nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		res[v] = struct{}{}
+	}
+	return res
+}
+
+func toExecuted(value interface{}) []executed {
+	res := []executed{}
+	entries, ok := value.([]interface{})
+	if !ok {
+		return res
+	}
+	seen := map[string]struct{}{}
+	for _, entry := range entries {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		e := executed{
+			path:    toString(m["path"]),
+			cmdline: toString(m["cmdline"]),
+			source:  toString(m["source"]),
+		}
+		// only absolute paths can be compared with the image files
+		if !strings.HasPrefix(e.path, "/") {
+			continue
+		}
+		if _, has := seen[e.path]; has {
+			continue
+		}
+		seen[e.path] = struct{}{}
+		if lineage, ok := m["lineage"].([]interface{}); ok {
+			for _, l := range lineage {
+				e.lineage = append(e.lineage, toString(l))
+			}
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func imageContains(index map[string]struct{}, path string) bool {
+	if _, has := index[path]; has {
+		return true
+	}
+	for _, dir := range mergedUsrDirs {
+		if strings.HasPrefix(path, dir) {
+			_, has := index["/usr"+path]
+			return has
+		}
+		if strings.HasPrefix(path, "/usr"+dir) {
+			_, has := index[strings.TrimPrefix(path, "/usr")]
+			return has
+		}
+	}
+	return false
+}
+
+func toString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
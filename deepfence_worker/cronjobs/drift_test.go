@@ -0,0 +1,62 @@
+package cronjobs
+
+import (
+	"testing"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+)
+
+func TestContainerDriftsRuntimeEvent(t *testing.T) {
+	index := &fileIndex{
+		owned:   toSet([]string{"/usr/bin/sh", "/usr/bin/curl"}),
+		unowned: toSet([]string{"/opt/app/run"}),
+	}
+	execs := toExecuted([]interface{}{
+		map[string]interface{}{
+			"path":    "/tmp/miner",
+			"cmdline": "/tmp/miner --pool x",
+			"lineage": []interface{}{"sh", "bash"},
+			"source":  model.DriftSourceRuntimeEvent,
+		},
+		map[string]interface{}{
+			"path":    "/bin/sh",
+			"cmdline": "sh -c id",
+			"source":  model.DriftSourceRuntimeEvent,
+		},
+		map[string]interface{}{
+			"path":    "/opt/app/run",
+			"cmdline": "/opt/app/run",
+			"source":  model.DriftSourceProcess,
+		},
+		// exec events without an executable are not comparable
+		map[string]interface{}{
+			"path":    "",
+			"cmdline": "miner",
+			"source":  model.DriftSourceRuntimeEvent,
+		},
+	})
+
+	drifts := containerDrifts(driftContainer{id: "c1", scanID: "s1"}, execs, index)
+	if len(drifts) != 2 {
+		t.Fatalf("expected 2 drifts, got %d: %+v", len(drifts), drifts)
+	}
+
+	d := drifts[0]
+	if d.ExecutablePath != "/tmp/miner" || d.Source != model.DriftSourceRuntimeEvent {
+		t.Errorf("unexpected runtime event drift %+v", d)
+	}
+	if d.Category != model.DriftCategoryNewExecutable || d.Severity != model.DriftSeverityHigh {
+		t.Errorf("expected a high new executable drift, got %s %s", d.Category, d.Severity)
+	}
+	if len(d.Lineage) != 2 || d.Lineage[0] != "sh" {
+		t.Errorf("unexpected lineage %v", d.Lineage)
+	}
+	if d.ContainerID != "c1" || d.SBOMScanID != "s1" {
+		t.Errorf("unexpected container of drift %+v", d)
+	}
+
+	d = drifts[1]
+	if d.ExecutablePath != "/opt/app/run" || d.Category != model.DriftCategoryUnownedFile || d.Severity != model.DriftSeverityLow {
+		t.Errorf("expected a low unowned file drift, got %+v", d)
+	}
+}
@@ -2,6 +2,7 @@ package cronjobs
 
 import (
 	"context"
+	"encoding/json"
 	"sync/atomic"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
 	ingestersUtil "github.com/deepfence/ThreatMapper/deepfence_utils/utils/ingesters"
+	"github.com/deepfence/ThreatMapper/deepfence_worker/ingesters"
 	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
 )
 
@@ -26,6 +28,8 @@ const (
 	ecsTaskRunningStatus                   = "RUNNING"
 	ecsTaskPublicEnabledConfig             = "ENABLED"
 	dbDeletionTimeThreshold                = time.Hour
+	dbRuntimeEventCleanUpTimeout           = time.Hour * 24 * 7
+	dbPodFlowCleanUpTimeout                = time.Hour * 24 * 7
 )
 
 var (
@@ -103,18 +107,21 @@ func CleanUpDB(ctx context.Context, task *asynq.Task) error {
 	start := time.Now()
 
 	// Set inactives
-	if _, err = session.Run(`
+	res, err := session.Run(`
 		MATCH (n:Node)
 		WHERE n.updated_at < TIMESTAMP()-$time_ms
 		AND NOT n.node_id IN ["in-the-internet", "out-the-internet"]
 		AND n.agent_running=true
 		AND n.active = true
 		WITH n LIMIT 10000
-		SET n.active=false, n.updated_at=TIMESTAMP()`,
-		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig); err != nil {
+		SET n.active=false, n.updated_at=TIMESTAMP()
+		RETURN n.node_id, n.host_name, n.kubernetes_cluster_id`,
+		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig)
+	if err != nil {
 		log.Error().Msgf("Error in Clean up DB task: %v", err)
 		return err
 	}
+	publishRemovedNodes(ctx, utils.NodeTypeHost, res)
 
 	if _, err = session.Run(`
 		MATCH (n:Node)
@@ -166,28 +173,34 @@ func CleanUpDB(ctx context.Context, task *asynq.Task) error {
 		return err
 	}
 
-	if _, err = session.Run(`
+	res, err = session.Run(`
 		MATCH (n:Container)
 		WHERE n.updated_at < TIMESTAMP()-$time_ms
 		AND n.active = true
 		WITH n LIMIT 10000
-		SET n.active=false, n.updated_at=TIMESTAMP()`,
-		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig); err != nil {
+		SET n.active=false, n.updated_at=TIMESTAMP()
+		RETURN n.node_id, n.host_name, n.kubernetes_cluster_id`,
+		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig)
+	if err != nil {
 		log.Error().Msgf("Error in Clean up DB task: %v", err)
 		return err
 	}
+	publishRemovedNodes(ctx, utils.NodeTypeContainer, res)
 
-	if _, err = session.Run(`
+	res, err = session.Run(`
 		MATCH (n:KubernetesCluster)
 		WHERE n.updated_at < TIMESTAMP()-$time_ms
 		AND n.active = true
 		AND n.agent_running=true
 		WITH n LIMIT 10000
-		SET n.active=false, n.updated_at=TIMESTAMP()`,
-		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig); err != nil {
+		SET n.active=false, n.updated_at=TIMESTAMP()
+		RETURN n.node_id, n.host_name, n.kubernetes_cluster_id`,
+		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig)
+	if err != nil {
 		log.Error().Msgf("Error in Clean up DB task: %v", err)
 		return err
 	}
+	publishRemovedNodes(ctx, utils.NodeTypeKubernetesCluster, res)
 
 	if _, err = session.Run(`
 		MATCH (n:KubernetesCluster)
@@ -267,10 +280,24 @@ func CleanUpDB(ctx context.Context, task *asynq.Task) error {
 		return err
 	}
 
-	if _, err = session.Run(`
+	res, err = session.Run(`
 		MATCH (n:Pod)
 		WHERE n.updated_at < TIMESTAMP()-$time_ms
 		WITH n LIMIT 10000
+		WITH n, n.node_id AS node_id, n.host_name AS host_name, n.kubernetes_cluster_id AS cluster_id
+		DETACH DELETE n
+		RETURN node_id, host_name, cluster_id`,
+		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig)
+	if err != nil {
+		log.Error().Msgf("Error in Clean up DB task: %v", err)
+		return err
+	}
+	publishRemovedNodes(ctx, utils.NodeTypePod, res)
+
+	if _, err = session.Run(`
+		MATCH (n:KubernetesRoleBinding)
+		WHERE n.updated_at < TIMESTAMP()-$time_ms
+		WITH n LIMIT 10000
 		DETACH DELETE n`,
 		map[string]interface{}{"time_ms": dbReportCleanUpTimeout.Milliseconds()}, txConfig); err != nil {
 		log.Error().Msgf("Error in Clean up DB task: %v", err)
@@ -287,6 +314,36 @@ func CleanUpDB(ctx context.Context, task *asynq.Task) error {
 		return err
 	}
 
+	if _, err = session.Run(`
+		MATCH (n:RuntimeEvent)
+		WHERE n.updated_at < TIMESTAMP()-$time_ms
+		WITH n LIMIT 10000
+		DETACH DELETE n`,
+		map[string]interface{}{"time_ms": dbRuntimeEventCleanUpTimeout.Milliseconds()}, txConfig); err != nil {
+		log.Error().Msgf("Error in Clean up DB task: %v", err)
+		return err
+	}
+
+	if _, err = session.Run(`
+		MATCH (n:Drift)
+		WHERE NOT exists((n) <-[:DRIFTED]- (:Container))
+		WITH n LIMIT 10000
+		DETACH DELETE n`,
+		map[string]interface{}{}, txConfig); err != nil {
+		log.Error().Msgf("Error in Clean up DB task: %v", err)
+		return err
+	}
+
+	if _, err = session.Run(`
+		MATCH () -[f:FLOWS]-> ()
+		WHERE f.last_seen_at < TIMESTAMP()-$time_ms
+		WITH f LIMIT 10000
+		DELETE f`,
+		map[string]interface{}{"time_ms": dbPodFlowCleanUpTimeout.Milliseconds()}, txConfig); err != nil {
+		log.Error().Msgf("Error in Clean up DB task: %v", err)
+		return err
+	}
+
 	for ts := range ingestersUtil.ScanStatusField {
 		if _, err = session.Run(`
 			MATCH (n:`+string(ts)+`) -[:SCANNED]-> (r)
@@ -601,6 +658,10 @@ func LinkCloudResources(ctx context.Context, task *asynq.Task) error {
 		return err
 	}
 
+	if err = ingesters.LinkNodesWithCloudResources(ctx); err != nil {
+		return err
+	}
+
 	log.Debug().Msgf("Link task took: %v", time.Since(start))
 
 	return nil
@@ -792,3 +853,54 @@ func RetryUpgradeAgent(ctx context.Context, task *asynq.Task) error {
 
 	return tx.Commit()
 }
+
+// publishRemovedNodes notifies the topology stream subscribers of the
+// nodes returned by a clean up query, as node_id, host_name, cluster_id
+func publishRemovedNodes(ctx context.Context, nodeType string, res neo4j.Result) {
+
+	log := log.WithCtx(ctx)
+
+	recs, err := res.Collect()
+	if err != nil {
+		log.Error().Msgf("Error collecting removed nodes: %v", err)
+		return
+	}
+	if len(recs) == 0 {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	events := make([]utils.TopologyEvent, 0, len(recs))
+	for _, rec := range recs {
+		nodeID, _ := rec.Values[0].(string)
+		hostName, _ := rec.Values[1].(string)
+		clusterID, _ := rec.Values[2].(string)
+		events = append(events, utils.TopologyEvent{
+			Type:                utils.TopologyNodeRemoved,
+			NodeID:              nodeID,
+			NodeType:            nodeType,
+			HostName:            hostName,
+			KubernetesClusterID: clusterID,
+			Timestamp:           now,
+		})
+	}
+
+	payload, err := json.Marshal(events)
+	if err != nil {
+		log.Error().Msgf("Error marshaling removed nodes: %v", err)
+		return
+	}
+	ns, err := directory.ExtractNamespace(ctx)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return
+	}
+	rdb, err := directory.RedisClient(ctx)
+	if err != nil {
+		log.Error().Msg(err.Error())
+		return
+	}
+	if err := rdb.Publish(ctx, utils.TopologyEventsChannel(string(ns)), payload).Err(); err != nil {
+		log.Error().Msgf("Error publishing removed nodes: %v", err)
+	}
+}
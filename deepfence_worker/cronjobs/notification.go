@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	reporters_posture "github.com/deepfence/ThreatMapper/deepfence_server/reporters/posture"
 	reporters_search "github.com/deepfence/ThreatMapper/deepfence_server/reporters/search"
 
 	"github.com/deepfence/ThreatMapper/deepfence_server/model"
@@ -215,6 +216,12 @@ func processIntegrationRow(integrationRow postgresql_db.Integration, ctx context
 		return processIntegration[model.Compliance](ctx, task, integrationRow)
 	case utils.ScanTypeDetectedNode[utils.NEO4JCloudComplianceScan]:
 		return processIntegration[model.CloudCompliance](ctx, task, integrationRow)
+	case reporters_posture.CoverageNotificationType:
+		// sent periodically by SendScanCoverageNotifications
+		return nil
+	case model.DriftNotificationType:
+		// sent by ComputeDrift when new drifts are found
+		return nil
 	}
 	return errors.New("No integration type")
 }
@@ -487,10 +494,14 @@ func integrationFilters2SearchScanReqs(filters model.IntegrationFilters) (*repor
 		containerFilterMap["node_type"] = []interface{}{"container"}
 	}
 
+	// without node filters, the nodes are selected by their asset tags
+	assetTagsOnly := len(filterMap) == 0 && len(containerFilterMap) == 0 && !filters.AssetFilter.IsEmpty()
+
 	var reqNC, reqC *reporters_search.SearchScanReq
-	if len(filterMap) > 0 {
+	if len(filterMap) > 0 || assetTagsOnly {
 		reqNC = &reporters_search.SearchScanReq{}
 		reqNC.NodeFilter.Filters.ContainsFilter.FieldsValues = filterMap
+		filters.AssetFilter.Apply(&reqNC.NodeFilter.Filters)
 		reqNC.ScanFilter = scanFilter
 		reqNC.Window = model.FetchWindow{}
 	}
@@ -498,6 +509,7 @@ func integrationFilters2SearchScanReqs(filters model.IntegrationFilters) (*repor
 	if len(containerFilterMap) > 0 {
 		reqC = &reporters_search.SearchScanReq{}
 		reqC.NodeFilter.Filters.ContainsFilter.FieldsValues = containerFilterMap
+		filters.AssetFilter.Apply(&reqC.NodeFilter.Filters)
 		reqC.ScanFilter = scanFilter
 		reqC.Window = model.FetchWindow{}
 	}
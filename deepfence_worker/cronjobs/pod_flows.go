@@ -0,0 +1,60 @@
+package cronjobs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/hibiken/asynq"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+var podFlowsRunning atomic.Bool
+
+// RecordPodFlows resolves the host level CONNECTS edges, and the
+// LOCAL_CONNECTS edges between the processes of a same host, to the pods
+// owning the connected processes and records them as FLOWS edges with the
+// destination port. Unlike CONNECTS, which only reflect the latest agent
+// reports, FLOWS are kept with their last seen time so that network
+// policies can be derived from the connections seen over a time window.
+func RecordPodFlows(ctx context.Context, task *asynq.Task) error {
+
+	log := log.WithCtx(ctx)
+
+	if !podFlowsRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer podFlowsRunning.Store(false)
+
+	nc, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return err
+	}
+	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	txConfig := neo4j.WithTxTimeout(120 * time.Second)
+
+	if _, err = session.Run(`
+		MATCH (n:Node) -[r:CONNECTS|LOCAL_CONNECTS]-> (m:Node)
+		WHERE r.left_pids IS NOT NULL
+		UNWIND range(0, size(r.left_pids) - 1) AS i
+		WITH n, m, r.left_pids[i] AS left_pid, r.right_pids[i] AS right_pid, r.local_ports[i] AS port
+		OPTIONAL MATCH (:Process{node_id: n.node_id + ';' + toString(left_pid)}) <-[:HOSTS]- (lc:Container)
+		OPTIONAL MATCH (lp:Pod{node_id: lc.pod_id})
+		OPTIONAL MATCH (:Process{node_id: m.node_id + ';' + toString(right_pid)}) <-[:HOSTS]- (rc:Container)
+		OPTIONAL MATCH (rp:Pod{node_id: rc.pod_id})
+		WITH DISTINCT coalesce(lp, n) AS src, coalesce(rp, m) AS dst, port
+		WHERE (src:Pod OR dst:Pod) AND src <> dst AND port IS NOT NULL
+		MERGE (src) -[f:FLOWS{port: port}]-> (dst)
+		ON CREATE SET f.first_seen_at = TIMESTAMP()
+		SET f.last_seen_at = TIMESTAMP()`,
+		map[string]interface{}{}, txConfig); err != nil {
+		log.Error().Msgf("Error recording pod flows: %v", err)
+		return err
+	}
+
+	return nil
+}
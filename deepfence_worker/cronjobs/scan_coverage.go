@@ -0,0 +1,111 @@
+package cronjobs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/integration"
+	reporters_posture "github.com/deepfence/ThreatMapper/deepfence_server/reporters/posture"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/hibiken/asynq"
+)
+
+const DefaultCoverageStaleDays = 7
+
+var CoverageStaleDays int
+
+func init() {
+	CoverageStaleDays = DefaultCoverageStaleDays
+	staleDaysStr := os.Getenv("DEEPFENCE_COVERAGE_STALE_DAYS")
+	if len(staleDaysStr) > 0 {
+		value, err := strconv.Atoi(staleDaysStr)
+		if err == nil && value >= 0 {
+			CoverageStaleDays = value
+		}
+	}
+	log.Info().Msgf("Setting scan coverage stale days to: %d", CoverageStaleDays)
+}
+
+// SendScanCoverageNotifications notifies the integrations subscribed to
+// scan coverage with the nodes never scanned or not scanned recently
+func SendScanCoverageNotifications(ctx context.Context, task *asynq.Task) error {
+
+	log := log.WithCtx(ctx)
+
+	pgClient, err := directory.PostgresClient(ctx)
+	if err != nil {
+		log.Error().Msgf("Error getting postgresCtx: %v", err)
+		return nil
+	}
+	integrations, err := pgClient.GetIntegrations(ctx)
+	if err != nil {
+		log.Error().Msgf("Error getting integrations: %v", err)
+		return nil
+	}
+
+	var coverage *reporters_posture.ScanCoverage
+	for _, integrationRow := range integrations {
+		if integrationRow.Resource != reporters_posture.CoverageNotificationType {
+			continue
+		}
+
+		if coverage == nil {
+			res, err := reporters_posture.GetScanCoverage(ctx,
+				reporters_posture.CoverageFilter{StaleDays: CoverageStaleDays})
+			if err != nil {
+				log.Error().Err(err).Msg("failed to get scan coverage")
+				return err
+			}
+			coverage = &res
+		}
+
+		if len(coverage.Nodes) == 0 {
+			log.Info().Msg("No uncovered nodes to notify")
+			return nil
+		}
+
+		iByte, err := json.Marshal(integrationRow)
+		if err != nil {
+			log.Error().Err(err).Msgf("integration id %d", integrationRow.ID)
+			continue
+		}
+
+		integrationModel, err := integration.GetIntegration(ctx, integrationRow.IntegrationType, iByte)
+		if err != nil {
+			log.Error().Err(err).Msgf("integration id %d", integrationRow.ID)
+			continue
+		}
+
+		messages := []map[string]interface{}{}
+		for _, n := range coverage.Nodes {
+			m := utils.ToMap(n)
+			if integration.IsMessagingFormat(integrationRow.IntegrationType) && n.LastScannedAt > 0 {
+				m["last_scanned_at"] = utils.PrintableTimeStamp(n.LastScannedAt)
+			}
+			messages = append(messages, m)
+		}
+		messageByte, err := json.Marshal(messages)
+		if err != nil {
+			return err
+		}
+
+		extras := map[string]interface{}{
+			"scan_type":  reporters_posture.CoverageNotificationType,
+			"stale_days": CoverageStaleDays,
+		}
+		err = integrationModel.SendNotification(ctx, string(messageByte), extras)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to send scan coverage using %s id %d",
+				integrationRow.IntegrationType, integrationRow.ID)
+			continue
+		}
+		log.Info().Msgf("Notification sent scan coverage %d nodes using %s id %d",
+			len(coverage.Nodes), integrationRow.IntegrationType, integrationRow.ID)
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
@@ -123,6 +124,9 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
 
 	txConfig := neo4j.WithTxTimeout(600 * time.Second)
 
+	scoring := getThreatScoringModel(ctx)
+	params := scoring.CypherParams()
+
 	var err error
 
 	if _, err = session.Run(`
@@ -134,11 +138,11 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
         MATCH (m) <-[:SCANNED]- (s:VulnerabilityScan{updated_at: most_recent})-[:DETECTED]->(c:Vulnerability)
         WITH c, m, vulnerabilities_count
         MATCH (c)
-        WHERE c.exploitability_score IS NOT NULL AND c.exploitability_score IN [1,2,3]
+        WHERE c.exploitability_score IS NOT NULL AND c.exploitability_score IN $vulnerability_exploitability_scores
         WITH m, count(distinct c) as exploitable_vulnerabilities_count, vulnerabilities_count
         SET m.exploitable_vulnerabilities_count = exploitable_vulnerabilities_count, 
         m.vulnerabilities_count = vulnerabilities_count`,
-		map[string]interface{}{}, txConfig); err != nil {
+		params, txConfig); err != nil {
 		return err
 	}
 
@@ -151,10 +155,10 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
         MATCH (m) <-[:SCANNED]- (s:SecretScan{updated_at: most_recent})-[:DETECTED]->(c:Secret)
 		WITH c, m, secrets_count
         MATCH (c)
-        WHERE c.level IN ['critical', 'high']
+        WHERE c.level IN $secret_severities
         WITH m, secrets_count, count(distinct c) as exploitable_secrets_count
         SET m.secrets_count = secrets_count, m.exploitable_secrets_count = exploitable_secrets_count`,
-		map[string]interface{}{}, txConfig); err != nil {
+		params, txConfig); err != nil {
 		return err
 	}
 
@@ -167,10 +171,10 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
         MATCH (m) <-[:SCANNED]- (s:MalwareScan{updated_at: most_recent})-[:DETECTED]->(c:Malware)
 		WITH c, m, malwares_count
         MATCH (c)                      
-        WHERE c.file_severity IN ['critical', 'high']
+        WHERE c.file_severity IN $malware_severities
         WITH m, malwares_count, count(distinct c) as exploitable_malwares_count
         SET m.malwares_count = malwares_count, m.exploitable_malwares_count = exploitable_malwares_count`,
-		map[string]interface{}{}, txConfig); err != nil {
+		params, txConfig); err != nil {
 		return err
 	}
 
@@ -183,10 +187,10 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
         MATCH (m) <-[:SCANNED]- (s:ComplianceScan{updated_at: most_recent})-[:DETECTED]->(c:Compliance)
 		WITH c, m, compliances_count
 		MATCH (c)
-		WHERE c.status IN ['warn', 'alarm']
+		WHERE c.status IN $compliance_statuses
 		WITH m, compliances_count, count(distinct c) as warn_alarm_count
 		SET m.compliances_count = compliances_count, m.warn_alarm_count = warn_alarm_count
-	`, map[string]interface{}{}, txConfig); err != nil {
+	`, params, txConfig); err != nil {
 		return err
 	}
 
@@ -197,10 +201,10 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
 		MATCH (s:CloudComplianceScan{updated_at: most_recent})-[:DETECTED]->(c:CloudCompliance) -[:SCANNED]->(m:CloudResource)
 		WITH c, m, count(distinct c) as cloud_compliances_count
 		MATCH (c)
-		WHERE c.status IN ['warn', 'alarm']
+		WHERE c.status IN $cloud_compliance_statuses
 		WITH m, cloud_compliances_count, count(distinct c) as cloud_warn_alarm_count
 		SET m.cloud_compliances_count = cloud_compliances_count, m.cloud_warn_alarm_count = cloud_warn_alarm_count
-	`, map[string]interface{}{}, txConfig); err != nil {
+	`, params, txConfig); err != nil {
 		return err
 	}
 
@@ -231,6 +235,23 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
 		return err
 	}
 
+	// Compute risk scores, nodes with a depth are reachable from the internet
+	if _, err = session.Run(`
+		MATCH (n)
+		WHERE n:Node OR n:CloudResource
+		SET n.risk_score = (
+				$vulnerability_weight * COALESCE(n.exploitable_vulnerabilities_count, 0) +
+				$secret_weight * COALESCE(n.exploitable_secrets_count, 0) +
+				$malware_weight * COALESCE(n.exploitable_malwares_count, 0) +
+				$compliance_weight * COALESCE(n.warn_alarm_count, 0) +
+				$cloud_compliance_weight * COALESCE(n.cloud_warn_alarm_count, 0)
+			)
+			* CASE WHEN n.depth IS NULL THEN 1.0 ELSE $internet_exposure_multiplier END
+			* COALESCE($asset_criticality_multipliers[n.asset_criticality], 1.0)`,
+		params, txConfig); err != nil {
+		return err
+	}
+
 	// Compute counts & sums
 	if _, err = session.Run(`
 		MATCH (n)
@@ -245,7 +266,8 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
 			n.sum_exploitable_secrets = COALESCE(n.exploitable_secrets_count, 0),
 			n.sum_exploitable_malwares = COALESCE(n.exploitable_malwares_count, 0),
 			n.sum_warn_alarm = COALESCE(n.warn_alarm_count, 0),
-			n.sum_cloud_warn_alarm = COALESCE(n.cloud_warn_alarm_count, 0)`,
+			n.sum_cloud_warn_alarm = COALESCE(n.cloud_warn_alarm_count, 0),
+			n.sum_risk_score = COALESCE(n.risk_score, 0.0)`,
 		map[string]interface{}{}, txConfig); err != nil {
 		return err
 	}
@@ -263,10 +285,25 @@ func computeThreatGraph(ctx context.Context, session neo4j.Session) error {
 			n.sum_exploitable_secrets = COALESCE(n.sum_exploitable_secrets, 0) + COALESCE(m.sum_exploitable_secrets, m.exploitable_secrets_count, 0),
 			n.sum_exploitable_malwares = COALESCE(n.sum_exploitable_malwares, 0) + COALESCE(m.sum_exploitable_malwares, m.exploitable_malwares_count, 0),
 			n.sum_warn_alarm = COALESCE(n.sum_warn_alarm, 0) + COALESCE(m.sum_warn_alarm, m.warn_alarm_count, 0),
-			n.sum_cloud_warn_alarm = COALESCE(n.sum_cloud_warn_alarm, 0) + COALESCE(m.sum_cloud_warn_alarm, m.cloud_warn_alarm_count, 0)`,
+			n.sum_cloud_warn_alarm = COALESCE(n.sum_cloud_warn_alarm, 0) + COALESCE(m.sum_cloud_warn_alarm, m.cloud_warn_alarm_count, 0),
+			n.sum_risk_score = COALESCE(n.sum_risk_score, 0.0) + COALESCE(m.sum_risk_score, m.risk_score, 0.0)`,
 		map[string]interface{}{}, txConfig); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func getThreatScoringModel(ctx context.Context) model.ThreatScoringModel {
+	pgClient, err := directory.PostgresClient(ctx)
+	if err != nil {
+		log.Warn().Msgf("Using default threat scoring model: %v", err)
+		return model.DefaultThreatScoringModel()
+	}
+	scoring, err := model.GetThreatScoringModel(ctx, pgClient)
+	if err != nil {
+		log.Warn().Msgf("Using default threat scoring model: %v", err)
+		return model.DefaultThreatScoringModel()
+	}
+	return scoring
+}
@@ -42,7 +42,19 @@ func initNeo4jDatabase(ctx context.Context) error {
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Node) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Container) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Pod) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:KubernetesRoleBinding) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Process) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:RuntimeEvent) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Drift) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:ImageConfigFinding) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:AgentRollout) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:ScanWindow) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:AgentCredential) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:ScanProfile) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:CustomRule) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:CustomRulesVersion) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:RevokedAgent) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:AgentEnrollment) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Secret) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:SecretRule) ASSERT n.rule_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Malware) ASSERT n.malware_id IS UNIQUE")
@@ -56,6 +68,8 @@ func initNeo4jDatabase(ctx context.Context) error {
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:Compliance) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:ComplianceRule) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:CloudCompliance) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:IaCSource) ASSERT n.node_id IS UNIQUE")
+	RunDisplayError(session, "CREATE CONSTRAINT ON (n:IaCFinding) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:AgentDiagnosticLogs) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:CloudScannerDiagnosticLogs) ASSERT n.node_id IS UNIQUE")
 	RunDisplayError(session, "CREATE CONSTRAINT ON (n:CloudComplianceExecutable) ASSERT n.node_id IS UNIQUE")
@@ -66,6 +80,7 @@ func initNeo4jDatabase(ctx context.Context) error {
 	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JComplianceScan))
 	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JCloudComplianceScan))
 	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JMalwareScan))
+	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JIaCScan))
 	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:Bulk%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JSecretScan))
 	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:Bulk%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JVulnerabilityScan))
 	RunDisplayError(session, fmt.Sprintf("CREATE CONSTRAINT ON (n:Bulk%s) ASSERT n.node_id IS UNIQUE", utils.NEO4JComplianceScan))
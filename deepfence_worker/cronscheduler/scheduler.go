@@ -297,6 +297,48 @@ func (s *Scheduler) addCronJobs(ctx context.Context) error {
 	}
 	jobIDs = append(jobIDs, jobID)
 
+	jobID, err = s.cron.AddFunc("@every 24h",
+		s.enqueueTask(namespace, utils.ScanCoverageNotificationTask, true, utils.LowTaskOpts()...))
+	if err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, jobID)
+
+	jobID, err = s.cron.AddFunc("@every 15m",
+		s.enqueueTask(namespace, utils.ComputeDriftTask, true, utils.DefaultTaskOpts()...))
+	if err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, jobID)
+
+	jobID, err = s.cron.AddFunc("@every 60s",
+		s.enqueueTask(namespace, utils.RecordPodFlowsTask, true, utils.DefaultTaskOpts()...))
+	if err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, jobID)
+
+	jobID, err = s.cron.AddFunc("@every 15m",
+		s.enqueueTask(namespace, utils.DeriveAssetTagsTask, true, utils.DefaultTaskOpts()...))
+	if err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, jobID)
+
+	jobID, err = s.cron.AddFunc("@every 1m",
+		s.enqueueTask(namespace, utils.ProgressAgentRolloutsTask, true, utils.DefaultTaskOpts()...))
+	if err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, jobID)
+
+	jobID, err = s.cron.AddFunc("@every 30s",
+		s.enqueueTask(namespace, utils.ValidateCustomRulesTask, true, utils.DefaultTaskOpts()...))
+	if err != nil {
+		return err
+	}
+	jobIDs = append(jobIDs, jobID)
+
 	jobID, err = s.cron.AddFunc("@every 15m",
 		s.enqueueTask(namespace, utils.CachePostureProviders, true, utils.CritialTaskOpts()...))
 	if err != nil {
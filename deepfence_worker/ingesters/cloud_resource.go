@@ -167,7 +167,10 @@ func ResourceToMaps(ms []ingestersUtil.CloudResource) ([]map[string]interface{},
 	return res, hosts, clusters
 }
 
-// TODO: Call somewhere
+// LinkNodesWithCloudResources links the hosts to the cloud instances they
+// run on, by the cloud metadata reported by their agent or, for the hosts
+// discovered by the cloud scanners, by their instance id. The links not found
+// anymore are removed.
 func LinkNodesWithCloudResources(ctx context.Context) error {
 	driver, err := directory.Neo4jClient(ctx)
 	if err != nil {
@@ -183,49 +186,73 @@ func LinkNodesWithCloudResources(ctx context.Context) error {
 	}
 	defer tx.Close()
 
+	now := time.Now().UnixMilli()
+
 	if _, err = tx.Run(`
-		MATCH (n:Node) -[r:IS]-> (m:CloudResource)
-		DELETE r`,
-		map[string]interface{}{}); err != nil {
+		MATCH (n:Node)
+		WHERE n.instance_id IS NOT NULL
+		MATCH (m:CloudResource{node_id: n.instance_id})
+		WHERE m.node_type IN $types
+		MERGE (n) -[r:IS]-> (m)
+		SET r.updated_at = $now`,
+		map[string]interface{}{
+			"types": []string{AwsEc2ResourceId, GcpComputeResourceId, AzureComputeResourceId},
+			"now":   now,
+		}); err != nil {
 		log.Error().Msgf("error: %+v", err)
 		return err
 	}
 
 	if _, err = tx.Run(`
 		MATCH (n:Node)
+		WHERE n.cloud_metadata IS NOT NULL
 		WITH apoc.convert.fromJsonMap(n.cloud_metadata) as map, n
 		WHERE map.label = 'AWS'
 		WITH map.id as id, n
 		MATCH (m:CloudResource)
 		WHERE m.node_type = 'aws_ec2_instance'
 		AND m.instance_id = id
-		MERGE (n) -[:IS]-> (m)`, map[string]interface{}{}); err != nil {
+		MERGE (n) -[r:IS]-> (m)
+		SET r.updated_at = $now`, map[string]interface{}{"now": now}); err != nil {
 		log.Error().Msgf("error: %+v", err)
 		return err
 	}
 
 	if _, err = tx.Run(`
 		MATCH (n:Node)
+		WHERE n.cloud_metadata IS NOT NULL
 		WITH apoc.convert.fromJsonMap(n.cloud_metadata) as map, n
 		WHERE map.label = 'GCP'
 		WITH map.hostname as hostname, n
 		MATCH (m:CloudResource)
 		WHERE m.node_type = 'gcp_compute_instance'
 		AND m.hostname = hostname
-		MERGE (n) -[:IS]-> (m)`, map[string]interface{}{}); err != nil {
+		MERGE (n) -[r:IS]-> (m)
+		SET r.updated_at = $now`, map[string]interface{}{"now": now}); err != nil {
 		log.Error().Msgf("error: %+v", err)
 		return err
 	}
 
 	if _, err = tx.Run(`
 		MATCH (n:Node)
+		WHERE n.cloud_metadata IS NOT NULL
 		WITH apoc.convert.fromJsonMap(n.cloud_metadata) as map, n
 		WHERE map.label = 'AZURE'
 		WITH map.vmId as vm, n
 		MATCH (m:CloudResource)
 		WHERE m.node_type = 'azure_compute_virtual_machine'
 		AND m.arn = vm
-		MERGE (n) -[:IS]-> (m)`, map[string]interface{}{}); err != nil {
+		MERGE (n) -[r:IS]-> (m)
+		SET r.updated_at = $now`, map[string]interface{}{"now": now}); err != nil {
+		log.Error().Msgf("error: %+v", err)
+		return err
+	}
+
+	if _, err = tx.Run(`
+		MATCH (:Node) -[r:IS]-> (:CloudResource)
+		WHERE r.updated_at IS NULL OR r.updated_at < $now
+		DELETE r`,
+		map[string]interface{}{"now": now}); err != nil {
 		log.Error().Msgf("error: %+v", err)
 		return err
 	}
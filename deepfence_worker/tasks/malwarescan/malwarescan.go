@@ -7,6 +7,7 @@ import (
 	"io/ioutil" //nolint:staticcheck
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	malwareConfig "github.com/deepfence/YaraHunter/pkg/config"
 	malwareScan "github.com/deepfence/YaraHunter/pkg/scan"
 	yararules "github.com/deepfence/YaraHunter/pkg/yararules"
+	"github.com/hillu/go-yara/v4"
 
 	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
@@ -38,6 +40,11 @@ var (
 
 var ScanMap sync.Map
 
+var (
+	customRulesGuard   sync.Mutex
+	customRulesVersion int64
+)
+
 func init() {
 	opts, yaraconfig, yr = initMalwareScanner()
 	ScanMap = sync.Map{}
@@ -135,8 +142,13 @@ func (s MalwareScan) StartMalwareScan(ctx context.Context, task *asynq.Task) err
 		return fmt.Errorf("registry id is empty in params %+v: %w", params, err)
 	}
 
+	rules, rulesErr := refreshCustomRules(ctx)
+	if rulesErr != nil {
+		log.Error().Msgf("Cannot load custom rules, scanning without the latest: %v", rulesErr)
+	}
+
 	// opts, yaraconfig, yr = initMalwareScanner()
-	yrScanner, err := yr.NewScanner()
+	yrScanner, err := rules.NewScanner()
 	if err != nil {
 		return err
 	}
@@ -238,6 +250,134 @@ func (s MalwareScan) StartMalwareScan(ctx context.Context, task *asynq.Task) err
 	return nil
 }
 
+// refreshCustomRules returns the rules to scan with, compiling the bundled
+// rules along with the custom rules released since the last scan. The
+// previous rules are kept when the new ones do not compile.
+func refreshCustomRules(ctx context.Context) (*yararules.YaraRules, error) {
+	customRulesGuard.Lock()
+	defer customRulesGuard.Unlock()
+
+	version, content, err := workerUtils.LatestCustomRules(ctx, utils.CustomMalwareRulesDir, customRulesVersion)
+	if err != nil || content == nil {
+		return yr, err
+	}
+
+	rules, err := compileRules(content)
+	if err != nil {
+		return yr, err
+	}
+
+	yr = rules
+	customRulesVersion = version
+	log.Info().Msgf("Loaded custom malware rules version %d", version)
+	return yr, nil
+}
+
+// ValidateCustomRules checks the custom rules of a bundle the way the
+// scanners load them: compiled along with the bundled rules, whose names
+// they must not reuse
+func ValidateCustomRules(content []byte) error {
+	if _, err := compileRules(content); err != nil {
+		return err
+	}
+
+	bundled := map[string]bool{}
+	files, err := os.ReadDir(rulesPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		names, err := ruleNames(func(c *yara.Compiler) error {
+			file, err := os.Open(filepath.Join(rulesPath, f.Name()))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return c.AddFile(file, f.Name())
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			bundled[name] = true
+		}
+	}
+
+	custom, err := ruleNames(func(c *yara.Compiler) error {
+		return c.AddString(string(content), "custom")
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range custom {
+		if bundled[name] {
+			return fmt.Errorf("malware rule %s: name already used by a bundled rule", name)
+		}
+	}
+	return nil
+}
+
+// compileRules compiles the bundled rules and the custom ones with the
+// scanner
+func compileRules(custom []byte) (*yararules.YaraRules, error) {
+	dir, err := os.MkdirTemp("", "yara-rules-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	bundled, err := os.ReadDir(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range bundled {
+		if f.IsDir() {
+			continue
+		}
+		err = os.Symlink(filepath.Join(rulesPath, f.Name()), filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, "custom.yar"), custom, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := yararules.New(dir)
+	err = rules.Compile(malwareScanConstants.Filescan, failOnCompileWarning)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ruleNames returns the names of the rules added to a compiler
+func ruleNames(add func(*yara.Compiler) error) ([]string, error) {
+	c, err := yara.NewCompiler()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Destroy()
+	if err := add(c); err != nil {
+		return nil, err
+	}
+	rules, err := c.GetRules()
+	if err != nil {
+		return nil, err
+	}
+	defer rules.Destroy()
+
+	names := []string{}
+	for _, rule := range rules.GetRules() {
+		names = append(names, rule.Identifier())
+	}
+	return names, nil
+}
+
 func initMalwareScanner() (*malwareConfig.Options, *malwareConfig.Config, *yararules.YaraRules) {
 	opts := malwareConfig.NewDefaultOptions()
 	opts.RulesPath = &rulesPath
@@ -0,0 +1,65 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	reporters_graph "github.com/deepfence/ThreatMapper/deepfence_server/reporters/graph"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+)
+
+const attackPathsScanType = "attack_paths"
+
+type AttackPathsInfo struct {
+	ScanType    string
+	Title       string
+	GeneratedAt string
+	IssueType   string
+	Paths       []reporters_graph.AttackPath
+}
+
+// report scan types mapped to threat graph issue types
+var attackPathIssueTypes = map[string]string{
+	VULNERABILITY: "vulnerability",
+	SECRET:        "secret",
+	MALWARE:       "malware",
+	COMPLIANCE:    "compliance",
+}
+
+func generateAttackPathsPDF(ctx context.Context, params utils.ReportParams) (string, error) {
+
+	log := log.WithCtx(ctx)
+
+	issueType, ok := attackPathIssueTypes[params.Filters.ScanType]
+	if !ok {
+		return "", ErrUnknownScanType
+	}
+
+	paths, err := reporters_graph.GetAttackPaths(ctx, reporters_graph.AttackPathsRequest{
+		IssueType: issueType,
+		NodeIDs:   params.Options.NodeIDs,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get attack paths")
+		return "", err
+	}
+
+	data := AttackPathsInfo{
+		ScanType:    attackPathsScanType,
+		Title:       "Attack Paths Report",
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		IssueType:   issueType,
+		Paths:       paths,
+	}
+
+	var rendered bytes.Buffer
+	err = templates.ExecuteTemplate(&rendered, "base.gohtml", data)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to render attack paths report")
+		return "", err
+	}
+
+	return writePDF(ctx, &rendered, params)
+}
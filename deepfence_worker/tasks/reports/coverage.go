@@ -0,0 +1,131 @@
+package reports
+
+import (
+	"context"
+	"time"
+
+	reporters_posture "github.com/deepfence/ThreatMapper/deepfence_server/reporters/posture"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	coverageNodesHeader = map[string]string{
+		"A1": "Node ID",
+		"B1": "Node Name",
+		"C1": "Node Type",
+		"D1": "Scan Type",
+		"E1": "Reason",
+		"F1": "Last Scanned At",
+	}
+	coverageSummaryHeader = map[string]string{
+		"A1": "Scan Type",
+		"B1": "Node Type",
+		"C1": "Total",
+		"D1": "Never Scanned",
+		"E1": "Stale",
+		"F1": "Coverage Percentage",
+	}
+)
+
+// report node types mapped to coverage node types
+var coverageNodeTypes = map[string]string{
+	"host":            "host",
+	"linux":           "host",
+	"container":       "container",
+	"container_image": "container_image",
+	"cluster":         "kubernetes_cluster",
+}
+
+func coverageFilter(params utils.ReportParams) (reporters_posture.CoverageFilter, error) {
+	filter := reporters_posture.CoverageFilter{StaleDays: params.Options.StaleDays}
+
+	switch params.Filters.ScanType {
+	case VULNERABILITY, SECRET, MALWARE, COMPLIANCE:
+		filter.ScanTypes = []string{params.Filters.ScanType}
+	default:
+		return filter, ErrUnknownScanType
+	}
+
+	nodeType, ok := coverageNodeTypes[params.Filters.NodeType]
+	if !ok {
+		return filter, reporters_posture.ErrUnsupportedCoverageNodeType
+	}
+	filter.NodeTypes = []string{nodeType}
+
+	return filter, nil
+}
+
+func generateCoverageXLSX(ctx context.Context, params utils.ReportParams) (string, error) {
+	filter, err := coverageFilter(params)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := reporters_posture.GetScanCoverage(ctx, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get scan coverage info")
+		return "", err
+	}
+
+	xlsx := excelize.NewFile()
+	defer func() {
+		if err := xlsx.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close file")
+		}
+	}()
+
+	xlsxSetHeader(xlsx, "Sheet1", coverageNodesHeader)
+
+	for i, n := range data.Nodes {
+		cellName, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			log.Error().Err(err).Msg("error generating cell name")
+		}
+		lastScannedAt := ""
+		if n.LastScannedAt > 0 {
+			lastScannedAt = time.UnixMilli(n.LastScannedAt).String()
+		}
+		value := []interface{}{
+			n.NodeID,
+			n.NodeName,
+			n.NodeType,
+			n.ScanType,
+			n.Reason,
+			lastScannedAt,
+		}
+		err = xlsx.SetSheetRow("Sheet1", cellName, &value)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+	}
+
+	if _, err := xlsx.NewSheet("Summary"); err != nil {
+		log.Error().Err(err).Msg("failed to create summary sheet")
+		return "", err
+	}
+
+	xlsxSetHeader(xlsx, "Summary", coverageSummaryHeader)
+
+	for i, s := range data.Summary {
+		cellName, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			log.Error().Err(err).Msg("error generating cell name")
+		}
+		value := []interface{}{
+			s.ScanType,
+			s.NodeType,
+			s.Total,
+			s.NeverScanned,
+			s.Stale,
+			s.CoveragePercentage,
+		}
+		err = xlsx.SetSheetRow("Summary", cellName, &value)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+	}
+
+	return xlsxSave(xlsx, params)
+}
@@ -39,6 +39,8 @@ type Info[T any] struct {
 type ScanData[T any] struct {
 	ScanInfo    model.ScanResultsCommon
 	ScanResults []T
+	// only set for vulnerability scans of images and containers
+	ImageConfigFindings []model.ImageConfigFinding
 }
 
 type NodeWiseData[T any] struct {
@@ -83,6 +85,12 @@ func searchScansFilter(params sdkUtils.ReportParams) rptSearch.SearchScanReq {
 		filters.NodeFilter.Filters.ContainsFilter.FieldsValues["node_id"] = sdkUtils.StringArrayToInterfaceArray(params.Filters.AdvancedReportFilters.AccountID)
 	}
 
+	model.AssetFilter{
+		UserTags:         params.Filters.AdvancedReportFilters.UserTags,
+		Owners:           params.Filters.AdvancedReportFilters.Owner,
+		AssetCriticality: params.Filters.AdvancedReportFilters.AssetCriticality,
+	}.Apply(&filters.NodeFilter.Filters)
+
 	if len(params.Filters.ScanID) > 0 {
 		filters.ScanFilter = rptSearch.SearchFilter{
 			Filters: reporters.FieldsFilters{
@@ -173,6 +181,8 @@ func getVulnerabilityData(ctx context.Context, params sdkUtils.ReportParams) (*I
 		}
 	}
 
+	addImageConfigFindings(ctx, nodeWiseData)
+
 	data := Info[model.Vulnerability]{
 		ScanType:       VULNERABILITY,
 		Title:          "Vulnerability Scan Report",
@@ -185,6 +195,24 @@ func getVulnerabilityData(ctx context.Context, params sdkUtils.ReportParams) (*I
 	return &data, nil
 }
 
+// addImageConfigFindings adds the config findings of the scanned images,
+// a report is still generated without them
+func addImageConfigFindings(ctx context.Context, nodeWiseData NodeWiseData[model.Vulnerability]) {
+	scanIDs := []string{}
+	for _, d := range nodeWiseData.ScanData {
+		scanIDs = append(scanIDs, d.ScanInfo.ScanID)
+	}
+	findings, err := rptScans.GetImageConfigFindings(ctx, scanIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get image config findings")
+		return
+	}
+	for name, d := range nodeWiseData.ScanData {
+		d.ImageConfigFindings = findings[d.ScanInfo.ScanID]
+		nodeWiseData.ScanData[name] = d
+	}
+}
+
 func getMostExploitableVulnData(ctx context.Context, params sdkUtils.ReportParams) (*Info[model.Vulnerability], error) {
 
 	var req rptSearch.SearchNodeReq
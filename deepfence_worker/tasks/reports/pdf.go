@@ -26,8 +26,6 @@ var (
 
 func generatePDF(ctx context.Context, params utils.ReportParams) (string, error) {
 
-	log := log.WithCtx(ctx)
-
 	var (
 		buffer *bytes.Buffer
 		err    error
@@ -52,6 +50,14 @@ func generatePDF(ctx context.Context, params utils.ReportParams) (string, error)
 		return "", err
 	}
 
+	return writePDF(ctx, buffer, params)
+}
+
+// writePDF converts the rendered html report to a pdf file
+func writePDF(ctx context.Context, buffer *bytes.Buffer, params utils.ReportParams) (string, error) {
+
+	log := log.WithCtx(ctx)
+
 	pdfGen, err := wkhtmltopdf.NewPDFGenerator()
 	if err != nil {
 		log.Error().Err(err).Msg("failed to create new pdf generator")
@@ -26,9 +26,9 @@ var ErrNotImplemented = errors.New("not implemented")
 
 func fileExt(reportType sdkUtils.ReportType) string {
 	switch reportType {
-	case sdkUtils.ReportXLSX:
+	case sdkUtils.ReportXLSX, sdkUtils.ReportCoverage:
 		return ".xlsx"
-	case sdkUtils.ReportPDF:
+	case sdkUtils.ReportPDF, sdkUtils.ReportAttackPaths:
 		return ".pdf"
 	case sdkUtils.ReportSBOM:
 		return ".json.gz"
@@ -42,15 +42,23 @@ func reportFileName(params sdkUtils.ReportParams) string {
 		sbomFormat = strings.Replace(sbomFormat, ".", "_", 1)
 		return fmt.Sprintf("sbom_%s_%s%s", sbomFormat, params.ReportID, fileExt(sdkUtils.ReportSBOM))
 	}
+	if sdkUtils.ReportType(params.ReportType) == sdkUtils.ReportCoverage {
+		list := []string{"coverage", params.Filters.ScanType, params.Filters.NodeType, params.ReportID}
+		return strings.Join(list, "_") + fileExt(sdkUtils.ReportCoverage)
+	}
+	if sdkUtils.ReportType(params.ReportType) == sdkUtils.ReportAttackPaths {
+		list := []string{"attack_paths", params.Filters.ScanType, params.ReportID}
+		return strings.Join(list, "_") + fileExt(sdkUtils.ReportAttackPaths)
+	}
 	list := []string{params.Filters.ScanType, params.Filters.NodeType, params.ReportID}
 	return strings.Join(list, "_") + fileExt(sdkUtils.ReportType(params.ReportType))
 }
 
 func putOpts(reportType sdkUtils.ReportType) minio.PutObjectOptions {
 	switch reportType {
-	case sdkUtils.ReportXLSX:
+	case sdkUtils.ReportXLSX, sdkUtils.ReportCoverage:
 		return minio.PutObjectOptions{ContentType: "application/xlsx"}
-	case sdkUtils.ReportPDF:
+	case sdkUtils.ReportPDF, sdkUtils.ReportAttackPaths:
 		return minio.PutObjectOptions{ContentType: "application/pdf"}
 	case sdkUtils.ReportSBOM:
 		return minio.PutObjectOptions{ContentType: "application/gzip"}
@@ -66,6 +74,10 @@ func generateReport(ctx context.Context, params sdkUtils.ReportParams) (string,
 		return generateXLSX(ctx, params)
 	case sdkUtils.ReportSBOM:
 		return generateSBOM(ctx, params)
+	case sdkUtils.ReportCoverage:
+		return generateCoverageXLSX(ctx, params)
+	case sdkUtils.ReportAttackPaths:
+		return generateAttackPathsPDF(ctx, params)
 	}
 	return "", ErrUnknownReportType
 }
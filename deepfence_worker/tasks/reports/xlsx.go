@@ -30,6 +30,17 @@ var (
 		"P1": "cloud_account_id",
 		"Q1": "masked",
 	}
+	imageConfigHeader = map[string]string{
+		"A1": "node_name",
+		"B1": "scan_id",
+		"C1": "rule_id",
+		"D1": "title",
+		"E1": "severity",
+		"F1": "evidence",
+		"G1": "description",
+		"H1": "remediation",
+		"I1": "masked",
+	}
 	secretHeader = map[string]string{
 		"A1": "Filename",
 		"B1": "Content",
@@ -176,6 +187,39 @@ func vulnerabilityXLSX(ctx context.Context, params utils.ReportParams) (string,
 		offset = offset + len(nodeScanData.ScanResults)
 	}
 
+	if _, err := xlsx.NewSheet("Image Config"); err != nil {
+		log.Error().Err(err).Msg("failed to create image config sheet")
+		return "", err
+	}
+
+	xlsxSetHeader(xlsx, "Image Config", imageConfigHeader)
+
+	offset = 0
+	for _, nodeScanData := range data.NodeWiseData.ScanData {
+		for i, f := range nodeScanData.ImageConfigFindings {
+			cellName, err := excelize.CoordinatesToCellName(1, offset+i+2)
+			if err != nil {
+				log.Error().Err(err).Msg("error generating cell name")
+			}
+			value := []interface{}{
+				nodeScanData.ScanInfo.NodeName,
+				nodeScanData.ScanInfo.ScanID,
+				f.RuleID,
+				f.Title,
+				f.Severity,
+				f.Evidence,
+				f.Description,
+				f.Remediation,
+				f.Masked,
+			}
+			err = xlsx.SetSheetRow("Image Config", cellName, &value)
+			if err != nil {
+				log.Error().Msg(err.Error())
+			}
+		}
+		offset = offset + len(nodeScanData.ImageConfigFindings)
+	}
+
 	return xlsxSave(xlsx, params)
 }
 
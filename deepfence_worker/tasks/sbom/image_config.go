@@ -0,0 +1,49 @@
+package sbom
+
+import (
+	"time"
+
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	"github.com/deepfence/ThreatMapper/deepfence_server/pkg/imageconfig"
+)
+
+// generateImageConfigFindings analyzes the image config recorded by syft,
+// SBOMs of directories have none
+func generateImageConfigFindings(sbomIn *sbom.SBOM) ([]model.ImageConfigFinding, error) {
+	var rawConfig []byte
+	switch metadata := sbomIn.Source.Metadata.(type) {
+	case source.StereoscopeImageSourceMetadata:
+		rawConfig = metadata.RawConfig
+	case *source.StereoscopeImageSourceMetadata:
+		rawConfig = metadata.RawConfig
+	}
+	if len(rawConfig) == 0 {
+		return nil, nil
+	}
+
+	distro := imageconfig.Distro{}
+	if release := sbomIn.Artifacts.LinuxDistribution; release != nil {
+		distro.ID = release.ID
+		distro.VersionID = release.VersionID
+	}
+
+	results, err := imageconfig.Analyze(rawConfig, distro, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]model.ImageConfigFinding, 0, len(results))
+	for _, f := range results {
+		findings = append(findings, model.ImageConfigFinding{
+			RuleID:      f.ID,
+			Title:       f.Title,
+			Description: f.Description,
+			Remediation: f.Remediation,
+			Severity:    f.Severity,
+			Evidence:    f.Evidence,
+		})
+	}
+	return findings, nil
+}
@@ -9,11 +9,14 @@ import (
 	"io"
 	"os"
 	"path"
+	"sort"
 	"time"
 
 	"github.com/anchore/syft/syft/formats"
+	"github.com/anchore/syft/syft/pkg"
 	"github.com/anchore/syft/syft/sbom"
 	"github.com/deepfence/ThreatMapper/deepfence_server/model"
+	reporters_scan "github.com/deepfence/ThreatMapper/deepfence_server/reporters/scan"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/log"
 	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
@@ -239,19 +242,49 @@ func (s SbomParser) ScanSBOM(ctx context.Context, task *asynq.Task) error {
 		log.Error().Msgf("Error in getting entityId: %v", err)
 	}
 
-	// generate runtime sbom
-	runtimeSbom, err := generateRuntimeSBOM(sbomFilePath, report, entityID)
+	sbomIn, err := readSBOM(sbomFilePath)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to generate runtime sbom")
+		log.Error().Err(err).Msgf("failed to read sbom")
 		return err
 	}
 
+	// image config findings are reported along with the vulnerabilities,
+	// failing to store them should not fail the scan
+	imageConfigFindings, err := generateImageConfigFindings(sbomIn)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to analyze image config")
+	} else if imageConfigFindings != nil {
+		err = reporters_scan.SaveImageConfigFindings(
+			directory.NewContextWithNameSpace(directory.NamespaceID(tenantID)), params.ScanID, imageConfigFindings)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to save image config findings")
+		}
+	}
+
+	// generate runtime sbom
+	runtimeSbom := generateRuntimeSBOM(sbomIn, report, entityID)
+
 	runtimeSbomBytes, err := json.Marshal(runtimeSbom)
 	if err != nil {
 		log.Error().Err(err).Msgf("failed to marshal runtime sbom")
 		return err
 	}
 
+	// list of files in the image, used for drift detection
+	fileIndexBytes, err := json.Marshal(generateFileIndex(sbomIn))
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to marshal sbom file index")
+		return err
+	}
+
+	fileIndexPath := workerUtil.GetSbomFileIndexPath(params.ScanID)
+	_, err = mc.UploadFile(context.Background(), fileIndexPath, fileIndexBytes, true,
+		minio.PutObjectOptions{ContentType: "application/json"})
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to upload sbom file index")
+		return err
+	}
+
 	runtimeSbomPath := path.Join("/sbom/", "runtime-"+utils.ScanIDReplacer.Replace(params.ScanID)+".json")
 	uploadInfo, err := mc.UploadFile(context.Background(), runtimeSbomPath, runtimeSbomBytes, true,
 		minio.PutObjectOptions{ContentType: "application/json"})
@@ -301,19 +334,11 @@ func mapVulnerabilities(vulnerabilities []ps.VulnerabilityScanReport) map[string
 }
 
 // generate runtime sbom format
-func generateRuntimeSBOM(path string, vulnerabilities []ps.VulnerabilityScanReport, entityID string) (*[]model.SbomResponse, error) {
-	var (
-		runSBOM = make([]model.SbomResponse, 0)
-		err     error
-	)
+func generateRuntimeSBOM(sbomIn *sbom.SBOM, vulnerabilities []ps.VulnerabilityScanReport, entityID string) *[]model.SbomResponse {
+	runSBOM := make([]model.SbomResponse, 0)
 
 	vMap := mapVulnerabilities(vulnerabilities)
 
-	sbomIn, err := readSBOM(path)
-	if err != nil {
-		return nil, err
-	}
-
 	for item := range sbomIn.Artifacts.Packages.Enumerate() {
 		cveInfo := vMap[item.Name+":"+item.Version]
 		licenses := []string{}
@@ -335,5 +360,41 @@ func generateRuntimeSBOM(path string, vulnerabilities []ps.VulnerabilityScanRepo
 		runSBOM = append(runSBOM, entry)
 	}
 
-	return &runSBOM, err
+	return &runSBOM
+}
+
+// generate sorted lists of files in the image: package locations and files
+// owned by packages, then the other cataloged files
+func generateFileIndex(sbomIn *sbom.SBOM) workerUtil.SbomFileIndex {
+	owned := map[string]struct{}{}
+	for item := range sbomIn.Artifacts.Packages.Enumerate() {
+		for _, p := range item.Locations.CoordinateSet().Paths() {
+			owned[p] = struct{}{}
+		}
+		if owner, ok := item.Metadata.(pkg.FileOwner); ok {
+			for _, p := range owner.OwnedFiles() {
+				owned[p] = struct{}{}
+			}
+		}
+	}
+	unowned := map[string]struct{}{}
+	for coordinates := range sbomIn.Artifacts.FileMetadata {
+		if _, has := owned[coordinates.RealPath]; !has {
+			unowned[coordinates.RealPath] = struct{}{}
+		}
+	}
+
+	return workerUtil.SbomFileIndex{
+		Owned:   sortedKeys(owned),
+		Unowned: sortedKeys(unowned),
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
 }
@@ -3,6 +3,7 @@ package secretscan
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil" //nolint:staticcheck
 	"os"
 	"os/exec"
@@ -19,12 +20,21 @@ import (
 	workerUtils "github.com/deepfence/ThreatMapper/deepfence_worker/utils"
 	pb "github.com/deepfence/agent-plugins-grpc/srcgo"
 	tasks "github.com/deepfence/golang_deepfence_sdk/utils/tasks"
+	"github.com/flier/gohs/hyperscan"
 	"github.com/hibiken/asynq"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"gopkg.in/yaml.v3"
 )
 
 var ScanMap sync.Map
 
+// customRulesGuard guards the signatures database of the scanner, read by
+// the running scans and rebuilt when the custom rules change
+var (
+	customRulesGuard   sync.RWMutex
+	customRulesVersion int64
+)
+
 func init() {
 	initSecretScanner()
 }
@@ -194,8 +204,14 @@ func (s SecretScan) StartSecretScan(ctx context.Context, task *asynq.Task) error
 		log.Error().Msg(err.Error())
 	}
 
+	if err := refreshCustomRules(ctx); err != nil {
+		log.Error().Msgf("Cannot load custom rules, scanning without the latest: %v", err)
+	}
+
 	// init secret scan
+	customRulesGuard.RLock()
 	scanResult, err := secretScan.ExtractAndScanFromTar(dir, imageName, scanCtx)
+	customRulesGuard.RUnlock()
 	if err != nil {
 		log.Error().Msg(err.Error())
 		hardErr = err
@@ -226,6 +242,83 @@ func (s SecretScan) StartSecretScan(ctx context.Context, task *asynq.Task) error
 	return nil
 }
 
+// refreshCustomRules rebuilds the signatures database with the custom rules
+// released since the last scan. The database is shared by the scans, so it
+// is only rebuilt when no scan is running, the others scan with the current
+// rules.
+func refreshCustomRules(ctx context.Context) error {
+	customRulesGuard.RLock()
+	current := customRulesVersion
+	customRulesGuard.RUnlock()
+
+	version, content, err := workerUtils.LatestCustomRules(ctx, utils.CustomSecretRulesDir, current)
+	if err != nil || content == nil {
+		return err
+	}
+	custom, err := parseCustomRules(content)
+	if err != nil {
+		return err
+	}
+
+	if !customRulesGuard.TryLock() {
+		log.Info().Msgf("Secret scans running, custom rules version %d loads once they are over", version)
+		return nil
+	}
+	defer customRulesGuard.Unlock()
+	if customRulesVersion == version {
+		return nil
+	}
+
+	bundled := core.GetSession().Config.Signatures
+	signatures := make([]core.ConfigSignature, 0, len(bundled)+len(custom))
+	signatures = append(signatures, bundled...)
+	signatures = append(signatures, custom...)
+	signature.ProcessSignatures(signatures)
+	signature.BuildHsDb()
+
+	customRulesVersion = version
+	log.Info().Msgf("Loaded %d custom secret rules, version %d", len(custom), version)
+	return nil
+}
+
+// ValidateCustomRules checks the custom signatures of a bundle the way the
+// scanners load them: their names must not clash with the bundled
+// signatures and their regexes must compile with hyperscan
+func ValidateCustomRules(content []byte) error {
+	custom, err := parseCustomRules(content)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, sig := range core.GetSession().Config.Signatures {
+		names[sig.Name] = true
+	}
+	for _, sig := range custom {
+		if names[sig.Name] {
+			return fmt.Errorf("secret rule %s: name already used by a bundled rule", sig.Name)
+		}
+		names[sig.Name] = true
+
+		db, err := hyperscan.NewBlockDatabase(hyperscan.NewPattern(sig.Regex, hyperscan.DotAll|hyperscan.SomLeftMost))
+		if err != nil {
+			return fmt.Errorf("secret rule %s: regex: %w", sig.Name, err)
+		}
+		db.Close()
+	}
+	return nil
+}
+
+func parseCustomRules(content []byte) ([]core.ConfigSignature, error) {
+	var custom struct {
+		Signatures []core.ConfigSignature `yaml:"signatures"`
+	}
+	if err := yaml.Unmarshal(content, &custom); err != nil {
+		return nil, err
+	}
+	return custom.Signatures, nil
+}
+
 func initSecretScanner() {
 	var sessionSecretScanner = core.GetSession()
 	// init secret scan builds hs db
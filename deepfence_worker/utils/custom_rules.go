@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deepfence/ThreatMapper/deepfence_utils/directory"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+)
+
+// LatestCustomRules returns the version of the custom rules bundle in dir
+// released to the scanners and its content, or version 0 when none was
+// released. The content is only downloaded when the version differs from
+// current.
+func LatestCustomRules(ctx context.Context, dir string, current int64) (int64, []byte, error) {
+	released, err := releasedCustomRulesVersion(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if released == 0 || released == current {
+		return released, nil, nil
+	}
+
+	content, err := CustomRulesBundle(ctx, dir, released)
+	if err != nil {
+		return 0, nil, err
+	}
+	return released, content, nil
+}
+
+// releasedCustomRulesVersion returns the version of the custom rules
+// validated by ValidateCustomRules, the published versions are released once
+// they load in the scanners
+func releasedCustomRulesVersion(ctx context.Context) (int64, error) {
+	nc, err := directory.Neo4jClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	session := nc.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	tx, err := session.BeginTransaction(neo4j.WithTxTimeout(30 * time.Second))
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Close()
+
+	r, err := tx.Run(`
+		OPTIONAL MATCH (v:CustomRulesVersion{node_id: $id})
+		RETURN COALESCE(v.version, 0)`,
+		map[string]interface{}{"id": utils.CustomRulesVersionID})
+	if err != nil {
+		return 0, err
+	}
+	rec, err := r.Single()
+	if err != nil {
+		return 0, err
+	}
+	return rec.Values[0].(int64), nil
+}
+
+// CustomRulesBundle downloads the bundle of the version from dir
+func CustomRulesBundle(ctx context.Context, dir string, version int64) ([]byte, error) {
+	mc, err := directory.MinioClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range mc.ListFiles(ctx, dir, false, 0, true) {
+		name := path.Base(obj.Key)
+		v, err := strconv.ParseInt(strings.TrimSuffix(name, path.Ext(name)), 10, 64)
+		if err != nil || v != version {
+			continue
+		}
+		return mc.DownloadFileContexts(ctx, path.Join(dir, name), minio.GetObjectOptions{})
+	}
+	return nil, fmt.Errorf("custom rules version %d not found in %s", version, dir)
+}
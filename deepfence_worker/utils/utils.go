@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/deepfence/ThreatMapper/deepfence_server/reporters"
+	"github.com/deepfence/ThreatMapper/deepfence_utils/utils"
 	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
 )
 
@@ -81,6 +83,19 @@ func GetEntityIdFromScanID(scanId, scanType string, tx neo4j.Transaction) (strin
 	return entityId, nil
 }
 
+// SbomFileIndex lists the files in an image, the ones owned by its packages
+// and the other files cataloged in the SBOM
+type SbomFileIndex struct {
+	Owned   []string `json:"owned"`
+	Unowned []string `json:"unowned"`
+}
+
+// GetSbomFileIndexPath returns the path of the list of files in the image
+// generated along with the SBOM of the scan
+func GetSbomFileIndexPath(scanID string) string {
+	return path.Join("/sbom/", "files-"+utils.ScanIDReplacer.Replace(scanID)+".json")
+}
+
 func GetVulnerabilityNodeID(packageName, cveID, entityID string) string {
 	nodeId := packageName + cveID
 	if len(entityID) > 0 {
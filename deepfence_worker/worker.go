@@ -195,6 +195,18 @@ func NewWorker(ns directory.NamespaceID, cfg wtils.Config) (Worker, context.Canc
 
 	worker.AddOneShotHandler(utils.SendNotificationTask, cronjobs.SendNotifications)
 
+	worker.AddOneShotHandler(utils.ScanCoverageNotificationTask, cronjobs.SendScanCoverageNotifications)
+
+	worker.AddOneShotHandler(utils.ComputeDriftTask, cronjobs.ComputeDrift)
+
+	worker.AddOneShotHandler(utils.RecordPodFlowsTask, cronjobs.RecordPodFlows)
+
+	worker.AddOneShotHandler(utils.DeriveAssetTagsTask, cronjobs.DeriveAssetTags)
+
+	worker.AddOneShotHandler(utils.ProgressAgentRolloutsTask, cronjobs.ProgressAgentRollouts)
+
+	worker.AddOneShotHandler(utils.ValidateCustomRulesTask, cronjobs.ValidateCustomRules)
+
 	worker.AddRetryableHandler(utils.ReportGeneratorTask, reports.GenerateReport)
 
 	worker.AddOneShotHandler(utils.ReportCleanUpTask, cronjobs.CleanUpReports)